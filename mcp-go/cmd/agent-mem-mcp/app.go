@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
 	"log/slog"
 	"strings"
 	"time"
@@ -10,6 +11,9 @@ import (
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+// serverVersion MCP serverInfo 与 OpenAPI 文档共用的版本号
+const serverVersion = "2.0.0"
+
 type App struct {
 	settings Settings
 	store    *Store
@@ -17,25 +21,48 @@ type App struct {
 	embedder *Embedder
 	searcher *Searcher
 	metrics  *MetricsCache
+	// searchCache 检索结果缓存，写入/回滚/关系变更时按项目失效
+	searchCache *SearchCache
+	// feedback 检索曝光与反馈，学习召回源权重与记忆加权
+	feedback *FeedbackTracker
+	// changes 跨实例变更通知（Postgres LISTEN/NOTIFY）
+	changes *ChangeFeed
+	// ingestQueue 异步写入 worker 池
+	ingestQueue *IngestQueue
+	// jobs 后台任务 worker 池（前瞻生成、重新向量化、清理与定时蒸馏）
+	jobs *JobRunner
 }
 
 func NewApp(settings Settings) (*App, error) {
-	store, err := NewStore(settings.Storage.DatabaseURL)
+	tokenizer, err := newTokenizer(settings.Lexical)
+	if err != nil {
+		return nil, err
+	}
+	store, err := NewStore(settings.Storage.DatabaseURL, tokenizer)
 	if err != nil {
 		return nil, err
 	}
 	llm := NewLLMClient(settings)
 	embedder := NewEmbedder(settings)
-	searcher := NewSearcher(store, llm, embedder, settings)
-
-	return &App{
-		settings: settings,
-		store:    store,
-		llm:      llm,
-		embedder: embedder,
-		searcher: searcher,
-		metrics:  NewMetricsCache(),
-	}, nil
+	searchCache := NewSearchCache(settings.Search.Cache)
+	feedback := NewFeedbackTracker(store, settings.Feedback)
+	searcher := NewSearcher(store, llm, embedder, settings, searchCache, feedback)
+
+	app := &App{
+		settings:    settings,
+		store:       store,
+		llm:         llm,
+		embedder:    embedder,
+		searcher:    searcher,
+		metrics:     NewMetricsCache(),
+		searchCache: searchCache,
+		feedback:    feedback,
+		changes:     NewChangeFeed(store.pool),
+	}
+	app.changes.OnChange(app.applyRemoteChange)
+	app.ingestQueue = NewIngestQueue(app, settings.IngestQueue)
+	app.jobs = NewJobRunner(app, settings.Jobs)
+	return app, nil
 }
 
 func (a *App) Close() {
@@ -48,17 +75,29 @@ func (a *App) EnsureSchema(ctx context.Context, reset bool) error {
 	if err := a.store.EnsureSchema(ctx, a.settings.Embedding.Dimension, reset); err != nil {
 		return err
 	}
-	return a.store.BackfillProjectIdentity(ctx, a.settings.Project.OwnerID)
+	if err := a.store.BackfillProjectIdentity(ctx, a.settings.Project.OwnerID); err != nil {
+		return err
+	}
+	if count, err := a.store.BackfillFragmentLexemes(ctx); err != nil {
+		return fmt.Errorf("重建片段词项失败: %w", err)
+	} else if count > 0 {
+		log.Printf("[INFO] 片段词项已重建: tokenizer=%s count=%d", lexemeTokenizerName(a.store.tokenizer), count)
+	}
+	return nil
 }
 
 func buildServer(app *App) *mcp.Server {
-	server := mcp.NewServer(&mcp.Implementation{Name: "agent-mem", Version: "2.0.0"}, &mcp.ServerOptions{
-		Logger:    slog.Default(),
-		KeepAlive: 30 * time.Second,
+	subscriptions := newResourceSubscriptions()
+	server := mcp.NewServer(&mcp.Implementation{Name: "agent-mem", Version: serverVersion}, &mcp.ServerOptions{
+		Logger:             slog.Default(),
+		KeepAlive:          30 * time.Second,
+		SubscribeHandler:   subscriptions.subscribe,
+		UnsubscribeHandler: subscriptions.unsubscribe,
+		CompletionHandler:  app.Complete,
 		Instructions: `云记忆中心 MCP 服务 - AI 知识库
 
 ## 核心流程
-1. 检索：mem.search → mem.get（两阶段，先搜索再获取完整内容）
+1. 检索：开始任务时 mem.context 获取打包上下文；细查用 mem.search → mem.get（两阶段，先搜索再获取完整内容）
 2. 写入：mem.ingest_memory（生成结论/方案/决策后立即写入）
 3. 最新：优先 latest 路径；无结果再 mem.timeline
 4. 关联：mem.link 创建记忆间关系，mem.relations 查询关联
@@ -72,47 +111,12 @@ func buildServer(app *App) *mcp.Server {
 - 新结论与旧结论冲突：index_path=["conflict","<主题>","..."] 且 tags=["conflict"]
 - 发现记忆之间有因果/依赖关系时，用 mem.link 显式建立关联
 
-## 何时必须检索（mem.search）
-
-| 触发词 | 场景示例 |
-|:---|:---|
-| 之前/上次/讨论过 | "之前的架构设计是什么" |
-| 项目/系统/模块 | "这个项目的数据库设计" |
-| 为什么/怎么决定 | "为什么选择 pgvector" |
-| 规范/标准/约定 | "代码风格规范是什么" |
-| 最新/现在/最终 | "最新的 API 设计"（优先 latest 路径，无结果再 mem.timeline） |
-| 开始任务前 | 写代码前先查记忆 |
-
-## 何时必须写入（mem.ingest_memory）
-
-| 场景 | content_type | 示例 |
-|:---|:---:|:---|
-| 确定需求 | requirement | 功能边界、业务规则、接口契约 |
-| 设计架构 | plan | 系统架构、技术选型、模块划分 |
-| 架构变更 | plan | 变更原因、新旧对比、影响范围 |
-| 实现方案 | development | 核心代码、数据模型、API设计 |
-| 开发规范 | development | 代码风格、命名约定、目录结构 |
-| 测试策略 | testing | 测试范围、方法、覆盖要求 |
-| Bug记录 | testing | 问题现象、根因、修复方案 |
-| 踩坑总结 | insight | 遇到问题、解决过程、经验教训 |
-| 技术决策 | insight | 选型理由、权衡取舍 |
-| 逆向发现 | discovery | API签名、协议分析、hook技巧 |
-| 市场研究 | research | 交易策略、数据源、市场结论 |
-| 运维记录 | ops | 部署配置、服务器设置、监控 |
-
-content_type 支持任意字符串，推荐用上述类型保持一致性。
-
-## 记忆关联（mem.link / mem.relations）
-- 发现两条记忆有因果关系时用 DERIVED_FROM
-- 发现矛盾时用 CONTRADICTS
-- 同一主题的补充信息用 SUPPORTS
-- 前后步骤用 FOLLOWING
-- 搜索结果自动附带 related_ids
-
-## 记忆蒸馏（mem.distill）
-- 一个项目积累了大量零碎记忆时，调用 mem.distill 浓缩
-- 蒸馏结果带 tags=["distilled","auto-digest"]
-- 适合阶段性总结：逆向完一个APP、策略回测一轮后
+## 常用工作流（MCP prompts，可作为斜杠命令使用）
+- recall-before-task：开始任务前召回相关记忆（用户提到之前/上次/设计/决策/规范/最新时同样先检索）
+- record-decision：记录技术决策与选型理由
+- record-bug-postmortem：记录 Bug 现象、根因与修复
+- summarize-session-into-memory：把会话结论沉淀为记忆
+- resolve-conflict：裁决新旧冲突结论
 
 ## 何时跳过
 - 简单问答（"这行代码什么意思"）
@@ -134,9 +138,39 @@ content_type 支持任意字符串，推荐用上述类型保持一致性。
 **content_type**：支持任意字符串。推荐值：
 requirement/plan/development/testing/insight/discovery/research/ops
 
-**必需参数**：owner_id=personal, content_type, content`,
-	}, func(ctx context.Context, _ *mcp.CallToolRequest, in IngestMemoryInput) (*mcp.CallToolResult, IngestMemoryOutput, error) {
-		output, err := app.IngestMemoryTool(ctx, in)
+**必需参数**：owner_id=personal, content_type, content
+
+**可选**：confirm_conflicts=true 时，与已有记忆冲突（建议替换）会请用户确认替换/保留两者/合并/跳过（需客户端支持 elicitation）
+
+**异步**：async=true 时校验后立即返回 status=queued 与 job_id，摘要、向量化与冲突检测在后台完成（不发起冲突确认）；完成前原文可被关键词检索召回，用 mem.ingest_status 查询结果`,
+	}, func(ctx context.Context, req *mcp.CallToolRequest, in IngestMemoryInput) (*mcp.CallToolResult, IngestMemoryOutput, error) {
+		output, err := app.IngestMemoryTool(toolConflictConfirmer(toolProgress(ctx, req), req), in)
+		return nil, output, err
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "mem.ingest_batch",
+		Description: `批量写入记忆（一次导入多条笔记，向量化合并请求）。
+
+**参数**：
+- items: mem.ingest_memory 入参列表（最多 100 条，逐条查重与仲裁）
+- mode: 可选，best_effort（默认，逐条提交，失败条目不影响其他条目）/ all_or_nothing（任一条失败则整批不写入）
+
+**返回**：results 按请求顺序给出每条的 status（created/updated/duplicate/skipped/error）与 id；批量写入不发起冲突确认`,
+	}, func(ctx context.Context, req *mcp.CallToolRequest, in IngestBatchInput) (*mcp.CallToolResult, IngestBatchOutput, error) {
+		output, err := app.IngestBatch(toolProgress(ctx, req), in)
+		return nil, output, err
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "mem.ingest_status",
+		Description: `查询异步写入（mem.ingest_memory async=true）任务状态。
+
+**参数**：job_ids（最多 50 个）
+
+**返回**：status 为 queued/running/done/failed（未知任务为 not_found）；done 时 result_status 与 memory_id 为最终写入结果（created/updated/duplicate/skipped）；failed 时原文仍保留在 memory_id，last_error 为失败原因`,
+	}, func(ctx context.Context, req *mcp.CallToolRequest, in IngestStatusInput) (*mcp.CallToolResult, IngestStatusResponse, error) {
+		output, err := app.IngestStatus(ctx, in)
 		return nil, output, err
 	})
 
@@ -154,11 +188,17 @@ requirement/plan/development/testing/insight/discovery/research/ops
 
 **参数**：
 - owner_id: 固定 "personal"
-- query: 搜索关键词
+- query: 搜索关键词；支持结构化语法：type:plan tag:auth -tag:deprecated path:dialogs/api/* after:2026-09-01 before:2026-10-01 "精确短语"
 - scope: 可选，过滤 content_type
-- limit: 返回数量，默认 20`,
-	}, func(ctx context.Context, _ *mcp.CallToolRequest, in SearchInput) (*mcp.CallToolResult, SearchResponse, error) {
-		output, err := app.SearchMemories(ctx, in)
+- content_types: 可选，多个 content_type（任一命中）
+- project_keys: 可选，跨多个项目检索
+- tags / exclude_tags: 可选，必须全部包含 / 任一包含即排除
+- since / until: 可选，时间范围（秒级时间戳）
+- mode: 可选，compact（默认）/ ids / full / highlight（片段围绕最佳命中截取，highlights 给出命中区间的 rune 偏移）
+- limit: 返回数量，默认 20
+- cursor: 可选，翻页时传入上一页 metadata.next_cursor（其余参数保持不变）；最多翻到前 200 条，之后 metadata.truncated=true 且不再返回 next_cursor`,
+	}, func(ctx context.Context, req *mcp.CallToolRequest, in SearchInput) (*mcp.CallToolResult, SearchResponse, error) {
+		output, err := app.SearchMemories(toolProgress(ctx, req), in)
 		return nil, output, err
 	})
 
@@ -174,6 +214,58 @@ requirement/plan/development/testing/insight/discovery/research/ops
 		return nil, output, err
 	})
 
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "mem.context",
+		Description: `按任务打包上下文（开始任务时优先调用，替代 mem.search + mem.get 拉取整条记忆）。
+
+返回在 token 预算内去重打包的蒸馏摘要、latest 路径结论、与任务相关的片段（非整条记忆）和未过期前瞻，每条以 [mem:ID] 标注引用。
+
+**参数**：
+- owner_id: 固定 "personal"
+- project_key: 必填
+- task: 任务描述
+- token_budget: 可选，token 预算，默认 2000
+- priority: 可选，打包顺序，如 ["fragment","latest","distilled","foresight"]`,
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, in ContextInput) (*mcp.CallToolResult, ContextPack, error) {
+		output, err := app.BuildContext(ctx, in)
+		return nil, output, err
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "mem.feedback",
+		Description: `标记检索结果是否有用，用于学习召回源权重与记忆排序。
+
+**何时调用**：mem.search 返回的某条记忆明显有用或无关时（mem.get 获取全文会自动记为隐式正反馈）
+
+**参数**：
+- owner_id: 固定 "personal"
+- memory_id: 记忆 ID
+- rating: helpful / unhelpful
+- query: 可选，对应的检索词（缺省时关联该记忆最近一次被检索返回的查询）
+- note: 可选，备注`,
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, in FeedbackInput) (*mcp.CallToolResult, FeedbackOutput, error) {
+		output, err := app.RecordFeedback(ctx, in)
+		return nil, output, err
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "mem.similar",
+		Description: `查找与指定记忆相近的记忆（"更多类似"，无需构造查询词）。
+
+**何时调用**：已通过 mem.get 拿到某条记忆，想找相关资料。
+
+**参数**：
+- owner_id: 固定 "personal"
+- memory_id: 种子记忆 ID（结果自动排除种子及其版本链）
+- project_key / project_keys: 可选，限定项目
+- scope / content_types / axes / index_path: 可选，过滤条件同 mem.search
+- with_tags / with_axes: 可选，按与种子的标签/纵横轴重合度加权
+- limit: 返回数量，默认 10`,
+	}, func(ctx context.Context, _ *mcp.CallToolRequest, in SimilarInput) (*mcp.CallToolResult, SearchResponse, error) {
+		output, err := app.SimilarMemories(ctx, in)
+		return nil, output, err
+	})
+
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "mem.timeline",
 		Description: `按时间线查询最近记忆。
@@ -184,7 +276,8 @@ requirement/plan/development/testing/insight/discovery/research/ops
 - owner_id: 固定 "personal"
 - project_key: 可选，限定项目
 - days: 查询天数，默认 7
-- limit: 返回数量，默认 20`,
+- limit: 返回数量，默认 20
+- cursor: 可选，上一页 metadata.next_cursor`,
 	}, func(ctx context.Context, _ *mcp.CallToolRequest, in TimelineInput) (*mcp.CallToolResult, TimelineResponse, error) {
 		output, err := app.Timeline(ctx, in)
 		return nil, output, err
@@ -192,7 +285,7 @@ requirement/plan/development/testing/insight/discovery/research/ops
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "mem.list_projects",
-		Description: "列出所有项目及其记忆统计。参数：owner_id=personal",
+		Description: "列出所有项目及其记忆统计。参数：owner_id=personal；翻页传 cursor=上一页 metadata.next_cursor",
 	}, func(ctx context.Context, _ *mcp.CallToolRequest, in ListProjectsInput) (*mcp.CallToolResult, ListProjectsResponse, error) {
 		output, err := app.ListProjects(ctx, in)
 		return nil, output, err
@@ -219,7 +312,7 @@ requirement/plan/development/testing/insight/discovery/research/ops
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "mem.arbitration_history",
-		Description: "查询仲裁历史（记忆更新/替换的决策记录：REPLACE/KEEP_BOTH/SKIP）",
+		Description: "查询仲裁历史（记忆更新/替换的决策记录：REPLACE/KEEP_BOTH/SKIP），翻页传 cursor=上一页 metadata.next_cursor",
 	}, func(ctx context.Context, _ *mcp.CallToolRequest, in ArbitrationHistoryInput) (*mcp.CallToolResult, ArbitrationHistoryResponse, error) {
 		output, err := app.ArbitrationHistory(ctx, in)
 		return nil, output, err
@@ -259,7 +352,8 @@ requirement/plan/development/testing/insight/discovery/research/ops
 
 **direction**：outgoing / incoming / both（默认 both）
 **relation_type**：可选，过滤关系类型
-**limit**：返回数量，默认 20`,
+**limit**：返回数量，默认 20
+**cursor**：可选，上一页 metadata.next_cursor`,
 	}, func(ctx context.Context, _ *mcp.CallToolRequest, in RelationsInput) (*mcp.CallToolResult, RelationsResponse, error) {
 		output, err := app.QueryRelations(ctx, in)
 		return nil, output, err
@@ -274,6 +368,7 @@ requirement/plan/development/testing/insight/discovery/research/ops
 - memory_id: 可选，查询某条记忆的前瞻
 - project_key: 可选，查询某项目的前瞻
 - limit: 返回数量，默认 20
+- cursor: 可选，上一页 metadata.next_cursor
 
 memory_id 或 project_key 至少提供一个。`,
 	}, func(ctx context.Context, _ *mcp.CallToolRequest, in ForesightInput) (*mcp.CallToolResult, ForesightResponse, error) {
@@ -285,19 +380,25 @@ memory_id 或 project_key 至少提供一个。`,
 		Name: "mem.distill",
 		Description: `记忆蒸馏（工作记忆→长期知识浓缩）。
 
-**何时调用**：项目产生大量零碎记忆后，蒸馏为精华长期知识。
+**何时调用**：项目产生大量零碎记忆后，蒸馏为精华长期知识。也可在 distill.policies 中配置自动蒸馏。
+
+**增量**：只蒸馏尚未被蒸馏覆盖的记忆，与上一版蒸馏结果合并后经仲裁替换 distill/<project_key>/latest（保留历史版本）；
+结果以 DERIVED_FROM 关系指向源记忆，返回 source_ids。
 
 **参数**：
 - owner_id: 固定 "personal"
 - project_key: 必填，指定蒸馏哪个项目
 - scope: 可选，限定 content_type（如 "development"）
-- since_days: 可选，蒸馏最近 N 天的记忆，默认 7
+- since_days: 可选，蒸馏最近 N 天内未蒸馏的记忆，默认 7
 - target_content_type: 可选，蒸馏结果的类型，默认 "insight"`,
-	}, func(ctx context.Context, _ *mcp.CallToolRequest, in DistillInput) (*mcp.CallToolResult, DistillOutput, error) {
-		output, err := app.DistillMemories(ctx, in)
+	}, func(ctx context.Context, req *mcp.CallToolRequest, in DistillInput) (*mcp.CallToolResult, DistillOutput, error) {
+		output, err := app.DistillMemories(toolProgress(ctx, req), in)
 		return nil, output, err
 	})
 
+	registerResources(server, app)
+	registerPrompts(server, app)
+	registerResourceNotifications(server, app, subscriptions)
 	return server
 }
 
@@ -306,6 +407,9 @@ func (a *App) IngestMemoryTool(ctx context.Context, input IngestMemoryInput) (In
 	if err != nil {
 		return IngestMemoryOutput{}, err
 	}
+	if normalized.Async != nil && *normalized.Async {
+		return a.enqueueIngest(ctx, normalized)
+	}
 	result, err := a.IngestMemory(ctx, normalized)
 	if err != nil {
 		return IngestMemoryOutput{}, err
@@ -318,14 +422,38 @@ func (a *App) IngestMemoryTool(ctx context.Context, input IngestMemoryInput) (In
 }
 
 func (a *App) SearchMemories(ctx context.Context, input SearchInput) (SearchResponse, error) {
+	ownerID, err := resolveOwnerID(input.OwnerID, a.settings)
+	if err != nil {
+		return SearchResponse{}, err
+	}
+	return a.searchWithOptions(ctx, input, a.userSearchOptions(ctx, ownerID))
+}
+
+// searchWithOptions 检索参数的规范化、查询语法合并与校验，按 opts 控制排序、缓存与曝光记录（见 searchOptions）；
+// 用户检索（SearchMemories）、服务内部检索与离线评估共用
+func (a *App) searchWithOptions(ctx context.Context, input SearchInput, opts searchOptions) (SearchResponse, error) {
 	normalized, err := normalizeSearchInput(input, a.settings)
 	if err != nil {
 		return SearchResponse{}, err
 	}
+	normalized, err = applySearchQuerySyntax(normalized)
+	if err != nil {
+		return SearchResponse{}, err
+	}
 	if err := validateSearchInput(normalized); err != nil {
 		return SearchResponse{}, err
 	}
-	return a.searcher.Search(ctx, normalized)
+	return a.searcher.search(ctx, normalized, opts)
+}
+
+// userSearchOptions 用户发起的检索：使用学习后的排序、走缓存并记录曝光
+func (a *App) userSearchOptions(ctx context.Context, ownerID string) searchOptions {
+	return searchOptions{ranking: a.feedback.Model(ctx, ownerID), useCache: true, recordImpressions: true}
+}
+
+// internalSearchOptions 服务内部检索（非用户发起）：与用户检索相同的排序与缓存，但不记录曝光，避免污染反馈学习
+func (a *App) internalSearchOptions(ctx context.Context, ownerID string) searchOptions {
+	return searchOptions{ranking: a.feedback.Model(ctx, ownerID), useCache: true}
 }
 
 func (a *App) GetMemories(ctx context.Context, ids []string) (GetMemoriesResponse, error) {
@@ -353,11 +481,15 @@ func (a *App) GetMemories(ctx context.Context, ids []string) (GetMemoriesRespons
 	}
 
 	results := make([]MemoryRecord, 0, len(ids))
+	fetched := make([]string, 0, len(ids))
 	for _, id := range ids {
 		if row, ok := rowMap[id]; ok {
 			results = append(results, row)
+			fetched = append(fetched, id)
 		}
 	}
+	// 检索后获取全文视为隐式正反馈
+	a.feedback.RecordImplicit(ctx, fetched)
 
 	return GetMemoriesResponse{Results: results}, nil
 }
@@ -370,8 +502,14 @@ func (a *App) Timeline(ctx context.Context, input TimelineInput) (TimelineRespon
 	if err := validateTimelineInput(normalized); err != nil {
 		return TimelineResponse{}, err
 	}
+	after, err := decodeCursor(normalized.Cursor, cursorKindTimeline)
+	if err != nil {
+		return TimelineResponse{}, err
+	}
 
 	sinceTs := time.Now().UTC().Add(-time.Duration(normalized.Days) * 24 * time.Hour).Unix()
+	// 多取一条用于判断是否还有下一页
+	var rows []TimelineRecord
 	if normalized.ProjectKey != "" {
 		projectID, err := a.store.FindProjectIDByKey(ctx, normalized.OwnerID, normalized.ProjectKey)
 		if err != nil {
@@ -380,31 +518,22 @@ func (a *App) Timeline(ctx context.Context, input TimelineInput) (TimelineRespon
 		if projectID == "" {
 			return TimelineResponse{Results: []TimelineItem{}, Metadata: SearchMetadata{Total: 0, Returned: 0}}, nil
 		}
-		rows, err := a.store.FetchTimeline(ctx, projectID, sinceTs, normalized.Limit)
+		rows, err = a.store.FetchTimeline(ctx, projectID, sinceTs, after, normalized.Limit+1)
 		if err != nil {
 			return TimelineResponse{}, err
 		}
-		results := make([]TimelineItem, 0, len(rows))
-		for _, row := range rows {
-			results = append(results, TimelineItem{
-				ID:          row.ID,
-				ContentType: row.ContentType,
-				Summary:     row.Summary,
-				Ts:          row.Ts,
-			})
+	} else {
+		rows, err = a.store.FetchTimelineByOwner(ctx, normalized.OwnerID, sinceTs, after, normalized.Limit+1)
+		if err != nil {
+			return TimelineResponse{}, err
 		}
-		return TimelineResponse{
-			Results: results,
-			Metadata: SearchMetadata{
-				Total:    len(results),
-				Returned: len(results),
-			},
-		}, nil
 	}
 
-	rows, err := a.store.FetchTimelineByOwner(ctx, normalized.OwnerID, sinceTs, normalized.Limit)
-	if err != nil {
-		return TimelineResponse{}, err
+	nextCursor := ""
+	if len(rows) > normalized.Limit {
+		rows = rows[:normalized.Limit]
+		last := rows[len(rows)-1]
+		nextCursor = encodeCursor(pageCursor{Kind: cursorKindTimeline, Ts: last.Ts, Key: last.ID})
 	}
 	results := make([]TimelineItem, 0, len(rows))
 	for _, row := range rows {
@@ -418,8 +547,9 @@ func (a *App) Timeline(ctx context.Context, input TimelineInput) (TimelineRespon
 	return TimelineResponse{
 		Results: results,
 		Metadata: SearchMetadata{
-			Total:    len(results),
-			Returned: len(results),
+			Total:      len(results),
+			Returned:   len(results),
+			NextCursor: nextCursor,
 		},
 	}, nil
 }
@@ -432,15 +562,26 @@ func (a *App) ListProjects(ctx context.Context, input ListProjectsInput) (ListPr
 	if err := validateListProjectsInput(normalized); err != nil {
 		return ListProjectsResponse{}, err
 	}
-	rows, err := a.store.ListProjects(ctx, normalized.OwnerID, normalized.Limit)
+	after, err := decodeCursor(normalized.Cursor, cursorKindProjects)
 	if err != nil {
 		return ListProjectsResponse{}, err
 	}
+	rows, err := a.store.ListProjects(ctx, normalized.OwnerID, after, normalized.Limit+1)
+	if err != nil {
+		return ListProjectsResponse{}, err
+	}
+	nextCursor := ""
+	if len(rows) > normalized.Limit {
+		rows = rows[:normalized.Limit]
+		last := rows[len(rows)-1]
+		nextCursor = encodeCursor(pageCursor{Kind: cursorKindProjects, Ts: last.LatestTs, Key: last.ProjectKey})
+	}
 	return ListProjectsResponse{
 		Results: rows,
 		Metadata: SearchMetadata{
-			Total:    len(rows),
-			Returned: len(rows),
+			Total:      len(rows),
+			Returned:   len(rows),
+			NextCursor: nextCursor,
 		},
 	}, nil
 }
@@ -568,6 +709,8 @@ func (a *App) LinkMemories(ctx context.Context, input LinkInput) (LinkOutput, er
 	if err != nil {
 		return LinkOutput{}, fmt.Errorf("创建关系失败: %w", err)
 	}
+	// 关系影响检索结果的 related_ids，两端记忆所在项目的缓存均需失效
+	a.invalidateSearchCacheForMemories(ctx, sourceID, targetID)
 	return LinkOutput{ID: id, Status: "created"}, nil
 }
 
@@ -601,19 +744,34 @@ func (a *App) QueryRelations(ctx context.Context, input RelationsInput) (Relatio
 		limit = 100
 	}
 
-	relations, err := a.store.FetchRelations(ctx, memoryID, direction, relationType, limit)
+	after, err := decodeCursor(input.Cursor, cursorKindRelations)
+	if err != nil {
+		return RelationsResponse{}, err
+	}
+	afterID := int64(0)
+	if after != nil {
+		afterID = after.ID
+	}
+
+	relations, err := a.store.FetchRelations(ctx, memoryID, direction, relationType, afterID, limit+1)
 	if err != nil {
 		return RelationsResponse{}, err
 	}
 	if relations == nil {
 		relations = []RelationRecord{}
 	}
+	nextCursor := ""
+	if len(relations) > limit {
+		relations = relations[:limit]
+		nextCursor = encodeCursor(pageCursor{Kind: cursorKindRelations, ID: relations[len(relations)-1].ID})
+	}
 
 	return RelationsResponse{
 		Relations: relations,
 		Metadata: SearchMetadata{
-			Total:    len(relations),
-			Returned: len(relations),
+			Total:      len(relations),
+			Returned:   len(relations),
+			NextCursor: nextCursor,
 		},
 	}, nil
 }
@@ -637,25 +795,39 @@ func (a *App) ArbitrationHistory(ctx context.Context, input ArbitrationHistoryIn
 		limit = 100
 	}
 
+	after, err := decodeCursor(input.Cursor, cursorKindArbitration)
+	if err != nil {
+		return ArbitrationHistoryResponse{}, err
+	}
+	afterID := int64(0)
+	if after != nil {
+		afterID = after.ID
+	}
+
 	projectID := ""
 	if input.ProjectKey != "" {
-		var err error
 		projectID, err = a.store.FindProjectIDByKey(ctx, ownerID, input.ProjectKey)
 		if err != nil {
 			return ArbitrationHistoryResponse{}, err
 		}
 	}
 
-	records, err := a.store.FetchArbitrationHistory(ctx, ownerID, input.MemoryID, projectID, limit)
+	records, err := a.store.FetchArbitrationHistory(ctx, ownerID, input.MemoryID, projectID, afterID, limit+1)
 	if err != nil {
 		return ArbitrationHistoryResponse{}, err
 	}
+	nextCursor := ""
+	if len(records) > limit {
+		records = records[:limit]
+		nextCursor = encodeCursor(pageCursor{Kind: cursorKindArbitration, ID: records[len(records)-1].ID})
+	}
 
 	return ArbitrationHistoryResponse{
 		Results: records,
 		Metadata: SearchMetadata{
-			Total:    len(records),
-			Returned: len(records),
+			Total:      len(records),
+			Returned:   len(records),
+			NextCursor: nextCursor,
 		},
 	}, nil
 }
@@ -687,7 +859,7 @@ func (a *App) MemoryChain(ctx context.Context, input MemoryChainInput) (MemoryCh
 	}
 
 	// 获取相关仲裁记录
-	arbitrations, err := a.store.FetchArbitrationHistory(ctx, ownerID, memoryID, "", 50)
+	arbitrations, err := a.store.FetchArbitrationHistory(ctx, ownerID, memoryID, "", 0, 50)
 	if err != nil {
 		return MemoryChainResponse{}, err
 	}
@@ -719,9 +891,9 @@ func (a *App) Rollback(ctx context.Context, input RollbackInput) (RollbackOutput
 		return RollbackOutput{Status: "failed", Message: "仲裁记录不存在"}, nil
 	}
 
-	// 只有 REPLACE 操作才能回滚
-	if arb.Action != "REPLACE" {
-		return RollbackOutput{Status: "failed", Message: "只有 REPLACE 操作可以回滚"}, nil
+	// 只有 REPLACE / MERGE 操作才能回滚
+	if arb.Action != string(ArbitrateReplace) && arb.Action != string(ArbitrateMerge) {
+		return RollbackOutput{Status: "failed", Message: "只有 REPLACE / MERGE 操作可以回滚"}, nil
 	}
 
 	memoryID := arb.CandidateMemoryID
@@ -735,10 +907,19 @@ func (a *App) Rollback(ctx context.Context, input RollbackInput) (RollbackOutput
 		return RollbackOutput{Status: "failed", Message: "没有可恢复的历史版本"}, nil
 	}
 
+	// 恢复前记录当前路径：新旧路径下的资源都需要通知
+	project, currentPath, _ := a.store.FetchMemoryLocation(ctx, memoryID)
+
 	// 执行恢复
 	if err := a.store.RestoreMemoryFromVersion(ctx, version); err != nil {
 		return RollbackOutput{Status: "failed", Message: err.Error()}, nil
 	}
+	a.invalidateSearchCache(ownerID, version.ProjectID)
+	event := a.newChangeEvent(changeKindRollback, ownerID, project)
+	event.ProjectID = version.ProjectID
+	event.MemoryIDs = []string{memoryID}
+	event.IndexPaths = [][]string{currentPath, version.IndexPath}
+	a.publishChange(ctx, event)
 
 	return RollbackOutput{
 		Status:           "success",
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
+)
+
+// arbitrationActions 仲裁评估的标签集合（混淆矩阵行列顺序）
+var arbitrationActions = []ArbitrateResult{ArbitrateReplace, ArbitrateKeepBoth, ArbitrateSkip}
+
+// arbitrationPair 一条带标签的新旧摘要对；similarity 为向量相似度（可选），
+// 低于阈值的样本在写入流程中不会进入 LLM 仲裁，按 KEEP_BOTH 计
+type arbitrationPair struct {
+	ID         string   `yaml:"id" json:"id"`
+	OldSummary string   `yaml:"old_summary" json:"old_summary"`
+	NewSummary string   `yaml:"new_summary" json:"new_summary"`
+	Expected   string   `yaml:"expected" json:"expected"`
+	Similarity *float64 `yaml:"similarity" json:"similarity,omitempty"`
+	DecidedBy  string   `yaml:"decided_by" json:"decided_by,omitempty"`
+}
+
+type arbitrationActionStats struct {
+	Action    string  `json:"action"`
+	Support   int     `json:"support"`
+	Predicted int     `json:"predicted"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+// arbitrationReport 某一相似度阈值下的评估结果，Confusion[期望][预测] 为样本数
+type arbitrationReport struct {
+	Model     string                    `json:"model"`
+	Threshold float64                   `json:"threshold"`
+	Pairs     int                       `json:"pairs"`
+	Errors    int                       `json:"errors"`
+	Gated     int                       `json:"gated"`
+	Accuracy  float64                   `json:"accuracy"`
+	MacroF1   float64                   `json:"macro_f1"`
+	Actions   []arbitrationActionStats  `json:"actions"`
+	Confusion map[string]map[string]int `json:"confusion"`
+}
+
+// loadArbitrationPairs 按扩展名读取 YAML（pairs: [...]）或 JSONL（每行一条）
+func loadArbitrationPairs(path string) ([]arbitrationPair, error) {
+	file, err := os.Open(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("读取样本失败: %w", err)
+	}
+	defer file.Close()
+	var pairs []arbitrationPair
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		pairs, err = parseArbitrationJSONL(file)
+	default:
+		var doc struct {
+			Pairs []arbitrationPair `yaml:"pairs"`
+		}
+		err = yaml.NewDecoder(file).Decode(&doc)
+		pairs = doc.Pairs
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析样本失败: %w", err)
+	}
+	for idx := range pairs {
+		if err := normalizeArbitrationPair(&pairs[idx], idx); err != nil {
+			return nil, err
+		}
+	}
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("样本为空")
+	}
+	return pairs, nil
+}
+
+func parseArbitrationJSONL(reader io.Reader) ([]arbitrationPair, error) {
+	var pairs []arbitrationPair
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" || strings.HasPrefix(raw, "#") {
+			continue
+		}
+		var pair arbitrationPair
+		if err := json.Unmarshal([]byte(raw), &pair); err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", line, err)
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, scanner.Err()
+}
+
+func normalizeArbitrationPair(pair *arbitrationPair, idx int) error {
+	if pair.ID == "" {
+		pair.ID = fmt.Sprintf("pair-%d", idx+1)
+	}
+	pair.Expected = strings.ToUpper(strings.TrimSpace(pair.Expected))
+	if !isArbitrationAction(pair.Expected) {
+		return fmt.Errorf("样本 %s 的 expected 无效: %q（可选 REPLACE/KEEP_BOTH/SKIP）", pair.ID, pair.Expected)
+	}
+	if strings.TrimSpace(pair.OldSummary) == "" || strings.TrimSpace(pair.NewSummary) == "" {
+		return fmt.Errorf("样本 %s 缺少 old_summary 或 new_summary", pair.ID)
+	}
+	return nil
+}
+
+func isArbitrationAction(action string) bool {
+	for _, candidate := range arbitrationActions {
+		if string(candidate) == action {
+			return true
+		}
+	}
+	return false
+}
+
+// arbitrationPairsFromHistory 将 memory_arbitrations 记录转为样本：默认只以人工确认（decided_by=human）的判定为标签，
+// 避免模型判定与模型自身对比；includeModel 时纳入模型判定，同一新旧摘要对以人工判定优先
+func arbitrationPairsFromHistory(records []ArbitrationRecord, includeModel bool) []arbitrationPair {
+	var pairs []arbitrationPair
+	seen := make(map[[2]string]int)
+	for _, record := range records {
+		if strings.TrimSpace(record.OldSummary) == "" || strings.TrimSpace(record.NewSummary) == "" || !isArbitrationAction(record.Action) {
+			continue
+		}
+		decidedBy := arbitrationDecider(record.DecidedBy)
+		if decidedBy != arbitrationDecidedByHuman && !includeModel {
+			continue
+		}
+		similarity := record.Similarity
+		pair := arbitrationPair{ID: fmt.Sprintf("arb-%d", record.ID), OldSummary: record.OldSummary, NewSummary: record.NewSummary, Expected: record.Action, Similarity: &similarity, DecidedBy: decidedBy}
+		key := [2]string{record.OldSummary, record.NewSummary}
+		if idx, ok := seen[key]; ok {
+			if pairs[idx].DecidedBy != arbitrationDecidedByHuman && decidedBy == arbitrationDecidedByHuman {
+				pairs[idx] = pair
+			}
+			continue
+		}
+		seen[key] = len(pairs)
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+// parseThresholds 解析逗号分隔的阈值列表，为空时使用配置中的 semantic_similarity_threshold
+func parseThresholds(raw string, fallback float64) ([]float64, error) {
+	if strings.TrimSpace(raw) == "" {
+		return []float64{semanticUpdateThreshold(fallback)}, nil
+	}
+	var thresholds []float64
+	for _, part := range strings.Split(raw, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || value <= 0 || value > 1 {
+			return nil, fmt.Errorf("阈值无效: %q（取值 (0,1]）", part)
+		}
+		thresholds = append(thresholds, value)
+	}
+	return thresholds, nil
+}
+
+// scoreArbitration 按阈值门控后统计混淆矩阵与各动作的 precision/recall/F1；
+// predictions 为模型对每条样本的判定（与阈值无关，扫描多个阈值时只需调用一次模型）
+func scoreArbitration(pairs []arbitrationPair, predictions []ArbitrateResult, threshold float64) arbitrationReport {
+	report := arbitrationReport{Threshold: threshold, Pairs: len(pairs), Confusion: map[string]map[string]int{}}
+	for _, action := range arbitrationActions {
+		report.Confusion[string(action)] = map[string]int{}
+	}
+	correct := 0
+	for idx, pair := range pairs {
+		predicted := predictions[idx]
+		if pair.Similarity != nil && *pair.Similarity < threshold {
+			predicted = ArbitrateKeepBoth
+			report.Gated++
+		}
+		report.Confusion[pair.Expected][string(predicted)]++
+		if pair.Expected == string(predicted) {
+			correct++
+		}
+	}
+	if len(pairs) > 0 {
+		report.Accuracy = float64(correct) / float64(len(pairs))
+	}
+	var f1Sum float64
+	for _, action := range arbitrationActions {
+		name := string(action)
+		stats := arbitrationActionStats{Action: name}
+		for _, expected := range arbitrationActions {
+			stats.Predicted += report.Confusion[string(expected)][name]
+			stats.Support += report.Confusion[name][string(expected)]
+		}
+		hits := report.Confusion[name][name]
+		if stats.Predicted > 0 {
+			stats.Precision = float64(hits) / float64(stats.Predicted)
+		}
+		if stats.Support > 0 {
+			stats.Recall = float64(hits) / float64(stats.Support)
+		}
+		if stats.Precision+stats.Recall > 0 {
+			stats.F1 = 2 * stats.Precision * stats.Recall / (stats.Precision + stats.Recall)
+		}
+		f1Sum += stats.F1
+		report.Actions = append(report.Actions, stats)
+	}
+	report.MacroF1 = f1Sum / float64(len(arbitrationActions))
+	return report
+}
+
+// runArbitrationEvalCommand agent-mem eval arbitration：评估仲裁模型/提示词在带标签样本上的表现
+func runArbitrationEvalCommand(args []string) int {
+	fs := flag.NewFlagSet("eval arbitration", flag.ContinueOnError)
+	var (
+		configPath   = fs.String("config", "", "配置文件路径")
+		pairsPath    = fs.String("pairs", "", "带标签样本路径（.yaml/.yml 或 .jsonl）")
+		replay       = fs.Bool("replay", false, "从 memory_arbitrations 读取样本（默认仅以人工确认的判定为标签）")
+		includeModel = fs.Bool("include-model", false, "replay 同时纳入模型判定的记录（同一样本人工判定优先；-export 时默认纳入）")
+		owner        = fs.String("owner", "", "replay 的 owner_id（默认取配置）")
+		projectKey   = fs.String("project-key", "", "replay 仅读取该项目")
+		limit        = fs.Int("limit", 500, "replay 最多读取的记录数")
+		exportPath   = fs.String("export", "", "将 replay 得到的样本写为 JSONL 后退出（用于人工标注）")
+		model        = fs.String("model", "", "覆盖 llm.model_arbitrate")
+		promptPath   = fs.String("prompt-file", "", "覆盖仲裁提示词模板（{old}/{new} 占位）")
+		thresholds   = fs.String("thresholds", "", "逗号分隔的相似度阈值列表，逐个报告（默认取 semantic_similarity_threshold）")
+		live         = fs.Bool("live", false, "调用配置中的真实 LLM（默认使用 mock 规则，保证可复现）")
+		concurrency  = fs.Int("concurrency", 4, "并发调用模型的样本数")
+		asJSON       = fs.Bool("json", false, "以 JSON 输出报告")
+		minAccuracy  = fs.Float64("min-accuracy", 0, "任一阈值下准确率低于该值时以非零状态退出（用于 CI）")
+	)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (*pairsPath == "") == !*replay {
+		fmt.Fprintln(os.Stderr, "用法: agent-mem eval arbitration (-pairs pairs.jsonl | -replay [-owner id] [-project-key key] [-include-model]) [-model m] [-prompt-file p.txt] [-thresholds 0.8,0.85,0.9]")
+		return 2
+	}
+	settings, err := loadSettings(*configPath)
+	if err != nil {
+		log.Printf("[CRITICAL] 配置加载失败: %v", err)
+		return 1
+	}
+	if *model != "" {
+		settings.LLM.ModelArbitrate = *model
+	}
+	if *promptPath != "" {
+		data, err := os.ReadFile(expandHome(*promptPath))
+		if err != nil {
+			log.Printf("[CRITICAL] 读取提示词失败: %v", err)
+			return 1
+		}
+		settings.LLM.PromptArbitrate = string(data)
+	}
+	if !*live {
+		os.Setenv("AGENT_MEM_LLM_MODE", "mock")
+	}
+	levels, err := parseThresholds(*thresholds, settings.Versioning.SemanticSimilarityThreshold)
+	if err != nil {
+		log.Printf("[CRITICAL] %v", err)
+		return 2
+	}
+
+	ctx := context.Background()
+	var pairs []arbitrationPair
+	if *replay {
+		pairs, err = replayArbitrationPairs(ctx, settings, *owner, *projectKey, *limit, *includeModel || *exportPath != "")
+	} else {
+		pairs, err = loadArbitrationPairs(*pairsPath)
+	}
+	if err != nil {
+		log.Printf("[CRITICAL] %v", err)
+		return 1
+	}
+	if *exportPath != "" {
+		if err := exportArbitrationPairs(*exportPath, pairs); err != nil {
+			log.Printf("[CRITICAL] 导出样本失败: %v", err)
+			return 1
+		}
+		log.Printf("[INFO] 已导出 %d 条样本到 %s", len(pairs), *exportPath)
+		return 0
+	}
+	if len(pairs) == 0 {
+		log.Printf("[CRITICAL] 没有可评估的样本")
+		return 1
+	}
+
+	llm := NewLLMClient(settings)
+	predictions, errCount := predictArbitration(ctx, llm, pairs, *concurrency)
+	modelName := "mock"
+	if !llm.mock {
+		modelName = settings.LLM.ModelArbitrate
+	}
+	var reports []arbitrationReport
+	for _, threshold := range levels {
+		report := scoreArbitration(pairs, predictions, threshold)
+		report.Model = modelName
+		report.Errors = errCount
+		reports = append(reports, report)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(reports)
+	} else {
+		for _, report := range reports {
+			writeArbitrationReport(os.Stdout, report)
+		}
+	}
+	for _, report := range reports {
+		if report.Accuracy < *minAccuracy {
+			log.Printf("[WARN] 阈值 %.2f 下准确率 %.3f 低于 %.3f", report.Threshold, report.Accuracy, *minAccuracy)
+			return 1
+		}
+	}
+	return 0
+}
+
+func replayArbitrationPairs(ctx context.Context, settings Settings, ownerID, projectKey string, limit int, includeModel bool) ([]arbitrationPair, error) {
+	app, err := NewApp(settings)
+	if err != nil {
+		return nil, err
+	}
+	defer app.Close()
+	if ownerID == "" {
+		ownerID = settings.Project.OwnerID
+	}
+	if ownerID == "" {
+		ownerID = defaultOwnerID
+	}
+	projectID := ""
+	if projectKey != "" {
+		projectID, err = app.store.FindProjectIDByKey(ctx, ownerID, projectKey)
+		if err != nil {
+			return nil, fmt.Errorf("项目不存在: %s", projectKey)
+		}
+	}
+	if limit <= 0 {
+		limit = 500
+	}
+	records, err := app.store.FetchArbitrationHistory(ctx, ownerID, "", projectID, 0, limit)
+	if err != nil {
+		return nil, err
+	}
+	pairs := arbitrationPairsFromHistory(records, includeModel)
+	if len(pairs) == 0 && !includeModel && len(records) > 0 {
+		log.Printf("[WARN] 最近 %d 条仲裁记录中没有人工确认的判定；可用 -export 导出后人工标注，或加 -include-model 对比模型判定", len(records))
+	}
+	return pairs, nil
+}
+
+// predictArbitration 并发调用仲裁模型；调用失败的样本按线上行为记为 KEEP_BOTH 并计入错误数
+func predictArbitration(ctx context.Context, llm *LLMClient, pairs []arbitrationPair, concurrency int) ([]ArbitrateResult, int) {
+	predictions := make([]ArbitrateResult, len(pairs))
+	failed := make([]bool, len(pairs))
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(max(1, concurrency))
+	for idx, pair := range pairs {
+		group.Go(func() error {
+			action, err := llm.arbitrate(groupCtx, pair.NewSummary, pair.OldSummary)
+			if err != nil {
+				log.Printf("[WARN] 样本 %s 仲裁失败: %v", pair.ID, err)
+				failed[idx] = true
+			}
+			predictions[idx] = action
+			return nil
+		})
+	}
+	_ = group.Wait()
+	errCount := 0
+	for _, f := range failed {
+		if f {
+			errCount++
+		}
+	}
+	return predictions, errCount
+}
+
+func exportArbitrationPairs(path string, pairs []arbitrationPair) error {
+	file, err := os.Create(expandHome(path))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	encoder.SetEscapeHTML(false)
+	for _, pair := range pairs {
+		if err := encoder.Encode(pair); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeArbitrationReport(w io.Writer, report arbitrationReport) {
+	fmt.Fprintf(w, "model=%s threshold=%.2f pairs=%d gated=%d errors=%d accuracy=%.3f macro_f1=%.3f\n",
+		report.Model, report.Threshold, report.Pairs, report.Gated, report.Errors, report.Accuracy, report.MacroF1)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "action\tsupport\tpredicted\tprecision\trecall\tf1")
+	for _, stats := range report.Actions {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.3f\t%.3f\t%.3f\n", stats.Action, stats.Support, stats.Predicted, stats.Precision, stats.Recall, stats.F1)
+	}
+	tw.Flush()
+
+	// 混淆矩阵：行为期望标签，列为预测
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "expected \\ predicted"
+	for _, action := range arbitrationActions {
+		header += "\t" + string(action)
+	}
+	fmt.Fprintln(tw, header)
+	for _, expected := range arbitrationActions {
+		row := string(expected)
+		for _, predicted := range arbitrationActions {
+			row += fmt.Sprintf("\t%d", report.Confusion[string(expected)][string(predicted)])
+		}
+		fmt.Fprintln(tw, row)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
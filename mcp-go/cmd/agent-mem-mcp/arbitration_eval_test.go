@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestArbitrationEvalMockPairs(t *testing.T) {
+	os.Setenv("AGENT_MEM_LLM_MODE", "mock")
+	defer os.Unsetenv("AGENT_MEM_LLM_MODE")
+
+	pairs, err := loadArbitrationPairs("testdata/arbitration_pairs.jsonl")
+	if err != nil {
+		t.Fatalf("加载样本失败: %v", err)
+	}
+	llm := NewLLMClient(defaultSettings())
+	predictions, errCount := predictArbitration(t.Context(), llm, pairs, 2)
+	if errCount != 0 {
+		t.Fatalf("mock 仲裁不应出错: %d", errCount)
+	}
+
+	// 阈值 0.85：low 样本被门控为 KEEP_BOTH，与期望 REPLACE 不符
+	report := scoreArbitration(pairs, predictions, 0.85)
+	if report.Gated != 1 || report.Confusion["REPLACE"]["KEEP_BOTH"] != 1 {
+		t.Fatalf("阈值门控错误: %+v", report)
+	}
+	if report.Accuracy != 0.75 {
+		t.Fatalf("准确率错误: %v", report.Accuracy)
+	}
+	replace := report.Actions[0]
+	if replace.Action != "REPLACE" || replace.Support != 2 || replace.Precision != 1 || replace.Recall != 0.5 {
+		t.Fatalf("REPLACE 统计错误: %+v", replace)
+	}
+
+	// 降低阈值后全部进入仲裁
+	if relaxed := scoreArbitration(pairs, predictions, 0.75); relaxed.Gated != 0 || relaxed.Accuracy != 1 || relaxed.MacroF1 != 1 {
+		t.Fatalf("放宽阈值后应全部正确: %+v", relaxed)
+	}
+}
+
+func TestArbitrationPairValidation(t *testing.T) {
+	if _, err := parseArbitrationJSONL(strings.NewReader(`{"old_summary":"a"`)); err == nil {
+		t.Fatalf("非法 JSON 应报错")
+	}
+	pair := arbitrationPair{OldSummary: "a", NewSummary: "b", Expected: "merge"}
+	if err := normalizeArbitrationPair(&pair, 0); err == nil {
+		t.Fatalf("非法标签应报错")
+	}
+	pair.Expected = " keep_both "
+	if err := normalizeArbitrationPair(&pair, 0); err != nil || pair.Expected != "KEEP_BOTH" || pair.ID != "pair-1" {
+		t.Fatalf("标签规范化错误: %+v %v", pair, err)
+	}
+
+	history := []ArbitrationRecord{
+		{ID: 9, OldSummary: "旧", NewSummary: "新", Action: "REPLACE", Similarity: 0.9, DecidedBy: arbitrationDecidedByModel},
+		{ID: 7, OldSummary: "旧", NewSummary: "新", Action: "KEEP_BOTH", Similarity: 0.9, DecidedBy: arbitrationDecidedByHuman},
+		{ID: 8, OldSummary: "", NewSummary: "新", Action: "SKIP", DecidedBy: arbitrationDecidedByHuman},
+		{ID: 6, OldSummary: "旧2", NewSummary: "新2", Action: "SKIP"},
+	}
+	replayed := arbitrationPairsFromHistory(history, false)
+	if len(replayed) != 1 || replayed[0].ID != "arb-7" || replayed[0].Expected != "KEEP_BOTH" || *replayed[0].Similarity != 0.9 {
+		t.Fatalf("默认应只以人工判定为标签: %+v", replayed)
+	}
+	replayed = arbitrationPairsFromHistory(history, true)
+	if len(replayed) != 2 || replayed[0].ID != "arb-7" || replayed[0].DecidedBy != arbitrationDecidedByHuman {
+		t.Fatalf("同一样本应以人工判定优先: %+v", replayed)
+	}
+	if replayed[1].ID != "arb-6" || replayed[1].DecidedBy != arbitrationDecidedByModel {
+		t.Fatalf("未标注决策方的记录应视为模型判定: %+v", replayed[1])
+	}
+
+	if prompt := buildArbitratePrompt("旧={old} 新={new}", "A", "B"); prompt != "旧=A 新=B" {
+		t.Fatalf("提示词模板替换错误: %s", prompt)
+	}
+	if _, err := parseThresholds("0.8,abc", 0.85); err == nil {
+		t.Fatalf("非法阈值应报错")
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// 变更通知：写入在事务内 pg_notify，提交后广播到共享同一数据库的所有实例；
+// 各实例 LISTEN 后失效本地检索缓存，并向订阅了相关资源的会话推送 notifications/resources/updated
+const (
+	changeChannel = "agent_mem_changes"
+
+	// NOTIFY 负载上限约 8000 字节，超出部分不逐条通知（项目级资源仍会通知）
+	changeMaxMemoryIDs  = 50
+	changeMaxIndexPaths = 20
+
+	changeReconnectMin = time.Second
+	changeReconnectMax = 30 * time.Second
+)
+
+// 变更类型
+const (
+	changeKindIngest   = "ingest"
+	changeKindReplace  = "replace"
+	changeKindRollback = "rollback"
+	changeKindDelete   = "delete"
+	// changeKindResync 监听断线重连后本地生成：期间的通知可能丢失，需全量失效
+	changeKindResync = "resync"
+)
+
+type changeEvent struct {
+	Kind           string     `json:"kind"`
+	Origin         string     `json:"origin"`
+	OwnerID        string     `json:"owner_id"`
+	ProjectID      string     `json:"project_id,omitempty"`
+	ProjectKey     string     `json:"project_key,omitempty"`
+	ProjectCreated bool       `json:"project_created,omitempty"`
+	MemoryIDs      []string   `json:"memory_ids,omitempty"`
+	IndexPaths     [][]string `json:"index_paths,omitempty"`
+}
+
+func encodeChangeEvent(event changeEvent) (string, error) {
+	if len(event.MemoryIDs) > changeMaxMemoryIDs {
+		event.MemoryIDs = event.MemoryIDs[:changeMaxMemoryIDs]
+	}
+	var paths [][]string
+	for _, path := range event.IndexPaths {
+		if len(path) > 0 && len(paths) < changeMaxIndexPaths {
+			paths = append(paths, path)
+		}
+	}
+	event.IndexPaths = paths
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func decodeChangeEvent(payload string) (changeEvent, error) {
+	var event changeEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return changeEvent{}, fmt.Errorf("变更通知解析失败: %w", err)
+	}
+	if event.Kind == "" {
+		return changeEvent{}, fmt.Errorf("变更通知缺少 kind")
+	}
+	return event, nil
+}
+
+// publishChangeTx 在事务内发送通知：事务回滚则通知不会送达
+func publishChangeTx(ctx context.Context, tx pgxTx, event changeEvent) error {
+	payload, err := encodeChangeEvent(event)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `SELECT pg_notify($1, $2)`, changeChannel, payload)
+	return err
+}
+
+// ChangeFeed 监听变更通知并分发给本进程的处理函数（含本实例发出的通知）
+type ChangeFeed struct {
+	pool *pgxpool.Pool
+	// origin 本实例标识，用于区分通知是否来自其他实例
+	origin string
+
+	mu          sync.RWMutex
+	handlers    []changeSubscriber
+	nextHandler uint64
+}
+
+type changeSubscriber struct {
+	id      uint64
+	handler func(changeEvent)
+}
+
+func NewChangeFeed(pool *pgxpool.Pool) *ChangeFeed {
+	return &ChangeFeed{pool: pool, origin: newID()}
+}
+
+func (f *ChangeFeed) OnChange(handler func(changeEvent)) {
+	f.Subscribe(handler)
+}
+
+// Subscribe 与 OnChange 相同，返回的函数用于取消订阅（gRPC Watch 等随连接结束的订阅）
+func (f *ChangeFeed) Subscribe(handler func(changeEvent)) (cancel func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextHandler++
+	id := f.nextHandler
+	f.handlers = append(f.handlers, changeSubscriber{id: id, handler: handler})
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.handlers = slices.DeleteFunc(f.handlers, func(sub changeSubscriber) bool { return sub.id == id })
+	}
+}
+
+func (f *ChangeFeed) dispatch(event changeEvent) {
+	f.mu.RLock()
+	handlers := append([]changeSubscriber{}, f.handlers...)
+	f.mu.RUnlock()
+	for _, sub := range handlers {
+		sub.handler(event)
+	}
+}
+
+// Run 持续监听直到 ctx 取消；连接断开后指数退避重连，重连成功后分发 resync
+func (f *ChangeFeed) Run(ctx context.Context) {
+	backoff := changeReconnectMin
+	connected := false
+	for {
+		err := f.listen(ctx, func() {
+			if connected {
+				f.dispatch(changeEvent{Kind: changeKindResync, Origin: f.origin})
+			}
+			connected = true
+			backoff = changeReconnectMin
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("[WARN] 变更通知监听中断，%s 后重连: %v", backoff, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, changeReconnectMax)
+	}
+}
+
+func (f *ChangeFeed) listen(ctx context.Context, onListening func()) error {
+	pooled, err := f.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// 监听连接长期占用，脱离连接池，退出时直接关闭
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+changeChannel); err != nil {
+		return err
+	}
+	onListening()
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		event, err := decodeChangeEvent(notification.Payload)
+		if err != nil {
+			log.Printf("[WARN] %v", err)
+			continue
+		}
+		f.dispatch(event)
+	}
+}
+
+func (a *App) newChangeEvent(kind, ownerID string, project ProjectRecord) changeEvent {
+	return changeEvent{
+		Kind:           kind,
+		Origin:         a.changes.origin,
+		OwnerID:        ownerID,
+		ProjectID:      project.ID,
+		ProjectKey:     project.ProjectKey,
+		ProjectCreated: project.Created,
+	}
+}
+
+// instanceID 本实例标识，也用作 worker 领取任务时的锁持有者前缀
+func (a *App) instanceID() string {
+	if a.changes == nil {
+		return newID()
+	}
+	return a.changes.origin
+}
+
+// publishChange 事务外发送通知（best-effort，失败只记录日志）
+func (a *App) publishChange(ctx context.Context, event changeEvent) {
+	if err := publishChangeTx(ctx, a.store.pool, event); err != nil {
+		log.Printf("[WARN] 变更通知发送失败: kind=%s project=%s err=%v", event.Kind, event.ProjectKey, err)
+	}
+}
+
+// applyRemoteChange 其他实例的写入：失效本地检索缓存（本实例写入已同步失效）
+func (a *App) applyRemoteChange(event changeEvent) {
+	if a.searchCache == nil {
+		return
+	}
+	if event.Kind == changeKindResync {
+		a.searchCache.Clear()
+		return
+	}
+	if event.Origin == a.changes.origin {
+		return
+	}
+	a.searchCache.InvalidateProject(event.OwnerID, event.ProjectID)
+}
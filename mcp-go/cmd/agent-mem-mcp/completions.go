@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// completion/complete：为 prompt 与资源模板参数补全已有的项目、标签、content_type、纵横轴取值与 index_path，
+// 让 agent 复用已有取值（避免 "pgvector"/"PGVector"/"pg-vector" 这类近似重复）
+const (
+	// 单次补全最多返回的取值数（协议上限 100）
+	completionMaxValues = 100
+	// 参与匹配的候选上限
+	completionCandidateLimit = 500
+)
+
+// recommendedContentTypes 推荐的 content_type，库中尚无记录时也参与补全
+var recommendedContentTypes = []string{"requirement", "plan", "development", "testing", "insight", "discovery", "research", "ops"}
+
+// Complete 按参数名选择候选来源；context.arguments 中已填的 project_key 用于限定项目范围
+func (a *App) Complete(ctx context.Context, req *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	params := req.Params
+	owner := a.resourceOwner()
+	projectID := ""
+	if params.Context != nil {
+		if projectKey := strings.TrimSpace(params.Context.Arguments["project_key"]); projectKey != "" && params.Argument.Name != "project_key" {
+			id, err := a.store.FindProjectIDByKey(ctx, owner, projectKey)
+			if err != nil {
+				return nil, err
+			}
+			projectID = id
+		}
+	}
+	isResource := params.Ref != nil && params.Ref.Type == "ref/resource"
+
+	value := params.Argument.Value
+	prefix := ""
+	var candidates []AxisCount
+	var err error
+	switch name := params.Argument.Name; name {
+	case "project_key":
+		candidates, err = a.projectCompletionCandidates(ctx, owner)
+	case "tags", "tag":
+		// 逗号分隔的多个标签：只补全最后一个，保留已输入部分
+		if idx := strings.LastIndex(value, ","); idx >= 0 {
+			prefix = value[:idx+1] + " "
+			value = value[idx+1:]
+		}
+		candidates, err = a.store.FetchTagCounts(ctx, projectID, owner, completionCandidateLimit, nil)
+	case "content_type":
+		candidates, err = a.store.FetchContentTypeCounts(ctx, projectID, owner, completionCandidateLimit)
+		candidates = mergeCompletionCandidates(candidates, recommendedContentTypes)
+	case "topic":
+		candidates, err = a.topicCompletionCandidates(ctx, projectID, owner)
+	case "index_path":
+		candidates, err = a.indexPathCompletionCandidates(ctx, projectID, owner)
+	default:
+		if isAxisAllowed(name) {
+			candidates, err = a.store.FetchAxisCounts(ctx, projectID, owner, name, completionCandidateLimit, nil)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	matched := rankCompletions(candidates, strings.TrimSpace(value))
+	result := &mcp.CompleteResult{Completion: mcp.CompletionResultDetails{Values: []string{}, Total: len(matched)}}
+	if len(matched) > completionMaxValues {
+		matched = matched[:completionMaxValues]
+		result.Completion.HasMore = true
+	}
+	for _, item := range matched {
+		if isResource && params.Argument.Name == "index_path" {
+			item = escapeIndexPathValue(item)
+		}
+		result.Completion.Values = append(result.Completion.Values, prefix+item)
+	}
+	return result, nil
+}
+
+func (a *App) projectCompletionCandidates(ctx context.Context, owner string) ([]AxisCount, error) {
+	projects, err := a.store.ListProjects(ctx, owner, nil, completionCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+	// 项目按最近更新排序，以倒序名次作为权重，最近活跃的项目排在前面
+	candidates := make([]AxisCount, 0, len(projects))
+	for idx, project := range projects {
+		candidates = append(candidates, AxisCount{Value: project.ProjectKey, Count: len(projects) - idx})
+	}
+	return candidates, nil
+}
+
+// topicCompletionCandidates 主题来自标签与 index_path 中的主题段（["dialogs","<主题>",...] 的第二段）
+func (a *App) topicCompletionCandidates(ctx context.Context, projectID, owner string) ([]AxisCount, error) {
+	tags, err := a.store.FetchTagCounts(ctx, projectID, owner, completionCandidateLimit, nil)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := a.store.FetchIndexPaths(ctx, projectID, owner, completionCandidateLimit, nil)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, tag := range tags {
+		counts[tag.Value] += tag.Count
+	}
+	for _, path := range paths {
+		if len(path.Path) >= 2 && path.Path[1] != "latest" {
+			counts[path.Path[1]] += path.Count
+		}
+	}
+	return completionCountsToCandidates(counts), nil
+}
+
+// indexPathCompletionCandidates 每条路径的所有前缀都可作为取值，段之间以 / 连接
+func (a *App) indexPathCompletionCandidates(ctx context.Context, projectID, owner string) ([]AxisCount, error) {
+	paths, err := a.store.FetchIndexPaths(ctx, projectID, owner, completionCandidateLimit, nil)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, path := range paths {
+		segments := make([]string, 0, len(path.Path))
+		for _, segment := range path.Path {
+			segments = append(segments, segment)
+			counts[strings.Join(segments, "/")] += path.Count
+		}
+	}
+	return completionCountsToCandidates(counts), nil
+}
+
+// escapeIndexPathValue 资源模板 {+index_path} 不会再编码，需逐段百分号编码（与 pathResourceURI 一致）
+func escapeIndexPathValue(value string) string {
+	segments := strings.Split(value, "/")
+	for idx, segment := range segments {
+		segments[idx] = escapeURISegment(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func completionCountsToCandidates(counts map[string]int) []AxisCount {
+	candidates := make([]AxisCount, 0, len(counts))
+	for value, count := range counts {
+		candidates = append(candidates, AxisCount{Value: value, Count: count})
+	}
+	return candidates
+}
+
+// mergeCompletionCandidates 追加尚未出现的默认取值（计数为 0，排在已有取值之后）
+func mergeCompletionCandidates(candidates []AxisCount, defaults []string) []AxisCount {
+	seen := map[string]bool{}
+	for _, item := range candidates {
+		seen[item.Value] = true
+	}
+	for _, value := range defaults {
+		if !seen[value] {
+			candidates = append(candidates, AxisCount{Value: value})
+		}
+	}
+	return candidates
+}
+
+// normalizeCompletionKey 忽略大小写与分隔符："PGVector"、"pg-vector"、"pg_vector" 归一为 "pgvector"
+func normalizeCompletionKey(value string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(value) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// completionMatchTier 匹配等级：0 前缀、1 子串、2 子序列（模糊），-1 不匹配
+func completionMatchTier(candidate, input string) int {
+	if input == "" {
+		return 0
+	}
+	key := normalizeCompletionKey(candidate)
+	want := normalizeCompletionKey(input)
+	if want == "" {
+		// 输入只有分隔符（如 "/"）时按原样做前缀匹配
+		if strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(input)) {
+			return 0
+		}
+		return -1
+	}
+	switch {
+	case strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(input)), strings.HasPrefix(key, want):
+		return 0
+	case strings.Contains(key, want):
+		return 1
+	case isSubsequence(key, want):
+		return 2
+	default:
+		return -1
+	}
+}
+
+func isSubsequence(value, sub string) bool {
+	runes := []rune(sub)
+	idx := 0
+	for _, r := range value {
+		if idx < len(runes) && r == runes[idx] {
+			idx++
+		}
+	}
+	return idx == len(runes)
+}
+
+// rankCompletions 按匹配等级、使用次数、取值排序
+func rankCompletions(candidates []AxisCount, input string) []string {
+	type ranked struct {
+		AxisCount
+		tier int
+	}
+	var matched []ranked
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		if candidate.Value == "" || seen[candidate.Value] {
+			continue
+		}
+		seen[candidate.Value] = true
+		if tier := completionMatchTier(candidate.Value, input); tier >= 0 {
+			matched = append(matched, ranked{AxisCount: candidate, tier: tier})
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].tier != matched[j].tier {
+			return matched[i].tier < matched[j].tier
+		}
+		if matched[i].Count != matched[j].Count {
+			return matched[i].Count > matched[j].Count
+		}
+		return matched[i].Value < matched[j].Value
+	})
+	values := make([]string, 0, len(matched))
+	for _, item := range matched {
+		values = append(values, item.Value)
+	}
+	return values
+}
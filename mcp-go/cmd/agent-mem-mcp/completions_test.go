@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestRankCompletions(t *testing.T) {
+	candidates := []AxisCount{
+		{Value: "postgres", Count: 9},
+		{Value: "pgvector", Count: 5},
+		{Value: "PGVector", Count: 1},
+		{Value: "go-pg-vector-client", Count: 7},
+		{Value: "vector-db", Count: 3},
+		{Value: "redis", Count: 2},
+	}
+	got := rankCompletions(candidates, "pg-vec")
+	want := []string{"pgvector", "PGVector", "go-pg-vector-client"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("前缀/子串匹配排序错误: %v", got)
+	}
+	// 同为子序列模糊匹配时按使用次数排序
+	got = rankCompletions(candidates, "pgvr")
+	if !reflect.DeepEqual(got, []string{"go-pg-vector-client", "pgvector", "PGVector"}) {
+		t.Fatalf("模糊匹配排序错误: %v", got)
+	}
+	if got = rankCompletions(candidates, ""); len(got) != len(candidates) || got[0] != "postgres" {
+		t.Fatalf("空输入应按使用次数返回全部: %v", got)
+	}
+	if got = rankCompletions(candidates, "mysql"); len(got) != 0 {
+		t.Fatalf("不应匹配: %v", got)
+	}
+}
+
+func TestCompletionCandidatesHelpers(t *testing.T) {
+	merged := mergeCompletionCandidates([]AxisCount{{Value: "plan", Count: 3}, {Value: "adr", Count: 1}}, recommendedContentTypes)
+	if got := rankCompletions(merged, "p"); !reflect.DeepEqual(got, []string{"plan", "development", "ops"}) {
+		t.Fatalf("content_type 补全错误: %v", got)
+	}
+	if got := escapeIndexPathValue("dialogs/数据库/latest"); got != "dialogs/%E6%95%B0%E6%8D%AE%E5%BA%93/latest" {
+		t.Fatalf("index_path 编码错误: %s", got)
+	}
+}
+
+func TestCompletionCapability(t *testing.T) {
+	server := buildServer(&App{settings: defaultSettings()})
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("服务端连接失败: %v", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("客户端连接失败: %v", err)
+	}
+	defer session.Close()
+
+	if session.InitializeResult().Capabilities.Completions == nil {
+		t.Fatalf("应声明 completions 能力")
+	}
+	// 无候选来源的参数（自由文本）返回空列表，不访问数据库
+	result, err := session.Complete(ctx, &mcp.CompleteParams{
+		Ref:      &mcp.CompleteReference{Type: "ref/prompt", Name: promptRecallBeforeTask},
+		Argument: mcp.CompleteParamsArgument{Name: "task", Value: "实现"},
+	})
+	if err != nil {
+		t.Fatalf("补全失败: %v", err)
+	}
+	if len(result.Completion.Values) != 0 {
+		t.Fatalf("自由文本参数不应有补全: %v", result.Completion.Values)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// 冲突确认：仲裁建议 REPLACE 且开启 confirm_conflicts 时，通过 MCP elicitation 请用户在提交前
+// 选择替换/保留两者/合并/跳过；用户的选择以 decided_by=human 记入 memory_arbitrations
+const (
+	arbitrationDecidedByModel = "model"
+	arbitrationDecidedByHuman = "human"
+)
+
+// conflictChoices elicitation 表单中的选项（取值 → 仲裁动作）
+var conflictChoices = []struct {
+	Value  string
+	Action ArbitrateResult
+	Label  string
+}{
+	{"replace", ArbitrateReplace, "替换旧记忆（保留历史版本，可回滚）"},
+	{"keep_both", ArbitrateKeepBoth, "保留两者（新建记忆并关联）"},
+	{"merge", ArbitrateMerge, "合并（新旧内容合并后替换旧记忆）"},
+	{"skip", ArbitrateSkip, "跳过（不写入新内容）"},
+}
+
+type conflictPrompt struct {
+	CandidateID string
+	OldSummary  string
+	NewSummary  string
+	Similarity  float64
+	Suggested   ArbitrateResult
+}
+
+// conflictConfirmer 返回用户选择的动作；客户端拒绝、取消或出错时 ok 为 false，沿用模型决策
+type conflictConfirmer func(ctx context.Context, prompt conflictPrompt) (action ArbitrateResult, ok bool)
+
+type conflictConfirmerKey struct{}
+
+func withConflictConfirmer(ctx context.Context, confirm conflictConfirmer) context.Context {
+	return context.WithValue(ctx, conflictConfirmerKey{}, confirm)
+}
+
+// toolConflictConfirmer 客户端声明 elicitation 能力时返回可向用户确认冲突的 ctx
+func toolConflictConfirmer(ctx context.Context, req *mcp.CallToolRequest) context.Context {
+	if req == nil || req.Session == nil {
+		return ctx
+	}
+	params := req.Session.InitializeParams()
+	if params == nil || params.Capabilities == nil || params.Capabilities.Elicitation == nil {
+		return ctx
+	}
+	session := req.Session
+	return withConflictConfirmer(ctx, func(ctx context.Context, prompt conflictPrompt) (ArbitrateResult, bool) {
+		result, err := session.Elicit(ctx, conflictElicitParams(prompt))
+		if err != nil {
+			log.Printf("[WARN] 冲突确认失败，沿用模型仲裁结果: %v", err)
+			return "", false
+		}
+		return parseConflictChoice(result)
+	})
+}
+
+// confirmConflict ctx 中没有确认方式（未开启或客户端不支持 elicitation）时 ok 为 false
+func confirmConflict(ctx context.Context, prompt conflictPrompt) (ArbitrateResult, bool) {
+	confirm, ok := ctx.Value(conflictConfirmerKey{}).(conflictConfirmer)
+	if !ok || confirm == nil {
+		return "", false
+	}
+	return confirm(ctx, prompt)
+}
+
+func conflictElicitParams(prompt conflictPrompt) *mcp.ElicitParams {
+	var message strings.Builder
+	fmt.Fprintf(&message, "新内容与已有记忆 %s 高度相似（相似度 %.2f），模型建议 %s。\n\n", prompt.CandidateID, prompt.Similarity, prompt.Suggested)
+	fmt.Fprintf(&message, "【已有记忆摘要】\n%s\n\n【新内容摘要】\n%s\n\n请选择处理方式：\n", prompt.OldSummary, prompt.NewSummary)
+	values := make([]string, 0, len(conflictChoices))
+	suggested := ""
+	for _, choice := range conflictChoices {
+		fmt.Fprintf(&message, "- %s：%s\n", choice.Value, choice.Label)
+		values = append(values, choice.Value)
+		if choice.Action == prompt.Suggested {
+			suggested = choice.Value
+		}
+	}
+	decision := map[string]any{
+		"type":        "string",
+		"title":       "处理方式",
+		"description": "replace / keep_both / merge / skip",
+		"enum":        values,
+	}
+	if suggested != "" {
+		decision["default"] = suggested
+	}
+	return &mcp.ElicitParams{
+		Message: strings.TrimSpace(message.String()),
+		RequestedSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"decision": decision},
+			"required":   []string{"decision"},
+		},
+	}
+}
+
+func parseConflictChoice(result *mcp.ElicitResult) (ArbitrateResult, bool) {
+	if result == nil || result.Action != "accept" {
+		return "", false
+	}
+	value, _ := result.Content["decision"].(string)
+	for _, choice := range conflictChoices {
+		if choice.Value == strings.TrimSpace(value) {
+			return choice.Action, true
+		}
+	}
+	return "", false
+}
+
+// arbitrationDecider 未标注决策方的仲裁记录视为模型决策
+func arbitrationDecider(decidedBy string) string {
+	if decidedBy == "" {
+		return arbitrationDecidedByModel
+	}
+	return decidedBy
+}
+
+// confirmConflictsEnabled 请求参数优先，未指定时使用 versioning.confirm_conflicts
+func (a *App) confirmConflictsEnabled(input IngestMemoryInput) bool {
+	if input.ConfirmConflicts != nil {
+		return *input.ConfirmConflicts
+	}
+	return a.settings.Versioning.ConfirmConflicts
+}
+
+// mergedMemory 用户选择合并时写入的内容：旧内容在前、新内容在后，标签取并集，索引路径缺省沿用旧记忆
+type mergedMemory struct {
+	Content   string
+	Tags      []string
+	IndexPath []string
+}
+
+func (a *App) mergeWithCandidate(ctx context.Context, candidateID, content string, tags, indexPath []string) (mergedMemory, error) {
+	rows, err := a.store.FetchMemories(ctx, []string{candidateID})
+	if err != nil {
+		return mergedMemory{}, err
+	}
+	if len(rows) == 0 {
+		return mergedMemory{}, fmt.Errorf("待合并的记忆不存在: %s", candidateID)
+	}
+	return mergeMemoryContent(rows[0], content, tags, indexPath), nil
+}
+
+func mergeMemoryContent(old MemoryRow, content string, tags, indexPath []string) mergedMemory {
+	merged := mergedMemory{
+		Content:   strings.TrimSpace(old.Content) + "\n\n" + strings.TrimSpace(content),
+		Tags:      normalizeTags(append(append([]string{}, old.Tags...), tags...)),
+		IndexPath: indexPath,
+	}
+	if len(merged.IndexPath) == 0 {
+		merged.IndexPath = old.IndexPath
+	}
+	return merged
+}
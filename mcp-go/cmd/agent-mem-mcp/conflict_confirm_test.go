@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestParseConflictChoice(t *testing.T) {
+	cases := []struct {
+		result *mcp.ElicitResult
+		want   ArbitrateResult
+		ok     bool
+	}{
+		{&mcp.ElicitResult{Action: "accept", Content: map[string]any{"decision": "merge"}}, ArbitrateMerge, true},
+		{&mcp.ElicitResult{Action: "accept", Content: map[string]any{"decision": "keep_both"}}, ArbitrateKeepBoth, true},
+		{&mcp.ElicitResult{Action: "accept", Content: map[string]any{"decision": "unknown"}}, "", false},
+		{&mcp.ElicitResult{Action: "decline"}, "", false},
+		{&mcp.ElicitResult{Action: "cancel"}, "", false},
+		{nil, "", false},
+	}
+	for idx, tc := range cases {
+		got, ok := parseConflictChoice(tc.result)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("用例 %d 解析错误: %s %v", idx, got, ok)
+		}
+	}
+}
+
+func TestMergeMemoryContent(t *testing.T) {
+	old := MemoryRow{Content: "旧结论：使用 HNSW 索引", Tags: []string{"pgvector", "index"}, IndexPath: []string{"dialogs", "db"}}
+	merged := mergeMemoryContent(old, "新结论：ef_search 调到 100", []string{"index", "tuning"}, nil)
+	if merged.Content != "旧结论：使用 HNSW 索引\n\n新结论：ef_search 调到 100" {
+		t.Fatalf("合并内容错误: %q", merged.Content)
+	}
+	if !reflect.DeepEqual(merged.Tags, []string{"pgvector", "index", "tuning"}) {
+		t.Fatalf("标签应取并集: %v", merged.Tags)
+	}
+	if !reflect.DeepEqual(merged.IndexPath, []string{"dialogs", "db"}) {
+		t.Fatalf("缺省索引路径应沿用旧记忆: %v", merged.IndexPath)
+	}
+}
+
+func TestConflictConfirmationViaElicitation(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	type confirmOutput struct {
+		Action string `json:"action"`
+		OK     bool   `json:"ok"`
+	}
+	mcp.AddTool(server, &mcp.Tool{Name: "confirm"}, func(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, confirmOutput, error) {
+		action, ok := confirmConflict(toolConflictConfirmer(ctx, req), conflictPrompt{
+			CandidateID: "mem_old",
+			OldSummary:  "旧摘要",
+			NewSummary:  "新摘要",
+			Similarity:  0.93,
+			Suggested:   ArbitrateReplace,
+		})
+		return nil, confirmOutput{Action: string(action), OK: ok}, nil
+	})
+
+	call := func(opts *mcp.ClientOptions) confirmOutput {
+		t.Helper()
+		clientTransport, serverTransport := mcp.NewInMemoryTransports()
+		ctx := context.Background()
+		serverSession, err := server.Connect(ctx, serverTransport, nil)
+		if err != nil {
+			t.Fatalf("服务端连接失败: %v", err)
+		}
+		defer serverSession.Close()
+		session, err := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, opts).Connect(ctx, clientTransport, nil)
+		if err != nil {
+			t.Fatalf("客户端连接失败: %v", err)
+		}
+		defer session.Close()
+		result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "confirm"})
+		if err != nil {
+			t.Fatalf("调用失败: %v", err)
+		}
+		out, _ := result.StructuredContent.(map[string]any)
+		action, _ := out["action"].(string)
+		ok, _ := out["ok"].(bool)
+		return confirmOutput{Action: action, OK: ok}
+	}
+
+	var message string
+	got := call(&mcp.ClientOptions{
+		ElicitationHandler: func(_ context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+			message = req.Params.Message
+			return &mcp.ElicitResult{Action: "accept", Content: map[string]any{"decision": "merge"}}, nil
+		},
+	})
+	if got.Action != string(ArbitrateMerge) || !got.OK {
+		t.Fatalf("应采用用户选择: %+v", got)
+	}
+	for _, want := range []string{"mem_old", "0.93", "旧摘要", "新摘要"} {
+		if !strings.Contains(message, want) {
+			t.Fatalf("确认信息缺少 %q: %s", want, message)
+		}
+	}
+
+	// 客户端不支持 elicitation 时沿用模型决策
+	if got := call(nil); got.OK {
+		t.Fatalf("不支持 elicitation 时不应确认: %+v", got)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// mem.context 条目类型（即打包优先级策略中的名称）
+const (
+	contextKindDistilled = "distilled"
+	contextKindLatest    = "latest"
+	contextKindFragment  = "fragment"
+	contextKindForesight = "foresight"
+)
+
+const (
+	// 每类候选的读取上限（最终数量由预算决定）
+	contextDistilledCandidates = 3
+	contextLatestCandidates    = 8
+	contextForesightCandidates = 5
+	// 剩余预算不足该值时不再截断放入，避免只剩半句话的条目
+	contextMinItemTokens = 32
+)
+
+var contextKindTitles = map[string]string{
+	contextKindDistilled: "蒸馏摘要",
+	contextKindLatest:    "最新结论",
+	contextKindFragment:  "相关片段",
+	contextKindForesight: "待跟进前瞻",
+}
+
+func validateContextPriority(priority []string) error {
+	seen := map[string]bool{}
+	for _, kind := range priority {
+		if _, ok := contextKindTitles[kind]; !ok || seen[kind] {
+			return newValidationError("invalid_request", "ERR_INVALID_PRIORITY", "priority 仅支持 distilled/latest/fragment/foresight 且不能重复", 400)
+		}
+		seen[kind] = true
+	}
+	return nil
+}
+
+// BuildContext 按任务打包上下文：蒸馏摘要、latest 路径条目、与任务相关的片段（而非整条记忆）与未过期前瞻，
+// 去重后按优先级与各类配额装入 token 预算
+func (a *App) BuildContext(ctx context.Context, input ContextInput) (ContextPack, error) {
+	normalized, err := normalizeContextInput(input, a.settings)
+	if err != nil {
+		return ContextPack{}, err
+	}
+	if err := validateContextInput(normalized, a.settings.Context); err != nil {
+		return ContextPack{}, err
+	}
+	pack := ContextPack{Task: normalized.Task, TokenBudget: normalized.TokenBudget, Priority: *normalized.Priority, Items: []ContextItem{}, Citations: []string{}}
+
+	projectID, err := a.store.FindProjectIDByKey(ctx, normalized.OwnerID, normalized.ProjectKey)
+	if err != nil {
+		return ContextPack{}, err
+	}
+	if projectID == "" {
+		return pack, nil
+	}
+
+	candidates := map[string][]ContextItem{}
+	distilled, err := a.store.FetchDistilledSummaries(ctx, projectID, contextDistilledCandidates)
+	if err != nil {
+		return ContextPack{}, err
+	}
+	candidates[contextKindDistilled] = summaryContextItems(contextKindDistilled, distilled)
+	latest, err := a.store.FetchLatestPathSummaries(ctx, projectID, contextLatestCandidates)
+	if err != nil {
+		return ContextPack{}, err
+	}
+	candidates[contextKindLatest] = summaryContextItems(contextKindLatest, latest)
+	fragments, err := a.contextFragments(ctx, normalized)
+	if err != nil {
+		return ContextPack{}, err
+	}
+	candidates[contextKindFragment] = fragments
+	foresights, err := a.store.FetchForesightsByProject(ctx, projectID, nil, contextForesightCandidates)
+	if err != nil {
+		return ContextPack{}, err
+	}
+	for _, row := range foresights {
+		candidates[contextKindForesight] = append(candidates[contextKindForesight], ContextItem{Kind: contextKindForesight, MemoryID: row.SourceMemoryID, Text: row.Prediction})
+	}
+
+	cpt := a.settings.Chunking.ApproxCharsPerToken
+	pack.Items, pack.Dropped = packContext(candidates, pack.Priority, pack.TokenBudget, a.settings.Context.Quotas, a.settings.Context.MaxItemTokens, cpt)
+	for _, item := range pack.Items {
+		pack.TokensUsed += item.Tokens
+		if !stringInSlice(pack.Citations, item.MemoryID) {
+			pack.Citations = append(pack.Citations, item.MemoryID)
+		}
+	}
+	pack.Text = renderContextPack(pack.Items, pack.Priority)
+	return pack, nil
+}
+
+func summaryContextItems(kind string, rows []MemorySummaryRow) []ContextItem {
+	var items []ContextItem
+	for _, row := range rows {
+		items = append(items, ContextItem{Kind: kind, MemoryID: row.ID, Text: row.Summary})
+	}
+	return items
+}
+
+// contextFragments 以任务描述检索相关记忆，再从每条记忆中挑选命中词项最多的若干片段
+func (a *App) contextFragments(ctx context.Context, input ContextInput) ([]ContextItem, error) {
+	// 任务描述是自由文本：去掉引号，避免被当作未闭合的短语语法
+	query := strings.TrimSpace(strings.ReplaceAll(input.Task, `"`, " "))
+	resp, err := a.searchWithOptions(ctx, SearchInput{
+		OwnerID:    input.OwnerID,
+		ProjectKey: input.ProjectKey,
+		Query:      query,
+		Limit:      a.settings.Context.SearchLimit,
+		Mode:       strPtr("ids"),
+	}, a.internalSearchOptions(ctx, input.OwnerID))
+	if err != nil {
+		return nil, err
+	}
+	ids := resultIDs(resp.Results)
+	rows, err := a.store.FetchFragmentsByMemoryIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	terms := highlightTerms(a.store.tokenizer, query, nil)
+	return selectContextFragments(ids, rows, terms, a.settings.Context.FragmentsPerMemory), nil
+}
+
+// selectContextFragments 按检索排名遍历记忆，每条记忆取命中不同词项最多的片段（同分取靠前的片段）；
+// 无词项命中（纯向量召回）时取首个片段
+func selectContextFragments(rankedIDs []string, rows []FragmentRow, terms []string, perMemory int) []ContextItem {
+	byMemory := map[string][]FragmentRow{}
+	for _, row := range rows {
+		byMemory[row.MemoryID] = append(byMemory[row.MemoryID], row)
+	}
+	var items []ContextItem
+	for _, id := range rankedIDs {
+		fragments := byMemory[id]
+		if len(fragments) == 0 {
+			continue
+		}
+		type scored struct {
+			row   FragmentRow
+			score int
+		}
+		var ranked []scored
+		for _, row := range fragments {
+			distinct := map[int]bool{}
+			for _, match := range findTermMatches([]rune(row.Content), terms) {
+				distinct[match.Term] = true
+			}
+			ranked = append(ranked, scored{row: row, score: len(distinct)})
+		}
+		sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+		for idx, candidate := range ranked {
+			if idx >= perMemory || (idx > 0 && candidate.score == 0) {
+				break
+			}
+			items = append(items, ContextItem{Kind: contextKindFragment, MemoryID: id, Text: strings.TrimSpace(candidate.row.Content)})
+		}
+	}
+	return items
+}
+
+// packContext 按优先级依次装入各类候选：跳过重复内容，单条超过 maxItemTokens 时截断，
+// 每类受配额约束；剩余预算不足以放下整条时截断放入（不少于 contextMinItemTokens）
+func packContext(candidates map[string][]ContextItem, priority []string, budget int, quotas map[string]float64, maxItemTokens, charsPerToken int) ([]ContextItem, int) {
+	var packed []ContextItem
+	var seenTexts []string
+	remaining := budget
+	dropped := 0
+	for _, kind := range priority {
+		kindBudget := remaining
+		if quota := quotas[kind]; quota > 0 {
+			kindBudget = min(remaining, int(math.Floor(quota*float64(budget))))
+		}
+		for _, item := range candidates[kind] {
+			text := strings.TrimSpace(item.Text)
+			if text == "" || isDuplicateContext(seenTexts, text) {
+				continue
+			}
+			limit := min(kindBudget, maxItemTokens)
+			tokens := estimateContextTokens(item.MemoryID, text, charsPerToken)
+			if tokens > limit {
+				if limit < contextMinItemTokens {
+					dropped++
+					continue
+				}
+				text = truncateToTokens(item.MemoryID, text, limit, charsPerToken)
+				tokens = estimateContextTokens(item.MemoryID, text, charsPerToken)
+				item.Truncated = true
+			}
+			item.Text = text
+			item.Tokens = tokens
+			packed = append(packed, item)
+			seenTexts = append(seenTexts, normalizeContextText(text))
+			kindBudget -= tokens
+			remaining -= tokens
+		}
+	}
+	return packed, dropped
+}
+
+func normalizeContextText(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// isDuplicateContext 与已放入的条目相同或互相包含（如单片段记忆的片段与其摘要）视为重复
+func isDuplicateContext(seen []string, text string) bool {
+	normalized := normalizeContextText(strings.TrimSuffix(text, highlightEllipsis))
+	for _, existing := range seen {
+		existing = strings.TrimSuffix(existing, highlightEllipsis)
+		if strings.Contains(existing, normalized) || strings.Contains(normalized, existing) {
+			return true
+		}
+	}
+	return false
+}
+
+// estimateTokens 粗略估算 token 数：CJK 字符按 1 token，其余字符按 chars_per_token 折算
+func estimateTokens(text string, charsPerToken int) int {
+	if charsPerToken <= 0 {
+		charsPerToken = 4
+	}
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+charsPerToken-1)/charsPerToken
+}
+
+// estimateContextTokens 条目 token 数包含渲染时的引用前缀
+func estimateContextTokens(memoryID, text string, charsPerToken int) int {
+	return estimateTokens(contextCitation(memoryID)+text, charsPerToken)
+}
+
+// truncateToTokens 截断文本使条目（含引用前缀与省略号）不超过 limit 个 token
+func truncateToTokens(memoryID, text string, limit, charsPerToken int) string {
+	runes := []rune(text)
+	low, high := 0, len(runes)
+	for low < high {
+		mid := (low + high + 1) / 2
+		if estimateContextTokens(memoryID, string(runes[:mid])+highlightEllipsis, charsPerToken) <= limit {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return strings.TrimSpace(string(runes[:low])) + highlightEllipsis
+}
+
+func contextCitation(memoryID string) string {
+	return fmt.Sprintf("- [mem:%s] ", memoryID)
+}
+
+// renderContextPack 按优先级分节渲染，每条以 [mem:ID] 标注来源
+func renderContextPack(items []ContextItem, priority []string) string {
+	var builder strings.Builder
+	for _, kind := range priority {
+		section := false
+		for _, item := range items {
+			if item.Kind != kind {
+				continue
+			}
+			if !section {
+				if builder.Len() > 0 {
+					builder.WriteString("\n")
+				}
+				builder.WriteString("## " + contextKindTitles[kind] + "\n")
+				section = true
+			}
+			builder.WriteString(contextCitation(item.MemoryID) + strings.ReplaceAll(item.Text, "\n", " ") + "\n")
+		}
+	}
+	return builder.String()
+}
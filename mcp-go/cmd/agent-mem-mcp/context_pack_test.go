@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	if got := estimateTokens("用户登录", 4); got != 4 {
+		t.Fatalf("中文 token 估算错误: %d", got)
+	}
+	if got := estimateTokens("abcdefgh", 4); got != 2 {
+		t.Fatalf("英文 token 估算错误: %d", got)
+	}
+	if got := estimateTokens("登录 login", 4); got != 2+2 {
+		t.Fatalf("混合 token 估算错误: %d", got)
+	}
+}
+
+func TestPackContextPriorityQuotaAndDedup(t *testing.T) {
+	long := strings.Repeat("鉴权", 200)
+	candidates := map[string][]ContextItem{
+		contextKindDistilled: {{Kind: contextKindDistilled, MemoryID: "d1", Text: long}},
+		contextKindLatest: {
+			{Kind: contextKindLatest, MemoryID: "l1", Text: "token 有效期 2 小时"},
+			{Kind: contextKindLatest, MemoryID: "l2", Text: "Token  有效期 2 小时"},
+		},
+		contextKindFragment: {
+			{Kind: contextKindFragment, MemoryID: "l1", Text: "有效期 2 小时"},
+			{Kind: contextKindFragment, MemoryID: "f1", Text: "刷新 token 存 Redis"},
+		},
+	}
+	priority := []string{contextKindDistilled, contextKindLatest, contextKindFragment, contextKindForesight}
+	items, dropped := packContext(candidates, priority, 200, map[string]float64{contextKindDistilled: 0.3}, 400, 4)
+	if dropped != 0 {
+		t.Fatalf("不应丢弃条目: %d", dropped)
+	}
+	if len(items) != 3 || items[0].MemoryID != "d1" || items[1].MemoryID != "l1" || items[2].MemoryID != "f1" {
+		t.Fatalf("打包顺序或去重错误: %+v", items)
+	}
+	if !items[0].Truncated || items[0].Tokens > 60 {
+		t.Fatalf("蒸馏摘要应按配额截断: %+v", items[0])
+	}
+	used := 0
+	for _, item := range items {
+		used += item.Tokens
+	}
+	if used > 200 {
+		t.Fatalf("超出预算: %d", used)
+	}
+
+	// 预算耗尽后剩余条目计入 dropped
+	items, dropped = packContext(candidates, priority, 40, nil, 400, 4)
+	if len(items) != 1 || dropped != 4 {
+		t.Fatalf("预算不足时处理错误: items=%+v dropped=%d", items, dropped)
+	}
+}
+
+func TestSelectContextFragments(t *testing.T) {
+	rows := []FragmentRow{
+		{MemoryID: "m1", ChunkIndex: 0, Content: "背景介绍"},
+		{MemoryID: "m1", ChunkIndex: 1, Content: "登录接口使用 JWT 鉴权"},
+		{MemoryID: "m1", ChunkIndex: 2, Content: "登录页面样式"},
+		{MemoryID: "m2", ChunkIndex: 0, Content: "无关内容"},
+	}
+	items := selectContextFragments([]string{"m2", "m1"}, rows, []string{"登录", "鉴权"}, 2)
+	if len(items) != 3 {
+		t.Fatalf("片段数量错误: %+v", items)
+	}
+	if items[0].MemoryID != "m2" || items[0].Text != "无关内容" {
+		t.Fatalf("无命中时应取首个片段: %+v", items[0])
+	}
+	if items[1].Text != "登录接口使用 JWT 鉴权" || items[2].Text != "登录页面样式" {
+		t.Fatalf("片段应按命中词项数排序: %+v", items[1:])
+	}
+}
+
+func TestRenderContextPackAndValidation(t *testing.T) {
+	items := []ContextItem{
+		{Kind: contextKindFragment, MemoryID: "f1", Text: "第一行\n第二行"},
+		{Kind: contextKindLatest, MemoryID: "l1", Text: "最新方案"},
+	}
+	text := renderContextPack(items, []string{contextKindLatest, contextKindFragment})
+	want := "## 最新结论\n- [mem:l1] 最新方案\n\n## 相关片段\n- [mem:f1] 第一行 第二行\n"
+	if text != want {
+		t.Fatalf("渲染结果错误:\n%s", text)
+	}
+
+	if err := validateContextPriority([]string{"fragment", "fragment"}); err == nil {
+		t.Fatalf("重复的 priority 应报错")
+	}
+	settings := defaultSettings()
+	input, _ := normalizeContextInput(ContextInput{ProjectKey: "p", Task: "实现登录", TokenBudget: 10}, settings)
+	if err := validateContextInput(input, settings.Context); err == nil {
+		t.Fatalf("过小的 token_budget 应报错")
+	}
+	input, _ = normalizeContextInput(ContextInput{ProjectKey: "p", Task: "实现登录", Priority: &[]string{"Latest"}}, settings)
+	if err := validateContextInput(input, settings.Context); err != nil || (*input.Priority)[0] != "latest" || input.TokenBudget != 2000 {
+		t.Fatalf("默认值处理错误: %+v %v", input, err)
+	}
+}
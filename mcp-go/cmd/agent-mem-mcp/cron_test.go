@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronNext(t *testing.T) {
+	base := time.Date(2026, 3, 14, 10, 17, 30, 0, time.UTC) // 周六
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/10 * * * *", time.Date(2026, 3, 14, 10, 20, 0, 0, time.UTC)},
+		{"30 3 * * *", time.Date(2026, 3, 15, 3, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"15,45 8-18/2 * * *", time.Date(2026, 3, 14, 10, 45, 0, 0, time.UTC)},
+		// 日与周同时受限时满足其一即可
+		{"0 0 20 * 1", time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@every 30m", time.Date(2026, 3, 14, 10, 30, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		schedule, err := parseCron(tc.spec)
+		if err != nil {
+			t.Fatalf("cron %q 解析失败: %v", tc.spec, err)
+		}
+		if got := schedule.Next(base); !got.Equal(tc.want) {
+			t.Fatalf("cron %q 下次触发时间错误: got=%s want=%s", tc.spec, got, tc.want)
+		}
+	}
+
+	never, err := parseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if got := never.Next(base); !got.IsZero() {
+		t.Fatalf("永不触发的表达式应返回零值: %s", got)
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every 10s", "@every soon", "@never"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Fatalf("cron %q 应解析失败", spec)
+		}
+	}
+}
+
+func TestDueSlots(t *testing.T) {
+	schedule, err := parseCron("*/5 * * * *")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	after := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+
+	slots := dueSlots(schedule, after, after.Add(12*time.Minute), 10)
+	if len(slots) != 2 || !slots[0].Equal(after.Add(5*time.Minute)) || !slots[1].Equal(after.Add(10*time.Minute)) {
+		t.Fatalf("触发时间点错误: %v", slots)
+	}
+	if slots := dueSlots(schedule, after, after.Add(4*time.Minute), 10); len(slots) != 0 {
+		t.Fatalf("区间内无触发时间点: %v", slots)
+	}
+	// 区间起点不含，终点含
+	if slots := dueSlots(schedule, after.Add(5*time.Minute), after.Add(10*time.Minute), 10); len(slots) != 1 || !slots[0].Equal(after.Add(10*time.Minute)) {
+		t.Fatalf("区间边界错误: %v", slots)
+	}
+	// 超出上限时保留最近的时间点
+	slots = dueSlots(schedule, after, after.Add(time.Hour), 3)
+	if len(slots) != 3 || !slots[2].Equal(after.Add(time.Hour)) {
+		t.Fatalf("补入队上限错误: %v", slots)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// 游标类型：不同列表接口的游标不可混用
+const (
+	cursorKindSearch      = "search"
+	cursorKindTimeline    = "timeline"
+	cursorKindProjects    = "projects"
+	cursorKindArbitration = "arbitration"
+	cursorKindRelations   = "relations"
+	cursorKindForesights  = "foresights"
+	cursorKindResources   = "resources"
+)
+
+// maxSearchPageDepth 搜索可翻页的最大深度（已返回结果数），达到后不再返回游标并标记 truncated
+const maxSearchPageDepth = 200
+
+// maxCursorLength 游标字符串长度上限
+const maxCursorLength = 8192
+
+// pageCursor 是对外不透明的分页游标（base64url(JSON)）。
+// 列表接口使用 keyset（Ts/Key/ID 为上一页最后一条的排序键）；
+// 搜索的分数每次请求都会重新计算（反馈加权、新增记忆、重排），不能用分数做 keyset：
+// Seen 记录此前各页已返回记忆的短摘要，下一页从重新排序的候选池中剔除这些记忆，Depth 为已返回条数，
+// Fingerprint 防止游标跨查询复用。
+type pageCursor struct {
+	Kind        string   `json:"k"`
+	Ts          int64    `json:"t,omitempty"`
+	Key         string   `json:"s,omitempty"`
+	ID          int64    `json:"i,omitempty"`
+	Depth       int      `json:"d,omitempty"`
+	Seen        []string `json:"m,omitempty"`
+	Fingerprint string   `json:"f,omitempty"`
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析游标；空字符串返回 nil。类型不匹配或格式错误返回 ERR_INVALID_CURSOR。
+func decodeCursor(raw, kind string) (*pageCursor, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return nil, nil
+	}
+	if len(value) > maxCursorLength {
+		return nil, newValidationError("invalid_request", "ERR_INVALID_CURSOR", "cursor 过长", 400)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, newValidationError("invalid_request", "ERR_INVALID_CURSOR", "cursor 格式错误", 400)
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, newValidationError("invalid_request", "ERR_INVALID_CURSOR", "cursor 格式错误", 400)
+	}
+	if cursor.Kind != kind {
+		return nil, newValidationError("invalid_request", "ERR_INVALID_CURSOR", "cursor 与当前接口不匹配", 400)
+	}
+	return &cursor, nil
+}
+
+// searchFingerprint 对除 cursor/limit 以外的检索参数求指纹，防止游标跨查询复用
+func searchFingerprint(input SearchInput) string {
+	input.Cursor = ""
+	input.Limit = 0
+	data, err := json.Marshal(input)
+	if err != nil {
+		return ""
+	}
+	return hashContent(string(data))[:16]
+}
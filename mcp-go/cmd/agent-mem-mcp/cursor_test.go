@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	raw := encodeCursor(pageCursor{Kind: cursorKindTimeline, Ts: 1700000000, Key: "mem_abc"})
+	if raw == "" || strings.ContainsAny(raw, "+/=") {
+		t.Fatalf("游标应为 base64url 且非空: %q", raw)
+	}
+	cursor, err := decodeCursor(raw, cursorKindTimeline)
+	if err != nil {
+		t.Fatalf("解析游标失败: %v", err)
+	}
+	if cursor.Ts != 1700000000 || cursor.Key != "mem_abc" {
+		t.Fatalf("游标内容错误: %+v", cursor)
+	}
+}
+
+func TestDecodeCursorEmpty(t *testing.T) {
+	cursor, err := decodeCursor("  ", cursorKindSearch)
+	if err != nil || cursor != nil {
+		t.Fatalf("空游标应返回 nil: %+v %v", cursor, err)
+	}
+}
+
+func TestDecodeCursorRejectsInvalid(t *testing.T) {
+	cases := []string{
+		"not-base64!",
+		encodeCursor(pageCursor{Kind: cursorKindProjects}),
+		strings.Repeat("a", maxCursorLength+1),
+	}
+	for _, raw := range cases {
+		_, err := decodeCursor(raw, cursorKindTimeline)
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.Code != "ERR_INVALID_CURSOR" {
+			t.Fatalf("非法游标应返回 ERR_INVALID_CURSOR: %q %v", raw, err)
+		}
+	}
+}
+
+func TestSearchFingerprintIgnoresPaging(t *testing.T) {
+	base := SearchInput{OwnerID: "personal", Query: "登录", Limit: 10}
+	paged := base
+	paged.Limit = 20
+	paged.Cursor = "abc"
+	if searchFingerprint(base) != searchFingerprint(paged) {
+		t.Fatalf("limit/cursor 不应影响指纹")
+	}
+	changed := base
+	changed.Query = "注册"
+	if searchFingerprint(base) == searchFingerprint(changed) {
+		t.Fatalf("query 变化应改变指纹")
+	}
+}
+
+func TestSortByRankTotalOrder(t *testing.T) {
+	rows := sortByRank([]FragmentRow{
+		{MemoryID: "mem_c", RankScore: 0.5, Ts: 10},
+		{MemoryID: "mem_a", RankScore: 0.9, Ts: 10},
+		{MemoryID: "mem_b", RankScore: 0.5, Ts: 10},
+		{MemoryID: "mem_d", RankScore: 0.5, Ts: 20},
+		{MemoryID: "mem_e", RankScore: 0.1, Ts: 30},
+	})
+	var order []string
+	for _, row := range rows {
+		order = append(order, row.MemoryID)
+	}
+	if strings.Join(order, ",") != "mem_a,mem_d,mem_b,mem_c,mem_e" {
+		t.Fatalf("排序应为分数降序、时间降序、ID 升序: %v", order)
+	}
+}
+
+func TestSearchCursorPagesStableWhileBoostChanges(t *testing.T) {
+	pool := func(model *rankingModel) []FragmentRow {
+		rows := []FragmentRow{
+			{MemoryID: "mem_a", RankScore: 0.9, Ts: 10},
+			{MemoryID: "mem_b", RankScore: 0.8, Ts: 10},
+			{MemoryID: "mem_c", RankScore: 0.7, Ts: 10},
+			{MemoryID: "mem_d", RankScore: 0.6, Ts: 10},
+			{MemoryID: "mem_e", RankScore: 0.5, Ts: 10},
+			{MemoryID: "mem_f", RankScore: 0.4, Ts: 10},
+		}
+		return sortByRank(model.applyBoosts(rows))
+	}
+
+	first, hasMore := pageAfterSeen(pool(nil), nil, 2)
+	if !hasMore || first[0].MemoryID != "mem_a" || first[1].MemoryID != "mem_b" {
+		t.Fatalf("第一页结果错误: %+v %v", first, hasMore)
+	}
+	raw := encodeCursor(pageCursor{Kind: cursorKindSearch, Depth: 2, Seen: appendSeenMemories(nil, first)})
+	cursor, err := decodeCursor(raw, cursorKindSearch)
+	if err != nil {
+		t.Fatalf("解析游标失败: %v", err)
+	}
+
+	// 翻页期间反馈改变加权：已返回的 mem_a 掉到末尾，靠后的 mem_f 升到第一
+	boosted := &rankingModel{Boosts: map[string]float64{"mem_a": 0.1, "mem_f": 3}}
+	second, _ := pageAfterSeen(pool(boosted), cursor.Seen, 2)
+	seen := appendSeenMemories(cursor.Seen, second)
+	third, hasMore := pageAfterSeen(pool(boosted), seen, 2)
+	if hasMore {
+		t.Fatalf("第三页后不应还有结果")
+	}
+
+	var all []string
+	for _, page := range [][]FragmentRow{first, second, third} {
+		for _, row := range page {
+			all = append(all, row.MemoryID)
+		}
+	}
+	if strings.Join(all, ",") != "mem_a,mem_b,mem_f,mem_c,mem_d,mem_e" {
+		t.Fatalf("翻页结果不应重复或遗漏: %v", all)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -14,7 +16,9 @@ import (
 )
 
 type Store struct {
-	pool *pgxpool.Pool
+	pool      *pgxpool.Pool
+	tokenizer Tokenizer
+	trigram   bool // pg_trgm 扩展可用时才启用模糊召回
 }
 
 type ProjectRecord struct {
@@ -22,6 +26,8 @@ type ProjectRecord struct {
 	ProjectName string
 	ProjectKey  string
 	OwnerID     string
+	// Created 本次 UpsertProject 新建了项目（用于资源列表变更通知）
+	Created bool
 }
 
 type TimelineRecord struct {
@@ -31,6 +37,21 @@ type TimelineRecord struct {
 	Ts          int64
 }
 
+// FragmentFilter 片段检索的过滤条件，统一下推到 SQL
+type FragmentFilter struct {
+	Scope        string
+	ContentTypes []string
+	ProjectIDs   []string
+	Axes         MemoryAxes
+	IndexPath    []string
+	Tags         []string
+	ExcludeTags  []string
+	Since        int64
+	Until        int64
+	// Phrases 结构化查询中的 "短语"，仅 BM25 召回使用：以 tsquery 相邻运算（<->）要求词项连续出现
+	Phrases []string
+}
+
 type MemoryRow struct {
 	ID          string
 	ContentType string
@@ -42,7 +63,7 @@ type MemoryRow struct {
 	Ts          int64
 }
 
-func NewStore(databaseURL string) (*Store, error) {
+func NewStore(databaseURL string, tokenizer Tokenizer) (*Store, error) {
 	cfg, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, err
@@ -54,7 +75,10 @@ func NewStore(databaseURL string) (*Store, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Store{pool: pool}, nil
+	if tokenizer == nil {
+		tokenizer = bigramTokenizer{}
+	}
+	return &Store{pool: pool, tokenizer: tokenizer}, nil
 }
 
 func (s *Store) Close() {
@@ -70,8 +94,18 @@ func (s *Store) EnsureSchema(ctx context.Context, dimension int, reset bool) err
 	if _, err := s.pool.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS pgcrypto"); err != nil {
 		return err
 	}
+	// pg_trgm 为可选依赖：无权限安装时仅关闭模糊召回，不影响其他功能
+	if _, err := s.pool.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS pg_trgm"); err != nil {
+		log.Printf("[WARN] pg_trgm 不可用，模糊召回已关闭: %v", err)
+		s.trigram = false
+	} else {
+		s.trigram = true
+	}
 	if reset {
 		cleanup := `
+DROP TABLE IF EXISTS jobs CASCADE;
+DROP TABLE IF EXISTS ingest_jobs CASCADE;
+DROP TABLE IF EXISTS search_feedback CASCADE;
 DROP TABLE IF EXISTS memory_foresights CASCADE;
 DROP TABLE IF EXISTS fragments CASCADE;
 DROP TABLE IF EXISTS memories CASCADE;
@@ -119,6 +153,8 @@ CREATE TABLE IF NOT EXISTS fragments (
   chunk_index INT NOT NULL,
   content TEXT NOT NULL,
   embedding VECTOR(%[1]d),
+  lexemes TSVECTOR,
+  lexeme_tokenizer TEXT,
   ts TIMESTAMPTZ DEFAULT NOW(),
   UNIQUE(memory_id, chunk_index)
 );
@@ -152,6 +188,7 @@ CREATE TABLE IF NOT EXISTS memory_arbitrations (
   old_summary TEXT,
   new_summary TEXT,
   model TEXT,
+  decided_by TEXT DEFAULT 'model',
   created_at TIMESTAMPTZ DEFAULT NOW()
 );
 
@@ -177,6 +214,56 @@ CREATE TABLE IF NOT EXISTS memory_foresights (
   created_at TIMESTAMPTZ DEFAULT NOW(),
   expires_at TIMESTAMPTZ
 );
+
+CREATE TABLE IF NOT EXISTS search_feedback (
+  id BIGSERIAL PRIMARY KEY,
+  owner_id TEXT NOT NULL,
+  memory_id TEXT NOT NULL REFERENCES memories(id) ON DELETE CASCADE,
+  query TEXT NOT NULL DEFAULT '',
+  helpful BOOLEAN NOT NULL,
+  implicit BOOLEAN NOT NULL DEFAULT FALSE,
+  sources JSONB NOT NULL DEFAULT '[]'::jsonb,
+  rank INT,
+  note TEXT,
+  created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS ingest_jobs (
+  id TEXT PRIMARY KEY,
+  owner_id TEXT NOT NULL,
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  memory_id TEXT,
+  payload JSONB NOT NULL,
+  status TEXT NOT NULL DEFAULT 'queued',
+  attempts INT NOT NULL DEFAULT 0,
+  max_attempts INT NOT NULL DEFAULT 5,
+  next_run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_error TEXT,
+  result_memory_id TEXT,
+  result_status TEXT,
+  locked_by TEXT,
+  locked_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS jobs (
+  id TEXT PRIMARY KEY,
+  kind TEXT NOT NULL,
+  payload JSONB NOT NULL DEFAULT '{}',
+  status TEXT NOT NULL DEFAULT 'queued',
+  attempts INT NOT NULL DEFAULT 0,
+  max_attempts INT NOT NULL DEFAULT 3,
+  run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  schedule TEXT,
+  scheduled_for TIMESTAMPTZ,
+  last_error TEXT,
+  locked_by TEXT,
+  locked_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  updated_at TIMESTAMPTZ DEFAULT NOW(),
+  finished_at TIMESTAMPTZ
+);
 `, dimension)
 
 	if _, err := s.pool.Exec(ctx, schema); err != nil {
@@ -263,6 +350,22 @@ CREATE TABLE IF NOT EXISTS memory_foresights (
 				ALTER TABLE memory_versions ADD COLUMN index_path JSONB;
 			END IF;
 		END $$`,
+		// fragments 表添加分词词项（由 Go 侧分词器生成，替代 to_tsvector('simple', content)）
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name='fragments' AND column_name='lexemes') THEN
+				ALTER TABLE fragments ADD COLUMN lexemes TSVECTOR;
+			END IF;
+			IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name='fragments' AND column_name='lexeme_tokenizer') THEN
+				ALTER TABLE fragments ADD COLUMN lexeme_tokenizer TEXT;
+			END IF;
+		END $$`,
+		"DROP INDEX IF EXISTS idx_fragments_fts",
+		// memory_arbitrations 表记录决策方（model / human）
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name='memory_arbitrations' AND column_name='decided_by') THEN
+				ALTER TABLE memory_arbitrations ADD COLUMN decided_by TEXT DEFAULT 'model';
+			END IF;
+		END $$`,
 	}
 	for _, stmt := range migrations {
 		if _, err := s.pool.Exec(ctx, stmt); err != nil {
@@ -291,7 +394,7 @@ CREATE TABLE IF NOT EXISTS memory_foresights (
 		"CREATE INDEX IF NOT EXISTS idx_memories_avg_embedding ON memories USING hnsw (avg_embedding vector_cosine_ops)",
 		"CREATE INDEX IF NOT EXISTS idx_fragments_memory ON fragments(memory_id)",
 		"CREATE INDEX IF NOT EXISTS idx_fragments_embedding ON fragments USING hnsw (embedding vector_cosine_ops)",
-		"CREATE INDEX IF NOT EXISTS idx_fragments_fts ON fragments USING GIN (to_tsvector('simple', content))",
+		"CREATE INDEX IF NOT EXISTS idx_fragments_lexemes ON fragments USING GIN (lexemes)",
 		"CREATE INDEX IF NOT EXISTS idx_memory_versions_memory ON memory_versions(memory_id)",
 		"CREATE INDEX IF NOT EXISTS idx_memory_versions_project ON memory_versions(project_id)",
 		"CREATE INDEX IF NOT EXISTS idx_memory_arbitrations_project ON memory_arbitrations(project_id)",
@@ -303,6 +406,16 @@ CREATE TABLE IF NOT EXISTS memory_foresights (
 		"CREATE INDEX IF NOT EXISTS idx_foresights_project ON memory_foresights(project_id)",
 		"CREATE INDEX IF NOT EXISTS idx_foresights_expires ON memory_foresights(expires_at)",
 		"CREATE INDEX IF NOT EXISTS idx_foresights_embedding ON memory_foresights USING hnsw (embedding vector_cosine_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_search_feedback_owner ON search_feedback(owner_id, created_at DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_search_feedback_memory ON search_feedback(memory_id)",
+		"CREATE INDEX IF NOT EXISTS idx_ingest_jobs_pending ON ingest_jobs(next_run_at) WHERE status IN ('queued', 'running')",
+		"CREATE INDEX IF NOT EXISTS idx_ingest_jobs_owner ON ingest_jobs(owner_id, created_at DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_jobs_pending ON jobs(run_at) WHERE status IN ('queued', 'running')",
+		"CREATE INDEX IF NOT EXISTS idx_jobs_created ON jobs(created_at DESC)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_schedule_slot ON jobs(schedule, scheduled_for) WHERE schedule IS NOT NULL",
+	}
+	if s.trigram {
+		indexes = append(indexes, "CREATE INDEX IF NOT EXISTS idx_fragments_content_trgm ON fragments USING GIN (content gin_trgm_ops)")
 	}
 	for _, stmt := range indexes {
 		if _, err := s.pool.Exec(ctx, stmt); err != nil {
@@ -313,6 +426,10 @@ CREATE TABLE IF NOT EXISTS memory_foresights (
 }
 
 func (s *Store) UpsertProject(ctx context.Context, ownerID, projectKey, projectName, machineName, projectPath string) (ProjectRecord, error) {
+	return upsertProjectTx(ctx, s.pool, ownerID, projectKey, projectName, machineName, projectPath)
+}
+
+func upsertProjectTx(ctx context.Context, tx pgxQuerier, ownerID, projectKey, projectName, machineName, projectPath string) (ProjectRecord, error) {
 	query := `
 INSERT INTO projects (owner_id, project_key, project_name, machine_name, project_path)
 VALUES ($1, $2, $3, $4, $5)
@@ -321,18 +438,19 @@ DO UPDATE SET project_name = EXCLUDED.project_name,
               machine_name = CASE WHEN EXCLUDED.machine_name IS NULL OR EXCLUDED.machine_name = '' THEN projects.machine_name ELSE EXCLUDED.machine_name END,
               project_path = CASE WHEN EXCLUDED.project_path IS NULL OR EXCLUDED.project_path = '' THEN projects.project_path ELSE EXCLUDED.project_path END,
               updated_at = NOW()
-RETURNING id, project_name, project_key, owner_id`
+RETURNING id, project_name, project_key, owner_id, (xmax = 0) AS created`
 
 	var (
 		projectID   string
 		storedName  string
 		storedKey   string
 		storedOwner string
+		created     bool
 	)
-	if err := s.pool.QueryRow(ctx, query, ownerID, projectKey, projectName, nullableString(machineName), nullableString(projectPath)).Scan(&projectID, &storedName, &storedKey, &storedOwner); err != nil {
+	if err := tx.QueryRow(ctx, query, ownerID, projectKey, projectName, nullableString(machineName), nullableString(projectPath)).Scan(&projectID, &storedName, &storedKey, &storedOwner, &created); err != nil {
 		return ProjectRecord{}, err
 	}
-	return ProjectRecord{ID: projectID, ProjectName: storedName, ProjectKey: storedKey, OwnerID: storedOwner}, nil
+	return ProjectRecord{ID: projectID, ProjectName: storedName, ProjectKey: storedKey, OwnerID: storedOwner, Created: created}, nil
 }
 
 func (s *Store) FindProjectIDByKey(ctx context.Context, ownerID, projectKey string) (string, error) {
@@ -347,6 +465,27 @@ func (s *Store) FindProjectIDByKey(ctx context.Context, ownerID, projectKey stri
 	return id, nil
 }
 
+// FindProjectIDsByKeys 批量解析 project_key，未找到的 key 直接忽略
+func (s *Store) FindProjectIDsByKeys(ctx context.Context, ownerID string, projectKeys []string) ([]string, error) {
+	if len(projectKeys) == 0 {
+		return nil, nil
+	}
+	rows, err := s.pool.Query(ctx, `SELECT id FROM projects WHERE owner_id = $1 AND project_key = ANY($2)`, ownerID, projectKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 func (s *Store) BackfillProjectIdentity(ctx context.Context, ownerID string) error {
 	owner := strings.TrimSpace(ownerID)
 	if owner == "" {
@@ -373,10 +512,144 @@ WHERE project_name IS NULL OR project_name = ''`); err != nil {
 	return nil
 }
 
-func (s *Store) FindDuplicateMemory(ctx context.Context, projectID, contentHash string, sinceTs int64) (string, error) {
-	query := `SELECT id FROM memories WHERE project_id = $1 AND content_hash = $2 AND ts >= $3 LIMIT 1`
+// BackfillFragmentLexemes 为缺失或由其他分词器生成词项的片段重建 lexemes（分批执行，返回处理数量）
+func (s *Store) BackfillFragmentLexemes(ctx context.Context) (int, error) {
+	const batchSize = 500
+	name := lexemeTokenizerName(s.tokenizer)
+	total := 0
+	for {
+		rows, err := s.pool.Query(ctx, `
+SELECT id, content FROM fragments
+WHERE lexeme_tokenizer IS DISTINCT FROM $1
+LIMIT $2`, name, batchSize)
+		if err != nil {
+			return total, err
+		}
+		batch := &pgx.Batch{}
+		count := 0
+		for rows.Next() {
+			var id, content string
+			if err := rows.Scan(&id, &content); err != nil {
+				rows.Close()
+				return total, err
+			}
+			batch.Queue(`UPDATE fragments SET lexemes = to_tsvector('simple', $2), lexeme_tokenizer = $3 WHERE id = $1`, id, lexemeDocument(s.tokenizer, content), name)
+			count++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, err
+		}
+		if count == 0 {
+			return total, nil
+		}
+		if err := s.pool.SendBatch(ctx, batch).Close(); err != nil {
+			return total, err
+		}
+		total += count
+		if count < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// FindMemoryOwnerProject 查询记忆所属的 owner 与 project_id，不存在时返回空串
+func (s *Store) FindMemoryOwnerProject(ctx context.Context, memoryID string) (string, string, error) {
+	var ownerID, projectID string
+	err := s.pool.QueryRow(ctx, `
+SELECT p.owner_id, m.project_id::text
+FROM memories m
+JOIN projects p ON m.project_id = p.id
+WHERE m.id = $1`, memoryID).Scan(&ownerID, &projectID)
+	if err == pgx.ErrNoRows {
+		return "", "", nil
+	}
+	return ownerID, projectID, err
+}
+
+// FetchContentHashes 批量获取记忆的内容哈希（id -> content_hash）
+func (s *Store) FetchContentHashes(ctx context.Context, memoryIDs []string) (map[string]string, error) {
+	result := map[string]string{}
+	if len(memoryIDs) == 0 {
+		return result, nil
+	}
+	rows, err := s.pool.Query(ctx, `SELECT id, COALESCE(content_hash, '') FROM memories WHERE id = ANY($1)`, memoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return nil, err
+		}
+		result[id] = hash
+	}
+	return result, rows.Err()
+}
+
+// DeleteOwnerProjects 删除 owner 下的全部项目（记忆、分片、版本等级联删除），返回被删除的项目；
+// 每个项目在同一事务内发送 delete 变更通知
+func (s *Store) DeleteOwnerProjects(ctx context.Context, ownerID, origin string) ([]ProjectRecord, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `DELETE FROM projects WHERE owner_id = $1 RETURNING id::text, project_name, project_key`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []ProjectRecord
+	for rows.Next() {
+		project := ProjectRecord{OwnerID: ownerID}
+		if err := rows.Scan(&project.ID, &project.ProjectName, &project.ProjectKey); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deleted = append(deleted, project)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, project := range deleted {
+		event := changeEvent{Kind: changeKindDelete, Origin: origin, OwnerID: ownerID, ProjectID: project.ID, ProjectKey: project.ProjectKey}
+		if err := publishChangeTx(ctx, tx, event); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// FetchMemoryLocation 查询记忆所属项目与当前 index_path（变更通知需要旧路径）
+func (s *Store) FetchMemoryLocation(ctx context.Context, memoryID string) (ProjectRecord, []string, error) {
+	var (
+		project   ProjectRecord
+		indexPath []byte
+	)
+	err := s.pool.QueryRow(ctx, `
+SELECT p.id::text, p.project_name, p.project_key, p.owner_id, COALESCE(m.index_path, '[]'::jsonb)
+FROM memories m
+JOIN projects p ON m.project_id = p.id
+WHERE m.id = $1`, memoryID).Scan(&project.ID, &project.ProjectName, &project.ProjectKey, &project.OwnerID, &indexPath)
+	if err != nil {
+		return ProjectRecord{}, nil, err
+	}
+	var path []string
+	_ = json.Unmarshal(indexPath, &path)
+	return project, path, nil
+}
+
+// FindDuplicateMemory excludeID 非空时排除该记忆（异步任务自身的临时记忆）
+func (s *Store) FindDuplicateMemory(ctx context.Context, projectID, contentHash string, sinceTs int64, excludeID string) (string, error) {
+	query := `SELECT id FROM memories WHERE project_id = $1 AND content_hash = $2 AND ts >= $3 AND id <> $4 LIMIT 1`
 	var id string
-	if err := s.pool.QueryRow(ctx, query, projectID, contentHash, sinceTs).Scan(&id); err != nil {
+	if err := s.pool.QueryRow(ctx, query, projectID, contentHash, sinceTs, excludeID).Scan(&id); err != nil {
 		if err == pgx.ErrNoRows {
 			return "", nil
 		}
@@ -428,10 +701,10 @@ func (s *Store) InsertFragments(ctx context.Context, fragments []FragmentInsert)
 	}
 	batch := &pgx.Batch{}
 	query := `
-INSERT INTO fragments (id, memory_id, chunk_index, content, embedding)
-VALUES ($1,$2,$3,$4,$5)`
+INSERT INTO fragments (id, memory_id, chunk_index, content, embedding, lexemes, lexeme_tokenizer)
+VALUES ($1,$2,$3,$4,$5,to_tsvector('simple', $6),$7)`
 	for _, frag := range fragments {
-		batch.Queue(query, frag.ID, frag.MemoryID, frag.ChunkIndex, frag.Content, pgvector.NewVector(frag.Embedding))
+		batch.Queue(query, frag.ID, frag.MemoryID, frag.ChunkIndex, frag.Content, pgvector.NewVector(frag.Embedding), lexemeDocument(s.tokenizer, frag.Content), lexemeTokenizerName(s.tokenizer))
 	}
 	br := s.pool.SendBatch(ctx, batch)
 	defer br.Close()
@@ -566,8 +839,8 @@ func (s *Store) InsertArbitrationLog(ctx context.Context, log ArbitrationLogInse
 	_, err := s.pool.Exec(ctx, `
 INSERT INTO memory_arbitrations (
   owner_id, project_id, candidate_memory_id, new_memory_id,
-  action, similarity, old_summary, new_summary, model, created_at
-) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`,
+  action, similarity, old_summary, new_summary, model, decided_by, created_at
+) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`,
 		log.OwnerID,
 		log.ProjectID,
 		nullableString(log.CandidateMemoryID),
@@ -577,19 +850,46 @@ INSERT INTO memory_arbitrations (
 		nullableString(log.OldSummary),
 		nullableString(log.NewSummary),
 		nullableString(log.Model),
+		arbitrationDecider(log.DecidedBy),
 		log.CreatedAt,
 	)
 	return err
 }
 
-func (s *Store) FetchTimeline(ctx context.Context, projectID string, sinceTs int64, limit int) ([]TimelineRecord, error) {
+func (s *Store) FetchTimeline(ctx context.Context, projectID string, sinceTs int64, after *pageCursor, limit int) ([]TimelineRecord, error) {
 	query := `
 SELECT id, content_type, COALESCE(summary, ''), ts
 FROM memories
-WHERE project_id = $1 AND ts >= $2
-ORDER BY ts DESC
-LIMIT $3`
-	rows, err := s.pool.Query(ctx, query, projectID, sinceTs, limit)
+WHERE project_id = $1 AND ts >= $2`
+	args := []any{projectID, sinceTs}
+	query, args = appendKeysetAfter(query, args, "ts", "id", after)
+	query += fmt.Sprintf(" ORDER BY ts DESC, id DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []TimelineRecord
+	for rows.Next() {
+		var row TimelineRecord
+		if err := rows.Scan(&row.ID, &row.ContentType, &row.Summary, &row.Ts); err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+// FetchMemoriesByIndexPath 查询项目内 index_path 以 prefix 开头的记忆（按时间倒序）
+func (s *Store) FetchMemoriesByIndexPath(ctx context.Context, projectID string, prefix []string, limit int) ([]TimelineRecord, error) {
+	where, args := appendIndexPathWhere("m.project_id = $1", []any{projectID}, prefix)
+	query := `
+SELECT m.id, m.content_type, COALESCE(m.summary, ''), m.ts
+FROM memories m
+WHERE ` + where + fmt.Sprintf(" ORDER BY m.ts DESC, m.id DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -605,15 +905,17 @@ LIMIT $3`
 	return results, rows.Err()
 }
 
-func (s *Store) FetchTimelineByOwner(ctx context.Context, ownerID string, sinceTs int64, limit int) ([]TimelineRecord, error) {
+func (s *Store) FetchTimelineByOwner(ctx context.Context, ownerID string, sinceTs int64, after *pageCursor, limit int) ([]TimelineRecord, error) {
 	query := `
 SELECT m.id, m.content_type, COALESCE(m.summary, ''), m.ts
 FROM memories m
 JOIN projects p ON m.project_id = p.id
-WHERE p.owner_id = $1 AND m.ts >= $2
-ORDER BY m.ts DESC
-LIMIT $3`
-	rows, err := s.pool.Query(ctx, query, ownerID, sinceTs, limit)
+WHERE p.owner_id = $1 AND m.ts >= $2`
+	args := []any{ownerID, sinceTs}
+	query, args = appendKeysetAfter(query, args, "m.ts", "m.id", after)
+	query += fmt.Sprintf(" ORDER BY m.ts DESC, m.id DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -629,7 +931,7 @@ LIMIT $3`
 	return results, rows.Err()
 }
 
-func (s *Store) ListProjects(ctx context.Context, ownerID string, limit int) ([]ProjectListItem, error) {
+func (s *Store) ListProjects(ctx context.Context, ownerID string, after *pageCursor, limit int) ([]ProjectListItem, error) {
 	query := `
 SELECT p.owner_id,
        p.project_key,
@@ -641,11 +943,16 @@ SELECT p.owner_id,
 FROM projects p
 LEFT JOIN memories m ON m.project_id = p.id
 WHERE ($1 = '' OR p.owner_id = $1)
-GROUP BY p.id
-ORDER BY COALESCE(MAX(m.ts), 0) DESC
-LIMIT $2`
+GROUP BY p.id`
+	args := []any{ownerID}
+	if after != nil {
+		query += fmt.Sprintf(" HAVING (COALESCE(MAX(m.ts), 0), p.project_key) < ($%d, $%d)", len(args)+1, len(args)+2)
+		args = append(args, after.Ts, after.Key)
+	}
+	query += fmt.Sprintf(" ORDER BY COALESCE(MAX(m.ts), 0) DESC, p.project_key DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
 
-	rows, err := s.pool.Query(ctx, query, ownerID, limit)
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -671,6 +978,11 @@ type MemoryVectorRow struct {
 	ID          string
 	ContentType string
 	Distance    float64
+	ProjectKey  string
+	Ts          int64
+	Tags        []string
+	Axes        MemoryAxes
+	IndexPath   []string
 }
 
 // MemorySummaryRow 用于仲裁时获取旧摘要
@@ -689,23 +1001,157 @@ func (s *Store) FetchMemorySummary(ctx context.Context, memoryID string) (Memory
 	return row, nil
 }
 
-// FetchRecentMemorySummaries 查询指定项目最近 N 天的记忆摘要列表（用于蒸馏）
-func (s *Store) FetchRecentMemorySummaries(ctx context.Context, projectID string, sinceTs int64, scope string, limit int) ([]MemorySummaryRow, error) {
-	query := `
-SELECT id, COALESCE(summary, '')
+// undistilledMemoryCondition 未被蒸馏覆盖的记忆（别名 m，$1 项目、$2 起始 ts）：
+// 排除蒸馏结果本身与处理中的异步写入临时记忆；覆盖关系上的内容哈希与当前内容不一致时视为未覆盖
+const undistilledMemoryCondition = `m.project_id = $1 AND m.ts >= $2
+  AND NOT (COALESCE(m.tags, '[]'::jsonb) ? 'distilled')
+  AND NOT EXISTS (
+    SELECT 1 FROM memory_relations r
+    WHERE r.target_id = m.id AND r.relation_type = 'DERIVED_FROM'
+      AND r.metadata->>'origin' = 'distill'
+      AND r.metadata->>'content_hash' IS NOT DISTINCT FROM m.content_hash
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM ingest_jobs j WHERE j.memory_id = m.id AND j.status IN ('queued', 'running')
+  )`
+
+// undistilledScopeFilter scope 为空或 all 时不限 content_type
+func undistilledScopeFilter(query string, args []any, scope string) (string, []any) {
+	if scope == "" || scope == "all" {
+		return query, args
+	}
+	args = append(args, scope)
+	return query + fmt.Sprintf(" AND m.content_type = $%d", len(args)), args
+}
+
+// FetchUndistilledMemories 查询项目中 sinceTs 之后尚未蒸馏的记忆（按时间正序），摘要为空时取正文开头
+func (s *Store) FetchUndistilledMemories(ctx context.Context, projectID string, sinceTs int64, scope string, limit int) ([]DistillSourceRow, error) {
+	query, args := undistilledScopeFilter(`
+SELECT m.id, COALESCE(NULLIF(m.summary, ''), LEFT(m.content, 500)), COALESCE(m.content_hash, '')
+FROM memories m
+WHERE `+undistilledMemoryCondition, []any{projectID, sinceTs}, scope)
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY m.ts, m.id LIMIT $%d", len(args))
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []DistillSourceRow
+	for rows.Next() {
+		var row DistillSourceRow
+		if err := rows.Scan(&row.ID, &row.Summary, &row.ContentHash); err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+// FetchDistillBacklog 项目中尚未蒸馏的记忆数与上次蒸馏时间（Unix 秒，从未蒸馏时为 0）
+func (s *Store) FetchDistillBacklog(ctx context.Context, projectID string, sinceTs int64, scope string) (int, int64, error) {
+	query, args := undistilledScopeFilter(`
+SELECT COUNT(*),
+       COALESCE((
+         SELECT EXTRACT(EPOCH FROM MAX(r.created_at))::BIGINT
+         FROM memory_relations r
+         JOIN memories d ON d.id = r.source_id
+         WHERE d.project_id = $1 AND r.relation_type = 'DERIVED_FROM' AND r.metadata->>'origin' = 'distill'
+       ), 0)
+FROM memories m
+WHERE `+undistilledMemoryCondition, []any{projectID, sinceTs}, scope)
+	var pending int
+	var lastDistilled int64
+	if err := s.pool.QueryRow(ctx, query, args...).Scan(&pending, &lastDistilled); err != nil {
+		return 0, 0, err
+	}
+	return pending, lastDistilled, nil
+}
+
+// DigestRecord 项目当前的蒸馏结果；ID 为空表示尚未蒸馏
+type DigestRecord struct {
+	ID      string
+	Content string
+}
+
+// FindLatestDigest 查找 index_path 对应的最新蒸馏结果
+func (s *Store) FindLatestDigest(ctx context.Context, projectID string, indexPath []string) (DigestRecord, error) {
+	pathJSON, _ := json.Marshal(indexPath)
+	var digest DigestRecord
+	err := s.pool.QueryRow(ctx, `
+SELECT id, content
 FROM memories
-WHERE project_id = $1 AND ts >= $2`
-	args := []any{projectID, sinceTs}
-	if scope != "" && scope != "all" {
-		query += " AND content_type = $3"
-		args = append(args, scope)
-		query += " ORDER BY ts DESC LIMIT $4"
-		args = append(args, limit)
-	} else {
-		query += " ORDER BY ts DESC LIMIT $3"
-		args = append(args, limit)
+WHERE project_id = $1 AND index_path = $2::jsonb
+ORDER BY ts DESC, id
+LIMIT 1`, projectID, string(pathJSON)).Scan(&digest.ID, &digest.Content)
+	if err == pgx.ErrNoRows {
+		return DigestRecord{}, nil
+	}
+	return digest, err
+}
+
+// InsertDigestSources 记录蒸馏结果到源记忆的 DERIVED_FROM 关系（metadata.origin=distill，附源记忆当时的内容哈希）；
+// 已删除的源记忆忽略，重复蒸馏时刷新哈希与时间
+func (s *Store) InsertDigestSources(ctx context.Context, digestID string, sources []DistillSourceRow) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	ids := make([]string, len(sources))
+	hashes := make([]string, len(sources))
+	for idx, source := range sources {
+		ids[idx] = source.ID
+		hashes[idx] = source.ContentHash
+	}
+	_, err := s.pool.Exec(ctx, `
+INSERT INTO memory_relations (source_id, target_id, relation_type, strength, metadata)
+SELECT $1, src.id, 'DERIVED_FROM', 1.0, jsonb_build_object('origin', 'distill', 'content_hash', NULLIF(src.hash, ''))
+FROM unnest($2::text[], $3::text[]) AS src(id, hash)
+JOIN memories m ON m.id = src.id
+WHERE src.id <> $1
+ON CONFLICT (source_id, target_id, relation_type)
+DO UPDATE SET metadata = EXCLUDED.metadata, created_at = NOW()`, digestID, ids, hashes)
+	return err
+}
+
+// MemorySimilarity 记忆平均向量与 vector 的相似度；记忆不存在或没有向量时 found 为 false
+func (s *Store) MemorySimilarity(ctx context.Context, memoryID string, vector pgvector.Vector) (float64, bool, error) {
+	var distance float64
+	err := s.pool.QueryRow(ctx, `
+SELECT avg_embedding <=> $2 FROM memories WHERE id = $1 AND avg_embedding IS NOT NULL`, memoryID, vector).Scan(&distance)
+	if err == pgx.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
 	}
+	return distanceToSimilarity(distance), true, nil
+}
+
+// FetchDistilledSummaries 查询项目最近的蒸馏结果（tags 含 distilled），摘要为空时取正文开头
+func (s *Store) FetchDistilledSummaries(ctx context.Context, projectID string, limit int) ([]MemorySummaryRow, error) {
+	return s.fetchSummaryRows(ctx, `
+SELECT id, COALESCE(NULLIF(summary, ''), LEFT(content, 2000))
+FROM memories
+WHERE project_id = $1 AND COALESCE(tags, '[]'::jsonb) ? 'distilled'
+ORDER BY ts DESC
+LIMIT $2`, projectID, limit)
+}
+
+// FetchLatestPathSummaries 查询项目中 index_path 以 latest 结尾的记忆（不含蒸馏结果）
+func (s *Store) FetchLatestPathSummaries(ctx context.Context, projectID string, limit int) ([]MemorySummaryRow, error) {
+	return s.fetchSummaryRows(ctx, `
+SELECT id, COALESCE(NULLIF(summary, ''), LEFT(content, 2000))
+FROM memories
+WHERE project_id = $1
+  AND jsonb_typeof(index_path) = 'array'
+  AND index_path ->> -1 = 'latest'
+  AND NOT COALESCE(tags, '[]'::jsonb) ? 'distilled'
+ORDER BY ts DESC
+LIMIT $2`, projectID, limit)
+}
 
+func (s *Store) fetchSummaryRows(ctx context.Context, query string, args ...any) ([]MemorySummaryRow, error) {
 	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
@@ -722,16 +1168,66 @@ WHERE project_id = $1 AND ts >= $2`
 	return results, rows.Err()
 }
 
-// SearchMemoryVectors searches memories by avg_embedding for semantic conflict detection
-// 只按 project_id 过滤，不按 content_type（因为类型不严格互斥）
-func (s *Store) SearchMemoryVectors(ctx context.Context, vector pgvector.Vector, projectID string, limit int) ([]MemoryVectorRow, error) {
+// FetchFragmentsByMemoryIDs 获取多条记忆的全部片段（按记忆、chunk_index 排序），用于上下文打包挑选相关片段
+func (s *Store) FetchFragmentsByMemoryIDs(ctx context.Context, memoryIDs []string) ([]FragmentRow, error) {
+	if len(memoryIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := s.pool.Query(ctx, `
+SELECT id, memory_id, chunk_index, content
+FROM fragments
+WHERE memory_id = ANY($1)
+ORDER BY memory_id, chunk_index`, memoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []FragmentRow
+	for rows.Next() {
+		var row FragmentRow
+		if err := rows.Scan(&row.FragmentID, &row.MemoryID, &row.ChunkIndex, &row.Content); err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+// MemoryVectorQuery avg_embedding 近邻检索范围：ProjectID 非空时限定项目，否则按 OwnerID；
+// Filter 复用片段检索的过滤条件（均为 memories 字段），ExcludeIDs 用于剔除种子及其版本链
+type MemoryVectorQuery struct {
+	ProjectID  string
+	OwnerID    string
+	Filter     FragmentFilter
+	ExcludeIDs []string
+}
+
+// SearchMemoryVectors searches memories by avg_embedding for semantic conflict detection and mem.similar
+// 冲突检测只按 project_id 过滤，不按 content_type（因为类型不严格互斥）
+func (s *Store) SearchMemoryVectors(ctx context.Context, vector pgvector.Vector, q MemoryVectorQuery, limit int) ([]MemoryVectorRow, error) {
 	query := `
-SELECT id, content_type, (avg_embedding <=> $1) AS distance
-FROM memories
-WHERE project_id = $2 AND avg_embedding IS NOT NULL
-ORDER BY avg_embedding <=> $1
-LIMIT $3`
-	rows, err := s.pool.Query(ctx, query, vector, projectID, limit)
+SELECT m.id, m.content_type, (m.avg_embedding <=> $1) AS distance,
+       p.project_key, m.ts, COALESCE(m.tags, '[]'::jsonb), COALESCE(m.axes, '{}'::jsonb), COALESCE(m.index_path, '[]'::jsonb)
+FROM memories m
+JOIN projects p ON m.project_id = p.id
+WHERE m.avg_embedding IS NOT NULL`
+	args := []any{vector}
+	if q.ProjectID != "" {
+		query += fmt.Sprintf(" AND m.project_id = $%d", len(args)+1)
+		args = append(args, q.ProjectID)
+	} else {
+		query += fmt.Sprintf(" AND p.owner_id = $%d", len(args)+1)
+		args = append(args, q.OwnerID)
+	}
+	if len(q.ExcludeIDs) > 0 {
+		query += fmt.Sprintf(" AND NOT (m.id = ANY($%d))", len(args)+1)
+		args = append(args, q.ExcludeIDs)
+	}
+	query, args = appendFragmentFilter(query, args, q.Filter)
+	query += fmt.Sprintf(" ORDER BY m.avg_embedding <=> $1 LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -740,15 +1236,54 @@ LIMIT $3`
 	var results []MemoryVectorRow
 	for rows.Next() {
 		var row MemoryVectorRow
-		if err := rows.Scan(&row.ID, &row.ContentType, &row.Distance); err != nil {
+		var tagsJSON, axesJSON, pathJSON []byte
+		if err := rows.Scan(&row.ID, &row.ContentType, &row.Distance, &row.ProjectKey, &row.Ts, &tagsJSON, &axesJSON, &pathJSON); err != nil {
 			return nil, err
 		}
+		row.Tags = decodeTags(tagsJSON)
+		row.Axes = decodeAxes(axesJSON)
+		row.IndexPath = decodeIndexPath(pathJSON)
 		results = append(results, row)
 	}
 	return results, rows.Err()
 }
 
-func (s *Store) SearchVectorFragments(ctx context.Context, vector pgvector.Vector, projectID, scope string, axes MemoryAxes, indexPath []string, limit int) ([]FragmentRow, error) {
+// FetchVersionChainIDs 返回记忆自身及其版本链上的记忆 ID：
+// DERIVED_FROM 关系（双向、传递，不含蒸馏结果指向源记忆的关系）与 REPLACE 仲裁的新旧记忆
+func (s *Store) FetchVersionChainIDs(ctx context.Context, memoryID string) ([]string, error) {
+	query := `
+WITH RECURSIVE chain(id, depth) AS (
+  SELECT $1::text, 0
+  UNION
+  SELECT CASE WHEN r.source_id = c.id THEN r.target_id ELSE r.source_id END, c.depth + 1
+  FROM memory_relations r
+  JOIN chain c ON r.source_id = c.id OR r.target_id = c.id
+  WHERE r.relation_type = 'DERIVED_FROM' AND COALESCE(r.metadata->>'origin', '') <> 'distill' AND c.depth < 20
+)
+SELECT id FROM chain
+UNION
+SELECT candidate_memory_id FROM memory_arbitrations
+WHERE new_memory_id = $1 AND action = 'REPLACE' AND candidate_memory_id IS NOT NULL
+UNION
+SELECT new_memory_id FROM memory_arbitrations
+WHERE candidate_memory_id = $1 AND action = 'REPLACE' AND new_memory_id IS NOT NULL`
+	rows, err := s.pool.Query(ctx, query, memoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *Store) SearchVectorFragments(ctx context.Context, vector pgvector.Vector, projectID string, filter FragmentFilter, limit int) ([]FragmentRow, error) {
 	query := `
 SELECT f.id, f.memory_id, f.chunk_index, f.content, m.content_type, p.project_key, m.ts, m.chunk_count,
        COALESCE(m.axes, '{}'::jsonb), COALESCE(m.index_path, '[]'::jsonb),
@@ -756,14 +1291,10 @@ SELECT f.id, f.memory_id, f.chunk_index, f.content, m.content_type, p.project_ke
 FROM fragments f
 JOIN memories m ON f.memory_id = m.id
 JOIN projects p ON m.project_id = p.id
-WHERE m.project_id = $2`
+WHERE m.project_id = $2
+  AND f.embedding IS NOT NULL`
 	args := []any{vector, projectID}
-	if scope != "all" && scope != "" {
-		query += " AND m.content_type = $3"
-		args = append(args, scope)
-	}
-	query, args = appendAxesFilter(query, args, axes)
-	query, args = appendIndexPathFilter(query, args, indexPath)
+	query, args = appendFragmentFilter(query, args, filter)
 	query += " ORDER BY f.embedding <=> $1 LIMIT $" + fmt.Sprintf("%d", len(args)+1)
 	args = append(args, limit)
 
@@ -775,7 +1306,7 @@ WHERE m.project_id = $2`
 	return scanFragmentRows(rows)
 }
 
-func (s *Store) SearchVectorFragmentsByOwner(ctx context.Context, vector pgvector.Vector, ownerID, scope string, axes MemoryAxes, indexPath []string, limit int) ([]FragmentRow, error) {
+func (s *Store) SearchVectorFragmentsByOwner(ctx context.Context, vector pgvector.Vector, ownerID string, filter FragmentFilter, limit int) ([]FragmentRow, error) {
 	query := `
 SELECT f.id, f.memory_id, f.chunk_index, f.content, m.content_type, p.project_key, m.ts, m.chunk_count,
        COALESCE(m.axes, '{}'::jsonb), COALESCE(m.index_path, '[]'::jsonb),
@@ -783,14 +1314,10 @@ SELECT f.id, f.memory_id, f.chunk_index, f.content, m.content_type, p.project_ke
 FROM fragments f
 JOIN memories m ON f.memory_id = m.id
 JOIN projects p ON m.project_id = p.id
-WHERE p.owner_id = $2`
+WHERE p.owner_id = $2
+  AND f.embedding IS NOT NULL`
 	args := []any{vector, ownerID}
-	if scope != "all" && scope != "" {
-		query += " AND m.content_type = $3"
-		args = append(args, scope)
-	}
-	query, args = appendAxesFilter(query, args, axes)
-	query, args = appendIndexPathFilter(query, args, indexPath)
+	query, args = appendFragmentFilter(query, args, filter)
 	query += " ORDER BY f.embedding <=> $1 LIMIT $" + fmt.Sprintf("%d", len(args)+1)
 	args = append(args, limit)
 
@@ -802,7 +1329,7 @@ WHERE p.owner_id = $2`
 	return scanFragmentRows(rows)
 }
 
-func (s *Store) SearchKeywordFragments(ctx context.Context, keyword, projectID, scope string, axes MemoryAxes, indexPath []string, limit int) ([]FragmentRow, error) {
+func (s *Store) SearchKeywordFragments(ctx context.Context, keyword, projectID string, filter FragmentFilter, limit int) ([]FragmentRow, error) {
 	query := `
 SELECT f.id, f.memory_id, f.chunk_index, f.content, m.content_type, p.project_key, m.ts, m.chunk_count,
        COALESCE(m.axes, '{}'::jsonb), COALESCE(m.index_path, '[]'::jsonb),
@@ -812,12 +1339,7 @@ JOIN memories m ON f.memory_id = m.id
 JOIN projects p ON m.project_id = p.id
 WHERE m.project_id = $1 AND f.content ILIKE $2`
 	args := []any{projectID, fmt.Sprintf("%%%s%%", keyword)}
-	if scope != "all" && scope != "" {
-		query += " AND m.content_type = $3"
-		args = append(args, scope)
-	}
-	query, args = appendAxesFilter(query, args, axes)
-	query, args = appendIndexPathFilter(query, args, indexPath)
+	query, args = appendFragmentFilter(query, args, filter)
 	query += " ORDER BY m.ts DESC LIMIT $" + fmt.Sprintf("%d", len(args)+1)
 	args = append(args, limit)
 	rows, err := s.pool.Query(ctx, query, args...)
@@ -828,7 +1350,7 @@ WHERE m.project_id = $1 AND f.content ILIKE $2`
 	return scanFragmentRows(rows)
 }
 
-func (s *Store) SearchKeywordFragmentsByOwner(ctx context.Context, keyword, ownerID, scope string, axes MemoryAxes, indexPath []string, limit int) ([]FragmentRow, error) {
+func (s *Store) SearchKeywordFragmentsByOwner(ctx context.Context, keyword, ownerID string, filter FragmentFilter, limit int) ([]FragmentRow, error) {
 	query := `
 SELECT f.id, f.memory_id, f.chunk_index, f.content, m.content_type, p.project_key, m.ts, m.chunk_count,
        COALESCE(m.axes, '{}'::jsonb), COALESCE(m.index_path, '[]'::jsonb),
@@ -838,12 +1360,7 @@ JOIN memories m ON f.memory_id = m.id
 JOIN projects p ON m.project_id = p.id
 WHERE p.owner_id = $1 AND f.content ILIKE $2`
 	args := []any{ownerID, fmt.Sprintf("%%%s%%", keyword)}
-	if scope != "all" && scope != "" {
-		query += " AND m.content_type = $3"
-		args = append(args, scope)
-	}
-	query, args = appendAxesFilter(query, args, axes)
-	query, args = appendIndexPathFilter(query, args, indexPath)
+	query, args = appendFragmentFilter(query, args, filter)
 	query += " ORDER BY m.ts DESC LIMIT $" + fmt.Sprintf("%d", len(args)+1)
 	args = append(args, limit)
 	rows, err := s.pool.Query(ctx, query, args...)
@@ -854,22 +1371,21 @@ WHERE p.owner_id = $1 AND f.content ILIKE $2`
 	return scanFragmentRows(rows)
 }
 
-func (s *Store) SearchBM25Fragments(ctx context.Context, keyword, projectID, scope string, axes MemoryAxes, indexPath []string, limit int) ([]FragmentRow, error) {
+func (s *Store) SearchBM25Fragments(ctx context.Context, keyword, projectID string, filter FragmentFilter, limit int) ([]FragmentRow, error) {
+	tsQuery := bm25Query(s.tokenizer, keyword, filter.Phrases)
+	if tsQuery == "" {
+		return nil, nil
+	}
 	query := `
 SELECT f.id, f.memory_id, f.chunk_index, f.content, m.content_type, p.project_key, m.ts, m.chunk_count,
        COALESCE(m.axes, '{}'::jsonb), COALESCE(m.index_path, '[]'::jsonb),
-       ts_rank_cd(to_tsvector('simple', f.content), plainto_tsquery('simple', $2)) AS rank
+       ts_rank_cd(f.lexemes, to_tsquery('simple', $2)) AS rank
 FROM fragments f
 JOIN memories m ON f.memory_id = m.id
 JOIN projects p ON m.project_id = p.id
-WHERE m.project_id = $1 AND to_tsvector('simple', f.content) @@ plainto_tsquery('simple', $2)`
-	args := []any{projectID, keyword}
-	if scope != "all" && scope != "" {
-		query += " AND m.content_type = $3"
-		args = append(args, scope)
-	}
-	query, args = appendAxesFilter(query, args, axes)
-	query, args = appendIndexPathFilter(query, args, indexPath)
+WHERE m.project_id = $1 AND f.lexemes @@ to_tsquery('simple', $2)`
+	args := []any{projectID, tsQuery}
+	query, args = appendFragmentFilter(query, args, filter)
 	query += " ORDER BY rank DESC LIMIT $" + fmt.Sprintf("%d", len(args)+1)
 	args = append(args, limit)
 
@@ -894,22 +1410,21 @@ WHERE m.project_id = $1 AND to_tsvector('simple', f.content) @@ plainto_tsquery(
 	return results, rows.Err()
 }
 
-func (s *Store) SearchBM25FragmentsByOwner(ctx context.Context, keyword, ownerID, scope string, axes MemoryAxes, indexPath []string, limit int) ([]FragmentRow, error) {
+func (s *Store) SearchBM25FragmentsByOwner(ctx context.Context, keyword, ownerID string, filter FragmentFilter, limit int) ([]FragmentRow, error) {
+	tsQuery := bm25Query(s.tokenizer, keyword, filter.Phrases)
+	if tsQuery == "" {
+		return nil, nil
+	}
 	query := `
 SELECT f.id, f.memory_id, f.chunk_index, f.content, m.content_type, p.project_key, m.ts, m.chunk_count,
        COALESCE(m.axes, '{}'::jsonb), COALESCE(m.index_path, '[]'::jsonb),
-       ts_rank_cd(to_tsvector('simple', f.content), plainto_tsquery('simple', $2)) AS rank
+       ts_rank_cd(f.lexemes, to_tsquery('simple', $2)) AS rank
 FROM fragments f
 JOIN memories m ON f.memory_id = m.id
 JOIN projects p ON m.project_id = p.id
-WHERE p.owner_id = $1 AND to_tsvector('simple', f.content) @@ plainto_tsquery('simple', $2)`
-	args := []any{ownerID, keyword}
-	if scope != "all" && scope != "" {
-		query += " AND m.content_type = $3"
-		args = append(args, scope)
-	}
-	query, args = appendAxesFilter(query, args, axes)
-	query, args = appendIndexPathFilter(query, args, indexPath)
+WHERE p.owner_id = $1 AND f.lexemes @@ to_tsquery('simple', $2)`
+	args := []any{ownerID, tsQuery}
+	query, args = appendFragmentFilter(query, args, filter)
 	query += " ORDER BY rank DESC LIMIT $" + fmt.Sprintf("%d", len(args)+1)
 	args = append(args, limit)
 
@@ -934,11 +1449,69 @@ WHERE p.owner_id = $1 AND to_tsvector('simple', f.content) @@ plainto_tsquery('s
 	return results, rows.Err()
 }
 
-func (s *Store) FetchTagCounts(ctx context.Context, projectID, ownerID string, limit int, indexPath []string) ([]AxisCount, error) {
-	query := `
-SELECT value, COUNT(*) FROM (
-  SELECT jsonb_array_elements_text(COALESCE(m.tags, '[]'::jsonb)) AS value
-  FROM memories m
+// SearchFuzzyFragments pg_trgm 模糊召回（项目内），按 word_similarity 降序
+func (s *Store) SearchFuzzyFragments(ctx context.Context, keyword, projectID string, threshold float64, filter FragmentFilter, limit int) ([]FragmentRow, error) {
+	return s.searchFuzzyFragments(ctx, "m.project_id = $1", projectID, keyword, threshold, filter, limit)
+}
+
+// SearchFuzzyFragmentsByOwner pg_trgm 模糊召回（owner 范围）
+func (s *Store) SearchFuzzyFragmentsByOwner(ctx context.Context, keyword, ownerID string, threshold float64, filter FragmentFilter, limit int) ([]FragmentRow, error) {
+	return s.searchFuzzyFragments(ctx, "p.owner_id = $1", ownerID, keyword, threshold, filter, limit)
+}
+
+func (s *Store) searchFuzzyFragments(ctx context.Context, scopeWhere string, scopeArg any, keyword string, threshold float64, filter FragmentFilter, limit int) ([]FragmentRow, error) {
+	if !s.trigram {
+		return nil, nil
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	// <% 运算符使用会话阈值，SET LOCAL 仅对本事务生效，保证 GIN 索引可用
+	if _, err := tx.Exec(ctx, "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)", fmt.Sprintf("%g", threshold)); err != nil {
+		return nil, err
+	}
+
+	query := `
+SELECT f.id, f.memory_id, f.chunk_index, f.content, m.content_type, p.project_key, m.ts, m.chunk_count,
+       COALESCE(m.axes, '{}'::jsonb), COALESCE(m.index_path, '[]'::jsonb),
+       word_similarity($2, f.content) AS rank
+FROM fragments f
+JOIN memories m ON f.memory_id = m.id
+JOIN projects p ON m.project_id = p.id
+WHERE ` + scopeWhere + ` AND $2 <% f.content`
+	args := []any{scopeArg, keyword}
+	query, args = appendFragmentFilter(query, args, filter)
+	query += " ORDER BY rank DESC, m.ts DESC LIMIT $" + fmt.Sprintf("%d", len(args)+1)
+	args = append(args, limit)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []FragmentRow
+	for rows.Next() {
+		var row FragmentRow
+		var axesJSON []byte
+		var pathJSON []byte
+		if err := rows.Scan(&row.FragmentID, &row.MemoryID, &row.ChunkIndex, &row.Content, &row.ContentType, &row.ProjectKey, &row.Ts, &row.ChunkCount, &axesJSON, &pathJSON, &row.RankScore); err != nil {
+			return nil, err
+		}
+		row.Axes = decodeAxes(axesJSON)
+		row.IndexPath = decodeIndexPath(pathJSON)
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+func (s *Store) FetchTagCounts(ctx context.Context, projectID, ownerID string, limit int, indexPath []string) ([]AxisCount, error) {
+	query := `
+SELECT value, COUNT(*) FROM (
+  SELECT jsonb_array_elements_text(COALESCE(m.tags, '[]'::jsonb)) AS value
+  FROM memories m
   JOIN projects p ON m.project_id = p.id
   WHERE %s
 ) t
@@ -969,6 +1542,38 @@ LIMIT $1`
 	return results, rows.Err()
 }
 
+// FetchContentTypeCounts 按 content_type 聚合记忆数（用于参数补全）
+func (s *Store) FetchContentTypeCounts(ctx context.Context, projectID, ownerID string, limit int) ([]AxisCount, error) {
+	query := `
+SELECT m.content_type, COUNT(*)
+FROM memories m
+JOIN projects p ON m.project_id = p.id
+WHERE %s AND m.content_type <> ''
+GROUP BY m.content_type
+ORDER BY COUNT(*) DESC
+LIMIT $1`
+	where := "p.owner_id = $2"
+	args := []any{limit, ownerID}
+	if strings.TrimSpace(projectID) != "" {
+		where = "m.project_id = $2"
+		args[1] = projectID
+	}
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(query, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []AxisCount
+	for rows.Next() {
+		var item AxisCount
+		if err := rows.Scan(&item.Value, &item.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, item)
+	}
+	return results, rows.Err()
+}
+
 func (s *Store) FetchAxisCounts(ctx context.Context, projectID, ownerID, axis string, limit int, indexPath []string) ([]AxisCount, error) {
 	if !isAxisAllowed(axis) {
 		return nil, fmt.Errorf("axis 不支持")
@@ -1122,6 +1727,61 @@ func scanFragmentRows(rows pgx.Rows) ([]FragmentRow, error) {
 	return results, rows.Err()
 }
 
+func appendFragmentFilter(query string, args []any, filter FragmentFilter) (string, []any) {
+	if filter.Scope != "all" && filter.Scope != "" {
+		query += " AND m.content_type = $" + fmt.Sprintf("%d", len(args)+1)
+		args = append(args, filter.Scope)
+	}
+	if len(filter.ContentTypes) > 0 {
+		query += " AND m.content_type = ANY($" + fmt.Sprintf("%d", len(args)+1) + ")"
+		args = append(args, filter.ContentTypes)
+	}
+	if len(filter.ProjectIDs) > 0 {
+		query += " AND m.project_id = ANY($" + fmt.Sprintf("%d", len(args)+1) + "::uuid[])"
+		args = append(args, filter.ProjectIDs)
+	}
+	query, args = appendAxesFilter(query, args, filter.Axes)
+	query, args = appendIndexPathFilter(query, args, filter.IndexPath)
+	query, args = appendTagsFilter(query, args, filter.Tags, filter.ExcludeTags)
+	query, args = appendTimeRangeFilter(query, args, filter.Since, filter.Until)
+	return query, args
+}
+
+// appendTagsFilter required 必须全部命中（?&），excluded 任一命中即排除（?|）
+func appendTagsFilter(query string, args []any, required, excluded []string) (string, []any) {
+	if len(required) > 0 {
+		query += " AND COALESCE(m.tags, '[]'::jsonb) ?& $" + fmt.Sprintf("%d", len(args)+1)
+		args = append(args, required)
+	}
+	if len(excluded) > 0 {
+		query += " AND NOT (COALESCE(m.tags, '[]'::jsonb) ?| $" + fmt.Sprintf("%d", len(args)+1) + ")"
+		args = append(args, excluded)
+	}
+	return query, args
+}
+
+// appendKeysetAfter 追加 keyset 分页条件：(tsExpr, keyExpr) < (after.Ts, after.Key)，配合倒序排序使用
+func appendKeysetAfter(query string, args []any, tsExpr, keyExpr string, after *pageCursor) (string, []any) {
+	if after == nil {
+		return query, args
+	}
+	query += fmt.Sprintf(" AND (%s, %s) < ($%d, $%d)", tsExpr, keyExpr, len(args)+1, len(args)+2)
+	args = append(args, after.Ts, after.Key)
+	return query, args
+}
+
+func appendTimeRangeFilter(query string, args []any, since, until int64) (string, []any) {
+	if since > 0 {
+		query += " AND m.ts >= $" + fmt.Sprintf("%d", len(args)+1)
+		args = append(args, since)
+	}
+	if until > 0 {
+		query += " AND m.ts <= $" + fmt.Sprintf("%d", len(args)+1)
+		args = append(args, until)
+	}
+	return query, args
+}
+
 func appendAxesFilter(query string, args []any, axes MemoryAxes) (string, []any) {
 	query, args = appendAxisFilter(query, args, "domain", axes.Domain)
 	query, args = appendAxisFilter(query, args, "stack", axes.Stack)
@@ -1202,12 +1862,12 @@ func nullableString(value string) any {
 
 // === 仲裁历史与回滚 ===
 
-// FetchArbitrationHistory 查询仲裁历史
-func (s *Store) FetchArbitrationHistory(ctx context.Context, ownerID, memoryID, projectID string, limit int) ([]ArbitrationRecord, error) {
+// FetchArbitrationHistory 查询仲裁历史（按 id 倒序，afterID > 0 时只取更早的记录）
+func (s *Store) FetchArbitrationHistory(ctx context.Context, ownerID, memoryID, projectID string, afterID int64, limit int) ([]ArbitrationRecord, error) {
 	query := `
 SELECT id, COALESCE(candidate_memory_id, ''), COALESCE(new_memory_id, ''), action,
        COALESCE(similarity, 0), COALESCE(old_summary, ''), COALESCE(new_summary, ''),
-       COALESCE(model, ''), EXTRACT(EPOCH FROM created_at)::BIGINT
+       COALESCE(model, ''), COALESCE(decided_by, 'model'), EXTRACT(EPOCH FROM created_at)::BIGINT
 FROM memory_arbitrations
 WHERE owner_id = $1`
 	args := []any{ownerID}
@@ -1220,7 +1880,11 @@ WHERE owner_id = $1`
 		query += " AND project_id = $" + fmt.Sprintf("%d", len(args)+1)
 		args = append(args, projectID)
 	}
-	query += " ORDER BY created_at DESC LIMIT $" + fmt.Sprintf("%d", len(args)+1)
+	if afterID > 0 {
+		query += " AND id < $" + fmt.Sprintf("%d", len(args)+1)
+		args = append(args, afterID)
+	}
+	query += " ORDER BY id DESC LIMIT $" + fmt.Sprintf("%d", len(args)+1)
 	args = append(args, limit)
 
 	rows, err := s.pool.Query(ctx, query, args...)
@@ -1233,7 +1897,7 @@ WHERE owner_id = $1`
 	for rows.Next() {
 		var r ArbitrationRecord
 		if err := rows.Scan(&r.ID, &r.CandidateMemoryID, &r.NewMemoryID, &r.Action,
-			&r.Similarity, &r.OldSummary, &r.NewSummary, &r.Model, &r.CreatedAt); err != nil {
+			&r.Similarity, &r.OldSummary, &r.NewSummary, &r.Model, &r.DecidedBy, &r.CreatedAt); err != nil {
 			return nil, err
 		}
 		results = append(results, r)
@@ -1272,13 +1936,13 @@ func (s *Store) FetchArbitrationByID(ctx context.Context, id int64) (Arbitration
 	query := `
 SELECT id, COALESCE(candidate_memory_id, ''), COALESCE(new_memory_id, ''), action,
        COALESCE(similarity, 0), COALESCE(old_summary, ''), COALESCE(new_summary, ''),
-       COALESCE(model, ''), EXTRACT(EPOCH FROM created_at)::BIGINT
+       COALESCE(model, ''), COALESCE(decided_by, 'model'), EXTRACT(EPOCH FROM created_at)::BIGINT
 FROM memory_arbitrations
 WHERE id = $1`
 
 	var r ArbitrationRecord
 	err := s.pool.QueryRow(ctx, query, id).Scan(&r.ID, &r.CandidateMemoryID, &r.NewMemoryID, &r.Action,
-		&r.Similarity, &r.OldSummary, &r.NewSummary, &r.Model, &r.CreatedAt)
+		&r.Similarity, &r.OldSummary, &r.NewSummary, &r.Model, &r.DecidedBy, &r.CreatedAt)
 	return r, err
 }
 
@@ -1340,6 +2004,124 @@ RETURNING id`,
 	return id, err
 }
 
+// FeedbackRecord 检索反馈：显式（mem.feedback）或隐式（检索后 mem.get 跟进）
+type FeedbackRecord struct {
+	OwnerID  string
+	MemoryID string
+	Query    string
+	Helpful  bool
+	Implicit bool
+	Sources  []string
+	Rank     int
+	Note     string
+}
+
+func (s *Store) InsertFeedback(ctx context.Context, record FeedbackRecord) (int64, error) {
+	sources := uniqueStrings(record.Sources)
+	if sources == nil {
+		sources = []string{}
+	}
+	sourcesJSON, err := json.Marshal(sources)
+	if err != nil {
+		return 0, err
+	}
+	var rank any
+	if record.Rank > 0 {
+		rank = record.Rank
+	}
+	var id int64
+	err = s.pool.QueryRow(ctx, `
+INSERT INTO search_feedback (owner_id, memory_id, query, helpful, implicit, sources, rank, note)
+VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7, NULLIF($8, ''))
+RETURNING id`,
+		record.OwnerID, record.MemoryID, record.Query, record.Helpful, record.Implicit, string(sourcesJSON), rank, record.Note,
+	).Scan(&id)
+	return id, err
+}
+
+// FeedbackStat 反馈聚合（隐式反馈按 implicitWeight 计权）
+type FeedbackStat struct {
+	Key      string
+	Positive float64
+	Total    float64
+}
+
+// FetchFeedbackSourceStats 按召回源聚合反馈：返回该源参与召回的结果被标记有用的加权次数
+func (s *Store) FetchFeedbackSourceStats(ctx context.Context, ownerID string, implicitWeight float64, windowDays int) ([]FeedbackStat, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT src, SUM(CASE WHEN f.helpful THEN f.w ELSE 0 END), SUM(f.w)
+FROM (
+  SELECT sources, helpful, CASE WHEN implicit THEN $2::float8 ELSE 1.0 END AS w
+  FROM search_feedback
+  WHERE owner_id = $1 AND created_at > NOW() - make_interval(days => $3)
+) f, jsonb_array_elements_text(f.sources) AS src
+GROUP BY src`, ownerID, implicitWeight, windowDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFeedbackStats(rows)
+}
+
+// FetchFeedbackMemoryStats 按记忆聚合反馈，用于单条记忆的排序加权
+func (s *Store) FetchFeedbackMemoryStats(ctx context.Context, ownerID string, implicitWeight float64, windowDays int) ([]FeedbackStat, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT memory_id, SUM(CASE WHEN helpful THEN w ELSE 0 END), SUM(w)
+FROM (
+  SELECT memory_id, helpful, CASE WHEN implicit THEN $2::float8 ELSE 1.0 END AS w
+  FROM search_feedback
+  WHERE owner_id = $1 AND created_at > NOW() - make_interval(days => $3)
+) f
+GROUP BY memory_id`, ownerID, implicitWeight, windowDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFeedbackStats(rows)
+}
+
+func scanFeedbackStats(rows pgx.Rows) ([]FeedbackStat, error) {
+	var stats []FeedbackStat
+	for rows.Next() {
+		var stat FeedbackStat
+		if err := rows.Scan(&stat.Key, &stat.Positive, &stat.Total); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+// FeedbackQuery 有反馈的历史查询及其中被标记有用的记忆，用于排序漂移报告
+type FeedbackQuery struct {
+	Query      string
+	HelpfulIDs []string
+}
+
+func (s *Store) FetchFeedbackQueries(ctx context.Context, ownerID string, limit int) ([]FeedbackQuery, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT query,
+       COALESCE(array_agg(DISTINCT memory_id) FILTER (WHERE helpful), '{}')
+FROM search_feedback
+WHERE owner_id = $1 AND query <> ''
+GROUP BY query
+ORDER BY MAX(created_at) DESC
+LIMIT $2`, ownerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []FeedbackQuery
+	for rows.Next() {
+		var item FeedbackQuery
+		if err := rows.Scan(&item.Query, &item.HelpfulIDs); err != nil {
+			return nil, err
+		}
+		results = append(results, item)
+	}
+	return results, rows.Err()
+}
+
 // InsertRelationTx 在事务中创建记忆间关系边（best-effort，忽略错误）
 func insertRelationTx(ctx context.Context, tx pgxTx, sourceID, targetID, relationType string, strength float64) {
 	_, _ = tx.Exec(ctx, `
@@ -1350,8 +2132,8 @@ ON CONFLICT (source_id, target_id, relation_type) DO NOTHING`,
 	)
 }
 
-// FetchRelations 查询记忆的关联关系
-func (s *Store) FetchRelations(ctx context.Context, memoryID, direction, relationType string, limit int) ([]RelationRecord, error) {
+// FetchRelations 查询记忆的关联关系（按 id 倒序，afterID > 0 时只取更早的记录）
+func (s *Store) FetchRelations(ctx context.Context, memoryID, direction, relationType string, afterID int64, limit int) ([]RelationRecord, error) {
 	if limit <= 0 {
 		limit = 20
 	}
@@ -1378,7 +2160,7 @@ WHERE target_id = $1`
 SELECT id, source_id, target_id, relation_type, COALESCE(strength, 1.0),
        metadata, EXTRACT(EPOCH FROM created_at)::BIGINT
 FROM memory_relations
-WHERE source_id = $1 OR target_id = $1`
+WHERE (source_id = $1 OR target_id = $1)`
 		args = []any{memoryID}
 	}
 
@@ -1386,7 +2168,11 @@ WHERE source_id = $1 OR target_id = $1`
 		query += " AND relation_type = $" + fmt.Sprintf("%d", len(args)+1)
 		args = append(args, relationType)
 	}
-	query += " ORDER BY created_at DESC LIMIT $" + fmt.Sprintf("%d", len(args)+1)
+	if afterID > 0 {
+		query += " AND id < $" + fmt.Sprintf("%d", len(args)+1)
+		args = append(args, afterID)
+	}
+	query += " ORDER BY id DESC LIMIT $" + fmt.Sprintf("%d", len(args)+1)
 	args = append(args, limit)
 
 	rows, err := s.pool.Query(ctx, query, args...)
@@ -1501,8 +2287,8 @@ WHERE memory_id = $1 AND replaced_at = $2`, version.MemoryID, version.ReplacedAt
 	return tx.Commit(ctx)
 }
 
-// FetchTopFragmentsByMemoryIDs 获取指定 memory IDs 的第一个片段（用于前瞻召回）
-func (s *Store) FetchTopFragmentsByMemoryIDs(ctx context.Context, memoryIDs []string) ([]FragmentRow, error) {
+// FetchTopFragmentsByMemoryIDs 获取指定 memory IDs 的第一个片段（用于前瞻召回），同样应用检索过滤条件
+func (s *Store) FetchTopFragmentsByMemoryIDs(ctx context.Context, memoryIDs []string, filter FragmentFilter) ([]FragmentRow, error) {
 	if len(memoryIDs) == 0 {
 		return nil, nil
 	}
@@ -1514,9 +2300,11 @@ SELECT DISTINCT ON (f.memory_id)
 FROM fragments f
 JOIN memories m ON f.memory_id = m.id
 JOIN projects p ON m.project_id = p.id
-WHERE f.memory_id = ANY($1)
-ORDER BY f.memory_id, f.chunk_index`
-	rows, err := s.pool.Query(ctx, query, memoryIDs)
+WHERE f.memory_id = ANY($1)`
+	args := []any{memoryIDs}
+	query, args = appendFragmentFilter(query, args, filter)
+	query += " ORDER BY f.memory_id, f.chunk_index"
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -1604,17 +2392,22 @@ func (s *Store) CleanExpiredForesights(ctx context.Context) (int64, error) {
 	return tag.RowsAffected(), nil
 }
 
+// foresightCreatedMicrosExpr 前瞻分页排序键：created_at 的微秒时间戳
+const foresightCreatedMicrosExpr = "(EXTRACT(EPOCH FROM created_at) * 1000000)::BIGINT"
+
 // FetchForesightsByMemory 查询某条记忆的前瞻（未过期）
-func (s *Store) FetchForesightsByMemory(ctx context.Context, memoryID string, limit int) ([]ForesightRow, error) {
+func (s *Store) FetchForesightsByMemory(ctx context.Context, memoryID string, after *pageCursor, limit int) ([]ForesightRow, error) {
 	query := `
 SELECT id, source_memory_id, project_id, prediction, relevance_score,
-       EXTRACT(EPOCH FROM expires_at)::BIGINT
+       EXTRACT(EPOCH FROM expires_at)::BIGINT, ` + foresightCreatedMicrosExpr + `
 FROM memory_foresights
 WHERE source_memory_id = $1
-  AND (expires_at IS NULL OR expires_at > NOW())
-ORDER BY created_at DESC
-LIMIT $2`
-	rows, err := s.pool.Query(ctx, query, memoryID, limit)
+  AND (expires_at IS NULL OR expires_at > NOW())`
+	args := []any{memoryID}
+	query, args = appendKeysetAfter(query, args, foresightCreatedMicrosExpr, "id", after)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -1622,7 +2415,7 @@ LIMIT $2`
 	var results []ForesightRow
 	for rows.Next() {
 		var row ForesightRow
-		if err := rows.Scan(&row.ID, &row.SourceMemoryID, &row.ProjectID, &row.Prediction, &row.RelevanceScore, &row.ExpiresAt); err != nil {
+		if err := rows.Scan(&row.ID, &row.SourceMemoryID, &row.ProjectID, &row.Prediction, &row.RelevanceScore, &row.ExpiresAt, &row.createdAtMicros); err != nil {
 			return nil, err
 		}
 		results = append(results, row)
@@ -1631,16 +2424,18 @@ LIMIT $2`
 }
 
 // FetchForesightsByProject 查询项目的前瞻（未过期）
-func (s *Store) FetchForesightsByProject(ctx context.Context, projectID string, limit int) ([]ForesightRow, error) {
+func (s *Store) FetchForesightsByProject(ctx context.Context, projectID string, after *pageCursor, limit int) ([]ForesightRow, error) {
 	query := `
 SELECT id, source_memory_id, project_id, prediction, relevance_score,
-       EXTRACT(EPOCH FROM expires_at)::BIGINT
+       EXTRACT(EPOCH FROM expires_at)::BIGINT, ` + foresightCreatedMicrosExpr + `
 FROM memory_foresights
 WHERE project_id = $1
-  AND (expires_at IS NULL OR expires_at > NOW())
-ORDER BY created_at DESC
-LIMIT $2`
-	rows, err := s.pool.Query(ctx, query, projectID, limit)
+  AND (expires_at IS NULL OR expires_at > NOW())`
+	args := []any{projectID}
+	query, args = appendKeysetAfter(query, args, foresightCreatedMicrosExpr, "id", after)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -1648,10 +2443,336 @@ LIMIT $2`
 	var results []ForesightRow
 	for rows.Next() {
 		var row ForesightRow
-		if err := rows.Scan(&row.ID, &row.SourceMemoryID, &row.ProjectID, &row.Prediction, &row.RelevanceScore, &row.ExpiresAt); err != nil {
+		if err := rows.Scan(&row.ID, &row.SourceMemoryID, &row.ProjectID, &row.Prediction, &row.RelevanceScore, &row.ExpiresAt, &row.createdAtMicros); err != nil {
 			return nil, err
 		}
 		results = append(results, row)
 	}
 	return results, rows.Err()
 }
+
+// ClaimIngestJob 领取一个到期的异步写入任务（SKIP LOCKED 保证多 worker、多实例不重复领取）；
+// 执行超过 staleAfter 仍未完成的任务视为 worker 已退出，重新领取
+func (s *Store) ClaimIngestJob(ctx context.Context, workerID string, staleAfter time.Duration) (IngestJobRecord, bool, error) {
+	var job IngestJobRecord
+	var memoryID sql.NullString
+	var payload []byte
+	err := s.pool.QueryRow(ctx, `
+UPDATE ingest_jobs
+SET status = 'running', attempts = attempts + 1, locked_by = $1, locked_at = NOW(), updated_at = NOW()
+WHERE id = (
+  SELECT id FROM ingest_jobs
+  WHERE (status = 'queued' AND next_run_at <= NOW())
+     OR (status = 'running' AND locked_at < NOW() - make_interval(secs => $2))
+  ORDER BY next_run_at
+  FOR UPDATE SKIP LOCKED
+  LIMIT 1
+)
+RETURNING id, owner_id, project_id::text, memory_id, payload, attempts, max_attempts`,
+		workerID, staleAfter.Seconds()).Scan(&job.ID, &job.OwnerID, &job.ProjectID, &memoryID, &payload, &job.Attempts, &job.MaxAttempts)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return IngestJobRecord{}, false, nil
+		}
+		return IngestJobRecord{}, false, err
+	}
+	job.MemoryID = memoryID.String
+	if err := json.Unmarshal(payload, &job.Payload); err != nil {
+		return job, true, fmt.Errorf("任务内容解析失败: %w", err)
+	}
+	return job, true, nil
+}
+
+// RetryIngestJob 任务放回队列，delay 后重试；任务已被其他 worker 重新领取时不做修改
+func (s *Store) RetryIngestJob(ctx context.Context, jobID, workerID, lastError string, delay time.Duration) error {
+	_, err := s.pool.Exec(ctx, `
+UPDATE ingest_jobs
+SET status = 'queued', next_run_at = NOW() + make_interval(secs => $4), last_error = $3,
+    locked_by = NULL, locked_at = NULL, updated_at = NOW()
+WHERE id = $1 AND locked_by = $2 AND status = 'running'`, jobID, workerID, lastError, delay.Seconds())
+	return err
+}
+
+// ReleaseIngestJobs 退出时放回这些 worker 仍持有的任务，立即可被重新领取；被打断的这次不计入尝试次数
+func (s *Store) ReleaseIngestJobs(ctx context.Context, workerIDs []string) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+UPDATE ingest_jobs
+SET status = 'queued', attempts = GREATEST(attempts - 1, 0), next_run_at = NOW(),
+    locked_by = NULL, locked_at = NULL, updated_at = NOW()
+WHERE locked_by = ANY($1) AND status = 'running'`, workerIDs)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// FetchIngestJobs 按 owner 查询异步写入任务状态
+func (s *Store) FetchIngestJobs(ctx context.Context, ownerID string, jobIDs []string) ([]IngestJobStatus, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT id, status, CASE WHEN status = 'failed' THEN '' ELSE COALESCE(result_memory_id, memory_id, '') END, COALESCE(result_status, ''),
+       attempts, max_attempts, EXTRACT(EPOCH FROM next_run_at)::BIGINT, COALESCE(last_error, ''),
+       EXTRACT(EPOCH FROM created_at)::BIGINT, EXTRACT(EPOCH FROM updated_at)::BIGINT
+FROM ingest_jobs
+WHERE owner_id = $1 AND id = ANY($2)`, ownerID, jobIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []IngestJobStatus
+	for rows.Next() {
+		var job IngestJobStatus
+		if err := rows.Scan(&job.JobID, &job.Status, &job.MemoryID, &job.ResultStatus, &job.Attempts, &job.MaxAttempts, &job.NextRunAt, &job.LastError, &job.CreatedAt, &job.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if job.Status != jobQueued {
+			job.NextRunAt = 0
+		}
+		results = append(results, job)
+	}
+	return results, rows.Err()
+}
+
+// jobColumns JobRecord 的查询列，与 scanJob 对应
+const jobColumns = `id, kind, status, payload, attempts, max_attempts, EXTRACT(EPOCH FROM run_at)::BIGINT,
+       COALESCE(schedule, ''), COALESCE(last_error, ''), COALESCE(locked_by, ''),
+       EXTRACT(EPOCH FROM created_at)::BIGINT, COALESCE(EXTRACT(EPOCH FROM finished_at)::BIGINT, 0)`
+
+func scanJob(row pgx.Row) (JobRecord, error) {
+	var job JobRecord
+	var payload []byte
+	err := row.Scan(&job.ID, &job.Kind, &job.Status, &payload, &job.Attempts, &job.MaxAttempts, &job.RunAt,
+		&job.Schedule, &job.LastError, &job.LockedBy, &job.CreatedAt, &job.FinishedAt)
+	job.Payload = payload
+	return job, err
+}
+
+// ClaimJob 领取一个到期的后台任务，规则与 ClaimIngestJob 相同
+func (s *Store) ClaimJob(ctx context.Context, workerID string, staleAfter time.Duration) (JobRecord, bool, error) {
+	job, err := scanJob(s.pool.QueryRow(ctx, `
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1, locked_by = $1, locked_at = NOW(), updated_at = NOW()
+WHERE id = (
+  SELECT id FROM jobs
+  WHERE (status = 'queued' AND run_at <= NOW())
+     OR (status = 'running' AND locked_at < NOW() - make_interval(secs => $2))
+  ORDER BY run_at
+  FOR UPDATE SKIP LOCKED
+  LIMIT 1
+)
+RETURNING `+jobColumns, workerID, staleAfter.Seconds()))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return JobRecord{}, false, nil
+		}
+		return JobRecord{}, false, err
+	}
+	return job, true, nil
+}
+
+// ReleaseJobs 退出时放回这些 worker 仍持有的任务，立即可被重新领取；被打断的这次不计入尝试次数
+func (s *Store) ReleaseJobs(ctx context.Context, workerIDs []string) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+UPDATE jobs
+SET status = 'queued', attempts = GREATEST(attempts - 1, 0), run_at = NOW(),
+    locked_by = NULL, locked_at = NULL, updated_at = NOW()
+WHERE locked_by = ANY($1) AND status = 'running'`, workerIDs)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// CompleteJob 任务执行成功；任务已被取消或被其他 worker 重新领取时不做修改
+func (s *Store) CompleteJob(ctx context.Context, jobID, workerID string) error {
+	_, err := s.pool.Exec(ctx, `
+UPDATE jobs
+SET status = 'done', last_error = NULL, locked_by = NULL, locked_at = NULL, updated_at = NOW(), finished_at = NOW()
+WHERE id = $1 AND locked_by = $2 AND status = 'running'`, jobID, workerID)
+	return err
+}
+
+// RetryJob 任务放回队列，delay 后重试
+func (s *Store) RetryJob(ctx context.Context, jobID, workerID, lastError string, delay time.Duration) error {
+	_, err := s.pool.Exec(ctx, `
+UPDATE jobs
+SET status = 'queued', run_at = NOW() + make_interval(secs => $4), last_error = $3,
+    locked_by = NULL, locked_at = NULL, updated_at = NOW()
+WHERE id = $1 AND locked_by = $2 AND status = 'running'`, jobID, workerID, lastError, delay.Seconds())
+	return err
+}
+
+// FailJob 任务不再重试
+func (s *Store) FailJob(ctx context.Context, jobID, workerID, lastError string) error {
+	_, err := s.pool.Exec(ctx, `
+UPDATE jobs
+SET status = 'failed', last_error = $3, locked_by = NULL, locked_at = NULL, updated_at = NOW(), finished_at = NOW()
+WHERE id = $1 AND locked_by = $2 AND status = 'running'`, jobID, workerID, lastError)
+	return err
+}
+
+// FetchJobStatus 任务不存在时返回空字符串
+func (s *Store) FetchJobStatus(ctx context.Context, jobID string) (string, error) {
+	var status string
+	err := s.pool.QueryRow(ctx, `SELECT status FROM jobs WHERE id = $1`, jobID).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return status, err
+}
+
+// CancelJobs 取消排队或执行中的任务，执行中的任务由 worker 轮询状态后中止；返回取消的任务数
+func (s *Store) CancelJobs(ctx context.Context, jobIDs []string) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+UPDATE jobs
+SET status = 'cancelled', locked_by = NULL, locked_at = NULL, updated_at = NOW(), finished_at = NOW()
+WHERE id = ANY($1) AND status IN ('queued', 'running')`, jobIDs)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// FetchJobs 按创建时间倒序列出任务，status/kind 为空时不过滤
+func (s *Store) FetchJobs(ctx context.Context, status, kind string, limit int) ([]JobRecord, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.pool.Query(ctx, `
+SELECT `+jobColumns+`
+FROM jobs
+WHERE ($1 = '' OR status = $1) AND ($2 = '' OR kind = $2)
+ORDER BY created_at DESC, id
+LIMIT $3`, status, kind, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []JobRecord{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, job)
+	}
+	return results, rows.Err()
+}
+
+// PruneFinishedJobs 删除早于 before 结束的后台任务与异步写入任务（排队、执行中的任务保留）
+func (s *Store) PruneFinishedJobs(ctx context.Context, before time.Time) (int64, error) {
+	jobsTag, err := s.pool.Exec(ctx, `
+DELETE FROM jobs WHERE status IN ('done', 'failed', 'cancelled') AND finished_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	ingestTag, err := s.pool.Exec(ctx, `
+DELETE FROM ingest_jobs WHERE status IN ('done', 'failed') AND updated_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return jobsTag.RowsAffected() + ingestTag.RowsAffected(), nil
+}
+
+// PruneMemoryVersions 每条记忆只保留最近 keep 个历史版本
+func (s *Store) PruneMemoryVersions(ctx context.Context, keep int) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+DELETE FROM memory_versions
+WHERE id IN (
+  SELECT id FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY memory_id ORDER BY replaced_at DESC, id DESC) AS rn
+    FROM memory_versions
+  ) ranked
+  WHERE rn > $1
+)`, keep)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// FetchMemoriesMissingEmbeddings 缺少向量的记忆（向量化失败或异步写入失败遗留），
+// 排队或执行中的异步写入临时记忆由写入任务补全，不在此列
+func (s *Store) FetchMemoriesMissingEmbeddings(ctx context.Context, limit int) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT m.id
+FROM memories m
+WHERE (m.avg_embedding IS NULL OR EXISTS (SELECT 1 FROM fragments f WHERE f.memory_id = m.id AND f.embedding IS NULL))
+  AND NOT EXISTS (
+    SELECT 1 FROM ingest_jobs j WHERE j.memory_id = m.id AND j.status IN ('queued', 'running')
+  )
+ORDER BY m.created_at
+LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// UpdateMemoryEmbeddings 写回片段向量与记忆平均向量；片段在此期间被替换时不做修改
+func (s *Store) UpdateMemoryEmbeddings(ctx context.Context, memoryID string, fragments []FragmentRow, embeddings [][]float32, avgEmbedding []float32) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	for idx, frag := range fragments {
+		tag, err := tx.Exec(ctx, `UPDATE fragments SET embedding = $3 WHERE id = $1 AND memory_id = $2`,
+			frag.FragmentID, memoryID, pgvector.NewVector(embeddings[idx]))
+		if err != nil {
+			return false, err
+		}
+		if tag.RowsAffected() == 0 {
+			return false, nil
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+UPDATE memories SET avg_embedding = $2, embedding_done = true WHERE id = $1`,
+		memoryID, pgvector.NewVector(avgEmbedding)); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// HasPendingJob 是否已有排队或执行中、参数包含 payload 的同类任务
+func (s *Store) HasPendingJob(ctx context.Context, kind string, payload any) (bool, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	var exists bool
+	err = s.pool.QueryRow(ctx, `
+SELECT EXISTS (
+  SELECT 1 FROM jobs WHERE kind = $1 AND status IN ('queued', 'running') AND payload @> $2::jsonb
+)`, kind, string(payloadJSON)).Scan(&exists)
+	return exists, err
+}
+
+// FetchAllProjects 所有 owner 的项目（自动蒸馏按策略逐个检查）
+func (s *Store) FetchAllProjects(ctx context.Context) ([]ProjectRecord, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT id::text, project_name, project_key, owner_id
+FROM projects
+ORDER BY owner_id, project_key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []ProjectRecord
+	for rows.Next() {
+		var project ProjectRecord
+		if err := rows.Scan(&project.ID, &project.ProjectName, &project.ProjectKey, &project.OwnerID); err != nil {
+			return nil, err
+		}
+		results = append(results, project)
+	}
+	return results, rows.Err()
+}
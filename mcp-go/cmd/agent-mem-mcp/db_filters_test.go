@@ -3,6 +3,7 @@ package main
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestAppendIndexPathFilterPrefix(t *testing.T) {
@@ -24,3 +25,57 @@ func TestAppendIndexPathWhere(t *testing.T) {
 		t.Fatalf("where 前缀条件缺失: %s", where)
 	}
 }
+
+func TestAppendFragmentFilter(t *testing.T) {
+	filter := FragmentFilter{
+		Scope:        "plan",
+		ContentTypes: []string{"plan", "insight"},
+		ProjectIDs:   []string{"p1", "p2"},
+		Tags:         []string{"auth"},
+		ExcludeTags:  []string{"deprecated"},
+		Since:        100,
+		Until:        200,
+	}
+	query, args := appendFragmentFilter("SELECT 1 WHERE m.project_id = $1", []any{"owner"}, filter)
+	if len(args) != 8 {
+		t.Fatalf("参数数量错误: %+v", args)
+	}
+	for _, want := range []string{
+		"m.content_type = $2",
+		"m.content_type = ANY($3)",
+		"m.project_id = ANY($4::uuid[])",
+		"?& $5",
+		"NOT (COALESCE(m.tags, '[]'::jsonb) ?| $6)",
+		"m.ts >= $7",
+		"m.ts <= $8",
+	} {
+		if !strings.Contains(query, want) {
+			t.Fatalf("缺少过滤条件 %q: %s", want, query)
+		}
+	}
+}
+
+func TestAppendFragmentFilterEmpty(t *testing.T) {
+	base := "SELECT 1 WHERE true"
+	query, args := appendFragmentFilter(base, nil, FragmentFilter{Scope: "all"})
+	if query != base || len(args) != 0 {
+		t.Fatalf("空过滤条件不应追加 SQL: %s %+v", query, args)
+	}
+}
+
+func TestParseUntilQueryDateOnlyIncludesWholeDay(t *testing.T) {
+	until, err := parseUntilQuery("2026-10-01")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if want := time.Date(2026, 10, 1, 23, 59, 59, 0, time.UTC).Unix(); until != want {
+		t.Fatalf("仅日期的 until 应取当天最后一秒: got=%d want=%d", until, want)
+	}
+	exact, err := parseUntilQuery("2026-10-01T08:00:00Z")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if want := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC).Unix(); exact != want {
+		t.Fatalf("RFC3339 的 until 不应调整: got=%d want=%d", exact, want)
+	}
+}
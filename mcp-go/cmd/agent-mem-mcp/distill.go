@@ -16,21 +16,35 @@ type DistillInput struct {
 	TargetContentType string `json:"target_content_type,omitempty"`
 }
 
-// DistillOutput 蒸馏工具输出
+// DistillOutput 蒸馏工具输出；Result 为蒸馏结果的写入结果（created/updated/skipped/duplicate），
+// SourceIDs 为本次蒸馏的源记忆（蒸馏结果以 DERIVED_FROM 关系指向它们）
 type DistillOutput struct {
-	ID          string `json:"id"`
-	Status      string `json:"status"`
-	SourceCount int    `json:"source_count"`
-	Summary     string `json:"summary"`
+	ID          string   `json:"id"`
+	Status      string   `json:"status"`
+	Result      string   `json:"result,omitempty"`
+	SourceCount int      `json:"source_count"`
+	SourceIDs   []string `json:"source_ids,omitempty"`
+	Summary     string   `json:"summary"`
+}
+
+// DistillSourceRow 未蒸馏的源记忆；ContentHash 记录在 DERIVED_FROM 关系上，记忆内容被替换后重新参与蒸馏
+type DistillSourceRow struct {
+	ID          string
+	Summary     string
+	ContentHash string
 }
 
 const (
-	defaultDistillSinceDays      = 7
-	defaultDistillContentType    = "insight"
-	maxDistillSourceMemories     = 50
+	defaultDistillSinceDays       = 7
+	defaultDistillContentType     = "insight"
+	maxDistillSourceMemories      = 50
+	defaultDistillPolicySinceDays = 30
+	// 蒸馏进度阶段：读取源记忆、LLM 蒸馏，其余区间由写入流程上报
+	distillProgressSteps = 4
 )
 
-// DistillMemories 蒸馏指定项目最近 N 天的记忆，浓缩为精华长期知识
+// DistillMemories 增量蒸馏指定项目最近 N 天内尚未被蒸馏覆盖的记忆：与上一版蒸馏结果合并后
+// 以新版本写入 distill/<项目>/latest（经正常仲裁替换上一版），并以 DERIVED_FROM 关系指向源记忆
 func (a *App) DistillMemories(ctx context.Context, input DistillInput) (DistillOutput, error) {
 	// === 参数规范化 ===
 	ownerID := strings.TrimSpace(input.OwnerID)
@@ -66,44 +80,47 @@ func (a *App) DistillMemories(ctx context.Context, input DistillInput) (DistillO
 		return DistillOutput{}, newValidationError("invalid_request", "ERR_PROJECT_NOT_FOUND", "项目不存在: "+projectKey, 404)
 	}
 
-	// === 查询最近 N 天的记忆摘要 ===
+	// === 查询最近 N 天内未蒸馏的记忆摘要（超过上限时先取较早的，其余留待下次） ===
 	sinceTs := time.Now().UTC().Add(-time.Duration(sinceDays) * 24 * time.Hour).Unix()
-	rows, err := a.store.FetchRecentMemorySummaries(ctx, projectID, sinceTs, scope, maxDistillSourceMemories)
+	sources, err := a.store.FetchUndistilledMemories(ctx, projectID, sinceTs, scope, maxDistillSourceMemories)
 	if err != nil {
 		return DistillOutput{}, fmt.Errorf("查询记忆摘要失败: %w", err)
 	}
-	if len(rows) == 0 {
+	if len(sources) == 0 {
 		return DistillOutput{
 			Status:      "empty",
 			SourceCount: 0,
-			Summary:     "没有找到可蒸馏的记忆",
+			Summary:     "没有尚未蒸馏的记忆",
 		}, nil
 	}
 
 	// === 拼接摘要上下文 ===
-	summaries := make([]string, 0, len(rows))
-	for _, row := range rows {
-		if strings.TrimSpace(row.Summary) != "" {
-			summaries = append(summaries, row.Summary)
-		}
-	}
-	if len(summaries) == 0 {
-		return DistillOutput{
-			Status:      "empty",
-			SourceCount: len(rows),
-			Summary:     "源记忆均无有效摘要",
-		}, nil
+	summaries := make([]string, 0, len(sources))
+	sourceIDs := make([]string, 0, len(sources))
+	for _, row := range sources {
+		summaries = append(summaries, row.Summary)
+		sourceIDs = append(sourceIDs, row.ID)
 	}
 
-	// === 调用 LLM 蒸馏 ===
-	distilled := a.llm.Distill(summaries, projectKey)
+	reportProgress(ctx, 1, distillProgressSteps, fmt.Sprintf("读取 %d 条源记忆", len(summaries)))
+
+	// === 调用 LLM 蒸馏：在上一版蒸馏结果基础上合并 ===
+	indexPath := []string{"distill", projectKey, "latest"}
+	previous, err := a.store.FindLatestDigest(ctx, projectID, indexPath)
+	if err != nil {
+		return DistillOutput{}, fmt.Errorf("查询上一版蒸馏结果失败: %w", err)
+	}
+	distilled := a.llm.Distill(ctx, previous.Content, summaries, projectKey)
+	if err := ctx.Err(); err != nil {
+		return DistillOutput{}, err
+	}
 	if strings.TrimSpace(distilled) == "" {
 		return DistillOutput{}, fmt.Errorf("LLM 蒸馏返回空结果")
 	}
+	reportProgress(ctx, 2, distillProgressSteps, "LLM 蒸馏")
 
 	// === 将蒸馏结果通过 IngestMemory 写入 ===
 	tags := []string{"distilled", "auto-digest"}
-	indexPath := []string{"distill", projectKey, "latest"}
 	now := time.Now().UTC()
 
 	ingestInput := IngestMemoryInput{
@@ -123,15 +140,56 @@ func (a *App) DistillMemories(ctx context.Context, input DistillInput) (DistillO
 		return DistillOutput{}, fmt.Errorf("蒸馏结果参数规范化失败: %w", err)
 	}
 
-	result, err := a.IngestMemory(ctx, normalized)
+	// 上一版作为仲裁候选，替换时保存历史版本，latest 路径只保留一条
+	result, err := a.ingestMemory(progressScope(ctx, 2, distillProgressSteps, distillProgressSteps), normalized, previous.ID)
 	if err != nil {
 		return DistillOutput{}, fmt.Errorf("蒸馏结果写入失败: %w", err)
 	}
+	// 只有新蒸馏结果实际写入（新建或替换上一版）时才记录来源；仲裁跳过或内容重复时这些记忆下次仍参与蒸馏
+	if result.Status == "created" || result.Status == "updated" {
+		if err := a.store.InsertDigestSources(ctx, result.ID, sources); err != nil {
+			return DistillOutput{}, fmt.Errorf("记录蒸馏来源失败: %w", err)
+		}
+	}
 
 	return DistillOutput{
 		ID:          result.ID,
 		Status:      "distilled",
-		SourceCount: len(summaries),
+		Result:      result.Status,
+		SourceCount: len(sources),
+		SourceIDs:   sourceIDs,
 		Summary:     truncateRunes(distilled, 500),
 	}, nil
 }
+
+// matchDistillPolicy 按顺序返回第一条匹配项目的自动蒸馏策略
+func matchDistillPolicy(policies []DistillPolicyConfig, ownerID, projectKey string) (DistillPolicyConfig, bool) {
+	for _, policy := range policies {
+		if policy.OwnerID != "" && policy.OwnerID != ownerID {
+			continue
+		}
+		if policy.ProjectKey != "*" && policy.ProjectKey != projectKey {
+			continue
+		}
+		return policy, true
+	}
+	return DistillPolicyConfig{}, false
+}
+
+// distillDue pending 为未蒸馏的记忆数，lastDistilled 为上次蒸馏时间（从未蒸馏时为零值）；
+// 两个条件都未启用的策略不自动蒸馏（可用于排除个别项目）；积压超过单次蒸馏上限时不等待条件，尽快分批消化
+func distillDue(policy DistillPolicyConfig, pending int, lastDistilled, now time.Time) bool {
+	if pending == 0 || (policy.MinNewMemories <= 0 && policy.IntervalDays <= 0) {
+		return false
+	}
+	if pending > maxDistillSourceMemories {
+		return true
+	}
+	if policy.MinNewMemories > 0 && pending >= policy.MinNewMemories {
+		return true
+	}
+	if policy.IntervalDays > 0 {
+		return lastDistilled.IsZero() || now.Sub(lastDistilled) >= time.Duration(policy.IntervalDays)*24*time.Hour
+	}
+	return false
+}
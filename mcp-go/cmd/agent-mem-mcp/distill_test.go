@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchDistillPolicy(t *testing.T) {
+	policies := []DistillPolicyConfig{
+		{ProjectKey: "scratch"},
+		{OwnerID: "team", ProjectKey: "*", MinNewMemories: 10},
+		{ProjectKey: "*", IntervalDays: 7},
+	}
+	cases := []struct {
+		owner, project string
+		want           int
+	}{
+		{"personal", "scratch", 0},
+		{"team", "api", 1},
+		{"personal", "api", 2},
+	}
+	for _, tc := range cases {
+		policy, ok := matchDistillPolicy(policies, tc.owner, tc.project)
+		if !ok || policy != policies[tc.want] {
+			t.Fatalf("%s/%s 应匹配第 %d 条策略: %+v", tc.owner, tc.project, tc.want, policy)
+		}
+	}
+	if _, ok := matchDistillPolicy(policies[:2], "personal", "api"); ok {
+		t.Fatalf("没有匹配的策略时不应蒸馏")
+	}
+}
+
+func TestDistillDue(t *testing.T) {
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	weekly := DistillPolicyConfig{ProjectKey: "*", IntervalDays: 7}
+	byCount := DistillPolicyConfig{ProjectKey: "*", MinNewMemories: 20}
+	cases := []struct {
+		name    string
+		policy  DistillPolicyConfig
+		pending int
+		last    time.Time
+		want    bool
+	}{
+		{"无新记忆", weekly, 0, time.Time{}, false},
+		{"从未蒸馏", weekly, 1, time.Time{}, true},
+		{"未满间隔", weekly, 5, now.Add(-6 * 24 * time.Hour), false},
+		{"已满间隔", weekly, 5, now.Add(-7 * 24 * time.Hour), true},
+		{"数量不足", byCount, 19, time.Time{}, false},
+		{"数量达到", byCount, 20, now, true},
+		{"积压超过单次上限", weekly, maxDistillSourceMemories + 1, now, true},
+		{"排除规则", DistillPolicyConfig{ProjectKey: "scratch"}, maxDistillSourceMemories + 1, time.Time{}, false},
+	}
+	for _, tc := range cases {
+		if got := distillDue(tc.policy, tc.pending, tc.last, now); got != tc.want {
+			t.Fatalf("%s: got=%v want=%v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -42,11 +42,16 @@ func NewEmbedder(settings Settings) *Embedder {
 }
 
 func (e *Embedder) EmbedQuery(text string) (pgvector.Vector, error) {
+	return e.EmbedQueryContext(context.Background(), text)
+}
+
+// EmbedQueryContext 同 EmbedQuery，但随 ctx 取消/超时中止 embedding 请求
+func (e *Embedder) EmbedQueryContext(ctx context.Context, text string) (pgvector.Vector, error) {
 	cacheKey := e.cacheKey(text)
 	if cached, ok := e.getCachedVector(cacheKey); ok {
 		return pgvector.NewVector(cached), nil
 	}
-	vectors, err := e.embed(context.Background(), []string{text})
+	vectors, err := e.embed(ctx, []string{text})
 	if err != nil {
 		return pgvector.NewVector([]float32{}), err
 	}
@@ -109,10 +114,14 @@ func (e *Embedder) embed(ctx context.Context, texts []string) ([]pgvector.Vector
 			var err error
 			for attempt := 0; attempt < 3; attempt++ {
 				vectors, err = e.client.Embeddings(ctx, e.model, texts[start:end])
-				if err == nil {
+				if err == nil || ctx.Err() != nil {
 					break
 				}
-				time.Sleep(time.Duration(200*(1<<attempt)) * time.Millisecond)
+				// 重试等待同样随 ctx 取消中止
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Duration(200*(1<<attempt)) * time.Millisecond):
+				}
 			}
 			if err != nil {
 				return nil, err
@@ -124,6 +133,7 @@ func (e *Embedder) embed(ctx context.Context, texts []string) ([]pgvector.Vector
 				vector = e.normalize(vector)
 				result = append(result, pgvector.NewVector(vector))
 			}
+			reportProgress(ctx, float64(end), float64(len(texts)), fmt.Sprintf("向量化 %d/%d", end, len(texts)))
 		}
 		return result, nil
 	case "fastembed":
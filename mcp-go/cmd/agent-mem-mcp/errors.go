@@ -1,8 +1,24 @@
 package main
 
+import (
+	"errors"
+	"log"
+	"time"
+)
+
 type ErrorResponse struct {
 	Error     string `json:"error"`
 	Message   string `json:"message"`
 	Code      string `json:"code,omitempty"`
 	Timestamp int64  `json:"timestamp"`
 }
+
+// errorResponseFor 单条错误（批量写入结果、gRPC 错误详情）的错误体，与 REST 一致：AppError 原样返回，其他错误记录日志后统一为 ERR_INTERNAL
+func errorResponseFor(err error, op string) *ErrorResponse {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return &ErrorResponse{Error: appErr.ErrorKey, Message: appErr.Message, Code: appErr.Code, Timestamp: time.Now().UTC().Unix()}
+	}
+	log.Printf("❌ %s 失败: %v", op, err)
+	return &ErrorResponse{Error: "internal_error", Message: "服务器错误", Code: "ERR_INTERNAL", Timestamp: time.Now().UTC().Unix()}
+}
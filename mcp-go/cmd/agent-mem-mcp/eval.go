@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gopkg.in/yaml.v3"
+)
+
+// goldenSet 检索评估黄金集：fixtures 为评估前写入的记忆（可选），queries 为查询及期望命中，
+// configurations 为需要对比的检索/写入配置（缺省时对比 fast/balanced/deep 三个 profile）
+type goldenSet struct {
+	Fixtures       []goldenFixture `yaml:"fixtures" json:"fixtures"`
+	Queries        []goldenQuery   `yaml:"queries" json:"queries"`
+	Configurations []evalConfig    `yaml:"configurations" json:"configurations"`
+}
+
+// goldenFixture ref 为 fixture 的引用名，查询通过 expected_refs 引用
+type goldenFixture struct {
+	Ref         string   `yaml:"ref" json:"ref"`
+	ProjectKey  string   `yaml:"project_key" json:"project_key"`
+	ContentType string   `yaml:"content_type" json:"content_type"`
+	Content     string   `yaml:"content" json:"content"`
+	Tags        []string `yaml:"tags" json:"tags"`
+	Ts          int64    `yaml:"ts" json:"ts"`
+}
+
+// goldenQuery 期望命中可用记忆 ID、内容哈希（hashContent）或 fixture 引用表示，同一条记忆只需列一次
+type goldenQuery struct {
+	Query          string   `yaml:"query" json:"query"`
+	ProjectKey     string   `yaml:"project_key" json:"project_key"`
+	Scope          string   `yaml:"scope" json:"scope"`
+	ExpectedIDs    []string `yaml:"expected_ids" json:"expected_ids"`
+	ExpectedHashes []string `yaml:"expected_hashes" json:"expected_hashes"`
+	ExpectedRefs   []string `yaml:"expected_refs" json:"expected_refs"`
+}
+
+// evalConfig 一组待评估配置：未设置的字段沿用基础配置
+type evalConfig struct {
+	Name                        string             `yaml:"name" json:"name"`
+	Profile                     string             `yaml:"profile" json:"profile"`
+	RRFWeights                  map[string]float64 `yaml:"rrf_weights" json:"rrf_weights"`
+	ChunkSize                   int                `yaml:"chunk_size" json:"chunk_size"`
+	Overlap                     int                `yaml:"overlap" json:"overlap"`
+	SemanticSimilarityThreshold *float64           `yaml:"semantic_similarity_threshold" json:"semantic_similarity_threshold"`
+	Fuzzy                       *bool              `yaml:"fuzzy" json:"fuzzy"`
+}
+
+// evalReport 单个配置的评估结果（指标为各查询均值）
+type evalReport struct {
+	Config        string  `json:"config"`
+	Queries       int     `json:"queries"`
+	Failed        int     `json:"failed"`
+	RecallAtK     float64 `json:"recall_at_k"`
+	MRR           float64 `json:"mrr"`
+	NDCGAtK       float64 `json:"ndcg_at_k"`
+	LatencyMeanMs float64 `json:"latency_mean_ms"`
+	LatencyP50Ms  float64 `json:"latency_p50_ms"`
+	LatencyP95Ms  float64 `json:"latency_p95_ms"`
+}
+
+// loadGoldenSet 按扩展名读取 YAML 或 JSONL；JSONL 每行一个对象，kind 为 fixture/query/config（缺省 query）
+func loadGoldenSet(path string) (goldenSet, error) {
+	file, err := os.Open(expandHome(path))
+	if err != nil {
+		return goldenSet{}, fmt.Errorf("读取黄金集失败: %w", err)
+	}
+	defer file.Close()
+	var set goldenSet
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		set, err = parseGoldenJSONL(file)
+	default:
+		err = yaml.NewDecoder(file).Decode(&set)
+	}
+	if err != nil {
+		return goldenSet{}, fmt.Errorf("解析黄金集失败: %w", err)
+	}
+	if len(set.Queries) == 0 {
+		return goldenSet{}, fmt.Errorf("黄金集没有查询")
+	}
+	refs := map[string]bool{}
+	for _, fixture := range set.Fixtures {
+		if strings.TrimSpace(fixture.Content) == "" {
+			return goldenSet{}, fmt.Errorf("fixture %q 内容为空", fixture.Ref)
+		}
+		refs[fixture.Ref] = true
+	}
+	for _, query := range set.Queries {
+		if strings.TrimSpace(query.Query) == "" {
+			return goldenSet{}, fmt.Errorf("黄金集存在空查询")
+		}
+		if len(query.ExpectedIDs)+len(query.ExpectedHashes)+len(query.ExpectedRefs) == 0 {
+			return goldenSet{}, fmt.Errorf("查询 %q 缺少期望命中", query.Query)
+		}
+		for _, ref := range query.ExpectedRefs {
+			if !refs[ref] {
+				return goldenSet{}, fmt.Errorf("查询 %q 引用了不存在的 fixture: %s", query.Query, ref)
+			}
+		}
+	}
+	if len(set.Configurations) == 0 {
+		set.Configurations = []evalConfig{{Name: "fast", Profile: "fast"}, {Name: "balanced", Profile: "balanced"}, {Name: "deep", Profile: "deep"}}
+	}
+	return set, nil
+}
+
+func parseGoldenJSONL(reader io.Reader) (goldenSet, error) {
+	var set goldenSet
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" || strings.HasPrefix(raw, "#") {
+			continue
+		}
+		var head struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal([]byte(raw), &head); err != nil {
+			return set, fmt.Errorf("第 %d 行: %w", line, err)
+		}
+		var err error
+		switch head.Kind {
+		case "", "query":
+			var query goldenQuery
+			err = json.Unmarshal([]byte(raw), &query)
+			set.Queries = append(set.Queries, query)
+		case "fixture":
+			var fixture goldenFixture
+			err = json.Unmarshal([]byte(raw), &fixture)
+			set.Fixtures = append(set.Fixtures, fixture)
+		case "config":
+			var config evalConfig
+			err = json.Unmarshal([]byte(raw), &config)
+			set.Configurations = append(set.Configurations, config)
+		default:
+			err = fmt.Errorf("未知 kind: %s", head.Kind)
+		}
+		if err != nil {
+			return set, fmt.Errorf("第 %d 行: %w", line, err)
+		}
+	}
+	return set, scanner.Err()
+}
+
+// applyEvalConfig 在基础配置上叠加评估配置
+func applyEvalConfig(base Settings, config evalConfig) Settings {
+	settings := base
+	if config.ChunkSize > 0 {
+		settings.Chunking.ChunkSize = config.ChunkSize
+	}
+	if config.Overlap > 0 {
+		settings.Chunking.Overlap = config.Overlap
+	}
+	if config.SemanticSimilarityThreshold != nil {
+		settings.Versioning.SemanticSimilarityThreshold = *config.SemanticSimilarityThreshold
+	}
+	if config.Fuzzy != nil {
+		settings.Fuzzy.Enabled = *config.Fuzzy
+	}
+	return settings
+}
+
+// evalRanking RRF 权重覆盖复用反馈学习的排序参数
+func evalRanking(config evalConfig) *rankingModel {
+	if len(config.RRFWeights) == 0 {
+		return nil
+	}
+	return &rankingModel{Weights: config.RRFWeights}
+}
+
+var evalOwnerPattern = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// evalOwnerID 每个配置的 fixture 写入独立 owner，互不影响，评估结束后清理
+func evalOwnerID(config evalConfig) string {
+	return "eval-" + evalOwnerPattern.ReplaceAllString(config.Name, "_")
+}
+
+// runEvalCommand agent-mem eval：在 mock/离线 provider 与 fixture 数据库上运行黄金集，对比各配置的检索指标；
+// agent-mem eval arbitration 评估写入仲裁（见 arbitration_eval.go）
+func runEvalCommand(args []string) int {
+	if len(args) > 0 && args[0] == "arbitration" {
+		return runArbitrationEvalCommand(args[1:])
+	}
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	var (
+		configPath = fs.String("config", "", "配置文件路径")
+		goldenPath = fs.String("golden", "", "黄金集路径（.yaml/.yml 或 .jsonl）")
+		k          = fs.Int("k", 10, "评估截断位置 k")
+		owner      = fs.String("owner", "", "无 fixtures 时检索的 owner_id（默认取配置）")
+		live       = fs.Bool("live", false, "使用配置中的真实 embedding/LLM（默认强制 mock，保证可复现）")
+		asJSON     = fs.Bool("json", false, "以 JSON 输出报告")
+		minRecall  = fs.Float64("min-recall", 0, "任一配置 recall@k 低于该值时以非零状态退出（用于 CI）")
+		keep       = fs.Bool("keep", false, "保留写入的 fixture 数据")
+		dbURL      = fs.String("database-url", "", "评估数据库（黄金集含 fixtures 时必填，且不能是配置中的数据库）")
+	)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *goldenPath == "" || *k <= 0 {
+		fmt.Fprintln(os.Stderr, "用法: agent-mem eval -golden golden.yaml -database-url postgresql://.../agent_mem_eval [-k 10] [-json] [-min-recall 0.8]")
+		return 2
+	}
+	set, err := loadGoldenSet(*goldenPath)
+	if err != nil {
+		log.Printf("[CRITICAL] %v", err)
+		return 1
+	}
+	base, err := loadSettings(*configPath)
+	if err != nil {
+		log.Printf("[CRITICAL] 配置加载失败: %v", err)
+		return 1
+	}
+	databaseURL, err := evalDatabaseURL(*dbURL, base.Storage.DatabaseURL, len(set.Fixtures) > 0)
+	if err != nil {
+		log.Printf("[CRITICAL] %v", err)
+		return 2
+	}
+	base.Storage.DatabaseURL = databaseURL
+	if !*live {
+		base.Embedding.Provider = "mock"
+		base.QueryExpand.Enabled = false
+		base.Rerank.Enabled = false
+		base.Feedback.Enabled = false
+		base.Search.Cache.TTLSeconds = 0
+		os.Setenv("AGENT_MEM_LLM_MODE", "mock")
+	}
+
+	ctx := context.Background()
+	var reports []evalReport
+	for idx, config := range set.Configurations {
+		if strings.TrimSpace(config.Name) == "" {
+			config.Name = fmt.Sprintf("config-%d", idx+1)
+		}
+		report, err := runEvalConfig(ctx, applyEvalConfig(base, config), config, set, *owner, *k, *keep)
+		if err != nil {
+			log.Printf("[CRITICAL] 配置 %s 评估失败: %v", config.Name, err)
+			return 1
+		}
+		reports = append(reports, report)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(reports)
+	} else {
+		writeEvalTable(os.Stdout, reports, *k)
+	}
+	for _, report := range reports {
+		if report.RecallAtK < *minRecall {
+			log.Printf("[WARN] 配置 %s recall@%d=%.3f 低于阈值 %.3f", report.Config, *k, report.RecallAtK, *minRecall)
+			return 1
+		}
+	}
+	return 0
+}
+
+// evalDatabaseURL 评估写入 fixtures 时会清空 eval-* owner 的项目，必须显式指定独立的评估数据库，
+// 拒绝指向配置（settings.yaml / DATABASE_URL）中的数据库；无 fixtures 时只读检索，未指定则使用配置中的数据库
+func evalDatabaseURL(flagValue, configured string, hasFixtures bool) (string, error) {
+	flagValue = strings.TrimSpace(flagValue)
+	if flagValue == "" {
+		if hasFixtures {
+			return "", errors.New("黄金集包含 fixtures：需用 -database-url 指定独立的评估数据库（会写入并清理 eval-* owner 的数据）")
+		}
+		return configured, nil
+	}
+	databaseURL := normalizeDatabaseURL(flagValue)
+	if hasFixtures && sameDatabase(databaseURL, configured) {
+		return "", errors.New("-database-url 与配置中的数据库相同：拒绝在该数据库上写入评估 fixtures，请使用独立的评估数据库")
+	}
+	return databaseURL, nil
+}
+
+// sameDatabase 按主机、端口与库名判断两个连接串是否指向同一数据库（无法解析时按字符串比较）
+func sameDatabase(a, b string) bool {
+	left, errLeft := pgconn.ParseConfig(a)
+	right, errRight := pgconn.ParseConfig(b)
+	if errLeft != nil || errRight != nil {
+		return strings.TrimSpace(a) == strings.TrimSpace(b)
+	}
+	return strings.EqualFold(left.Host, right.Host) && left.Port == right.Port && left.Database == right.Database
+}
+
+func runEvalConfig(ctx context.Context, settings Settings, config evalConfig, set goldenSet, owner string, k int, keep bool) (evalReport, error) {
+	app, err := NewApp(settings)
+	if err != nil {
+		return evalReport{}, err
+	}
+	defer app.Close()
+	if err := app.EnsureSchema(ctx, false); err != nil {
+		return evalReport{}, err
+	}
+
+	refIDs := map[string]string{}
+	if len(set.Fixtures) > 0 {
+		owner = evalOwnerID(config)
+		if _, err := app.store.DeleteOwnerProjects(ctx, owner, app.changes.origin); err != nil {
+			return evalReport{}, err
+		}
+		if !keep {
+			defer func() {
+				if _, err := app.store.DeleteOwnerProjects(context.Background(), owner, app.changes.origin); err != nil {
+					log.Printf("[WARN] 清理评估数据失败: %v", err)
+				}
+			}()
+		}
+		base := time.Now().UTC().Unix() - int64(len(set.Fixtures))
+		for idx, fixture := range set.Fixtures {
+			ts := fixture.Ts
+			if ts <= 0 {
+				ts = base + int64(idx)
+			}
+			projectKey := fixture.ProjectKey
+			if projectKey == "" {
+				projectKey = "eval"
+			}
+			contentType := fixture.ContentType
+			if contentType == "" {
+				contentType = "development"
+			}
+			tags := fixture.Tags
+			result, err := app.IngestMemory(ctx, IngestMemoryInput{
+				OwnerID:     owner,
+				ProjectKey:  projectKey,
+				ProjectName: projectKey,
+				ContentType: contentType,
+				Content:     fixture.Content,
+				Tags:        &tags,
+				SkipLLM:     true,
+				Ts:          ts,
+			})
+			if err != nil {
+				return evalReport{}, fmt.Errorf("写入 fixture %q 失败: %w", fixture.Ref, err)
+			}
+			if fixture.Ref != "" {
+				refIDs[fixture.Ref] = result.ID
+			}
+		}
+	}
+
+	report := evalReport{Config: config.Name}
+	var latencies []float64
+	var recallSum, mrrSum, ndcgSum float64
+	profile := config.Profile
+	for _, query := range set.Queries {
+		input := SearchInput{OwnerID: owner, ProjectKey: query.ProjectKey, ProjectName: query.ProjectKey, Query: query.Query, Scope: query.Scope, Limit: k, Mode: strPtr("ids")}
+		if profile != "" {
+			input.Profile = strPtr(profile)
+		}
+		start := time.Now()
+		resp, err := app.searchWithOptions(ctx, input, searchOptions{ranking: evalRanking(config)})
+		latencies = append(latencies, float64(time.Since(start).Microseconds())/1000)
+		report.Queries++
+		if err != nil {
+			log.Printf("[WARN] 查询 %q 失败: %v", query.Query, err)
+			report.Failed++
+			continue
+		}
+		ids := resultIDs(resp.Results)
+		var hashes map[string]string
+		if len(query.ExpectedHashes) > 0 {
+			hashes, err = app.store.FetchContentHashes(ctx, ids)
+			if err != nil {
+				return evalReport{}, err
+			}
+		}
+		expected := append([]string(nil), query.ExpectedIDs...)
+		for _, ref := range query.ExpectedRefs {
+			expected = append(expected, refIDs[ref])
+		}
+		relevance := relevanceVector(ids, hashes, expected, query.ExpectedHashes)
+		total := len(uniqueStrings(expected)) + len(uniqueStrings(query.ExpectedHashes))
+		recallSum += recallAtK(relevance, total, k)
+		mrrSum += reciprocalRank(relevance)
+		ndcgSum += ndcgAtK(relevance, total, k)
+	}
+	if report.Queries > 0 {
+		n := float64(report.Queries)
+		report.RecallAtK = recallSum / n
+		report.MRR = mrrSum / n
+		report.NDCGAtK = ndcgSum / n
+	}
+	report.LatencyMeanMs, report.LatencyP50Ms, report.LatencyP95Ms = latencyStats(latencies)
+	return report, nil
+}
+
+// relevanceVector 结果逐位的相关性（1/0）：ID 命中期望 ID 或内容哈希命中期望哈希
+func relevanceVector(ids []string, hashes map[string]string, expectedIDs, expectedHashes []string) []int {
+	idSet := map[string]bool{}
+	for _, id := range expectedIDs {
+		idSet[id] = true
+	}
+	hashSet := map[string]bool{}
+	for _, hash := range expectedHashes {
+		hashSet[hash] = true
+	}
+	relevance := make([]int, len(ids))
+	for idx, id := range ids {
+		if idSet[id] || hashSet[hashes[id]] {
+			relevance[idx] = 1
+		}
+	}
+	return relevance
+}
+
+func recallAtK(relevance []int, total, k int) float64 {
+	if total == 0 {
+		return 0
+	}
+	hits := 0
+	for idx := 0; idx < len(relevance) && idx < k; idx++ {
+		hits += relevance[idx]
+	}
+	return math.Min(1, float64(hits)/float64(total))
+}
+
+func reciprocalRank(relevance []int) float64 {
+	for idx, rel := range relevance {
+		if rel > 0 {
+			return 1 / float64(idx+1)
+		}
+	}
+	return 0
+}
+
+// ndcgAtK 二值相关性 nDCG：理想排序为前 min(total, k) 位全部相关
+func ndcgAtK(relevance []int, total, k int) float64 {
+	var dcg, idcg float64
+	for idx := 0; idx < len(relevance) && idx < k; idx++ {
+		dcg += float64(relevance[idx]) / math.Log2(float64(idx+2))
+	}
+	for idx := 0; idx < total && idx < k; idx++ {
+		idcg += 1 / math.Log2(float64(idx+2))
+	}
+	if idcg == 0 {
+		return 0
+	}
+	return dcg / idcg
+}
+
+func latencyStats(values []float64) (mean, p50, p95 float64) {
+	if len(values) == 0 {
+		return 0, 0, 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	var sum float64
+	for _, value := range sorted {
+		sum += value
+	}
+	percentile := func(p float64) float64 {
+		idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return sorted[max(0, min(idx, len(sorted)-1))]
+	}
+	return sum / float64(len(sorted)), percentile(0.5), percentile(0.95)
+}
+
+func writeEvalTable(w io.Writer, reports []evalReport, k int) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "config\tqueries\tfailed\trecall@%d\tMRR\tnDCG@%d\tmean(ms)\tp50(ms)\tp95(ms)\n", k, k)
+	for _, report := range reports {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.3f\t%.3f\t%.3f\t%.1f\t%.1f\t%.1f\n",
+			report.Config, report.Queries, report.Failed, report.RecallAtK, report.MRR, report.NDCGAtK,
+			report.LatencyMeanMs, report.LatencyP50Ms, report.LatencyP95Ms)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// 反馈学习参数：
+// 召回源权重 = 默认权重 × clamp(该源有用率 / 整体有用率, 0.5, 2)，有用率以整体有用率为先验平滑；
+// 记忆加权 = 1 + scale × (正反馈 - 负反馈) / (总反馈 + prior)，作用于 RRF 融合分
+const (
+	feedbackSourcePrior     = 10.0
+	feedbackWeightMinRatio  = 0.5
+	feedbackWeightMaxRatio  = 2.0
+	feedbackMemoryPrior     = 3.0
+	feedbackMemoryBoostSpan = 0.3
+
+	feedbackModelTTL        = 5 * time.Minute
+	feedbackImpressionTTL   = 30 * time.Minute
+	feedbackImpressionLimit = 5000
+)
+
+// rankingModel 排序参数：nil 表示使用 rrfWeight 默认权重且不做记忆加权
+type rankingModel struct {
+	Weights map[string]float64
+	Boosts  map[string]float64
+	Version string
+}
+
+func (m *rankingModel) weight(source string) float64 {
+	if m != nil {
+		if weight, ok := m.Weights[source]; ok {
+			return weight
+		}
+	}
+	return rrfWeight(source)
+}
+
+func (m *rankingModel) version() string {
+	if m == nil {
+		return ""
+	}
+	return m.Version
+}
+
+// applyBoosts 按记忆反馈调整融合分并重新排序
+func (m *rankingModel) applyBoosts(rows []FragmentRow) []FragmentRow {
+	if m == nil || len(m.Boosts) == 0 {
+		return rows
+	}
+	for i := range rows {
+		if boost, ok := m.Boosts[rows[i].MemoryID]; ok {
+			rows[i].RankScore *= boost
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].RankScore == rows[j].RankScore {
+			return rows[i].Ts > rows[j].Ts
+		}
+		return rows[i].RankScore > rows[j].RankScore
+	})
+	return rows
+}
+
+// buildRankingModel 由反馈聚合计算召回源权重与记忆加权
+func buildRankingModel(sourceStats, memoryStats []FeedbackStat) *rankingModel {
+	model := &rankingModel{Weights: map[string]float64{}, Boosts: map[string]float64{}}
+	var positive, total float64
+	for _, stat := range sourceStats {
+		positive += stat.Positive
+		total += stat.Total
+	}
+	if total > 0 {
+		overall := positive / total
+		for _, stat := range sourceStats {
+			rate := (stat.Positive + feedbackSourcePrior*overall) / (stat.Total + feedbackSourcePrior)
+			ratio := feedbackWeightMaxRatio
+			if overall > 0 {
+				ratio = math.Min(feedbackWeightMaxRatio, math.Max(feedbackWeightMinRatio, rate/overall))
+			} else {
+				ratio = feedbackWeightMinRatio
+			}
+			model.Weights[stat.Key] = rrfWeight(stat.Key) * ratio
+		}
+	}
+	for _, stat := range memoryStats {
+		if stat.Total <= 0 {
+			continue
+		}
+		negative := stat.Total - stat.Positive
+		model.Boosts[stat.Key] = 1 + feedbackMemoryBoostSpan*(stat.Positive-negative)/(stat.Total+feedbackMemoryPrior)
+	}
+	// 版本覆盖每条记忆的加权：未带召回源的显式反馈只改变记忆加权，也必须让检索缓存失效
+	model.Version = hashContent(fmt.Sprint(sortedFactors(model.Weights), sortedFactors(model.Boosts), positive, total))[:12]
+	return model
+}
+
+// sortedFactors 将 key → 系数按 key 排序格式化，用于计算模型版本
+func sortedFactors(factors map[string]float64) []string {
+	keys := make([]string, 0, len(factors))
+	for key, value := range factors {
+		keys = append(keys, fmt.Sprintf("%s=%.4f", key, value))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// searchImpression 记忆被某次检索（owner + query）返回时的上下文，用于关联反馈与召回源
+type searchImpression struct {
+	OwnerID string
+	Query   string
+	Sources []string
+	Rank    int
+	Expires time.Time
+	// Followed 已记录过隐式正反馈，避免同一次曝光重复计数
+	Followed bool
+}
+
+// FeedbackTracker 记录检索曝光、写入反馈，并按 owner 缓存学习到的排序参数
+type FeedbackTracker struct {
+	store    *Store
+	settings FeedbackConfig
+
+	mu sync.Mutex
+	// impressions 按 owner + query + memory_id 记录曝光：同一记忆在不同查询中的排名与召回源互不覆盖
+	impressions map[impressionKey]searchImpression
+	// latest 每条记忆最近一次曝光，供未指定 query 的反馈（mem.get 隐式反馈、缺省 query 的 mem.feedback）关联
+	latest map[string]impressionKey
+	models map[string]cachedRanking
+}
+
+type impressionKey struct {
+	OwnerID  string
+	Query    string
+	MemoryID string
+}
+
+type cachedRanking struct {
+	Model   *rankingModel
+	Expires time.Time
+}
+
+func NewFeedbackTracker(store *Store, settings FeedbackConfig) *FeedbackTracker {
+	return &FeedbackTracker{
+		store:       store,
+		settings:    settings,
+		impressions: map[impressionKey]searchImpression{},
+		latest:      map[string]impressionKey{},
+		models:      map[string]cachedRanking{},
+	}
+}
+
+func (t *FeedbackTracker) enabled() bool {
+	return t != nil && t.settings.Enabled
+}
+
+// RecordImpressions 记录检索返回的记忆及其召回源（同一 owner + query 下按 memory_id 覆盖为最近一次）
+func (t *FeedbackTracker) RecordImpressions(ownerID, query string, results []SearchResult, memorySources map[string][]string) {
+	if !t.enabled() {
+		return
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.impressions)+len(results) > feedbackImpressionLimit {
+		for key, impression := range t.impressions {
+			if impression.Expires.Before(now) {
+				t.deleteImpression(key)
+			}
+		}
+		for key := range t.impressions {
+			if len(t.impressions)+len(results) <= feedbackImpressionLimit {
+				break
+			}
+			t.deleteImpression(key)
+		}
+	}
+	for idx, result := range results {
+		key := impressionKey{OwnerID: ownerID, Query: query, MemoryID: result.ID}
+		t.impressions[key] = searchImpression{
+			OwnerID: ownerID,
+			Query:   query,
+			Sources: memorySources[result.ID],
+			Rank:    idx + 1,
+			Expires: now.Add(feedbackImpressionTTL),
+		}
+		t.latest[result.ID] = key
+	}
+}
+
+func (t *FeedbackTracker) deleteImpression(key impressionKey) {
+	delete(t.impressions, key)
+	if t.latest[key.MemoryID] == key {
+		delete(t.latest, key.MemoryID)
+	}
+}
+
+// impression 查找记忆在 owner + query 检索中的曝光；query 为空时取该记忆最近一次曝光
+func (t *FeedbackTracker) impression(ownerID, query, memoryID string, markFollowed bool) (searchImpression, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := impressionKey{OwnerID: ownerID, Query: query, MemoryID: memoryID}
+	if query == "" {
+		latest, ok := t.latest[memoryID]
+		if !ok || (ownerID != "" && latest.OwnerID != ownerID) {
+			return searchImpression{}, false
+		}
+		key = latest
+	}
+	impression, ok := t.impressions[key]
+	if !ok || impression.Expires.Before(time.Now()) {
+		t.deleteImpression(key)
+		return searchImpression{}, false
+	}
+	if markFollowed {
+		if impression.Followed {
+			return searchImpression{}, false
+		}
+		impression.Followed = true
+		t.impressions[key] = impression
+	}
+	return impression, true
+}
+
+// RecordImplicit mem.get 跟进最近检索返回的记忆时记为隐式正反馈（失败仅记录日志，不影响 mem.get）
+func (t *FeedbackTracker) RecordImplicit(ctx context.Context, memoryIDs []string) {
+	if !t.enabled() {
+		return
+	}
+	for _, memoryID := range memoryIDs {
+		impression, ok := t.impression("", "", memoryID, true)
+		if !ok {
+			continue
+		}
+		_, err := t.store.InsertFeedback(ctx, FeedbackRecord{
+			OwnerID:  impression.OwnerID,
+			MemoryID: memoryID,
+			Query:    impression.Query,
+			Helpful:  true,
+			Implicit: true,
+			Sources:  impression.Sources,
+			Rank:     impression.Rank,
+		})
+		if err != nil {
+			log.Printf("[WARN] 记录隐式反馈失败: %v", err)
+		}
+	}
+}
+
+// Model 返回 owner 的排序参数（缓存 feedbackModelTTL）；无反馈或加载失败时返回 nil（默认权重）
+func (t *FeedbackTracker) Model(ctx context.Context, ownerID string) *rankingModel {
+	if !t.enabled() {
+		return nil
+	}
+	t.mu.Lock()
+	cached, ok := t.models[ownerID]
+	t.mu.Unlock()
+	if ok && cached.Expires.After(time.Now()) {
+		return cached.Model
+	}
+	model, err := t.LoadModel(ctx, ownerID)
+	if err != nil {
+		log.Printf("[WARN] 加载反馈排序参数失败，使用默认权重: %v", err)
+		return nil
+	}
+	t.mu.Lock()
+	t.models[ownerID] = cachedRanking{Model: model, Expires: time.Now().Add(feedbackModelTTL)}
+	t.mu.Unlock()
+	return model
+}
+
+// InvalidateModel 丢弃 owner 缓存的排序参数，下次检索重新从反馈表计算
+func (t *FeedbackTracker) InvalidateModel(ownerID string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	delete(t.models, ownerID)
+	t.mu.Unlock()
+}
+
+// LoadModel 直接从反馈表计算排序参数（不走缓存）
+func (t *FeedbackTracker) LoadModel(ctx context.Context, ownerID string) (*rankingModel, error) {
+	sourceStats, err := t.store.FetchFeedbackSourceStats(ctx, ownerID, t.settings.ImplicitWeight, t.settings.WindowDays)
+	if err != nil {
+		return nil, err
+	}
+	memoryStats, err := t.store.FetchFeedbackMemoryStats(ctx, ownerID, t.settings.ImplicitWeight, t.settings.WindowDays)
+	if err != nil {
+		return nil, err
+	}
+	if len(sourceStats) == 0 && len(memoryStats) == 0 {
+		return nil, nil
+	}
+	return buildRankingModel(sourceStats, memoryStats), nil
+}
+
+// RecordFeedback mem.feedback：写入显式反馈，召回源与排名取自该记忆在同一 query 下的检索曝光（未指定 query 时取最近一次）
+func (a *App) RecordFeedback(ctx context.Context, input FeedbackInput) (FeedbackOutput, error) {
+	normalized, err := normalizeFeedbackInput(input, a.settings)
+	if err != nil {
+		return FeedbackOutput{}, err
+	}
+	if err := validateFeedbackInput(normalized); err != nil {
+		return FeedbackOutput{}, err
+	}
+	if _, err := a.store.FetchMemorySnapshot(ctx, normalized.MemoryID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return FeedbackOutput{}, newValidationError("not_found", "ERR_MEMORY_NOT_FOUND", "memory_id 不存在", 404)
+		}
+		return FeedbackOutput{}, err
+	}
+
+	record := FeedbackRecord{
+		OwnerID:  normalized.OwnerID,
+		MemoryID: normalized.MemoryID,
+		Query:    normalized.Query,
+		Helpful:  normalized.Rating == "helpful",
+		Note:     normalized.Note,
+	}
+	if impression, ok := a.feedback.impression(normalized.OwnerID, normalized.Query, normalized.MemoryID, false); ok {
+		record.Query = impression.Query
+		record.Sources = impression.Sources
+		record.Rank = impression.Rank
+	}
+	id, err := a.store.InsertFeedback(ctx, record)
+	if err != nil {
+		return FeedbackOutput{}, fmt.Errorf("写入反馈失败: %w", err)
+	}
+	// 显式反馈立即生效：丢弃缓存的排序参数，新模型版本随之进入检索缓存键
+	a.feedback.InvalidateModel(record.OwnerID)
+	return FeedbackOutput{ID: id, Status: "recorded", Query: record.Query, Sources: record.Sources}, nil
+}
+
+// FeedbackDriftReport 排序漂移报告：对有反馈的历史查询分别以默认权重与学习后的权重重新检索，比较排序差异
+type FeedbackDriftReport struct {
+	OwnerID        string               `json:"owner_id"`
+	DefaultWeights map[string]float64   `json:"default_weights"`
+	LearnedWeights map[string]float64   `json:"learned_weights"`
+	BoostedCount   int                  `json:"boosted_memories"`
+	Queries        []FeedbackQueryDrift `json:"queries"`
+	// MeanOverlap 默认/学习后 top-k 结果的平均 Jaccard 重合度（越低漂移越大）
+	MeanOverlap float64 `json:"mean_overlap"`
+	// MeanHelpfulShift 被标记有用的记忆平均排名提升（正数表示学习后更靠前）
+	MeanHelpfulShift float64 `json:"mean_helpful_shift"`
+}
+
+type FeedbackQueryDrift struct {
+	Query        string  `json:"query"`
+	Overlap      float64 `json:"overlap"`
+	HelpfulShift float64 `json:"helpful_shift"`
+	Error        string  `json:"error,omitempty"`
+}
+
+const feedbackDriftTopK = 10
+
+func (a *App) FeedbackDriftReport(ctx context.Context, ownerID string, limit int) (FeedbackDriftReport, error) {
+	ownerID, err := resolveOwnerID(ownerID, a.settings)
+	if err != nil {
+		return FeedbackDriftReport{}, err
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	report := FeedbackDriftReport{
+		OwnerID:        ownerID,
+		DefaultWeights: map[string]float64{},
+		LearnedWeights: map[string]float64{},
+		Queries:        []FeedbackQueryDrift{},
+	}
+	model, err := a.feedback.LoadModel(ctx, ownerID)
+	if err != nil {
+		return report, err
+	}
+	for _, source := range []string{"keyword", "bm25", "fuzzy", "vector", "foresight"} {
+		report.DefaultWeights[source] = rrfWeight(source)
+		report.LearnedWeights[source] = model.weight(source)
+	}
+	if model != nil {
+		report.BoostedCount = len(model.Boosts)
+	}
+	queries, err := a.store.FetchFeedbackQueries(ctx, ownerID, limit)
+	if err != nil {
+		return report, err
+	}
+
+	var overlapSum, shiftSum float64
+	counted := 0
+	for _, item := range queries {
+		input := SearchInput{OwnerID: ownerID, Query: item.Query, Limit: feedbackDriftTopK, Mode: strPtr("ids")}
+		drift := FeedbackQueryDrift{Query: item.Query}
+		baseline, err := a.searchWithOptions(ctx, input, searchOptions{})
+		if err == nil {
+			var learned SearchResponse
+			learned, err = a.searchWithOptions(ctx, input, searchOptions{ranking: model})
+			if err == nil {
+				drift.Overlap = jaccard(resultIDs(baseline.Results), resultIDs(learned.Results))
+				drift.HelpfulShift = meanRankShift(resultIDs(baseline.Results), resultIDs(learned.Results), item.HelpfulIDs)
+				overlapSum += drift.Overlap
+				shiftSum += drift.HelpfulShift
+				counted++
+			}
+		}
+		if err != nil {
+			drift.Error = err.Error()
+		}
+		report.Queries = append(report.Queries, drift)
+	}
+	if counted > 0 {
+		report.MeanOverlap = overlapSum / float64(counted)
+		report.MeanHelpfulShift = shiftSum / float64(counted)
+	}
+	return report, nil
+}
+
+func resultIDs(results []SearchResult) []string {
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.ID)
+	}
+	return ids
+}
+
+// meanRankShift 有用记忆的平均排名变化（未进入结果视为排在 len+1 位）；无有用记忆时返回 0
+func meanRankShift(before, after, helpful []string) float64 {
+	if len(helpful) == 0 {
+		return 0
+	}
+	rank := func(ids []string, id string) int {
+		for idx, value := range ids {
+			if value == id {
+				return idx + 1
+			}
+		}
+		return len(ids) + 1
+	}
+	total := 0
+	for _, id := range helpful {
+		total += rank(before, id) - rank(after, id)
+	}
+	return float64(total) / float64(len(helpful))
+}
+
+// runFeedbackReportCommand agent-mem feedback-report：输出排序漂移报告（JSON）
+func runFeedbackReportCommand(args []string) int {
+	fs := flag.NewFlagSet("feedback-report", flag.ContinueOnError)
+	var (
+		configPath = fs.String("config", "", "配置文件路径")
+		owner      = fs.String("owner", "", "报告的 owner_id（默认取配置）")
+		limit      = fs.Int("limit", 50, "最多比较的历史查询数")
+	)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 || *limit <= 0 {
+		fmt.Fprintln(os.Stderr, "用法: agent-mem feedback-report [-owner personal] [-limit 50]")
+		return 2
+	}
+	settings, err := loadSettings(*configPath)
+	if err != nil {
+		log.Printf("[CRITICAL] 配置加载失败: %v", err)
+		return 1
+	}
+	app, err := NewApp(settings)
+	if err != nil {
+		log.Printf("[CRITICAL] 应用初始化失败: %v", err)
+		return 1
+	}
+	defer app.Close()
+	ctx := context.Background()
+	if err := app.EnsureSchema(ctx, false); err != nil {
+		log.Printf("[CRITICAL] 数据库 schema 初始化失败: %v", err)
+		return 1
+	}
+	report, err := app.FeedbackDriftReport(ctx, *owner, *limit)
+	if err != nil {
+		log.Printf("[CRITICAL] 生成排序漂移报告失败: %v", err)
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Printf("[CRITICAL] 输出排序漂移报告失败: %v", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuildRankingModelWeights(t *testing.T) {
+	model := buildRankingModel([]FeedbackStat{
+		{Key: "bm25", Positive: 40, Total: 50},
+		{Key: "vector", Positive: 5, Total: 50},
+	}, nil)
+	if model.weight("bm25") <= rrfWeight("bm25") {
+		t.Fatalf("有用率高的召回源权重应上调: %v", model.weight("bm25"))
+	}
+	if model.weight("vector") >= rrfWeight("vector") {
+		t.Fatalf("有用率低的召回源权重应下调: %v", model.weight("vector"))
+	}
+	if model.weight("vector") < rrfWeight("vector")*feedbackWeightMinRatio {
+		t.Fatalf("权重下调不应超过下限: %v", model.weight("vector"))
+	}
+	if model.weight("keyword") != rrfWeight("keyword") {
+		t.Fatalf("无反馈的召回源应保持默认权重")
+	}
+	var nilModel *rankingModel
+	if nilModel.weight("bm25") != rrfWeight("bm25") || nilModel.version() != "" {
+		t.Fatalf("nil 模型应使用默认权重")
+	}
+}
+
+func TestRankingModelApplyBoosts(t *testing.T) {
+	model := buildRankingModel(nil, []FeedbackStat{
+		{Key: "m2", Positive: 5, Total: 5},
+		{Key: "m1", Positive: 0, Total: 5},
+	})
+	rows := model.applyBoosts([]FragmentRow{
+		{MemoryID: "m1", RankScore: 0.020},
+		{MemoryID: "m2", RankScore: 0.018},
+	})
+	if rows[0].MemoryID != "m2" {
+		t.Fatalf("正反馈记忆应被提升: %+v", rows)
+	}
+	if math.Abs(model.Boosts["m2"]-(1+feedbackMemoryBoostSpan*5/8)) > 1e-9 {
+		t.Fatalf("记忆加权计算错误: %v", model.Boosts["m2"])
+	}
+}
+
+func TestBuildRankingModelVersionTracksBoosts(t *testing.T) {
+	sources := []FeedbackStat{{Key: "bm25", Positive: 4, Total: 5}}
+	before := buildRankingModel(sources, []FeedbackStat{{Key: "m1", Positive: 1, Total: 2}})
+	after := buildRankingModel(sources, []FeedbackStat{{Key: "m1", Positive: 2, Total: 3}})
+	if before.Boosts["m1"] == after.Boosts["m1"] {
+		t.Fatalf("测试前提：记忆加权应变化")
+	}
+	if before.version() == after.version() {
+		t.Fatalf("记忆加权变化（召回源统计不变）应改变模型版本")
+	}
+	if before.version() != buildRankingModel(sources, []FeedbackStat{{Key: "m1", Positive: 1, Total: 2}}).version() {
+		t.Fatalf("相同反馈应得到相同版本")
+	}
+}
+
+func TestFeedbackInvalidateModel(t *testing.T) {
+	tracker := NewFeedbackTracker(nil, FeedbackConfig{Enabled: true})
+	tracker.models["owner"] = cachedRanking{Model: &rankingModel{Version: "v1"}, Expires: time.Now().Add(time.Hour)}
+	tracker.models["other"] = cachedRanking{Model: &rankingModel{Version: "v2"}, Expires: time.Now().Add(time.Hour)}
+	tracker.InvalidateModel("owner")
+	if _, ok := tracker.models["owner"]; ok {
+		t.Fatalf("应丢弃该 owner 缓存的排序参数")
+	}
+	if _, ok := tracker.models["other"]; !ok {
+		t.Fatalf("不应影响其他 owner")
+	}
+}
+
+func TestFeedbackImplicitFollowOnce(t *testing.T) {
+	tracker := NewFeedbackTracker(nil, FeedbackConfig{Enabled: true})
+	tracker.RecordImpressions("owner", "pgvector", []SearchResult{{ID: "m1"}, {ID: "m2"}}, map[string][]string{"m2": {"bm25", "vector"}})
+	impression, ok := tracker.impression("", "", "m2", true)
+	if !ok || impression.Rank != 2 || !reflect.DeepEqual(impression.Sources, []string{"bm25", "vector"}) {
+		t.Fatalf("曝光记录错误: %v %+v", ok, impression)
+	}
+	if _, ok := tracker.impression("", "", "m2", true); ok {
+		t.Fatalf("同一次曝光只应记录一次隐式反馈")
+	}
+	if _, ok := tracker.impression("owner", "pgvector", "m2", false); !ok {
+		t.Fatalf("显式反馈仍应能关联已跟进的曝光")
+	}
+}
+
+func TestFeedbackImpressionsKeyedByQuery(t *testing.T) {
+	tracker := NewFeedbackTracker(nil, FeedbackConfig{Enabled: true})
+	tracker.RecordImpressions("owner", "pgvector", []SearchResult{{ID: "m1"}}, map[string][]string{"m1": {"vector"}})
+	tracker.RecordImpressions("owner", "连接池", []SearchResult{{ID: "m0"}, {ID: "m1"}}, map[string][]string{"m1": {"bm25"}})
+
+	first, ok := tracker.impression("owner", "pgvector", "m1", false)
+	if !ok || first.Rank != 1 || !reflect.DeepEqual(first.Sources, []string{"vector"}) {
+		t.Fatalf("不同查询的曝光不应互相覆盖: %v %+v", ok, first)
+	}
+	latest, ok := tracker.impression("owner", "", "m1", false)
+	if !ok || latest.Query != "连接池" || latest.Rank != 2 {
+		t.Fatalf("未指定 query 时应取最近一次曝光: %v %+v", ok, latest)
+	}
+	if _, ok := tracker.impression("other", "", "m1", false); ok {
+		t.Fatalf("其他 owner 不应关联到该曝光")
+	}
+	if _, ok := tracker.impression("owner", "未检索过", "m1", false); ok {
+		t.Fatalf("未检索过的 query 不应关联曝光")
+	}
+}
+
+func TestMemorySourceNames(t *testing.T) {
+	got := memorySourceNames([]SourceRows{
+		{Name: "keyword", Rows: []FragmentRow{{MemoryID: "m1"}}},
+		{Name: "vector", Rows: []FragmentRow{{MemoryID: "m1"}, {MemoryID: "m2"}}},
+		{Name: "keyword", Rows: []FragmentRow{{MemoryID: "m1"}}},
+	})
+	if !reflect.DeepEqual(got["m1"], []string{"keyword", "vector"}) || !reflect.DeepEqual(got["m2"], []string{"vector"}) {
+		t.Fatalf("召回源归属错误: %v", got)
+	}
+}
+
+func TestMeanRankShift(t *testing.T) {
+	before := []string{"a", "b", "c"}
+	after := []string{"c", "a", "b"}
+	if got := meanRankShift(before, after, []string{"c"}); got != 2 {
+		t.Fatalf("排名提升计算错误: %v", got)
+	}
+	if got := meanRankShift(before, []string{"a", "b"}, []string{"c"}); got != 0 {
+		t.Fatalf("移出结果应视为末位: %v", got)
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"strings"
 	"time"
@@ -20,6 +19,8 @@ type ForesightRow struct {
 	Prediction     string  `json:"prediction"`
 	RelevanceScore float64 `json:"relevance_score"`
 	ExpiresAt      int64   `json:"expires_at"`
+
+	createdAtMicros int64 // 分页排序键，不对外输出
 }
 
 // ForesightInput mem.foresights 工具的输入
@@ -28,6 +29,7 @@ type ForesightInput struct {
 	MemoryID   string `json:"memory_id,omitempty"`
 	ProjectKey string `json:"project_key,omitempty"`
 	Limit      int    `json:"limit,omitempty"`
+	Cursor     string `json:"cursor,omitempty"`
 }
 
 // ForesightResponse mem.foresights 工具的输出
@@ -36,7 +38,7 @@ type ForesightResponse struct {
 	Metadata   SearchMetadata `json:"metadata"`
 }
 
-// GenerateForesights 基于一条记忆生成前瞻预测，异步调用不阻塞 ingest
+// GenerateForesights 基于一条记忆生成前瞻预测，由后台任务在写入提交后调用
 func (a *App) GenerateForesights(ctx context.Context, memoryID string, projectID string, content string, summary string, contentType string) error {
 	// 内容太短不生成前瞻
 	if len([]rune(strings.TrimSpace(content))) <= foresightMinContentLen {
@@ -44,7 +46,7 @@ func (a *App) GenerateForesights(ctx context.Context, memoryID string, projectID
 	}
 
 	// 调用 LLM 生成前瞻预测
-	predictions := a.llm.PredictForesights(content, summary, contentType)
+	predictions := a.llm.PredictForesights(ctx, content, summary, contentType)
 	if len(predictions) == 0 {
 		return nil
 	}
@@ -106,14 +108,17 @@ func (a *App) QueryForesights(ctx context.Context, input ForesightInput) (Foresi
 	projectKey := strings.TrimSpace(input.ProjectKey)
 
 	if memoryID == "" && projectKey == "" {
-		return ForesightResponse{}, fmt.Errorf("memory_id 或 project_key 至少提供一个")
+		return ForesightResponse{}, newValidationError("invalid_request", "ERR_INVALID_FORESIGHT_TARGET", "memory_id 或 project_key 至少提供一个", 400)
 	}
 
-	var rows []ForesightRow
-	var err error
+	after, err := decodeCursor(input.Cursor, cursorKindForesights)
+	if err != nil {
+		return ForesightResponse{}, err
+	}
 
+	var rows []ForesightRow
 	if memoryID != "" {
-		rows, err = a.store.FetchForesightsByMemory(ctx, memoryID, limit)
+		rows, err = a.store.FetchForesightsByMemory(ctx, memoryID, after, limit+1)
 	} else {
 		projectID, findErr := a.store.FindProjectIDByKey(ctx, ownerID, projectKey)
 		if findErr != nil {
@@ -125,7 +130,7 @@ func (a *App) QueryForesights(ctx context.Context, input ForesightInput) (Foresi
 				Metadata:   SearchMetadata{Total: 0, Returned: 0},
 			}, nil
 		}
-		rows, err = a.store.FetchForesightsByProject(ctx, projectID, limit)
+		rows, err = a.store.FetchForesightsByProject(ctx, projectID, after, limit+1)
 	}
 
 	if err != nil {
@@ -134,23 +139,25 @@ func (a *App) QueryForesights(ctx context.Context, input ForesightInput) (Foresi
 	if rows == nil {
 		rows = []ForesightRow{}
 	}
+	nextCursor := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		nextCursor = encodeCursor(pageCursor{Kind: cursorKindForesights, Ts: last.createdAtMicros, Key: last.ID})
+	}
 
 	return ForesightResponse{
 		Foresights: rows,
 		Metadata: SearchMetadata{
-			Total:    len(rows),
-			Returned: len(rows),
+			Total:      len(rows),
+			Returned:   len(rows),
+			NextCursor: nextCursor,
 		},
 	}, nil
 }
 
 // SearchForesightsForQuery 在搜索流程中搜索前瞻记忆，返回命中的 source_memory_id
 func (a *App) SearchForesightsForQuery(ctx context.Context, vector pgvector.Vector, projectID string, limit int) []string {
-	// lazy cleanup: 每次搜索时顺便清理过期前瞻
-	if cleaned, err := a.store.CleanExpiredForesights(ctx); err == nil && cleaned > 0 {
-		log.Printf("[INFO] 清理过期前瞻: %d 条", cleaned)
-	}
-
 	rows, err := a.store.SearchForesightVectors(ctx, vector, projectID, limit)
 	if err != nil {
 		log.Printf("[WARN] 前瞻向量搜索失败: %v", err)
@@ -172,11 +179,6 @@ func (a *App) SearchForesightsForQuery(ctx context.Context, vector pgvector.Vect
 
 // SearchForesightsForQueryByOwner 按 owner 搜索前瞻记忆
 func (a *App) SearchForesightsForQueryByOwner(ctx context.Context, vector pgvector.Vector, ownerID string, limit int) []string {
-	// lazy cleanup
-	if cleaned, err := a.store.CleanExpiredForesights(ctx); err == nil && cleaned > 0 {
-		log.Printf("[INFO] 清理过期前瞻: %d 条", cleaned)
-	}
-
 	rows, err := a.store.SearchForesightVectorsByOwner(ctx, vector, ownerID, limit)
 	if err != nil {
 		log.Printf("[WARN] 前瞻向量搜索(owner)失败: %v", err)
@@ -0,0 +1,82 @@
+package main
+
+import (
+	agentmemv1 "agent-mem-mcp/proto/agentmem/v1"
+)
+
+// gRPC 类型化消息与内部结构体互转（BulkIngest、Watch）；repeated 字段为空时转为 nil，与 REST 省略字段一致
+
+func ingestInputFromProto(msg *agentmemv1.IngestMemoryInput) IngestMemoryInput {
+	in := IngestMemoryInput{
+		OwnerID:     msg.GetOwnerId(),
+		ProjectKey:  msg.GetProjectKey(),
+		ProjectName: msg.GetProjectName(),
+		MachineName: msg.GetMachineName(),
+		ProjectPath: msg.GetProjectPath(),
+		ContentType: msg.GetContentType(),
+		Content:     msg.GetContent(),
+		Summary:     msg.GetSummary(),
+		SkipLLM:     msg.GetSkipLlm(),
+		Ts:          msg.GetTs(),
+	}
+	if tags := msg.GetTags(); len(tags) > 0 {
+		in.Tags = &tags
+	}
+	if indexPath := msg.GetIndexPath(); len(indexPath) > 0 {
+		in.IndexPath = &indexPath
+	}
+	if axes := msg.GetAxes(); axes != nil {
+		in.Axes = &MemoryAxes{
+			Domain:    axes.GetDomain(),
+			Stack:     axes.GetStack(),
+			Problem:   axes.GetProblem(),
+			Lifecycle: axes.GetLifecycle(),
+			Component: axes.GetComponent(),
+		}
+	}
+	return in
+}
+
+// bulkIngestResponseToProto 汇总每条结果并统计成功/失败数
+func bulkIngestResponseToProto(results []IngestBatchItemResult) *agentmemv1.BulkIngestResponse {
+	response := &agentmemv1.BulkIngestResponse{Results: make([]*agentmemv1.IngestBatchItemResult, 0, len(results))}
+	for _, result := range results {
+		item := &agentmemv1.IngestBatchItemResult{
+			Index:  int32(result.Index),
+			Id:     result.ID,
+			Status: result.Status,
+			Ts:     result.Ts,
+		}
+		if result.Error != nil {
+			item.Error = &agentmemv1.ErrorResponse{
+				Error:     result.Error.Error,
+				Message:   result.Error.Message,
+				Code:      result.Error.Code,
+				Timestamp: result.Error.Timestamp,
+			}
+		}
+		response.Results = append(response.Results, item)
+		if result.Status == "error" {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+	return response
+}
+
+func changeEventToProto(event changeEvent) *agentmemv1.ChangeEvent {
+	msg := &agentmemv1.ChangeEvent{
+		Kind:           event.Kind,
+		Origin:         event.Origin,
+		OwnerId:        event.OwnerID,
+		ProjectId:      event.ProjectID,
+		ProjectKey:     event.ProjectKey,
+		ProjectCreated: event.ProjectCreated,
+		MemoryIds:      event.MemoryIDs,
+	}
+	for _, path := range event.IndexPaths {
+		msg.IndexPaths = append(msg.IndexPaths, &agentmemv1.IndexPath{Segments: path})
+	}
+	return msg
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	agentmemv1 "agent-mem-mcp/proto/agentmem/v1"
+)
+
+// gRPC 服务（-grpc-port 开启）：面向内部服务的批量/流式访问。
+// IDL 见 mcp-go/proto/agentmem/v1/memory.proto，服务注册与消息类型使用由其生成的 agentmemv1 包；
+// 一元调用的消息为 google.protobuf.Struct，字段与 MCP 工具/REST 接口的入参/出参结构体一致（定义见 /openapi.json），
+// 流式调用（BulkIngest、Watch）使用类型化消息；服务端转换为结构体后调用同一实现。
+const (
+	grpcErrorDomain  = "agent-mem"
+	grpcWatchBacklog = 64
+)
+
+// decodeGRPCStruct 将 Struct 消息转为入参结构体；与 REST 接口一致，拒绝未知字段
+func decodeGRPCStruct(msg *structpb.Struct, v any) error {
+	data, err := json.Marshal(msg.AsMap())
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// encodeGRPCStruct 将出参结构体按 JSON 字段转为 Struct 消息
+func encodeGRPCStruct(v any) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(fields)
+}
+
+// WatchInput 为空时推送全部变更；resync 事件（监听重连或推送积压）总是推送
+type WatchInput struct {
+	OwnerID    string   `json:"owner_id,omitempty"`
+	ProjectKey string   `json:"project_key,omitempty"`
+	Kinds      []string `json:"kinds,omitempty"`
+}
+
+func (in WatchInput) matches(event changeEvent) bool {
+	if event.Kind == changeKindResync {
+		return true
+	}
+	if in.OwnerID != "" && event.OwnerID != in.OwnerID {
+		return false
+	}
+	if in.ProjectKey != "" && event.ProjectKey != in.ProjectKey {
+		return false
+	}
+	if len(in.Kinds) > 0 && !slices.Contains(in.Kinds, event.Kind) {
+		return false
+	}
+	return true
+}
+
+type grpcMemoryServer struct {
+	agentmemv1.UnimplementedMemoryServer
+
+	app    *App
+	server *grpc.Server
+	// closing 关闭后结束所有 Watch 流，避免 GracefulStop 被长连接阻塞
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+func newGRPCServer(app *App, token string) *grpcMemoryServer {
+	s := &grpcMemoryServer{app: app, closing: make(chan struct{})}
+	expected := strings.TrimSpace(token)
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if !matchGRPCToken(ctx, expected) {
+				return nil, grpcUnauthenticated()
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if !matchGRPCToken(stream.Context(), expected) {
+				return grpcUnauthenticated()
+			}
+			return handler(srv, stream)
+		}),
+	)
+	agentmemv1.RegisterMemoryServer(s.server, s)
+	return s
+}
+
+func (s *grpcMemoryServer) Serve(listener net.Listener) error {
+	return s.server.Serve(listener)
+}
+
+// Shutdown 先结束 Watch 流再等待进行中的调用完成；ctx 到期后强制关闭
+func (s *grpcMemoryServer) Shutdown(ctx context.Context) {
+	s.closeOnce.Do(func() { close(s.closing) })
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+}
+
+// 令牌与 HTTP 相同（AGENT_MEM_HTTP_TOKEN），通过 metadata authorization: Bearer <token> 或 x-agent-mem-token 传递
+func matchGRPCToken(ctx context.Context, expected string) bool {
+	if expected == "" {
+		return true
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get("authorization") {
+		auth = strings.TrimSpace(auth)
+		if strings.HasPrefix(strings.ToLower(auth), "bearer ") && strings.TrimSpace(auth[7:]) == expected {
+			return true
+		}
+	}
+	for _, value := range md.Get("x-agent-mem-token") {
+		if strings.TrimSpace(value) == expected {
+			return true
+		}
+	}
+	return false
+}
+
+func grpcUnauthenticated() error {
+	return grpcStatusError(&AppError{Status: http.StatusUnauthorized, ErrorKey: "unauthorized", Code: "ERR_UNAUTHORIZED", Message: "未授权请求"}, "")
+}
+
+func (s *grpcMemoryServer) Ingest(ctx context.Context, msg *structpb.Struct) (*structpb.Struct, error) {
+	return grpcUnary(ctx, s.app, "Ingest", msg, (*App).IngestMemoryTool)
+}
+
+func (s *grpcMemoryServer) IngestBatch(ctx context.Context, msg *structpb.Struct) (*structpb.Struct, error) {
+	return grpcUnary(ctx, s.app, "IngestBatch", msg, (*App).IngestBatch)
+}
+
+func (s *grpcMemoryServer) IngestStatus(ctx context.Context, msg *structpb.Struct) (*structpb.Struct, error) {
+	return grpcUnary(ctx, s.app, "IngestStatus", msg, (*App).IngestStatus)
+}
+
+func (s *grpcMemoryServer) Search(ctx context.Context, msg *structpb.Struct) (*structpb.Struct, error) {
+	return grpcUnary(ctx, s.app, "Search", msg, (*App).SearchMemories)
+}
+
+func (s *grpcMemoryServer) Get(ctx context.Context, msg *structpb.Struct) (*structpb.Struct, error) {
+	return grpcUnary(ctx, s.app, "Get", msg, func(a *App, ctx context.Context, in GetMemoriesInput) (GetMemoriesResponse, error) {
+		return a.GetMemories(ctx, in.IDs)
+	})
+}
+
+func (s *grpcMemoryServer) Context(ctx context.Context, msg *structpb.Struct) (*structpb.Struct, error) {
+	return grpcUnary(ctx, s.app, "Context", msg, (*App).BuildContext)
+}
+
+func (s *grpcMemoryServer) Timeline(ctx context.Context, msg *structpb.Struct) (*structpb.Struct, error) {
+	return grpcUnary(ctx, s.app, "Timeline", msg, (*App).Timeline)
+}
+
+func (s *grpcMemoryServer) Similar(ctx context.Context, msg *structpb.Struct) (*structpb.Struct, error) {
+	return grpcUnary(ctx, s.app, "Similar", msg, (*App).SimilarMemories)
+}
+
+func (s *grpcMemoryServer) Feedback(ctx context.Context, msg *structpb.Struct) (*structpb.Struct, error) {
+	return grpcUnary(ctx, s.app, "Feedback", msg, (*App).RecordFeedback)
+}
+
+// grpcUnary 一元调用：Struct 入参转为工具结构体后调用 App 方法，出参再转回 Struct
+func grpcUnary[I, O any](ctx context.Context, app *App, name string, msg *structpb.Struct, call func(*App, context.Context, I) (O, error)) (*structpb.Struct, error) {
+	var in I
+	if err := decodeGRPCStruct(msg, &in); err != nil {
+		return nil, grpcDecodeError(err)
+	}
+	out, err := call(app, ctx, in)
+	if err != nil {
+		return nil, grpcStatusError(err, "grpc "+name)
+	}
+	reply, err := encodeGRPCStruct(out)
+	if err != nil {
+		return nil, grpcStatusError(err, "grpc "+name)
+	}
+	return reply, nil
+}
+
+// BulkIngest 客户端流：按 maxIngestBatchItems 分批交给 IngestBatch（best_effort）写入，
+// 单条失败记入结果而不中断整个流；结果的 index 为该条在流中的序号
+func (s *grpcMemoryServer) BulkIngest(stream agentmemv1.Memory_BulkIngestServer) error {
+	ctx := stream.Context()
+	var (
+		results []IngestBatchItemResult
+		items   []IngestMemoryInput
+		indexes []int
+	)
+	flush := func() error {
+		if len(items) == 0 {
+			return nil
+		}
+		out, err := s.app.IngestBatch(ctx, IngestBatchInput{Items: items})
+		if err != nil {
+			if ctx.Err() != nil {
+				return grpcStatusError(ctx.Err(), "grpc BulkIngest")
+			}
+			for _, index := range indexes {
+				results = append(results, IngestBatchItemResult{Index: index, Status: "error", Error: errorResponseFor(err, "grpc BulkIngest")})
+			}
+		} else {
+			for _, result := range out.Results {
+				result.Index = indexes[result.Index]
+				results = append(results, result)
+			}
+		}
+		items, indexes = items[:0], indexes[:0]
+		return nil
+	}
+	for index := 0; ; index++ {
+		msg, err := stream.Recv()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return err
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			break
+		}
+		items = append(items, ingestInputFromProto(msg))
+		indexes = append(indexes, index)
+		if len(items) == maxIngestBatchItems {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	slices.SortFunc(results, func(a, b IngestBatchItemResult) int { return a.Index - b.Index })
+	return stream.SendAndClose(bulkIngestResponseToProto(results))
+}
+
+// Watch 服务端流：推送变更通知（与资源订阅同源）；客户端消费过慢导致积压时丢弃并补发 resync
+func (s *grpcMemoryServer) Watch(msg *agentmemv1.WatchInput, stream agentmemv1.Memory_WatchServer) error {
+	in := WatchInput{
+		OwnerID:    strings.TrimSpace(msg.GetOwnerId()),
+		ProjectKey: strings.TrimSpace(msg.GetProjectKey()),
+		Kinds:      msg.GetKinds(),
+	}
+
+	events := make(chan changeEvent, grpcWatchBacklog)
+	var lagged atomic.Bool
+	unsubscribe := s.app.changes.Subscribe(func(event changeEvent) {
+		if !in.matches(event) {
+			return
+		}
+		select {
+		case events <- event:
+		default:
+			lagged.Store(true)
+		}
+	})
+	defer unsubscribe()
+	// 订阅完成后发送 header，客户端收到 header 即可确认不会漏掉之后的变更
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.closing:
+			return status.Error(codes.Unavailable, "服务正在关闭")
+		case event := <-events:
+			if lagged.Swap(false) {
+				if err := stream.Send(changeEventToProto(changeEvent{Kind: changeKindResync, Origin: event.Origin})); err != nil {
+					return err
+				}
+			}
+			if err := stream.Send(changeEventToProto(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func grpcDecodeError(err error) error {
+	return grpcStatusError(decodeAppError(err), "")
+}
+
+func decodeAppError(err error) *AppError {
+	if unknown := parseUnknownField(err); unknown != "" {
+		return newValidationError("invalid_field", "ERR_INVALID_FIELD", fmt.Sprintf("unknown field: %s", unknown), http.StatusBadRequest)
+	}
+	return newValidationError("invalid_request", "ERR_INVALID_BODY", "请求体解析失败", http.StatusBadRequest)
+}
+
+// grpcStatusError AppError 按 HTTP 状态映射为 gRPC 状态码，错误码与 error key 放在 ErrorInfo 详情中
+func grpcStatusError(err error, op string) error {
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	payload := errorResponseFor(err, op)
+	st := status.New(grpcCode(err), payload.Message)
+	detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   payload.Code,
+		Domain:   grpcErrorDomain,
+		Metadata: map[string]string{"error": payload.Error},
+	})
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func grpcCode(err error) codes.Code {
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		return codes.Internal
+	}
+	switch appErr.Status {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
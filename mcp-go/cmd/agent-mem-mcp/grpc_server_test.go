@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	agentmemv1 "agent-mem-mcp/proto/agentmem/v1"
+)
+
+func startTestGRPC(t *testing.T, app *App, token string) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := newGRPCServer(app, token)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	})
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("连接 gRPC 失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func grpcMethod(name string) string {
+	return "/" + agentmemv1.Memory_ServiceDesc.ServiceName + "/" + name
+}
+
+func grpcMessage(t *testing.T, v any) *structpb.Struct {
+	t.Helper()
+	msg, err := encodeGRPCStruct(v)
+	if err != nil {
+		t.Fatalf("构造消息失败: %v", err)
+	}
+	return msg
+}
+
+func grpcInvoke(t *testing.T, conn *grpc.ClientConn, ctx context.Context, name string, in, out any) error {
+	t.Helper()
+	reply := new(structpb.Struct)
+	if err := conn.Invoke(ctx, grpcMethod(name), grpcMessage(t, in), reply); err != nil {
+		return err
+	}
+	if err := decodeGRPCStruct(reply, out); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	return nil
+}
+
+func grpcErrorReason(err error) (codes.Code, string) {
+	st := status.Convert(err)
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return st.Code(), info.Reason
+		}
+	}
+	return st.Code(), ""
+}
+
+func TestGRPCUnaryAndErrorMapping(t *testing.T) {
+	conn := startTestGRPC(t, &App{settings: defaultSettings(), changes: NewChangeFeed(nil)}, "")
+	ctx := context.Background()
+
+	var got GetMemoriesResponse
+	if err := grpcInvoke(t, conn, ctx, "Get", GetMemoriesInput{}, &got); err != nil {
+		t.Fatalf("Get 调用失败: %v", err)
+	}
+	if got.Results == nil || len(got.Results) != 0 {
+		t.Fatalf("空 ids 应返回空列表: %+v", got)
+	}
+
+	var search SearchResponse
+	err := grpcInvoke(t, conn, ctx, "Search", SearchInput{OwnerID: "personal", Query: "x"}, &search)
+	if code, reason := grpcErrorReason(err); code != codes.InvalidArgument || reason != "ERR_INVALID_QUERY" {
+		t.Fatalf("参数错误应映射为 InvalidArgument: %v", err)
+	}
+	err = grpcInvoke(t, conn, ctx, "Get", map[string]any{"ids": []string{}, "extra": 1}, &got)
+	if code, reason := grpcErrorReason(err); code != codes.InvalidArgument || reason != "ERR_INVALID_FIELD" {
+		t.Fatalf("未知字段应拒绝: %v", err)
+	}
+
+	cases := map[int]codes.Code{
+		http.StatusBadRequest:          codes.InvalidArgument,
+		http.StatusUnauthorized:        codes.Unauthenticated,
+		http.StatusNotFound:            codes.NotFound,
+		http.StatusInternalServerError: codes.Internal,
+	}
+	for httpStatus, want := range cases {
+		if code := grpcCode(&AppError{Status: httpStatus}); code != want {
+			t.Fatalf("状态 %d 映射错误: %s", httpStatus, code)
+		}
+	}
+}
+
+func TestGRPCBulkIngestPerItemResults(t *testing.T) {
+	conn := startTestGRPC(t, &App{settings: defaultSettings(), changes: NewChangeFeed(nil)}, "")
+	stream, err := agentmemv1.NewMemoryClient(conn).BulkIngest(context.Background())
+	if err != nil {
+		t.Fatalf("打开流失败: %v", err)
+	}
+	items := []*agentmemv1.IngestMemoryInput{
+		{OwnerId: "personal", ProjectKey: "demo", ContentType: "plan"},
+		{OwnerId: "personal", ContentType: "plan", Content: "内容"},
+		{OwnerId: "personal", ProjectKey: "demo", ContentType: strings.Repeat("x", 51), Content: "内容"},
+	}
+	for _, item := range items {
+		if err := stream.Send(item); err != nil {
+			t.Fatalf("发送失败: %v", err)
+		}
+	}
+	response, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("接收结果失败: %v", err)
+	}
+	if len(response.GetResults()) != 3 || response.GetFailed() != 3 || response.GetSucceeded() != 0 {
+		t.Fatalf("结果数量错误: %+v", response)
+	}
+	wantCodes := []string{"ERR_INVALID_CONTENT", "ERR_INVALID_PROJECT", "ERR_INVALID_CONTENT_TYPE"}
+	for idx, result := range response.GetResults() {
+		if int(result.GetIndex()) != idx || result.GetStatus() != "error" || result.GetError().GetCode() != wantCodes[idx] {
+			t.Fatalf("第 %d 条结果错误: %+v", idx, result)
+		}
+	}
+}
+
+func TestGRPCBulkIngestChunksPreserveStreamIndex(t *testing.T) {
+	conn := startTestGRPC(t, &App{settings: defaultSettings(), changes: NewChangeFeed(nil)}, "")
+	stream, err := agentmemv1.NewMemoryClient(conn).BulkIngest(context.Background())
+	if err != nil {
+		t.Fatalf("打开流失败: %v", err)
+	}
+	total := maxIngestBatchItems + 5
+	for idx := range total {
+		item := &agentmemv1.IngestMemoryInput{OwnerId: "personal", ProjectKey: "demo", ContentType: "plan"}
+		if idx == maxIngestBatchItems/2 {
+			item = &agentmemv1.IngestMemoryInput{OwnerId: "personal", ContentType: "plan", Content: "内容"}
+		}
+		if err := stream.Send(item); err != nil {
+			t.Fatalf("发送失败: %v", err)
+		}
+	}
+	response, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("接收结果失败: %v", err)
+	}
+	if len(response.GetResults()) != total || int(response.GetFailed()) != total {
+		t.Fatalf("跨批次结果数量错误: %d %d", len(response.GetResults()), response.GetFailed())
+	}
+	for idx, result := range response.GetResults() {
+		want := "ERR_INVALID_CONTENT"
+		if idx == maxIngestBatchItems/2 {
+			want = "ERR_INVALID_PROJECT"
+		}
+		if int(result.GetIndex()) != idx || result.GetError().GetCode() != want {
+			t.Fatalf("第 %d 条结果序号或错误码错误: %+v", idx, result)
+		}
+	}
+}
+
+func TestIngestInputFromProto(t *testing.T) {
+	in := ingestInputFromProto(&agentmemv1.IngestMemoryInput{
+		OwnerId:     "personal",
+		ProjectKey:  "demo",
+		ContentType: "plan",
+		Content:     "内容",
+		Tags:        []string{"go"},
+		Axes:        &agentmemv1.MemoryAxes{Domain: []string{"auth"}},
+		Ts:          1700000000,
+	})
+	if in.OwnerID != "personal" || in.ProjectKey != "demo" || in.Content != "内容" || in.Ts != 1700000000 {
+		t.Fatalf("基本字段转换错误: %+v", in)
+	}
+	if in.Tags == nil || len(*in.Tags) != 1 || in.Axes == nil || len(in.Axes.Domain) != 1 {
+		t.Fatalf("tags/axes 转换错误: %+v", in)
+	}
+	if in.IndexPath != nil {
+		t.Fatalf("未设置的 index_path 应为 nil")
+	}
+}
+
+func TestGRPCWatchStreamsFilteredChanges(t *testing.T) {
+	app := &App{settings: defaultSettings(), changes: NewChangeFeed(nil)}
+	client := agentmemv1.NewMemoryClient(startTestGRPC(t, app, "secret"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 未携带令牌
+	stream, err := client.Watch(ctx, &agentmemv1.WatchInput{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("未授权应返回 Unauthenticated: %v", err)
+	}
+
+	authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+	stream, err = client.Watch(authCtx, &agentmemv1.WatchInput{OwnerId: "personal", ProjectKey: "demo"})
+	if err != nil {
+		t.Fatalf("打开流失败: %v", err)
+	}
+	// 收到 header 表示服务端已完成订阅
+	if _, err := stream.Header(); err != nil {
+		t.Fatalf("等待订阅失败: %v", err)
+	}
+
+	app.changes.dispatch(changeEvent{Kind: changeKindIngest, OwnerID: "personal", ProjectKey: "other", MemoryIDs: []string{"m0"}})
+	app.changes.dispatch(changeEvent{Kind: changeKindIngest, OwnerID: "personal", ProjectKey: "demo", MemoryIDs: []string{"m1"}, IndexPaths: [][]string{{"dialogs", "api"}}})
+	app.changes.dispatch(changeEvent{Kind: changeKindResync})
+
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("接收变更失败: %v", err)
+	}
+	second, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("接收变更失败: %v", err)
+	}
+	if first.GetProjectKey() != "demo" || len(first.GetMemoryIds()) != 1 || first.GetMemoryIds()[0] != "m1" || second.GetKind() != changeKindResync {
+		t.Fatalf("变更过滤错误: %+v %+v", first, second)
+	}
+	if paths := first.GetIndexPaths(); len(paths) != 1 || strings.Join(paths[0].GetSegments(), "/") != "dialogs/api" {
+		t.Fatalf("index_paths 转换错误: %+v", paths)
+	}
+
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		app.changes.mu.RLock()
+		remaining := len(app.changes.handlers)
+		app.changes.mu.RUnlock()
+		if remaining == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("流结束后应取消订阅")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 生成代码与 proto/agentmem/v1/memory.proto 保持一致：方法名、流方向与消息类型（修改 IDL 后需重新生成）
+func TestGRPCGeneratedCodeMatchesProto(t *testing.T) {
+	data, err := os.ReadFile("../../proto/agentmem/v1/memory.proto")
+	if err != nil {
+		t.Fatalf("读取 IDL 失败: %v", err)
+	}
+	rpcPattern := regexp.MustCompile(`rpc (\w+)\((stream )?([\w.]+)\) returns \((stream )?([\w.]+)\)`)
+	declared := map[string]string{}
+	for _, match := range rpcPattern.FindAllStringSubmatch(string(data), -1) {
+		declared[match[1]] = strings.Join(match[2:], "|")
+	}
+	generated := map[string]string{}
+	methods := agentmemv1.File_agentmem_v1_memory_proto.Services().ByName("Memory").Methods()
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.Get(i)
+		generated[string(method.Name())] = strings.Join([]string{
+			streamPrefix(method.IsStreamingClient()), protoTypeName(method.Input().FullName()),
+			streamPrefix(method.IsStreamingServer()), protoTypeName(method.Output().FullName()),
+		}, "|")
+	}
+	if len(declared) == 0 || len(declared) != len(generated) {
+		t.Fatalf("IDL 与生成代码的方法数不一致: %v %v", declared, generated)
+	}
+	for name, signature := range generated {
+		if declared[name] != signature {
+			t.Fatalf("方法 %s 与 IDL 不一致，需重新生成: %q %q", name, declared[name], signature)
+		}
+	}
+}
+
+func streamPrefix(streaming bool) string {
+	if streaming {
+		return "stream "
+	}
+	return ""
+}
+
+// protoTypeName 同包消息在 IDL 中不带包名
+func protoTypeName(name protoreflect.FullName) string {
+	return strings.TrimPrefix(string(name), "agentmem.v1.")
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// mode=highlight：片段围绕最佳命中段落截取，并返回命中位置（rune 偏移，相对于 snippet）
+const (
+	highlightSnippetRunes = 200
+	// 向量命中逐句计算相似度时，每条结果最多参与比较的句子数
+	highlightMaxSentences = 12
+	highlightEllipsis     = "..."
+)
+
+type runeSpan struct {
+	Start int
+	End   int
+	Term  int
+}
+
+// highlightTerms 高亮词项：短语优先，其次为查询分词（与 BM25/关键词召回一致）
+func highlightTerms(tokenizer Tokenizer, lexicalQuery string, phrases []string) []string {
+	var terms []string
+	for _, phrase := range phrases {
+		terms = append(terms, strings.ToLower(phrase))
+	}
+	terms = append(terms, tokenizeLexicalQuery(tokenizer, lexicalQuery)...)
+	return uniqueStrings(terms)
+}
+
+// buildHighlightSnippet 按词项命中选取覆盖词项最多的窗口；无命中时 ok=false
+func buildHighlightSnippet(content string, terms []string, limit int) (string, []HighlightSpan, bool) {
+	runes := []rune(strings.TrimSpace(content))
+	matches := findTermMatches(runes, terms)
+	if len(matches) == 0 {
+		return "", nil, false
+	}
+	start, end := chooseHighlightWindow(len(runes), matches, limit)
+	snippet, spans := renderHighlight(runes, start, end, mergeSpans(matches), "lexical")
+	return snippet, spans, true
+}
+
+// buildSentenceSnippet 以指定句子为中心截取片段，并将该句标记为向量命中
+func buildSentenceSnippet(content string, sentence runeSpan, limit int) (string, []HighlightSpan) {
+	runes := []rune(strings.TrimSpace(content))
+	length := sentence.End - sentence.Start
+	start := sentence.Start
+	if length < limit {
+		start = max(0, sentence.Start-(limit-length)/2)
+	}
+	end := min(len(runes), start+limit)
+	start = max(0, end-limit)
+	return renderHighlight(runes, start, end, []runeSpan{sentence}, "vector")
+}
+
+// findTermMatches 大小写不敏感地查找所有词项出现位置（逐 rune 小写，保证偏移与原文一致）
+func findTermMatches(runes []rune, terms []string) []runeSpan {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	var matches []runeSpan
+	for idx, term := range terms {
+		needle := []rune(term)
+		if len(needle) == 0 {
+			continue
+		}
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if runesEqual(lower[i:i+len(needle)], needle) {
+				matches = append(matches, runeSpan{Start: i, End: i + len(needle), Term: idx})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Start == matches[j].Start {
+			return matches[i].End > matches[j].End
+		}
+		return matches[i].Start < matches[j].Start
+	})
+	return matches
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// chooseHighlightWindow 以每个命中为锚点（前留 1/4 上下文），选覆盖不同词项最多、命中次数最多的窗口
+func chooseHighlightWindow(total int, matches []runeSpan, limit int) (int, int) {
+	if total <= limit {
+		return 0, total
+	}
+	bestStart, bestDistinct, bestCount := 0, -1, -1
+	for _, anchor := range matches {
+		start := max(0, anchor.Start-limit/4)
+		end := min(total, start+limit)
+		start = max(0, end-limit)
+		distinct := map[int]bool{}
+		count := 0
+		for _, match := range matches {
+			if match.Start >= start && match.End <= end {
+				distinct[match.Term] = true
+				count++
+			}
+		}
+		if len(distinct) > bestDistinct || (len(distinct) == bestDistinct && count > bestCount) {
+			bestStart, bestDistinct, bestCount = start, len(distinct), count
+		}
+	}
+	return bestStart, min(total, bestStart+limit)
+}
+
+// mergeSpans 合并重叠/相邻的命中（如 bigram "用户""户登""登录" 合并为 "用户登录"）
+func mergeSpans(spans []runeSpan) []runeSpan {
+	var merged []runeSpan
+	for _, span := range spans {
+		if n := len(merged); n > 0 && span.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, span.End)
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// renderHighlight 截取 [start,end) 并把落在窗口内的命中换算为 snippet 内偏移（含省略号前缀）
+func renderHighlight(runes []rune, start, end int, spans []runeSpan, source string) (string, []HighlightSpan) {
+	var builder strings.Builder
+	offset := -start
+	if start > 0 {
+		builder.WriteString(highlightEllipsis)
+		offset += len([]rune(highlightEllipsis))
+	}
+	builder.WriteString(string(runes[start:end]))
+	if end < len(runes) {
+		builder.WriteString(highlightEllipsis)
+	}
+	var highlights []HighlightSpan
+	for _, span := range spans {
+		spanStart := max(span.Start, start)
+		spanEnd := min(span.End, end)
+		if spanStart >= spanEnd {
+			continue
+		}
+		highlights = append(highlights, HighlightSpan{Start: spanStart + offset, End: spanEnd + offset, Source: source})
+	}
+	return builder.String(), highlights
+}
+
+// splitSentences 按中英文句末标点与换行切句，返回去除首尾空白后的 rune 区间
+func splitSentences(content string) []runeSpan {
+	runes := []rune(strings.TrimSpace(content))
+	var sentences []runeSpan
+	start := 0
+	emit := func(end int) {
+		s, e := start, end
+		for s < e && unicode.IsSpace(runes[s]) {
+			s++
+		}
+		for e > s && unicode.IsSpace(runes[e-1]) {
+			e--
+		}
+		if e > s {
+			sentences = append(sentences, runeSpan{Start: s, End: e})
+		}
+		start = end
+	}
+	for i, r := range runes {
+		switch r {
+		case '。', '！', '？', '；', '.', '!', '?', ';', '\n':
+			emit(i + 1)
+		}
+	}
+	emit(len(runes))
+	return sentences
+}
+
+// mostSimilarSentence 返回与查询向量余弦相似度最高的句子下标，向量缺失时返回 -1
+func mostSimilarSentence(query []float32, sentences [][]float32) int {
+	best, bestScore := -1, math.Inf(-1)
+	for idx, vector := range sentences {
+		if len(vector) == 0 || len(vector) != len(query) {
+			continue
+		}
+		if score := cosineSimilarity(query, vector); score > bestScore {
+			best, bestScore = idx, score
+		}
+	}
+	return best
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// highlightVectorSentences 对无词项命中的向量结果，逐句 embedding（一次批量请求）并高亮最相似的句子；
+// embedding 失败或超时时保留默认片段
+func (s *Searcher) highlightVectorSentences(ctx context.Context, embedding *queryEmbedding, results []SearchResult, contents map[int]string) {
+	if embedding == nil || len(contents) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, sourceTimeout(s.settings.SearchSources, "vector"))
+	defer cancel()
+	queryVector, err := embedding.wait(ctx)
+	if err != nil {
+		return
+	}
+
+	type pending struct {
+		index     int
+		content   string
+		sentences []runeSpan
+		offset    int
+	}
+	var items []pending
+	var texts []string
+	for index, content := range contents {
+		sentences := splitSentences(content)
+		if len(sentences) > highlightMaxSentences {
+			sentences = sentences[:highlightMaxSentences]
+		}
+		if len(sentences) == 0 {
+			continue
+		}
+		runes := []rune(strings.TrimSpace(content))
+		items = append(items, pending{index: index, content: content, sentences: sentences, offset: len(texts)})
+		for _, sentence := range sentences {
+			texts = append(texts, string(runes[sentence.Start:sentence.End]))
+		}
+	}
+	if len(texts) == 0 {
+		return
+	}
+	vectors, err := s.embedder.EmbedBatch(ctx, texts)
+	if err != nil || len(vectors) != len(texts) {
+		return
+	}
+	for _, item := range items {
+		best := mostSimilarSentence(queryVector.Slice(), vectors[item.offset:item.offset+len(item.sentences)])
+		if best < 0 {
+			continue
+		}
+		snippet, spans := buildSentenceSnippet(item.content, item.sentences[best], highlightSnippetRunes)
+		results[item.index].Snippet = snippet
+		results[item.index].Highlights = spans
+	}
+}
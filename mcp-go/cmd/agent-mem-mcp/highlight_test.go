@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func spanText(snippet string, span HighlightSpan) string {
+	return string([]rune(snippet)[span.Start:span.End])
+}
+
+func TestBuildHighlightSnippetMergesBigrams(t *testing.T) {
+	terms := highlightTerms(bigramTokenizer{}, "用户登录", nil)
+	snippet, spans, ok := buildHighlightSnippet("系统支持用户登录和注册", terms, 200)
+	if !ok || len(spans) != 1 {
+		t.Fatalf("应命中一个合并区间: %v %+v", ok, spans)
+	}
+	if got := spanText(snippet, spans[0]); got != "用户登录" || spans[0].Source != "lexical" {
+		t.Fatalf("高亮区间错误: %q %+v", got, spans[0])
+	}
+}
+
+func TestBuildHighlightSnippetCentersOnMatch(t *testing.T) {
+	content := strings.Repeat("无关内容。", 80) + "这里配置 PgVector 索引参数。" + strings.Repeat("其他说明。", 80)
+	snippet, spans, ok := buildHighlightSnippet(content, []string{"pgvector"}, 60)
+	if !ok || len(spans) != 1 {
+		t.Fatalf("应命中: %v %+v", ok, spans)
+	}
+	if !strings.HasPrefix(snippet, highlightEllipsis) || !strings.HasSuffix(snippet, highlightEllipsis) {
+		t.Fatalf("窗口应位于正文中部: %q", snippet)
+	}
+	if got := spanText(snippet, spans[0]); got != "PgVector" {
+		t.Fatalf("偏移应指向原文大小写: %q", got)
+	}
+}
+
+func TestBuildHighlightSnippetPrefersDistinctTerms(t *testing.T) {
+	content := "alpha alpha alpha " + strings.Repeat("x", 100) + " alpha beta"
+	snippet, spans, ok := buildHighlightSnippet(content, []string{"alpha", "beta"}, 30)
+	if !ok {
+		t.Fatalf("应命中")
+	}
+	found := map[string]bool{}
+	for _, span := range spans {
+		found[spanText(snippet, span)] = true
+	}
+	if !found["alpha"] || !found["beta"] {
+		t.Fatalf("应选择覆盖更多词项的窗口: %q %+v", snippet, spans)
+	}
+}
+
+func TestBuildHighlightSnippetNoMatch(t *testing.T) {
+	if _, _, ok := buildHighlightSnippet("毫不相关", []string{"pgvector"}, 200); ok {
+		t.Fatalf("无命中时应返回 ok=false")
+	}
+}
+
+func TestSentenceHighlight(t *testing.T) {
+	content := "第一句话。第二句讲索引！Third sentence."
+	sentences := splitSentences(content)
+	if len(sentences) != 3 {
+		t.Fatalf("切句错误: %+v", sentences)
+	}
+	best := mostSimilarSentence([]float32{1, 0}, [][]float32{{0, 1}, {0.9, 0.1}, {0.1, 0.9}})
+	if best != 1 {
+		t.Fatalf("最相似句下标错误: %d", best)
+	}
+	snippet, spans := buildSentenceSnippet(content, sentences[best], 200)
+	if len(spans) != 1 || spanText(snippet, spans[0]) != "第二句讲索引！" || spans[0].Source != "vector" {
+		t.Fatalf("向量高亮错误: %q %+v", snippet, spans)
+	}
+}
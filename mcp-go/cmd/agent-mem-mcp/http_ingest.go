@@ -6,42 +6,56 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// httpRoute REST 接口，与 MCP 工具一一对应；路由注册与 /openapi.json 共用此表
+type httpRoute struct {
+	Path    string
+	Method  string
+	Tool    string
+	Summary string
+	// Input 为 MCP 工具的入参结构体：GET（以及 QueryInput 的 POST）按顶层字段展开为 query 参数，其余为 JSON 请求体
+	Input      reflect.Type
+	Output     reflect.Type
+	QueryInput bool
+	// TextOutput 响应为纯文本（Prometheus 指标）
+	TextOutput bool
+	Handler    func(http.ResponseWriter, *http.Request, *App)
+}
+
+var httpRoutes = []httpRoute{
+	{Path: "/ingest/memory", Method: http.MethodPost, Tool: "mem.ingest_memory", Summary: "写入记忆", Input: reflect.TypeFor[IngestMemoryInput](), Output: reflect.TypeFor[IngestMemoryOutput](), Handler: handleIngestMemory},
+	{Path: "/ingest/batch", Method: http.MethodPost, Tool: "mem.ingest_batch", Summary: "批量写入记忆", Input: reflect.TypeFor[IngestBatchInput](), Output: reflect.TypeFor[IngestBatchOutput](), Handler: handleIngestBatch},
+	{Path: "/ingest/status", Method: http.MethodGet, Tool: "mem.ingest_status", Summary: "异步写入任务状态", Input: reflect.TypeFor[IngestStatusInput](), Output: reflect.TypeFor[IngestStatusResponse](), Handler: handleIngestStatus},
+	{Path: "/memories/search", Method: http.MethodGet, Tool: "mem.search", Summary: "语义检索记忆", Input: reflect.TypeFor[SearchInput](), Output: reflect.TypeFor[SearchResponse](), Handler: handleSearchMemories},
+	{Path: "/memories", Method: http.MethodGet, Tool: "mem.get", Summary: "获取记忆完整内容", Input: reflect.TypeFor[GetMemoriesInput](), Output: reflect.TypeFor[GetMemoriesResponse](), Handler: handleGetMemories},
+	{Path: "/memories/timeline", Method: http.MethodGet, Tool: "mem.timeline", Summary: "时间线查询", Input: reflect.TypeFor[TimelineInput](), Output: reflect.TypeFor[TimelineResponse](), Handler: handleTimeline},
+	{Path: "/memories/index", Method: http.MethodGet, Tool: "mem.index", Summary: "纵横轴与 index_path 统计", Input: reflect.TypeFor[IndexInput](), Output: reflect.TypeFor[IndexResponse](), Handler: handleIndex},
+	{Path: "/memories/metrics", Method: http.MethodGet, Tool: "mem.metrics", Summary: "Prometheus 格式指标", Input: reflect.TypeFor[IndexInput](), Output: reflect.TypeFor[MetricsResponse](), TextOutput: true, Handler: handleMetrics},
+	{Path: "/projects", Method: http.MethodGet, Tool: "mem.list_projects", Summary: "项目列表", Input: reflect.TypeFor[ListProjectsInput](), Output: reflect.TypeFor[ListProjectsResponse](), Handler: handleListProjects},
+	{Path: "/arbitrations", Method: http.MethodGet, Tool: "mem.arbitration_history", Summary: "仲裁历史", Input: reflect.TypeFor[ArbitrationHistoryInput](), Output: reflect.TypeFor[ArbitrationHistoryResponse](), Handler: handleArbitrationHistory},
+	{Path: "/memories/similar", Method: http.MethodGet, Tool: "mem.similar", Summary: "相似记忆", Input: reflect.TypeFor[SimilarInput](), Output: reflect.TypeFor[SearchResponse](), Handler: handleSimilarMemories},
+	{Path: "/memories/feedback", Method: http.MethodPost, Tool: "mem.feedback", Summary: "标记检索结果有用/无用", Input: reflect.TypeFor[FeedbackInput](), Output: reflect.TypeFor[FeedbackOutput](), Handler: handleFeedback},
+	{Path: "/memories/context", Method: http.MethodPost, Tool: "mem.context", Summary: "按任务打包上下文", Input: reflect.TypeFor[ContextInput](), Output: reflect.TypeFor[ContextPack](), Handler: handleContext},
+	{Path: "/memories/chain", Method: http.MethodGet, Tool: "mem.memory_chain", Summary: "记忆演进链", Input: reflect.TypeFor[MemoryChainInput](), Output: reflect.TypeFor[MemoryChainResponse](), Handler: handleMemoryChain},
+	{Path: "/memories/rollback", Method: http.MethodPost, Tool: "mem.rollback", Summary: "回滚 REPLACE/MERGE 仲裁", Input: reflect.TypeFor[RollbackInput](), Output: reflect.TypeFor[RollbackOutput](), QueryInput: true, Handler: handleRollback},
+	{Path: "/memories/link", Method: http.MethodPost, Tool: "mem.link", Summary: "创建记忆间关系边", Input: reflect.TypeFor[LinkInput](), Output: reflect.TypeFor[LinkOutput](), Handler: handleLink},
+	{Path: "/memories/relations", Method: http.MethodGet, Tool: "mem.relations", Summary: "查询记忆的关联关系", Input: reflect.TypeFor[RelationsInput](), Output: reflect.TypeFor[RelationsResponse](), Handler: handleRelations},
+	{Path: "/memories/foresights", Method: http.MethodGet, Tool: "mem.foresights", Summary: "查询前瞻记忆", Input: reflect.TypeFor[ForesightInput](), Output: reflect.TypeFor[ForesightResponse](), Handler: handleForesights},
+	{Path: "/memories/distill", Method: http.MethodPost, Tool: "mem.distill", Summary: "记忆蒸馏", Input: reflect.TypeFor[DistillInput](), Output: reflect.TypeFor[DistillOutput](), Handler: handleDistill},
+}
+
 func registerHTTPRoutes(mux *http.ServeMux, app *App) {
-	mux.HandleFunc("/ingest/memory", func(w http.ResponseWriter, r *http.Request) {
-		handleIngestMemory(w, r, app)
-	})
-	mux.HandleFunc("/memories/search", func(w http.ResponseWriter, r *http.Request) {
-		handleSearchMemories(w, r, app)
-	})
-	mux.HandleFunc("/memories", func(w http.ResponseWriter, r *http.Request) {
-		handleGetMemories(w, r, app)
-	})
-	mux.HandleFunc("/memories/timeline", func(w http.ResponseWriter, r *http.Request) {
-		handleTimeline(w, r, app)
-	})
-	mux.HandleFunc("/memories/index", func(w http.ResponseWriter, r *http.Request) {
-		handleIndex(w, r, app)
-	})
-	mux.HandleFunc("/memories/metrics", func(w http.ResponseWriter, r *http.Request) {
-		handleMetrics(w, r, app)
-	})
-	mux.HandleFunc("/projects", func(w http.ResponseWriter, r *http.Request) {
-		handleListProjects(w, r, app)
-	})
-	mux.HandleFunc("/arbitrations", func(w http.ResponseWriter, r *http.Request) {
-		handleArbitrationHistory(w, r, app)
-	})
-	mux.HandleFunc("/memories/chain", func(w http.ResponseWriter, r *http.Request) {
-		handleMemoryChain(w, r, app)
-	})
-	mux.HandleFunc("/memories/rollback", func(w http.ResponseWriter, r *http.Request) {
-		handleRollback(w, r, app)
-	})
+	for _, route := range httpRoutes {
+		mux.HandleFunc(route.Path, func(w http.ResponseWriter, r *http.Request) {
+			route.Handler(w, r, app)
+		})
+	}
+	mux.HandleFunc("/openapi.json", handleOpenAPI)
 }
 
 func handleIngestMemory(w http.ResponseWriter, r *http.Request, app *App) {
@@ -62,27 +76,53 @@ func handleIngestMemory(w http.ResponseWriter, r *http.Request, app *App) {
 		writeError(w, http.StatusBadRequest, "invalid_request", "请求体解析失败", "ERR_INVALID_BODY")
 		return
 	}
-	normalized, err := normalizeIngestInput(payload, app.settings, time.Now().UTC())
+
+	output, err := app.IngestMemoryTool(r.Context(), payload)
 	if err != nil {
-		writeValidationError(w, err)
+		writeAppError(w, err, "ingest")
 		return
 	}
-	if err := validateIngestInput(normalized); err != nil {
-		writeValidationError(w, err)
+	writeJSON(w, http.StatusOK, output)
+}
+
+func handleIngestBatch(w http.ResponseWriter, r *http.Request, app *App) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 POST", "ERR_METHOD")
+		return
+	}
+	var payload IngestBatchInput
+	if !decodeJSONBody(w, r, &payload) {
 		return
 	}
 
-	result, err := app.IngestMemory(r.Context(), normalized)
+	output, err := app.IngestBatch(r.Context(), payload)
 	if err != nil {
-		log.Printf("❌ ingest 失败: %v", err)
-		writeError(w, http.StatusInternalServerError, "internal_error", "服务器错误", "ERR_INTERNAL")
+		writeAppError(w, err, "ingest_batch")
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+func handleIngestStatus(w http.ResponseWriter, r *http.Request, app *App) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 GET", "ERR_METHOD")
 		return
 	}
-	status := result.Status
-	if status == "" {
-		status = "created"
+	if err := rejectUnknownQuery(r, map[string]bool{"owner_id": true, "job_ids": true}); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_field", err.Error(), "ERR_INVALID_FIELD")
+		return
 	}
-	writeJSON(w, http.StatusOK, IngestMemoryOutput{ID: result.ID, Status: status, Ts: normalized.Ts})
+
+	input := IngestStatusInput{
+		OwnerID: strings.TrimSpace(r.URL.Query().Get("owner_id")),
+		JobIDs:  r.URL.Query()["job_ids"],
+	}
+	output, err := app.IngestStatus(r.Context(), input)
+	if err != nil {
+		writeAppError(w, err, "ingest_status")
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
 }
 
 func handleSearchMemories(w http.ResponseWriter, r *http.Request, app *App) {
@@ -90,7 +130,7 @@ func handleSearchMemories(w http.ResponseWriter, r *http.Request, app *App) {
 		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 GET", "ERR_METHOD")
 		return
 	}
-	if err := rejectUnknownQuery(r, map[string]bool{"owner_id": true, "project_key": true, "project_name": true, "machine_name": true, "project_path": true, "query": true, "scope": true, "profile": true, "mode": true, "axes": true, "index_path": true, "limit": true}); err != nil {
+	if err := rejectUnknownQuery(r, map[string]bool{"owner_id": true, "project_key": true, "project_name": true, "machine_name": true, "project_path": true, "query": true, "scope": true, "profile": true, "mode": true, "axes": true, "index_path": true, "limit": true, "content_types": true, "project_keys": true, "tags": true, "exclude_tags": true, "since": true, "until": true, "cursor": true}); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid_field", err.Error(), "ERR_INVALID_FIELD")
 		return
 	}
@@ -105,6 +145,7 @@ func handleSearchMemories(w http.ResponseWriter, r *http.Request, app *App) {
 		Scope:       strings.TrimSpace(r.URL.Query().Get("scope")),
 		Profile:     strPtr(strings.TrimSpace(r.URL.Query().Get("profile"))),
 		Mode:        strPtr(strings.TrimSpace(r.URL.Query().Get("mode"))),
+		Cursor:      strings.TrimSpace(r.URL.Query().Get("cursor")),
 	}
 	axes, err := parseAxesQuery(r.URL.Query().Get("axes"))
 	if err != nil {
@@ -128,6 +169,22 @@ func handleSearchMemories(w http.ResponseWriter, r *http.Request, app *App) {
 		return
 	}
 	payload.Limit = limit
+	payload.ContentTypes = parseStringListQuery(r.URL.Query()["content_types"])
+	payload.ProjectKeys = parseStringListQuery(r.URL.Query()["project_keys"])
+	payload.Tags = parseStringListQuery(r.URL.Query()["tags"])
+	payload.ExcludeTags = parseStringListQuery(r.URL.Query()["exclude_tags"])
+	since, err := parseTimeQuery(r.URL.Query().Get("since"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "since "+err.Error(), "ERR_INVALID_TIME_RANGE")
+		return
+	}
+	until, err := parseUntilQuery(r.URL.Query().Get("until"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "until "+err.Error(), "ERR_INVALID_TIME_RANGE")
+		return
+	}
+	payload.Since = since
+	payload.Until = until
 
 	output, err := app.SearchMemories(r.Context(), payload)
 	if err != nil {
@@ -183,12 +240,130 @@ func handleGetMemories(w http.ResponseWriter, r *http.Request, app *App) {
 	writeJSON(w, http.StatusOK, output)
 }
 
+func handleSimilarMemories(w http.ResponseWriter, r *http.Request, app *App) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 GET", "ERR_METHOD")
+		return
+	}
+	if err := rejectUnknownQuery(r, map[string]bool{"owner_id": true, "memory_id": true, "project_key": true, "project_keys": true, "scope": true, "content_types": true, "axes": true, "index_path": true, "with_tags": true, "with_axes": true, "limit": true}); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_field", err.Error(), "ERR_INVALID_FIELD")
+		return
+	}
+
+	payload := SimilarInput{
+		OwnerID:      strings.TrimSpace(r.URL.Query().Get("owner_id")),
+		MemoryID:     strings.TrimSpace(r.URL.Query().Get("memory_id")),
+		ProjectKey:   strings.TrimSpace(r.URL.Query().Get("project_key")),
+		ProjectKeys:  parseStringListQuery(r.URL.Query()["project_keys"]),
+		Scope:        strings.TrimSpace(r.URL.Query().Get("scope")),
+		ContentTypes: parseStringListQuery(r.URL.Query()["content_types"]),
+	}
+	axes, err := parseAxesQuery(r.URL.Query().Get("axes"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "axes 参数格式错误", "ERR_INVALID_AXES")
+		return
+	}
+	payload.Axes = axes
+	indexPath, err := parseIndexPathQuery(r.URL.Query()["index_path"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "index_path 参数格式错误", "ERR_INVALID_INDEX_PATH")
+		return
+	}
+	if indexPath != nil {
+		payload.IndexPath = &indexPath
+	}
+	limit, err := parseOptionalInt(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error(), "ERR_INVALID_LIMIT")
+		return
+	}
+	payload.Limit = limit
+	payload.WithTags, _ = strconv.ParseBool(r.URL.Query().Get("with_tags"))
+	payload.WithAxes, _ = strconv.ParseBool(r.URL.Query().Get("with_axes"))
+
+	output, err := app.SimilarMemories(r.Context(), payload)
+	if err != nil {
+		var appErr *AppError
+		if errors.As(err, &appErr) {
+			writeValidationError(w, err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "internal_error", "服务器错误", "ERR_INTERNAL")
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+func handleFeedback(w http.ResponseWriter, r *http.Request, app *App) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 POST", "ERR_METHOD")
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	var payload FeedbackInput
+	if err := decoder.Decode(&payload); err != nil {
+		unknown := parseUnknownField(err)
+		if unknown != "" {
+			writeError(w, http.StatusBadRequest, "invalid_field", fmt.Sprintf("unknown field: %s", unknown), "ERR_INVALID_FIELD")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid_request", "请求体解析失败", "ERR_INVALID_BODY")
+		return
+	}
+
+	output, err := app.RecordFeedback(r.Context(), payload)
+	if err != nil {
+		var appErr *AppError
+		if errors.As(err, &appErr) {
+			writeValidationError(w, err)
+			return
+		}
+		log.Printf("❌ feedback 失败: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "服务器错误", "ERR_INTERNAL")
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+func handleContext(w http.ResponseWriter, r *http.Request, app *App) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 POST", "ERR_METHOD")
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	var payload ContextInput
+	if err := decoder.Decode(&payload); err != nil {
+		unknown := parseUnknownField(err)
+		if unknown != "" {
+			writeError(w, http.StatusBadRequest, "invalid_field", fmt.Sprintf("unknown field: %s", unknown), "ERR_INVALID_FIELD")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid_request", "请求体解析失败", "ERR_INVALID_BODY")
+		return
+	}
+
+	output, err := app.BuildContext(r.Context(), payload)
+	if err != nil {
+		var appErr *AppError
+		if errors.As(err, &appErr) {
+			writeValidationError(w, err)
+			return
+		}
+		log.Printf("❌ context 失败: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "服务器错误", "ERR_INTERNAL")
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
 func handleTimeline(w http.ResponseWriter, r *http.Request, app *App) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 GET", "ERR_METHOD")
 		return
 	}
-	if err := rejectUnknownQuery(r, map[string]bool{"owner_id": true, "project_key": true, "project_name": true, "machine_name": true, "project_path": true, "days": true, "limit": true}); err != nil {
+	if err := rejectUnknownQuery(r, map[string]bool{"owner_id": true, "project_key": true, "project_name": true, "machine_name": true, "project_path": true, "days": true, "limit": true, "cursor": true}); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid_field", err.Error(), "ERR_INVALID_FIELD")
 		return
 	}
@@ -199,6 +374,7 @@ func handleTimeline(w http.ResponseWriter, r *http.Request, app *App) {
 		ProjectName: strings.TrimSpace(r.URL.Query().Get("project_name")),
 		MachineName: strings.TrimSpace(r.URL.Query().Get("machine_name")),
 		ProjectPath: strings.TrimSpace(r.URL.Query().Get("project_path")),
+		Cursor:      strings.TrimSpace(r.URL.Query().Get("cursor")),
 	}
 	days, err := parseOptionalInt(r.URL.Query().Get("days"))
 	if err != nil {
@@ -347,7 +523,7 @@ func handleListProjects(w http.ResponseWriter, r *http.Request, app *App) {
 		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 GET", "ERR_METHOD")
 		return
 	}
-	if err := rejectUnknownQuery(r, map[string]bool{"owner_id": true, "limit": true}); err != nil {
+	if err := rejectUnknownQuery(r, map[string]bool{"owner_id": true, "limit": true, "cursor": true}); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid_field", err.Error(), "ERR_INVALID_FIELD")
 		return
 	}
@@ -360,6 +536,7 @@ func handleListProjects(w http.ResponseWriter, r *http.Request, app *App) {
 	payload := ListProjectsInput{
 		OwnerID: strings.TrimSpace(r.URL.Query().Get("owner_id")),
 		Limit:   limit,
+		Cursor:  strings.TrimSpace(r.URL.Query().Get("cursor")),
 	}
 	output, err := app.ListProjects(r.Context(), payload)
 	if err != nil {
@@ -459,6 +636,61 @@ func parseIndexPathQuery(values []string) ([]string, error) {
 	return normalizeIndexPath(path), nil
 }
 
+// parseStringListQuery 支持重复参数（?tags=a&tags=b）和逗号分隔（?tags=a,b）两种写法
+func parseStringListQuery(values []string) *[]string {
+	var items []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			items = append(items, part)
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return &items
+}
+
+// parseTimeQuery 支持秒/毫秒时间戳、RFC3339 以及 YYYY-MM-DD（UTC）
+func parseTimeQuery(raw string) (int64, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return 0, nil
+	}
+	if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if ts <= 0 {
+			return 0, errors.New("必须为正整数")
+		}
+		return normalizeTimestampSeconds(ts), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Unix(), nil
+	}
+	if t, ok := parseDateOnly(value); ok {
+		return t.Unix(), nil
+	}
+	return 0, errors.New("参数格式错误")
+}
+
+// parseUntilQuery 与 parseTimeQuery 相同，但 until 为闭区间上界：YYYY-MM-DD 取当天最后一秒，整天都包含在内
+func parseUntilQuery(raw string) (int64, error) {
+	if t, ok := parseDateOnly(strings.TrimSpace(raw)); ok {
+		return t.AddDate(0, 0, 1).Unix() - 1, nil
+	}
+	return parseTimeQuery(raw)
+}
+
+func parseDateOnly(value string) (time.Time, bool) {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
+
 func rejectUnknownQuery(r *http.Request, allowed map[string]bool) error {
 	for key := range r.URL.Query() {
 		if !allowed[key] {
@@ -489,8 +721,16 @@ func handleArbitrationHistory(w http.ResponseWriter, r *http.Request, app *App)
 		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 GET", "ERR_METHOD")
 		return
 	}
+	if err := rejectUnknownQuery(r, map[string]bool{"owner_id": true, "memory_id": true, "project_key": true, "limit": true, "cursor": true}); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_field", err.Error(), "ERR_INVALID_FIELD")
+		return
+	}
 
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	limit, err := parseOptionalInt(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error(), "ERR_INVALID_LIMIT")
+		return
+	}
 	if limit <= 0 {
 		limit = 20
 	}
@@ -500,11 +740,12 @@ func handleArbitrationHistory(w http.ResponseWriter, r *http.Request, app *App)
 		MemoryID:   strings.TrimSpace(r.URL.Query().Get("memory_id")),
 		ProjectKey: strings.TrimSpace(r.URL.Query().Get("project_key")),
 		Limit:      limit,
+		Cursor:     strings.TrimSpace(r.URL.Query().Get("cursor")),
 	}
 
 	result, err := app.ArbitrationHistory(r.Context(), input)
 	if err != nil {
-		writeValidationError(w, err)
+		writeAppError(w, err, "arbitration_history")
 		return
 	}
 	writeJSON(w, http.StatusOK, result)
@@ -515,6 +756,10 @@ func handleMemoryChain(w http.ResponseWriter, r *http.Request, app *App) {
 		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 GET", "ERR_METHOD")
 		return
 	}
+	if err := rejectUnknownQuery(r, map[string]bool{"owner_id": true, "memory_id": true}); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_field", err.Error(), "ERR_INVALID_FIELD")
+		return
+	}
 
 	input := MemoryChainInput{
 		OwnerID:  strings.TrimSpace(r.URL.Query().Get("owner_id")),
@@ -523,7 +768,7 @@ func handleMemoryChain(w http.ResponseWriter, r *http.Request, app *App) {
 
 	result, err := app.MemoryChain(r.Context(), input)
 	if err != nil {
-		writeValidationError(w, err)
+		writeAppError(w, err, "memory_chain")
 		return
 	}
 	writeJSON(w, http.StatusOK, result)
@@ -534,8 +779,16 @@ func handleRollback(w http.ResponseWriter, r *http.Request, app *App) {
 		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 POST", "ERR_METHOD")
 		return
 	}
+	if err := rejectUnknownQuery(r, map[string]bool{"owner_id": true, "arbitration_id": true}); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_field", err.Error(), "ERR_INVALID_FIELD")
+		return
+	}
 
-	arbID, _ := strconv.ParseInt(r.URL.Query().Get("arbitration_id"), 10, 64)
+	arbID, err := strconv.ParseInt(strings.TrimSpace(r.URL.Query().Get("arbitration_id")), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "arbitration_id 必须为正整数", "ERR_INVALID_ARBITRATION_ID")
+		return
+	}
 	input := RollbackInput{
 		OwnerID:       strings.TrimSpace(r.URL.Query().Get("owner_id")),
 		ArbitrationID: arbID,
@@ -543,12 +796,136 @@ func handleRollback(w http.ResponseWriter, r *http.Request, app *App) {
 
 	result, err := app.Rollback(r.Context(), input)
 	if err != nil {
-		writeValidationError(w, err)
+		writeAppError(w, err, "rollback")
 		return
 	}
 	writeJSON(w, http.StatusOK, result)
 }
 
+func handleLink(w http.ResponseWriter, r *http.Request, app *App) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 POST", "ERR_METHOD")
+		return
+	}
+	var payload LinkInput
+	if !decodeJSONBody(w, r, &payload) {
+		return
+	}
+
+	output, err := app.LinkMemories(r.Context(), payload)
+	if err != nil {
+		writeAppError(w, err, "link")
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+func handleRelations(w http.ResponseWriter, r *http.Request, app *App) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 GET", "ERR_METHOD")
+		return
+	}
+	if err := rejectUnknownQuery(r, map[string]bool{"memory_id": true, "direction": true, "relation_type": true, "limit": true, "cursor": true}); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_field", err.Error(), "ERR_INVALID_FIELD")
+		return
+	}
+
+	limit, err := parseOptionalInt(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error(), "ERR_INVALID_LIMIT")
+		return
+	}
+	payload := RelationsInput{
+		MemoryID:     strings.TrimSpace(r.URL.Query().Get("memory_id")),
+		Direction:    strings.TrimSpace(r.URL.Query().Get("direction")),
+		RelationType: strings.TrimSpace(r.URL.Query().Get("relation_type")),
+		Limit:        limit,
+		Cursor:       strings.TrimSpace(r.URL.Query().Get("cursor")),
+	}
+
+	output, err := app.QueryRelations(r.Context(), payload)
+	if err != nil {
+		writeAppError(w, err, "relations")
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+func handleForesights(w http.ResponseWriter, r *http.Request, app *App) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 GET", "ERR_METHOD")
+		return
+	}
+	if err := rejectUnknownQuery(r, map[string]bool{"owner_id": true, "memory_id": true, "project_key": true, "limit": true, "cursor": true}); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_field", err.Error(), "ERR_INVALID_FIELD")
+		return
+	}
+
+	limit, err := parseOptionalInt(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error(), "ERR_INVALID_LIMIT")
+		return
+	}
+	payload := ForesightInput{
+		OwnerID:    strings.TrimSpace(r.URL.Query().Get("owner_id")),
+		MemoryID:   strings.TrimSpace(r.URL.Query().Get("memory_id")),
+		ProjectKey: strings.TrimSpace(r.URL.Query().Get("project_key")),
+		Limit:      limit,
+		Cursor:     strings.TrimSpace(r.URL.Query().Get("cursor")),
+	}
+
+	output, err := app.QueryForesights(r.Context(), payload)
+	if err != nil {
+		writeAppError(w, err, "foresights")
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+func handleDistill(w http.ResponseWriter, r *http.Request, app *App) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 POST", "ERR_METHOD")
+		return
+	}
+	var payload DistillInput
+	if !decodeJSONBody(w, r, &payload) {
+		return
+	}
+
+	output, err := app.DistillMemories(r.Context(), payload)
+	if err != nil {
+		writeAppError(w, err, "distill")
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+// decodeJSONBody 解析 JSON 请求体（拒绝未知字段），失败时已写入错误响应
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, payload any) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(payload); err != nil {
+		if unknown := parseUnknownField(err); unknown != "" {
+			writeError(w, http.StatusBadRequest, "invalid_field", fmt.Sprintf("unknown field: %s", unknown), "ERR_INVALID_FIELD")
+			return false
+		}
+		writeError(w, http.StatusBadRequest, "invalid_request", "请求体解析失败", "ERR_INVALID_BODY")
+		return false
+	}
+	return true
+}
+
+// writeAppError AppError 按其状态码与错误码返回，其余错误记录日志后返回 500
+func writeAppError(w http.ResponseWriter, err error, op string) {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		writeValidationError(w, err)
+		return
+	}
+	log.Printf("❌ %s 失败: %v", op, err)
+	writeError(w, http.StatusInternalServerError, "internal_error", "服务器错误", "ERR_INTERNAL")
+}
+
 func strPtr(s string) *string {
 	if s == "" {
 		return nil
@@ -16,6 +16,14 @@ func newMemoryID() string {
 	return "mem_" + newID()
 }
 
+func newIngestJobID() string {
+	return "job_" + newID()
+}
+
 func newFragmentID(index int) string {
 	return fmt.Sprintf("frag_%s_%d", newID(), index)
 }
+
+func newJobID() string {
+	return "task_" + newID()
+}
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/pgvector/pgvector-go"
 )
 
@@ -21,31 +22,127 @@ type IngestResult struct {
 
 const defaultSemanticUpdateCandidates = 20
 
+// ingestProgressSteps 写入进度阶段：摘要与标签、索引抽取、切分、向量化（占两步）、冲突检测、提交
+const ingestProgressSteps = 7
+
 func (a *App) IngestMemory(ctx context.Context, input IngestMemoryInput) (IngestResult, error) {
-	normalized, err := normalizeIngestInput(input, a.settings, time.Now().UTC())
+	return a.ingestMemory(ctx, input, "")
+}
+
+// ingestMemory supersedes 非空时新记忆是该记忆的下一版本（如 distill/<项目>/latest），见 ingestPlan.supersedes
+func (a *App) ingestMemory(ctx context.Context, input IngestMemoryInput, supersedes string) (IngestResult, error) {
+	plan, err := a.prepareIngest(ctx, input, "")
 	if err != nil {
 		return IngestResult{}, err
 	}
-	if err := validateIngestInput(normalized); err != nil {
+	plan.supersedes = supersedes
+	if plan.duplicateID != "" {
+		return a.markDuplicate(ctx, plan)
+	}
+	reportProgress(ctx, 3, ingestProgressSteps, fmt.Sprintf("切分为 %d 个片段", len(plan.chunks)))
+
+	if err := a.embedIngest(progressScope(ctx, 3, 5, ingestProgressSteps), []*ingestPlan{plan}); err != nil {
 		return IngestResult{}, err
 	}
-	input = normalized
+	decision, err := a.arbitrateIngest(ctx, plan)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	reportProgress(ctx, 6, ingestProgressSteps, "冲突检测: "+string(decision.action))
 
-	project, err := a.store.UpsertProject(ctx, input.OwnerID, input.ProjectKey, input.ProjectName, input.MachineName, input.ProjectPath)
+	tx, err := a.store.pool.Begin(ctx)
 	if err != nil {
-		return IngestResult{}, fmt.Errorf("项目写入失败: %w", err)
+		return IngestResult{}, fmt.Errorf("事务开启失败: %w", err)
 	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	result, err := a.writeIngestTx(ctx, tx, plan, decision)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return IngestResult{}, fmt.Errorf("事务提交失败: %w", err)
+	}
+	a.afterIngest(plan, result)
+	if result.Status != "skipped" {
+		reportProgress(ctx, ingestProgressSteps, ingestProgressSteps, "写入完成")
+	}
+	return result, nil
+}
 
-	contentHash := hashContent(input.Content)
-	duplicateID, err := a.store.FindDuplicateMemory(ctx, project.ID, contentHash, 0)
+// ingestPlan 写入前的准备结果。预处理、向量化、仲裁与事务写入分阶段进行，
+// 批量写入时各条目的向量化合并为一次 EmbedBatch
+type ingestPlan struct {
+	input       IngestMemoryInput
+	project     ProjectRecord
+	contentHash string
+	// pendingID 异步写入入队时创建的临时记忆（仅原文与片段，无向量），处理完成后被正式记忆取代
+	pendingID string
+	// duplicateID 非空时项目内已有相同内容，只刷新其时间
+	duplicateID string
+	// supersedes 指定的上一版本：不论向量相似度都以它为仲裁候选，仲裁为保留两者时改为替换
+	supersedes string
+	summary    string
+	tags       []string
+	axes       MemoryAxes
+	indexPath  []string
+	chunks     []string
+	embeddings [][]float32
+	avgVector  []float32
+}
+
+// ingestDecision 冲突检测与仲裁结果；targetID 为空表示没有相似记忆
+type ingestDecision struct {
+	action     ArbitrateResult
+	targetID   string
+	similarity float64
+	oldSummary string
+	decidedBy  string
+}
+
+// prepareIngest 校验、查重、摘要/标签、索引抽取与切分（不写入记忆）；pendingID 为异步任务的临时记忆，查重时排除
+func (a *App) prepareIngest(ctx context.Context, input IngestMemoryInput, pendingID string) (*ingestPlan, error) {
+	return a.prepareIngestPlan(ctx, input, pendingID, true)
+}
+
+// prepareIngestPlan upsertProject 为 false 时只读取已有项目、不写入任何数据（项目不存在时 plan.project.ID 为空，
+// 不查重也没有仲裁候选），由调用方在写入事务内创建项目
+func (a *App) prepareIngestPlan(ctx context.Context, input IngestMemoryInput, pendingID string, upsertProject bool) (*ingestPlan, error) {
+	normalized, err := normalizeIngestInput(input, a.settings, time.Now().UTC())
 	if err != nil {
-		return IngestResult{}, fmt.Errorf("重复内容检查失败: %w", err)
+		return nil, err
 	}
-	if duplicateID != "" {
-		if err := a.store.UpdateMemoryTimestamp(ctx, duplicateID, input.Ts); err != nil {
-			return IngestResult{}, fmt.Errorf("更新重复内容时间失败: %w", err)
+	if err := validateIngestInput(normalized); err != nil {
+		return nil, err
+	}
+	input = normalized
+
+	var project ProjectRecord
+	if upsertProject {
+		project, err = a.store.UpsertProject(ctx, input.OwnerID, input.ProjectKey, input.ProjectName, input.MachineName, input.ProjectPath)
+		if err != nil {
+			return nil, fmt.Errorf("项目写入失败: %w", err)
+		}
+	} else {
+		projectID, err := a.store.FindProjectIDByKey(ctx, input.OwnerID, input.ProjectKey)
+		if err != nil {
+			return nil, fmt.Errorf("项目查询失败: %w", err)
 		}
-		return IngestResult{ID: duplicateID, Status: "duplicate"}, nil
+		project = ProjectRecord{ID: projectID, ProjectName: input.ProjectName, ProjectKey: input.ProjectKey, OwnerID: input.OwnerID}
+	}
+
+	plan := &ingestPlan{input: input, project: project, contentHash: hashContent(input.Content), pendingID: pendingID}
+	duplicateID := ""
+	if project.ID != "" {
+		duplicateID, err = a.store.FindDuplicateMemory(ctx, project.ID, plan.contentHash, 0, pendingID)
+		if err != nil {
+			return nil, fmt.Errorf("重复内容检查失败: %w", err)
+		}
+	}
+	if duplicateID != "" {
+		plan.duplicateID = duplicateID
+		return plan, nil
 	}
 
 	summary := strings.TrimSpace(input.Summary)
@@ -57,10 +154,10 @@ func (a *App) IngestMemory(ctx context.Context, input IngestMemoryInput) (Ingest
 	skipLLM := input.SkipLLM || len([]rune(contentTrimmed)) <= 120
 	if !skipLLM {
 		if summary == "" {
-			summary = a.llm.Summarize(input.Content)
+			summary = a.llm.Summarize(ctx, input.Content)
 		}
 		if len(tags) == 0 {
-			tags = a.llm.ExtractTags(input.Content)
+			tags = a.llm.ExtractTags(ctx, input.Content)
 		}
 	}
 	if summary == "" {
@@ -69,6 +166,7 @@ func (a *App) IngestMemory(ctx context.Context, input IngestMemoryInput) (Ingest
 	if len(tags) == 0 {
 		tags = fallbackTags(input.Content)
 	}
+	reportProgress(ctx, 1, ingestProgressSteps, "摘要与标签")
 
 	axes := MemoryAxes{}
 	if input.Axes != nil {
@@ -83,7 +181,7 @@ func (a *App) IngestMemory(ctx context.Context, input IngestMemoryInput) (Ingest
 		extractedAxes := MemoryAxes{}
 		var extractedPath []string
 		if needExtract {
-			extractedAxes, extractedPath = a.llm.ExtractIndex(input.ContentType, summary, tags, input.Content)
+			extractedAxes, extractedPath = a.llm.ExtractIndex(ctx, input.ContentType, summary, tags, input.Content)
 		}
 		if a.settings.Indexing.PreferClient {
 			if axesEmpty(axes) {
@@ -102,120 +200,223 @@ func (a *App) IngestMemory(ctx context.Context, input IngestMemoryInput) (Ingest
 		}
 	}
 
+	// LLM 调用失败会降级，被取消时不再继续写入
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	reportProgress(ctx, 2, ingestProgressSteps, "索引抽取")
+
 	chunks := chunkContent(input.Content, a.settings.Chunking)
 	if len(chunks) == 0 {
-		return IngestResult{}, errors.New("内容切分失败")
+		return nil, errors.New("内容切分失败")
+	}
+	plan.summary = summary
+	plan.tags = tags
+	plan.axes = axes
+	plan.indexPath = indexPath
+	plan.chunks = chunks
+	return plan, nil
+}
+
+// markDuplicate 重复内容只刷新已有记忆的时间
+func (a *App) markDuplicate(ctx context.Context, plan *ingestPlan) (IngestResult, error) {
+	if err := a.store.UpdateMemoryTimestamp(ctx, plan.duplicateID, plan.input.Ts); err != nil {
+		return IngestResult{}, fmt.Errorf("更新重复内容时间失败: %w", err)
 	}
+	a.invalidateSearchCache(plan.input.OwnerID, plan.project.ID)
+	event := a.newChangeEvent(changeKindIngest, plan.input.OwnerID, plan.project)
+	event.MemoryIDs = []string{plan.duplicateID}
+	a.publishChange(ctx, event)
+	return IngestResult{ID: plan.duplicateID, Status: "duplicate"}, nil
+}
 
-	embeddings, err := a.embedder.EmbedBatch(ctx, chunks)
+// embedIngest 多个条目的片段合并为一次 EmbedBatch（按提供方批量上限分批请求），再按条目拆回
+func (a *App) embedIngest(ctx context.Context, plans []*ingestPlan) error {
+	var texts []string
+	for _, plan := range plans {
+		texts = append(texts, plan.chunks...)
+	}
+	embeddings, err := a.embedder.EmbedBatch(ctx, texts)
 	if err != nil {
-		return IngestResult{}, fmt.Errorf("向量化失败: %w", err)
+		return fmt.Errorf("向量化失败: %w", err)
 	}
-	if len(embeddings) != len(chunks) {
-		return IngestResult{}, errors.New("向量数量与片段数量不一致")
+	if len(embeddings) != len(texts) {
+		return errors.New("向量数量与片段数量不一致")
 	}
+	offset := 0
+	for _, plan := range plans {
+		plan.embeddings = embeddings[offset : offset+len(plan.chunks)]
+		offset += len(plan.chunks)
+		// 计算 memory 级别的平均向量（用于冲突检测和存储）
+		plan.avgVector = l2Normalize(averageEmbedding(plan.embeddings, a.embedder.dimension))
+	}
+	return nil
+}
 
-	// 计算 memory 级别的平均向量（用于冲突检测和存储）
-	avgVector := averageEmbedding(embeddings, a.embedder.dimension)
-	avgVector = l2Normalize(avgVector)
-
-	// 两层冲突检测：向量粗筛 + LLM 仲裁
-	var action ArbitrateResult = ArbitrateKeepBoth // 默认新建
-	semanticTargetID := ""
-	semanticSimilarity := 0.0
-	oldSummary := ""
-
-	if len(avgVector) > 0 {
+// arbitrateIngest 两层冲突检测：向量粗筛 + LLM 仲裁；选择合并时就地改写 plan 的内容与向量
+func (a *App) arbitrateIngest(ctx context.Context, plan *ingestPlan) (ingestDecision, error) {
+	decision := ingestDecision{action: ArbitrateKeepBoth, decidedBy: arbitrationDecidedByModel} // 默认新建
+
+	// 第一层：向量粗筛（只按项目过滤，不按类型）；指定了上一版本时直接以它为候选（上一版没有向量时同样如此）
+	if plan.supersedes != "" {
+		decision.targetID = plan.supersedes
+		if len(plan.avgVector) > 0 {
+			similarity, _, err := a.store.MemorySimilarity(ctx, plan.supersedes, pgvector.NewVector(plan.avgVector))
+			if err != nil {
+				return ingestDecision{}, fmt.Errorf("语义更新候选查找失败: %w", err)
+			}
+			decision.similarity = similarity
+		}
+	} else if len(plan.avgVector) > 0 && plan.project.ID != "" {
 		threshold := semanticUpdateThreshold(a.settings.Versioning.SemanticSimilarityThreshold)
-		vector := pgvector.NewVector(avgVector)
-
-		// 第一层：向量粗筛（只按项目过滤，不按类型）
-		candidateID, similarity, err := findSemanticUpdateCandidate(ctx, a.store, vector, project.ID, threshold, defaultSemanticUpdateCandidates)
+		candidateID, similarity, err := findSemanticUpdateCandidate(ctx, a.store, pgvector.NewVector(plan.avgVector), plan.project.ID, threshold, defaultSemanticUpdateCandidates)
 		if err != nil {
-			return IngestResult{}, fmt.Errorf("语义更新候选查找失败: %w", err)
+			return ingestDecision{}, fmt.Errorf("语义更新候选查找失败: %w", err)
 		}
-
 		// 第二层：LLM 仲裁（仅当向量相似度超过阈值时）
 		if candidateID != "" && similarity >= threshold {
-			semanticSimilarity = similarity
-			semanticTargetID = candidateID
-			// 获取旧摘要
-			oldMemory, err := a.store.FetchMemorySummary(ctx, candidateID)
-			if err == nil && oldMemory.Summary != "" {
-				oldSummary = oldMemory.Summary
-				// LLM 仲裁：比较新旧摘要
-				action = a.llm.Arbitrate(summary, oldMemory.Summary)
-			} else {
-				// 获取旧摘要失败，保守处理：替换
-				action = ArbitrateReplace
-			}
+			decision.targetID = candidateID
+			decision.similarity = similarity
 		}
 	}
 
+	if decision.targetID != "" {
+		// 获取旧摘要
+		oldMemory, err := a.store.FetchMemorySummary(ctx, decision.targetID)
+		switch {
+		case err == nil && oldMemory.Summary != "":
+			decision.oldSummary = oldMemory.Summary
+			// LLM 仲裁：比较新旧摘要
+			decision.action = a.llm.Arbitrate(ctx, plan.summary, oldMemory.Summary)
+		case errors.Is(err, pgx.ErrNoRows) && plan.supersedes != "":
+			// 上一版本已被删除：按新建处理
+			decision.targetID = ""
+		default:
+			// 获取旧摘要失败，保守处理：替换
+			decision.action = ArbitrateReplace
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return ingestDecision{}, err
+	}
+
+	// 人工确认：仲裁建议替换时请用户选择（客户端不支持 elicitation 或用户未作选择时沿用模型决策）
+	if decision.action == ArbitrateReplace && decision.targetID != "" && a.confirmConflictsEnabled(plan.input) {
+		if choice, ok := confirmConflict(ctx, conflictPrompt{
+			CandidateID: decision.targetID,
+			OldSummary:  decision.oldSummary,
+			NewSummary:  plan.summary,
+			Similarity:  decision.similarity,
+			Suggested:   decision.action,
+		}); ok {
+			decision.action = choice
+			decision.decidedBy = arbitrationDecidedByHuman
+		}
+	}
+	// 同一路径只保留一个版本：在人工确认之后应用，保留两者（不论模型还是用户选择）一律改为替换，不再记为人工决策
+	if plan.supersedes != "" && decision.targetID != "" && decision.action == ArbitrateKeepBoth {
+		decision.action = ArbitrateReplace
+		decision.decidedBy = arbitrationDecidedByModel
+	}
+	if decision.action == ArbitrateMerge {
+		merged, err := a.mergeWithCandidate(ctx, decision.targetID, plan.input.Content, plan.tags, plan.indexPath)
+		if err != nil {
+			return ingestDecision{}, fmt.Errorf("合并记忆失败: %w", err)
+		}
+		plan.input.Content = merged.Content
+		plan.tags = merged.Tags
+		plan.indexPath = merged.IndexPath
+		plan.contentHash = hashContent(merged.Content)
+		if !plan.input.SkipLLM {
+			plan.summary = a.llm.Summarize(ctx, merged.Content)
+		}
+		if plan.summary == "" {
+			plan.summary = fallbackSummary(merged.Content)
+		}
+		plan.chunks = chunkContent(merged.Content, a.settings.Chunking)
+		if err := a.embedIngest(ctx, []*ingestPlan{plan}); err != nil {
+			return ingestDecision{}, err
+		}
+	}
+	return decision, nil
+}
+
+// writeIngestTx 在事务内写入记忆、片段、仲裁日志与变更通知；提交后需调用 afterIngest
+func (a *App) writeIngestTx(ctx context.Context, tx pgxTx, plan *ingestPlan, decision ingestDecision) (IngestResult, error) {
+	input := plan.input
+	action := decision.action
+	semanticTargetID := decision.targetID
+
+	// MERGE 与 REPLACE 一样原地更新旧记忆（保存历史版本，可回滚）
+	replacing := (action == ArbitrateReplace || action == ArbitrateMerge) && semanticTargetID != ""
 	memoryID := newMemoryID()
-	if (action == ArbitrateReplace || action == ArbitrateSkip) && semanticTargetID != "" {
+	if plan.pendingID != "" {
+		// 异步写入新建时沿用临时记忆 ID，入队时返回的 id 保持有效
+		memoryID = plan.pendingID
+	}
+	if (replacing || action == ArbitrateSkip) && semanticTargetID != "" {
 		memoryID = semanticTargetID
 	}
+	arbitrationLog := ArbitrationLogInsert{
+		OwnerID:           input.OwnerID,
+		ProjectID:         plan.project.ID,
+		CandidateMemoryID: semanticTargetID,
+		NewMemoryID:       memoryID,
+		Action:            string(action),
+		Similarity:        decision.similarity,
+		OldSummary:        decision.oldSummary,
+		NewSummary:        plan.summary,
+		Model:             a.settings.LLM.ModelArbitrate,
+		DecidedBy:         decision.decidedBy,
+		CreatedAt:         time.Now().UTC(),
+	}
 
 	if action == ArbitrateSkip {
 		if semanticTargetID != "" {
-			_ = a.store.InsertArbitrationLog(ctx, ArbitrationLogInsert{
-				OwnerID:           input.OwnerID,
-				ProjectID:         project.ID,
-				CandidateMemoryID: semanticTargetID,
-				NewMemoryID:       memoryID,
-				Action:            string(action),
-				Similarity:        semanticSimilarity,
-				OldSummary:        oldSummary,
-				NewSummary:        summary,
-				Model:             a.settings.LLM.ModelArbitrate,
-				CreatedAt:         time.Now().UTC(),
-			})
+			if err := insertArbitrationLogTx(ctx, tx, arbitrationLog); err != nil {
+				return IngestResult{}, fmt.Errorf("记录仲裁日志失败: %w", err)
+			}
+		}
+		if err := a.discardPendingTx(ctx, tx, plan); err != nil {
+			return IngestResult{}, err
 		}
 		return IngestResult{ID: memoryID, Status: "skipped"}, nil
 	}
 
 	memory := MemoryInsert{
 		ID:           memoryID,
-		ProjectID:    project.ID,
+		ProjectID:    plan.project.ID,
 		ContentType:  input.ContentType,
 		Content:      input.Content,
-		ContentHash:  contentHash,
+		ContentHash:  plan.contentHash,
 		Ts:           input.Ts,
-		Summary:      summary,
-		Tags:         tags,
-		Axes:         axes,
-		IndexPath:    indexPath,
-		ChunkCount:   len(chunks),
+		Summary:      plan.summary,
+		Tags:         plan.tags,
+		Axes:         plan.axes,
+		IndexPath:    plan.indexPath,
+		ChunkCount:   len(plan.chunks),
 		Embedded:     true,
-		AvgEmbedding: avgVector,
+		AvgEmbedding: plan.avgVector,
 		CreatedAt:    time.Now().UTC(),
 	}
 
-	tx, err := a.store.pool.Begin(ctx)
-	if err != nil {
-		return IngestResult{}, fmt.Errorf("事务开启失败: %w", err)
+	change := a.newChangeEvent(changeKindIngest, input.OwnerID, plan.project)
+	change.MemoryIDs = []string{memoryID}
+	change.IndexPaths = [][]string{plan.indexPath}
+	if replacing {
+		change.Kind = changeKindReplace
+		// 被替换记忆的旧路径同样需要通知（best-effort）
+		if _, oldPath, err := a.store.FetchMemoryLocation(ctx, semanticTargetID); err == nil {
+			change.IndexPaths = append(change.IndexPaths, oldPath)
+		}
 	}
-	defer func() {
-		_ = tx.Rollback(ctx)
-	}()
 
-	if action == ArbitrateReplace && semanticTargetID != "" {
+	if replacing {
 		if err := insertMemoryVersionFromMemoryTx(ctx, tx, memoryID); err != nil {
 			return IngestResult{}, fmt.Errorf("保存旧版本失败: %w", err)
 		}
-		if err := insertArbitrationLogTx(ctx, tx, ArbitrationLogInsert{
-			OwnerID:           input.OwnerID,
-			ProjectID:         project.ID,
-			CandidateMemoryID: semanticTargetID,
-			NewMemoryID:       memoryID,
-			Action:            string(action),
-			Similarity:        semanticSimilarity,
-			OldSummary:        oldSummary,
-			NewSummary:        summary,
-			Model:             a.settings.LLM.ModelArbitrate,
-			CreatedAt:         time.Now().UTC(),
-		}); err != nil {
+		if err := insertArbitrationLogTx(ctx, tx, arbitrationLog); err != nil {
 			return IngestResult{}, fmt.Errorf("记录仲裁日志失败: %w", err)
 		}
 		// 替换模式：更新旧记忆，删除旧片段
@@ -229,25 +430,24 @@ func (a *App) IngestMemory(ctx context.Context, input IngestMemoryInput) (Ingest
 		if memoryID != semanticTargetID {
 			insertRelationTx(ctx, tx, memoryID, semanticTargetID, "DERIVED_FROM", 1.0)
 		}
+		if err := a.discardPendingTx(ctx, tx, plan); err != nil {
+			return IngestResult{}, err
+		}
 	} else {
 		if semanticTargetID != "" {
-			if err := insertArbitrationLogTx(ctx, tx, ArbitrationLogInsert{
-				OwnerID:           input.OwnerID,
-				ProjectID:         project.ID,
-				CandidateMemoryID: semanticTargetID,
-				NewMemoryID:       memoryID,
-				Action:            string(action),
-				Similarity:        semanticSimilarity,
-				OldSummary:        oldSummary,
-				NewSummary:        summary,
-				Model:             a.settings.LLM.ModelArbitrate,
-				CreatedAt:         time.Now().UTC(),
-			}); err != nil {
+			if err := insertArbitrationLogTx(ctx, tx, arbitrationLog); err != nil {
 				return IngestResult{}, fmt.Errorf("记录仲裁日志失败: %w", err)
 			}
 		}
-		// 新建模式
-		if err := insertMemoryTx(ctx, tx, memory); err != nil {
+		// 新建模式；异步写入就地补全临时记忆
+		if plan.pendingID != "" {
+			if err := updateMemoryTx(ctx, tx, memory); err != nil {
+				return IngestResult{}, fmt.Errorf("更新记忆失败: %w", err)
+			}
+			if err := deleteFragmentsTx(ctx, tx, memoryID); err != nil {
+				return IngestResult{}, fmt.Errorf("清理旧片段失败: %w", err)
+			}
+		} else if err := insertMemoryTx(ctx, tx, memory); err != nil {
 			return IngestResult{}, fmt.Errorf("写入记忆失败: %w", err)
 		}
 		// KEEP_BOTH 时自动创建 RELATED 关系（best-effort）
@@ -256,37 +456,57 @@ func (a *App) IngestMemory(ctx context.Context, input IngestMemoryInput) (Ingest
 		}
 	}
 
-	fragments := make([]FragmentInsert, 0, len(chunks))
-	for idx, chunk := range chunks {
+	fragments := make([]FragmentInsert, 0, len(plan.chunks))
+	for idx, chunk := range plan.chunks {
 		fragments = append(fragments, FragmentInsert{
 			ID:         newFragmentID(idx),
 			MemoryID:   memoryID,
 			ChunkIndex: idx,
 			Content:    chunk,
-			Embedding:  embeddings[idx],
+			Embedding:  plan.embeddings[idx],
 		})
 	}
 
-	if err := insertFragmentsTx(ctx, tx, fragments); err != nil {
+	if err := insertFragmentsTx(ctx, tx, a.store.tokenizer, fragments); err != nil {
 		return IngestResult{}, fmt.Errorf("写入片段失败: %w", err)
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return IngestResult{}, fmt.Errorf("事务提交失败: %w", err)
+	// 前瞻生成随事务入队，写入回滚时不会留下孤立任务
+	if len([]rune(strings.TrimSpace(input.Content))) > foresightMinContentLen {
+		job, err := newJob(jobKindForesight, foresightJobPayload{MemoryID: memoryID, OwnerID: input.OwnerID, ProjectID: plan.project.ID}, a.settings.Jobs.MaxAttempts)
+		if err != nil {
+			return IngestResult{}, err
+		}
+		if _, err := insertJobTx(ctx, tx, job); err != nil {
+			return IngestResult{}, fmt.Errorf("前瞻任务入队失败: %w", err)
+		}
 	}
 
-	// 异步生成前瞻记忆（不阻塞 ingest 返回）
-	go func() {
-		bgCtx := context.Background()
-		_ = a.GenerateForesights(bgCtx, memoryID, project.ID, input.Content, summary, input.ContentType)
-	}()
+	// 通知随事务提交送达
+	if err := publishChangeTx(ctx, tx, change); err != nil {
+		return IngestResult{}, fmt.Errorf("发送变更通知失败: %w", err)
+	}
 
-	if action == ArbitrateReplace {
+	if replacing {
 		return IngestResult{ID: memoryID, Status: "updated"}, nil
 	}
 	return IngestResult{ID: memoryID, Status: "created"}, nil
 }
 
+// afterIngest 事务提交后失效检索缓存，并唤醒后台任务 worker 生成前瞻记忆
+func (a *App) afterIngest(plan *ingestPlan, result IngestResult) {
+	input := plan.input
+	if plan.pendingID != "" {
+		// 临时记忆已被补全或删除
+		a.invalidateSearchCache(input.OwnerID, plan.project.ID)
+	}
+	if result.Status != "created" && result.Status != "updated" {
+		return
+	}
+	a.invalidateSearchCache(input.OwnerID, plan.project.ID)
+	a.jobs.Wake()
+}
+
 func insertMemoryTx(ctx context.Context, tx pgxTx, memory MemoryInsert) error {
 	tagsJSON, _ := json.Marshal(memory.Tags)
 	axesJSON, _ := json.Marshal(memory.Axes)
@@ -377,15 +597,20 @@ func deleteFragmentsTx(ctx context.Context, tx pgxTx, memoryID string) error {
 	return err
 }
 
-func insertFragmentsTx(ctx context.Context, tx pgxTx, fragments []FragmentInsert) error {
+func insertFragmentsTx(ctx context.Context, tx pgxTx, tokenizer Tokenizer, fragments []FragmentInsert) error {
 	if len(fragments) == 0 {
 		return nil
 	}
 	query := `
-INSERT INTO fragments (id, memory_id, chunk_index, content, embedding)
-VALUES ($1,$2,$3,$4,$5)`
+INSERT INTO fragments (id, memory_id, chunk_index, content, embedding, lexemes, lexeme_tokenizer)
+VALUES ($1,$2,$3,$4,$5,to_tsvector('simple', $6),$7)`
 	for _, frag := range fragments {
-		if _, err := tx.Exec(ctx, query, frag.ID, frag.MemoryID, frag.ChunkIndex, frag.Content, pgvector.NewVector(frag.Embedding)); err != nil {
+		// 异步写入的临时片段尚无向量，只参与全文召回
+		var embedding any
+		if len(frag.Embedding) > 0 {
+			embedding = pgvector.NewVector(frag.Embedding)
+		}
+		if _, err := tx.Exec(ctx, query, frag.ID, frag.MemoryID, frag.ChunkIndex, frag.Content, embedding, lexemeDocument(tokenizer, frag.Content), lexemeTokenizerName(tokenizer)); err != nil {
 			return err
 		}
 	}
@@ -418,8 +643,8 @@ func insertArbitrationLogTx(ctx context.Context, tx pgxTx, log ArbitrationLogIns
 	_, err := tx.Exec(ctx, `
 INSERT INTO memory_arbitrations (
   owner_id, project_id, candidate_memory_id, new_memory_id,
-  action, similarity, old_summary, new_summary, model, created_at
-) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`,
+  action, similarity, old_summary, new_summary, model, decided_by, created_at
+) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`,
 		log.OwnerID,
 		log.ProjectID,
 		nullableString(log.CandidateMemoryID),
@@ -429,6 +654,7 @@ INSERT INTO memory_arbitrations (
 		nullableString(log.OldSummary),
 		nullableString(log.NewSummary),
 		nullableString(log.Model),
+		arbitrationDecider(log.DecidedBy),
 		log.CreatedAt,
 	)
 	return err
@@ -510,7 +736,7 @@ func findSemanticUpdateCandidate(ctx context.Context, store *Store, vector pgvec
 		threshold = 1
 	}
 	// 直接在 memory 级别做向量搜索，更准确
-	rows, err := store.SearchMemoryVectors(ctx, vector, projectID, maxCandidates)
+	rows, err := store.SearchMemoryVectors(ctx, vector, MemoryVectorQuery{ProjectID: projectID}, maxCandidates)
 	if err != nil {
 		return "", 0, err
 	}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// 批量写入：各条目并发预处理（摘要/标签/索引/切分），全部片段合并为一次 EmbedBatch，
+// 再按请求顺序逐条仲裁与写入，查重与冲突仲裁和单条写入相同
+const (
+	ingestBatchModeBestEffort   = "best_effort"
+	ingestBatchModeAllOrNothing = "all_or_nothing"
+
+	maxIngestBatchItems    = 100
+	ingestBatchConcurrency = 4
+
+	// ingestBatchProgressSteps 批量写入进度阶段：预处理、向量化、仲裁与写入
+	ingestBatchProgressSteps = 3
+)
+
+func (a *App) IngestBatch(ctx context.Context, input IngestBatchInput) (IngestBatchOutput, error) {
+	mode := ingestBatchModeBestEffort
+	if input.Mode != nil && strings.TrimSpace(*input.Mode) != "" {
+		mode = strings.ToLower(strings.TrimSpace(*input.Mode))
+	}
+	if mode != ingestBatchModeBestEffort && mode != ingestBatchModeAllOrNothing {
+		return IngestBatchOutput{}, newValidationError("invalid_request", "ERR_INVALID_BATCH_MODE", "mode 仅支持 best_effort / all_or_nothing", http.StatusBadRequest)
+	}
+	if len(input.Items) == 0 {
+		return IngestBatchOutput{}, newValidationError("invalid_request", "ERR_INVALID_BATCH", "items 不能为空", http.StatusBadRequest)
+	}
+	if len(input.Items) > maxIngestBatchItems {
+		return IngestBatchOutput{}, newValidationError("invalid_request", "ERR_INVALID_BATCH", fmt.Sprintf("items 最多 %d 条", maxIngestBatchItems), http.StatusBadRequest)
+	}
+
+	batch := &ingestBatch{
+		app:     a,
+		mode:    mode,
+		plans:   make([]*ingestPlan, len(input.Items)),
+		hashes:  make([]string, len(input.Items)),
+		results: make([]IngestBatchItemResult, len(input.Items)),
+	}
+	for idx := range batch.results {
+		batch.results[idx] = IngestBatchItemResult{Index: idx}
+	}
+
+	if err := batch.prepare(progressScope(ctx, 0, 1, ingestBatchProgressSteps), input.Items); err != nil {
+		return IngestBatchOutput{}, err
+	}
+	if mode == ingestBatchModeAllOrNothing && batch.failed() {
+		return batch.output(false), nil
+	}
+	if err := batch.embed(progressScope(ctx, 1, 2, ingestBatchProgressSteps)); err != nil {
+		return IngestBatchOutput{}, err
+	}
+	if mode == ingestBatchModeAllOrNothing && batch.failed() {
+		return batch.output(false), nil
+	}
+	writeCtx := progressScope(ctx, 2, 3, ingestBatchProgressSteps)
+	if mode == ingestBatchModeAllOrNothing {
+		return batch.writeAll(writeCtx)
+	}
+	return batch.writeEach(writeCtx)
+}
+
+type ingestBatch struct {
+	app   *App
+	mode  string
+	plans []*ingestPlan
+	// hashes 预处理时的内容哈希（MERGE 会改写 plan 内容），用于批次内查重
+	hashes  []string
+	results []IngestBatchItemResult
+}
+
+// pending 已完成预处理、尚未得出结果的条目
+func (b *ingestBatch) pending(idx int) bool {
+	return b.plans[idx] != nil && b.results[idx].Status == ""
+}
+
+func (b *ingestBatch) fail(idx int, err error) {
+	b.results[idx].ID = ""
+	b.results[idx].Status = "error"
+	b.results[idx].Error = errorResponseFor(err, "ingest_batch")
+}
+
+func (b *ingestBatch) failed() bool {
+	for _, result := range b.results {
+		if result.Status == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *ingestBatch) succeed(idx int, result IngestResult) {
+	b.results[idx].ID = result.ID
+	b.results[idx].Status = result.Status
+	b.results[idx].Ts = b.plans[idx].input.Ts
+}
+
+func (b *ingestBatch) prepare(ctx context.Context, items []IngestMemoryInput) error {
+	var group errgroup.Group
+	group.SetLimit(ingestBatchConcurrency)
+	var finished atomic.Int64
+	itemCtx := withoutProgress(ctx)
+	for idx, item := range items {
+		group.Go(func() error {
+			defer func() {
+				done := finished.Add(1)
+				reportProgress(ctx, float64(done), float64(len(items)), fmt.Sprintf("预处理 %d/%d", done, len(items)))
+			}()
+			// all_or_nothing 的项目在写入事务内创建，预处理不落库
+			plan, err := b.app.prepareIngestPlan(itemCtx, item, "", b.mode != ingestBatchModeAllOrNothing)
+			if err != nil {
+				b.fail(idx, err)
+				return nil
+			}
+			b.plans[idx] = plan
+			b.hashes[idx] = plan.contentHash
+			return nil
+		})
+	}
+	_ = group.Wait()
+	return ctx.Err()
+}
+
+// embed 已有相同内容的条目不再向量化
+func (b *ingestBatch) embed(ctx context.Context) error {
+	var plans []*ingestPlan
+	for idx, plan := range b.plans {
+		if b.pending(idx) && plan.duplicateID == "" {
+			plans = append(plans, plan)
+		}
+	}
+	if len(plans) == 0 {
+		return nil
+	}
+	if err := b.app.embedIngest(ctx, plans); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for idx, plan := range b.plans {
+			if b.pending(idx) && plan.duplicateID == "" {
+				b.fail(idx, err)
+			}
+		}
+		return nil
+	}
+	reportProgress(ctx, 1, 1, "向量化完成")
+	return nil
+}
+
+// batchDuplicateOf 批次内更早的条目已写入相同内容（同一项目）时返回其序号
+func (b *ingestBatch) batchDuplicateOf(idx int) (int, bool) {
+	return b.duplicateOf(idx, func(earlier int) bool {
+		switch b.results[earlier].Status {
+		case "created", "updated", "duplicate":
+			return true
+		}
+		return false
+	})
+}
+
+// duplicateOf 按项目（owner + project_key，all_or_nothing 预处理时新项目尚无 ID）与内容哈希查找 written 的更早条目
+func (b *ingestBatch) duplicateOf(idx int, written func(int) bool) (int, bool) {
+	plan := b.plans[idx]
+	for earlier := range idx {
+		other := b.plans[earlier]
+		if other == nil || !written(earlier) {
+			continue
+		}
+		if other.input.OwnerID == plan.input.OwnerID && other.input.ProjectKey == plan.input.ProjectKey && b.hashes[earlier] == b.hashes[idx] {
+			return earlier, true
+		}
+	}
+	return 0, false
+}
+
+// writeEach best_effort：逐条在各自事务中写入，后面的条目可与前面已提交的条目查重、仲裁
+func (b *ingestBatch) writeEach(ctx context.Context) (IngestBatchOutput, error) {
+	for idx := range b.plans {
+		if !b.pending(idx) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return IngestBatchOutput{}, err
+		}
+		plan := b.plans[idx]
+		if earlier, ok := b.batchDuplicateOf(idx); ok {
+			b.succeed(idx, IngestResult{ID: b.results[earlier].ID, Status: "duplicate"})
+			continue
+		}
+		if plan.duplicateID != "" {
+			result, err := b.app.markDuplicate(ctx, plan)
+			if err != nil {
+				b.fail(idx, err)
+				continue
+			}
+			b.succeed(idx, result)
+			continue
+		}
+		result, err := b.writeOne(ctx, plan)
+		if err != nil {
+			if ctx.Err() != nil {
+				return IngestBatchOutput{}, ctx.Err()
+			}
+			b.fail(idx, err)
+			continue
+		}
+		b.succeed(idx, result)
+		reportProgress(ctx, float64(idx+1), float64(len(b.plans)), fmt.Sprintf("写入 %d/%d", idx+1, len(b.plans)))
+	}
+	return b.output(true), nil
+}
+
+func (b *ingestBatch) writeOne(ctx context.Context, plan *ingestPlan) (IngestResult, error) {
+	decision, err := b.app.arbitrateIngest(ctx, plan)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	tx, err := b.app.store.pool.Begin(ctx)
+	if err != nil {
+		return IngestResult{}, fmt.Errorf("事务开启失败: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	result, err := b.app.writeIngestTx(ctx, tx, plan, decision)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return IngestResult{}, fmt.Errorf("事务提交失败: %w", err)
+	}
+	b.app.afterIngest(plan, result)
+	return result, nil
+}
+
+// writeAll all_or_nothing：先逐条仲裁（LLM 调用与人工确认都在事务外），再在一个短事务内创建项目并写入全部条目，
+// 任一条失败则回滚整批。事务提交前前面的条目对仲裁不可见：同批多条命中同一条旧记忆时只有第一条替换，其余按 KEEP_BOTH 处理
+func (b *ingestBatch) writeAll(ctx context.Context) (IngestBatchOutput, error) {
+	decisions := make([]ingestDecision, len(b.plans))
+	// batchDuplicates 批次内重复条目 -> 更早的相同条目；写入后才有 ID
+	batchDuplicates := map[int]int{}
+	planned := make([]bool, len(b.plans))
+	claimed := map[string]bool{}
+	for idx, plan := range b.plans {
+		if !b.pending(idx) {
+			continue
+		}
+		if earlier, ok := b.duplicateOf(idx, func(earlier int) bool { return planned[earlier] }); ok {
+			batchDuplicates[idx] = earlier
+			planned[idx] = true
+			continue
+		}
+		if plan.duplicateID != "" {
+			planned[idx] = true
+			continue
+		}
+		decision, err := b.app.arbitrateIngest(ctx, plan)
+		if err != nil {
+			if ctx.Err() != nil {
+				return IngestBatchOutput{}, ctx.Err()
+			}
+			b.fail(idx, err)
+			return b.output(false), nil
+		}
+		if decision.action == ArbitrateReplace || decision.action == ArbitrateMerge {
+			if claimed[decision.targetID] {
+				decision.action = ArbitrateKeepBoth
+			}
+			claimed[decision.targetID] = true
+		}
+		decisions[idx] = decision
+		planned[idx] = decision.action != ArbitrateSkip
+		reportProgress(ctx, float64(idx+1), float64(len(b.plans)*2), fmt.Sprintf("仲裁 %d/%d", idx+1, len(b.plans)))
+	}
+
+	tx, err := b.app.store.pool.Begin(ctx)
+	if err != nil {
+		return IngestBatchOutput{}, fmt.Errorf("事务开启失败: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	projects := map[[2]string]ProjectRecord{}
+	var duplicates []int
+	for idx, plan := range b.plans {
+		if !b.pending(idx) {
+			continue
+		}
+		input := plan.input
+		key := [2]string{input.OwnerID, input.ProjectKey}
+		project, ok := projects[key]
+		if !ok {
+			project, err = upsertProjectTx(ctx, tx, input.OwnerID, input.ProjectKey, input.ProjectName, input.MachineName, input.ProjectPath)
+			if err != nil {
+				if ctx.Err() != nil {
+					return IngestBatchOutput{}, ctx.Err()
+				}
+				b.fail(idx, fmt.Errorf("项目写入失败: %w", err))
+				return b.output(false), nil
+			}
+			projects[key] = project
+		}
+		plan.project = project
+		if earlier, ok := batchDuplicates[idx]; ok {
+			b.succeed(idx, IngestResult{ID: b.results[earlier].ID, Status: "duplicate"})
+			continue
+		}
+		if plan.duplicateID != "" {
+			// 刷新时间不可回滚，提交后再执行
+			b.succeed(idx, IngestResult{ID: plan.duplicateID, Status: "duplicate"})
+			duplicates = append(duplicates, idx)
+			continue
+		}
+		result, err := b.app.writeIngestTx(ctx, tx, plan, decisions[idx])
+		if err != nil {
+			if ctx.Err() != nil {
+				return IngestBatchOutput{}, ctx.Err()
+			}
+			b.fail(idx, err)
+			return b.output(false), nil
+		}
+		b.succeed(idx, result)
+		reportProgress(ctx, float64(len(b.plans)+idx+1), float64(len(b.plans)*2), fmt.Sprintf("写入 %d/%d", idx+1, len(b.plans)))
+	}
+	if err := tx.Commit(ctx); err != nil {
+		if ctx.Err() != nil {
+			return IngestBatchOutput{}, ctx.Err()
+		}
+		for idx := range b.results {
+			b.fail(idx, fmt.Errorf("事务提交失败: %w", err))
+		}
+		return b.output(false), nil
+	}
+
+	for idx, plan := range b.plans {
+		if plan != nil {
+			b.app.afterIngest(plan, IngestResult{ID: b.results[idx].ID, Status: b.results[idx].Status})
+		}
+	}
+	for _, idx := range duplicates {
+		// 整批已提交，刷新时间失败不影响结果
+		if _, err := b.app.markDuplicate(ctx, b.plans[idx]); err != nil {
+			log.Printf("[WARN] 批量写入刷新重复内容时间失败: id=%s err=%v", b.plans[idx].duplicateID, err)
+		}
+	}
+	return b.output(true), nil
+}
+
+// output 未提交时，未出错的条目标记为 ERR_BATCH_ABORTED
+func (b *ingestBatch) output(committed bool) IngestBatchOutput {
+	out := IngestBatchOutput{Mode: b.mode, Committed: committed, Results: b.results, Counts: map[string]int{}}
+	for idx, result := range out.Results {
+		if !committed && result.Status != "error" {
+			b.fail(idx, newValidationError("batch_aborted", "ERR_BATCH_ABORTED", "批次中有条目失败，整批未写入", http.StatusConflict))
+			result = b.results[idx]
+		}
+		out.Counts[result.Status]++
+	}
+	return out
+}
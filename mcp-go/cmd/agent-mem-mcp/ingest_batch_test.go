@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIngestBatchValidation(t *testing.T) {
+	app := &App{settings: defaultSettings(), changes: NewChangeFeed(nil)}
+	ctx := context.Background()
+	mode := "partial"
+	cases := []struct {
+		input IngestBatchInput
+		code  string
+	}{
+		{IngestBatchInput{}, "ERR_INVALID_BATCH"},
+		{IngestBatchInput{Items: make([]IngestMemoryInput, maxIngestBatchItems+1)}, "ERR_INVALID_BATCH"},
+		{IngestBatchInput{Items: []IngestMemoryInput{{}}, Mode: &mode}, "ERR_INVALID_BATCH_MODE"},
+	}
+	for _, tc := range cases {
+		_, err := app.IngestBatch(ctx, tc.input)
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.Code != tc.code {
+			t.Fatalf("应返回 %s: %v", tc.code, err)
+		}
+	}
+
+	// 校验失败的条目不访问数据库，逐条返回错误
+	out, err := app.IngestBatch(ctx, IngestBatchInput{Items: []IngestMemoryInput{
+		{OwnerID: "personal", ProjectKey: "demo", ContentType: "plan"},
+		{OwnerID: "personal", ContentType: "plan", Content: "内容"},
+	}})
+	if err != nil {
+		t.Fatalf("批量写入失败: %v", err)
+	}
+	if !out.Committed || out.Mode != ingestBatchModeBestEffort || out.Counts["error"] != 2 {
+		t.Fatalf("结果错误: %+v", out)
+	}
+	if out.Results[0].Error.Code != "ERR_INVALID_CONTENT" || out.Results[1].Error.Code != "ERR_INVALID_PROJECT" {
+		t.Fatalf("逐条错误码错误: %+v %+v", out.Results[0].Error, out.Results[1].Error)
+	}
+}
+
+func TestIngestBatchAbortAndDuplicates(t *testing.T) {
+	// all_or_nothing 预处理时新项目尚无 ID，按 owner + project_key 区分项目
+	demo := IngestMemoryInput{OwnerID: "personal", ProjectKey: "demo"}
+	other := IngestMemoryInput{OwnerID: "personal", ProjectKey: "other"}
+	batch := &ingestBatch{
+		mode:    ingestBatchModeAllOrNothing,
+		plans:   []*ingestPlan{{input: demo}, {input: demo}, {input: other}, nil},
+		hashes:  []string{"h1", "h1", "h1", ""},
+		results: []IngestBatchItemResult{{Index: 0}, {Index: 1}, {Index: 2}, {Index: 3}},
+	}
+	batch.succeed(0, IngestResult{ID: "mem_1", Status: "created"})
+	if earlier, ok := batch.batchDuplicateOf(1); !ok || earlier != 0 {
+		t.Fatalf("同项目相同内容应视为批次内重复")
+	}
+	if _, ok := batch.batchDuplicateOf(2); ok {
+		t.Fatalf("不同项目不应视为重复")
+	}
+
+	batch.fail(3, newValidationError("invalid_request", "ERR_INVALID_CONTENT", "content 不能为空", 400))
+	out := batch.output(false)
+	if out.Committed || out.Counts["error"] != 4 {
+		t.Fatalf("整批回滚时所有条目应为 error: %+v", out)
+	}
+	if out.Results[0].ID != "" || out.Results[0].Error.Code != "ERR_BATCH_ABORTED" || out.Results[3].Error.Code != "ERR_INVALID_CONTENT" {
+		t.Fatalf("回滚错误码错误: %+v", out.Results)
+	}
+}
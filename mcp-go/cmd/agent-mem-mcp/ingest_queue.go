@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// 异步写入：入队时只做校验、查重与切分，原文与片段（无向量）作为临时记忆和任务在同一事务内落库，
+// 处理完成前临时记忆可被全文检索召回；worker 完成摘要、向量化与仲裁后就地补全临时记忆，
+// 仲裁为替换/跳过或任务最终失败时删除临时记忆
+const maxIngestStatusJobs = 50
+
+func (a *App) enqueueIngest(ctx context.Context, input IngestMemoryInput) (IngestMemoryOutput, error) {
+	if !a.settings.IngestQueue.Enabled {
+		return IngestMemoryOutput{}, newValidationError("invalid_request", "ERR_ASYNC_DISABLED", "异步写入未启用（ingest_queue.enabled）", http.StatusBadRequest)
+	}
+	if err := validateIngestInput(input); err != nil {
+		return IngestMemoryOutput{}, err
+	}
+	input.Async = nil
+
+	project, err := a.store.UpsertProject(ctx, input.OwnerID, input.ProjectKey, input.ProjectName, input.MachineName, input.ProjectPath)
+	if err != nil {
+		return IngestMemoryOutput{}, fmt.Errorf("项目写入失败: %w", err)
+	}
+	plan := &ingestPlan{input: input, project: project, contentHash: hashContent(input.Content)}
+	duplicateID, err := a.store.FindDuplicateMemory(ctx, project.ID, plan.contentHash, 0, "")
+	if err != nil {
+		return IngestMemoryOutput{}, fmt.Errorf("重复内容检查失败: %w", err)
+	}
+	if duplicateID != "" {
+		plan.duplicateID = duplicateID
+		result, err := a.markDuplicate(ctx, plan)
+		if err != nil {
+			return IngestMemoryOutput{}, err
+		}
+		return IngestMemoryOutput{ID: result.ID, Status: result.Status, Ts: input.Ts}, nil
+	}
+
+	chunks := chunkContent(input.Content, a.settings.Chunking)
+	if len(chunks) == 0 {
+		return IngestMemoryOutput{}, errors.New("内容切分失败")
+	}
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return IngestMemoryOutput{}, fmt.Errorf("任务序列化失败: %w", err)
+	}
+
+	summary := strings.TrimSpace(input.Summary)
+	if summary == "" {
+		summary = fallbackSummary(input.Content)
+	}
+	var tags []string
+	if input.Tags != nil {
+		tags = normalizeTags(*input.Tags)
+	}
+	if len(tags) == 0 {
+		tags = fallbackTags(input.Content)
+	}
+	memory := MemoryInsert{
+		ID:          newMemoryID(),
+		ProjectID:   project.ID,
+		ContentType: input.ContentType,
+		Content:     input.Content,
+		ContentHash: plan.contentHash,
+		Ts:          input.Ts,
+		Summary:     summary,
+		Tags:        tags,
+		ChunkCount:  len(chunks),
+		Embedded:    false,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if input.Axes != nil {
+		memory.Axes = *input.Axes
+	}
+	if input.IndexPath != nil {
+		memory.IndexPath = *input.IndexPath
+	}
+	fragments := make([]FragmentInsert, 0, len(chunks))
+	for idx, chunk := range chunks {
+		fragments = append(fragments, FragmentInsert{
+			ID:         newFragmentID(idx),
+			MemoryID:   memory.ID,
+			ChunkIndex: idx,
+			Content:    chunk,
+		})
+	}
+	jobID := newIngestJobID()
+	change := a.newChangeEvent(changeKindIngest, input.OwnerID, project)
+	change.MemoryIDs = []string{memory.ID}
+	change.IndexPaths = [][]string{memory.IndexPath}
+
+	tx, err := a.store.pool.Begin(ctx)
+	if err != nil {
+		return IngestMemoryOutput{}, fmt.Errorf("事务开启失败: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if err := insertMemoryTx(ctx, tx, memory); err != nil {
+		return IngestMemoryOutput{}, fmt.Errorf("写入记忆失败: %w", err)
+	}
+	if err := insertFragmentsTx(ctx, tx, a.store.tokenizer, fragments); err != nil {
+		return IngestMemoryOutput{}, fmt.Errorf("写入片段失败: %w", err)
+	}
+	if err := insertIngestJobTx(ctx, tx, jobID, input.OwnerID, project.ID, memory.ID, payload, a.settings.IngestQueue.MaxAttempts); err != nil {
+		return IngestMemoryOutput{}, fmt.Errorf("写入任务失败: %w", err)
+	}
+	if err := publishChangeTx(ctx, tx, change); err != nil {
+		return IngestMemoryOutput{}, fmt.Errorf("发送变更通知失败: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return IngestMemoryOutput{}, fmt.Errorf("事务提交失败: %w", err)
+	}
+	a.invalidateSearchCache(input.OwnerID, project.ID)
+	a.ingestQueue.Wake()
+	return IngestMemoryOutput{ID: memory.ID, Status: jobQueued, Ts: input.Ts, JobID: jobID}, nil
+}
+
+// processIngestJob 与同步写入相同的预处理、向量化与仲裁；任务完成标记与写入在同一事务内提交
+func (a *App) processIngestJob(ctx context.Context, workerID string, job IngestJobRecord) (IngestResult, error) {
+	plan, err := a.prepareIngest(ctx, job.Payload, job.MemoryID)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	var decision ingestDecision
+	if plan.duplicateID == "" {
+		if err := a.embedIngest(ctx, []*ingestPlan{plan}); err != nil {
+			return IngestResult{}, err
+		}
+		decision, err = a.arbitrateIngest(ctx, plan)
+		if err != nil {
+			return IngestResult{}, err
+		}
+	}
+
+	tx, err := a.store.pool.Begin(ctx)
+	if err != nil {
+		return IngestResult{}, fmt.Errorf("事务开启失败: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	var result IngestResult
+	if plan.duplicateID != "" {
+		result, err = a.writeQueuedDuplicateTx(ctx, tx, plan)
+	} else {
+		result, err = a.writeIngestTx(ctx, tx, plan, decision)
+	}
+	if err != nil {
+		return IngestResult{}, err
+	}
+	if err := completeIngestJobTx(ctx, tx, job.ID, workerID, result); err != nil {
+		return IngestResult{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return IngestResult{}, fmt.Errorf("事务提交失败: %w", err)
+	}
+	a.afterIngest(plan, result)
+	return result, nil
+}
+
+// writeQueuedDuplicateTx 入队后才出现相同内容（如另一任务先完成）：删除临时记忆，刷新已有记忆时间
+func (a *App) writeQueuedDuplicateTx(ctx context.Context, tx pgxTx, plan *ingestPlan) (IngestResult, error) {
+	if _, err := tx.Exec(ctx, `UPDATE memories SET ts = $2, updated_at = NOW() WHERE id = $1`, plan.duplicateID, plan.input.Ts); err != nil {
+		return IngestResult{}, fmt.Errorf("更新重复内容时间失败: %w", err)
+	}
+	if err := a.discardPendingTx(ctx, tx, plan); err != nil {
+		return IngestResult{}, err
+	}
+	change := a.newChangeEvent(changeKindIngest, plan.input.OwnerID, plan.project)
+	change.MemoryIDs = []string{plan.duplicateID}
+	if err := publishChangeTx(ctx, tx, change); err != nil {
+		return IngestResult{}, fmt.Errorf("发送变更通知失败: %w", err)
+	}
+	return IngestResult{ID: plan.duplicateID, Status: "duplicate"}, nil
+}
+
+// discardPendingTx 异步任务最终替换了已有记忆或被跳过时删除临时记忆（片段级联删除）
+func (a *App) discardPendingTx(ctx context.Context, tx pgxTx, plan *ingestPlan) error {
+	if plan.pendingID == "" {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM memories WHERE id = $1`, plan.pendingID); err != nil {
+		return fmt.Errorf("清理临时记忆失败: %w", err)
+	}
+	change := a.newChangeEvent(changeKindDelete, plan.input.OwnerID, plan.project)
+	change.MemoryIDs = []string{plan.pendingID}
+	if plan.input.IndexPath != nil {
+		change.IndexPaths = [][]string{*plan.input.IndexPath}
+	}
+	if err := publishChangeTx(ctx, tx, change); err != nil {
+		return fmt.Errorf("发送变更通知失败: %w", err)
+	}
+	return nil
+}
+
+func insertIngestJobTx(ctx context.Context, tx pgxTx, jobID, ownerID, projectID, memoryID string, payload []byte, maxAttempts int) error {
+	_, err := tx.Exec(ctx, `
+INSERT INTO ingest_jobs (id, owner_id, project_id, memory_id, payload, status, max_attempts)
+VALUES ($1,$2,$3,$4,$5::jsonb,$6,$7)`,
+		jobID, ownerID, projectID, memoryID, string(payload), jobQueued, maxAttempts)
+	return err
+}
+
+// completeIngestJobTx 任务已超时被其他 worker 重新领取时返回错误，回滚本次写入
+func completeIngestJobTx(ctx context.Context, tx pgxTx, jobID, workerID string, result IngestResult) error {
+	tag, err := tx.Exec(ctx, `
+UPDATE ingest_jobs
+SET status = $3, result_memory_id = $4, result_status = $5, last_error = NULL,
+    locked_by = NULL, locked_at = NULL, updated_at = NOW()
+WHERE id = $1 AND locked_by = $2 AND status = $6`,
+		jobID, workerID, jobDone, result.ID, result.Status, jobRunning)
+	if err != nil {
+		return fmt.Errorf("更新任务状态失败: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("任务已被其他 worker 重新领取")
+	}
+	return nil
+}
+
+// failIngestJob 任务不再重试：与失败标记在同一事务内删除临时记忆（未向量化、摘要为兜底结果，保留会被查重当作已有内容；
+// 原文仍保存在任务 payload 中）。任务已被其他 worker 重新领取时不做修改
+func (a *App) failIngestJob(ctx context.Context, workerID string, job IngestJobRecord, lastError string) error {
+	tx, err := a.store.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("事务开启失败: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	var memoryID *string
+	err = tx.QueryRow(ctx, `
+UPDATE ingest_jobs
+SET status = $4, last_error = $3, locked_by = NULL, locked_at = NULL, updated_at = NOW()
+WHERE id = $1 AND locked_by = $2 AND status = $5
+RETURNING memory_id`, job.ID, workerID, lastError, jobFailed, jobRunning).Scan(&memoryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("更新任务状态失败: %w", err)
+	}
+	if memoryID != nil && *memoryID != "" {
+		input := job.Payload
+		input.OwnerID = job.OwnerID
+		plan := &ingestPlan{input: input, project: ProjectRecord{ID: job.ProjectID, ProjectKey: input.ProjectKey, OwnerID: job.OwnerID}, pendingID: *memoryID}
+		if err := a.discardPendingTx(ctx, tx, plan); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("事务提交失败: %w", err)
+	}
+	a.invalidateSearchCache(job.OwnerID, job.ProjectID)
+	return nil
+}
+
+func (a *App) IngestStatus(ctx context.Context, input IngestStatusInput) (IngestStatusResponse, error) {
+	ownerID, err := resolveOwnerID(input.OwnerID, a.settings)
+	if err != nil {
+		return IngestStatusResponse{}, err
+	}
+	var jobIDs []string
+	for _, id := range input.JobIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			jobIDs = append(jobIDs, id)
+		}
+	}
+	jobIDs = uniqueStrings(jobIDs)
+	if len(jobIDs) == 0 {
+		return IngestStatusResponse{}, newValidationError("invalid_request", "ERR_INVALID_JOB_IDS", "job_ids 不能为空", http.StatusBadRequest)
+	}
+	if len(jobIDs) > maxIngestStatusJobs {
+		return IngestStatusResponse{}, newValidationError("invalid_request", "ERR_INVALID_JOB_IDS", fmt.Sprintf("job_ids 最多 %d 个", maxIngestStatusJobs), http.StatusBadRequest)
+	}
+
+	jobs, err := a.store.FetchIngestJobs(ctx, ownerID, jobIDs)
+	if err != nil {
+		return IngestStatusResponse{}, err
+	}
+	byID := make(map[string]IngestJobStatus, len(jobs))
+	for _, job := range jobs {
+		byID[job.JobID] = job
+	}
+	results := make([]IngestJobStatus, 0, len(jobIDs))
+	for _, id := range jobIDs {
+		job, ok := byID[id]
+		if !ok {
+			job = IngestJobStatus{JobID: id, Status: "not_found"}
+		}
+		results = append(results, job)
+	}
+	return IngestStatusResponse{Results: results}, nil
+}
+
+// IngestQueue 异步写入 worker 池：任务由 Postgres 行锁分配，多实例可同时运行
+type IngestQueue struct {
+	app      *App
+	settings IngestQueueConfig
+	workers  *workerPool
+}
+
+func NewIngestQueue(app *App, settings IngestQueueConfig) *IngestQueue {
+	return &IngestQueue{
+		app:      app,
+		settings: settings,
+		workers:  newWorkerPool("异步写入", settings.Workers, time.Duration(settings.PollIntervalMs)*time.Millisecond, app.store.ReleaseIngestJobs),
+	}
+}
+
+// Wake 本实例有任务入队时唤醒空闲 worker，其他实例入队的任务靠轮询发现
+func (q *IngestQueue) Wake() {
+	if q == nil {
+		return
+	}
+	q.workers.Wake()
+}
+
+// Start 启动 worker；ctx 取消后不再领取新任务，执行中的任务继续完成（由 Drain 等待）
+func (q *IngestQueue) Start(ctx context.Context) {
+	if q == nil || !q.settings.Enabled {
+		return
+	}
+	q.workers.Start(ctx, q.app.instanceID(), q.step)
+}
+
+// Drain 等待执行中的任务完成，最多 drain_timeout_seconds；超时后本实例仍持有的任务放回队列
+func (q *IngestQueue) Drain(ctx context.Context) {
+	if q == nil {
+		return
+	}
+	q.workers.Drain(ctx, time.Duration(q.settings.DrainTimeoutSeconds)*time.Second)
+}
+
+func (q *IngestQueue) step(ctx context.Context, workerID string) bool {
+	job, ok, err := q.app.store.ClaimIngestJob(ctx, workerID, q.staleAfter())
+	if ok {
+		q.run(ctx, workerID, job, err)
+		return true
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Printf("[WARN] 领取异步写入任务失败: %v", err)
+	}
+	return false
+}
+
+// run claimErr 为任务内容解析失败，直接标记失败
+func (q *IngestQueue) run(ctx context.Context, workerID string, job IngestJobRecord, claimErr error) {
+	// 退出信号不打断执行中的任务；超时与重新领取的判定一致
+	base := context.WithoutCancel(ctx)
+	runCtx, cancel := context.WithTimeout(base, q.staleAfter())
+	defer cancel()
+
+	err := claimErr
+	if err == nil {
+		_, err = q.app.processIngestJob(runCtx, workerID, job)
+	}
+	if err == nil {
+		return
+	}
+	var appErr *AppError
+	if errors.As(err, &appErr) || claimErr != nil || job.Attempts >= job.MaxAttempts {
+		log.Printf("[WARN] 异步写入任务失败: job=%s attempts=%d err=%v", job.ID, job.Attempts, err)
+		if err := q.app.failIngestJob(base, workerID, job, err.Error()); err != nil {
+			log.Printf("[WARN] 标记任务失败出错: job=%s err=%v", job.ID, err)
+		}
+		return
+	}
+	delay := retryBackoff(q.settings.BackoffSeconds, q.settings.MaxBackoffSeconds, job.Attempts)
+	log.Printf("[WARN] 异步写入任务 %s 后重试: job=%s attempts=%d/%d err=%v", delay, job.ID, job.Attempts, job.MaxAttempts, err)
+	if err := q.app.store.RetryIngestJob(base, job.ID, workerID, err.Error(), delay); err != nil {
+		log.Printf("[WARN] 更新任务状态失败: job=%s err=%v", job.ID, err)
+	}
+}
+
+func (q *IngestQueue) staleAfter() time.Duration {
+	return time.Duration(q.settings.StaleAfterSeconds) * time.Second
+}
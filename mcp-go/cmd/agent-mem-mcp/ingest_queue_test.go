@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0: 5 * time.Second,
+		1: 5 * time.Second,
+		2: 10 * time.Second,
+		3: 20 * time.Second,
+		4: 40 * time.Second,
+		5: 60 * time.Second,
+		9: 60 * time.Second,
+	}
+	for attempts, want := range cases {
+		if got := retryBackoff(5, 60, attempts); got != want {
+			t.Fatalf("第 %d 次失败后的退避错误: got=%s want=%s", attempts, got, want)
+		}
+	}
+}
+
+func TestIngestAsyncValidation(t *testing.T) {
+	settings := defaultSettings()
+	settings.IngestQueue.Enabled = false
+	app := &App{settings: settings}
+	async := true
+	input := IngestMemoryInput{OwnerID: "personal", ProjectKey: "demo", ContentType: "plan", Content: "内容", Async: &async}
+
+	_, err := app.IngestMemoryTool(context.Background(), input)
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Code != "ERR_ASYNC_DISABLED" {
+		t.Fatalf("队列未启用时应拒绝异步写入: %v", err)
+	}
+
+	// 入队前完成校验，不访问数据库
+	app.settings.IngestQueue.Enabled = true
+	input.Content = ""
+	_, err = app.IngestMemoryTool(context.Background(), input)
+	if !errors.As(err, &appErr) || appErr.Code != "ERR_INVALID_CONTENT" {
+		t.Fatalf("异步写入应先校验内容: %v", err)
+	}
+}
+
+func TestIngestStatusValidation(t *testing.T) {
+	app := &App{settings: defaultSettings()}
+	var appErr *AppError
+	_, err := app.IngestStatus(context.Background(), IngestStatusInput{JobIDs: []string{" ", ""}})
+	if !errors.As(err, &appErr) || appErr.Code != "ERR_INVALID_JOB_IDS" {
+		t.Fatalf("空 job_ids 应拒绝: %v", err)
+	}
+	ids := make([]string, maxIngestStatusJobs+1)
+	for idx := range ids {
+		ids[idx] = fmt.Sprintf("job_%d", idx)
+	}
+	_, err = app.IngestStatus(context.Background(), IngestStatusInput{JobIDs: ids})
+	if !errors.As(err, &appErr) || appErr.Code != "ERR_INVALID_JOB_IDS" {
+		t.Fatalf("job_ids 超过上限应拒绝: %v", err)
+	}
+	_, err = app.IngestStatus(context.Background(), IngestStatusInput{OwnerID: "other", JobIDs: []string{"job_1"}})
+	if !errors.As(err, &appErr) || appErr.Code != "ERR_OWNER_MISMATCH" {
+		t.Fatalf("owner 不一致应拒绝: %v", err)
+	}
+}
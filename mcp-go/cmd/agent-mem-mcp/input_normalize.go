@@ -11,6 +11,7 @@ const (
 	defaultTimelineLimit     = 20
 	defaultListProjectsLimit = 50
 	defaultIndexLimit        = 20
+	defaultSimilarLimit      = 10
 	maxClockSkew             = 10 * time.Minute
 )
 
@@ -78,12 +79,82 @@ func normalizeSearchInput(input SearchInput, settings Settings) (SearchInput, er
 	input.Mode = normalizeSearchModePtr(input.Mode)
 	input.Axes = normalizeAxesInput(input.Axes)
 	input.IndexPath = normalizeIndexPathPtr(input.IndexPath)
+	input.ContentTypes = normalizeStringListPtr(input.ContentTypes)
+	input.ProjectKeys = normalizeStringListPtr(input.ProjectKeys)
+	input.Tags = normalizeStringListPtr(input.Tags)
+	input.ExcludeTags = normalizeStringListPtr(input.ExcludeTags)
+	if input.Since > 0 {
+		input.Since = normalizeTimestampSeconds(input.Since)
+	}
+	if input.Until > 0 {
+		input.Until = normalizeTimestampSeconds(input.Until)
+	}
 	if input.Limit <= 0 {
 		input.Limit = defaultSearchLimit
 	}
 	return input, nil
 }
 
+func normalizeFeedbackInput(input FeedbackInput, settings Settings) (FeedbackInput, error) {
+	ownerID, err := resolveOwnerID(input.OwnerID, settings)
+	if err != nil {
+		return input, err
+	}
+	input.OwnerID = ownerID
+	input.MemoryID = strings.TrimSpace(input.MemoryID)
+	input.Query = strings.TrimSpace(input.Query)
+	input.Rating = strings.ToLower(strings.TrimSpace(input.Rating))
+	input.Note = strings.TrimSpace(input.Note)
+	return input, nil
+}
+
+func normalizeContextInput(input ContextInput, settings Settings) (ContextInput, error) {
+	ownerID, err := resolveOwnerID(input.OwnerID, settings)
+	if err != nil {
+		return input, err
+	}
+	input.OwnerID = ownerID
+	input.ProjectKey = strings.TrimSpace(input.ProjectKey)
+	input.Task = strings.TrimSpace(input.Task)
+	if input.TokenBudget <= 0 {
+		input.TokenBudget = settings.Context.TokenBudget
+	}
+	input.Priority = normalizeStringListPtr(input.Priority)
+	if input.Priority == nil || len(*input.Priority) == 0 {
+		priority := append([]string(nil), settings.Context.Priority...)
+		input.Priority = &priority
+	} else {
+		priority := make([]string, 0, len(*input.Priority))
+		for _, kind := range *input.Priority {
+			priority = append(priority, strings.ToLower(kind))
+		}
+		input.Priority = &priority
+	}
+	return input, nil
+}
+
+func normalizeSimilarInput(input SimilarInput, settings Settings) (SimilarInput, error) {
+	ownerID, err := resolveOwnerID(input.OwnerID, settings)
+	if err != nil {
+		return input, err
+	}
+	input.OwnerID = ownerID
+	input.MemoryID = strings.TrimSpace(input.MemoryID)
+	input.ProjectKey = strings.TrimSpace(input.ProjectKey)
+	input.ProjectKeys = normalizeStringListPtr(input.ProjectKeys)
+	input.Scope = strings.TrimSpace(input.Scope)
+	if input.Scope == "" {
+		input.Scope = "all"
+	}
+	input.ContentTypes = normalizeStringListPtr(input.ContentTypes)
+	input.Axes = normalizeAxesInput(input.Axes)
+	input.IndexPath = normalizeIndexPathPtr(input.IndexPath)
+	if input.Limit <= 0 {
+		input.Limit = defaultSimilarLimit
+	}
+	return input, nil
+}
+
 func normalizeTimelineInput(input TimelineInput, settings Settings) (TimelineInput, error) {
 	ownerID, err := resolveOwnerID(input.OwnerID, settings)
 	if err != nil {
@@ -175,6 +246,8 @@ func normalizeSearchMode(value string) string {
 		return "ids"
 	case "full":
 		return "full"
+	case "highlight":
+		return "highlight"
 	default:
 		return "compact"
 	}
@@ -256,3 +329,14 @@ func normalizeIndexPathPtr(value *[]string) *[]string {
 	result := normalizeIndexPath(*value)
 	return &result
 }
+
+func normalizeStringListPtr(value *[]string) *[]string {
+	if value == nil {
+		return nil
+	}
+	result := uniqueStrings(*value)
+	if len(result) == 0 {
+		return nil
+	}
+	return &result
+}
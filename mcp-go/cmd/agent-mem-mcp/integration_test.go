@@ -1130,3 +1130,426 @@ func fileExists(path string) bool {
 	}
 	return !info.IsDir()
 }
+
+func TestContextPackIntegration(t *testing.T) {
+	if os.Getenv("AGENT_MEM_INTEGRATION") == "" {
+		t.Skip("未设置 AGENT_MEM_INTEGRATION，跳过集成测试")
+	}
+	settings := defaultSettings()
+	settings.Storage.DatabaseURL = envOrDefault("DATABASE_URL", settings.Storage.DatabaseURL)
+	settings.Embedding.Provider = "mock"
+	settings.Embedding.Dimension = 1536
+	settings.Chunking = ChunkingConfig{ChunkSize: 500, Overlap: 50, ApproxCharsPerToken: 4}
+
+	os.Setenv("AGENT_MEM_LLM_MODE", "mock")
+	app, err := NewApp(settings)
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer app.Close()
+
+	ctx := context.Background()
+	if err := app.EnsureSchema(ctx, true); err != nil {
+		t.Fatalf("初始化表结构失败: %v", err)
+	}
+
+	projectName := "agent-mem-context-test"
+	now := time.Now().UTC().Unix()
+	entries := []IngestMemoryInput{
+		{OwnerID: "personal", ProjectName: projectName, ProjectKey: projectName, ContentType: "plan", Content: "登录方案最终采用 JWT 鉴权，token 有效期 2 小时。", IndexPath: strArrPtr("dialogs", "auth", "latest"), Ts: now},
+		{OwnerID: "personal", ProjectName: projectName, ProjectKey: projectName, ContentType: "development", Content: "刷新 token 存储在 Redis 中，登录接口返回 access 与 refresh。", Ts: now + 1},
+	}
+	for _, entry := range entries {
+		if _, err := app.IngestMemory(ctx, entry); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+	}
+
+	pack, err := app.BuildContext(ctx, ContextInput{OwnerID: "personal", ProjectKey: projectName, Task: "实现登录 token 刷新", TokenBudget: 300})
+	if err != nil {
+		t.Fatalf("打包上下文失败: %v", err)
+	}
+	if pack.TokensUsed > 300 || len(pack.Items) == 0 || len(pack.Citations) == 0 {
+		t.Fatalf("打包结果异常: %+v", pack)
+	}
+	kinds := map[string]bool{}
+	for _, item := range pack.Items {
+		kinds[item.Kind] = true
+	}
+	if !kinds[contextKindLatest] || !kinds[contextKindFragment] {
+		t.Fatalf("应包含 latest 与片段: %+v", pack.Items)
+	}
+	if !strings.Contains(pack.Text, "[mem:") {
+		t.Fatalf("渲染结果缺少引用: %s", pack.Text)
+	}
+}
+
+func TestIngestBatchIntegration(t *testing.T) {
+	if os.Getenv("AGENT_MEM_INTEGRATION") == "" {
+		t.Skip("未设置 AGENT_MEM_INTEGRATION，跳过集成测试")
+	}
+	settings := defaultSettings()
+	settings.Storage.DatabaseURL = envOrDefault("DATABASE_URL", settings.Storage.DatabaseURL)
+	settings.Embedding.Provider = "mock"
+	settings.Embedding.Dimension = 1536
+
+	os.Setenv("AGENT_MEM_LLM_MODE", "mock")
+	app, err := NewApp(settings)
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer app.Close()
+
+	ctx := context.Background()
+	if err := app.EnsureSchema(ctx, true); err != nil {
+		t.Fatalf("初始化表结构失败: %v", err)
+	}
+
+	item := func(content string) IngestMemoryInput {
+		return IngestMemoryInput{OwnerID: "personal", ProjectKey: "batch-test", ContentType: "development", Content: content, SkipLLM: true}
+	}
+	allOrNothing := ingestBatchModeAllOrNothing
+	out, err := app.IngestBatch(ctx, IngestBatchInput{Mode: &allOrNothing, Items: []IngestMemoryInput{
+		item("批量写入第一条：使用 HNSW 索引"),
+		{OwnerID: "personal", ProjectKey: "batch-test", ContentType: "development"},
+	}})
+	if err != nil {
+		t.Fatalf("批量写入失败: %v", err)
+	}
+	if out.Committed || out.Results[0].Error == nil || out.Results[0].Error.Code != "ERR_BATCH_ABORTED" {
+		t.Fatalf("all_or_nothing 应整批回滚: %+v", out)
+	}
+	timeline, err := app.Timeline(ctx, TimelineInput{OwnerID: "personal", ProjectKey: "batch-test"})
+	if err != nil {
+		t.Fatalf("时间线查询失败: %v", err)
+	}
+	if len(timeline.Results) != 0 {
+		t.Fatalf("回滚后不应有记忆: %d", len(timeline.Results))
+	}
+
+	out, err = app.IngestBatch(ctx, IngestBatchInput{Items: []IngestMemoryInput{
+		item("批量写入第一条：使用 HNSW 索引"),
+		item("批量写入第一条：使用 HNSW 索引"),
+		{OwnerID: "personal", ProjectKey: "batch-test", ContentType: "development"},
+		item("批量写入第二条：连接池上限调到 50"),
+	}})
+	if err != nil {
+		t.Fatalf("批量写入失败: %v", err)
+	}
+	statuses := []string{}
+	for _, result := range out.Results {
+		statuses = append(statuses, result.Status)
+	}
+	if statuses[0] != "created" || statuses[1] != "duplicate" || statuses[2] != "error" || statuses[3] == "error" {
+		t.Fatalf("best_effort 逐条结果错误: %v", statuses)
+	}
+	if out.Results[1].ID != out.Results[0].ID {
+		t.Fatalf("批次内重复应指向先写入的记忆")
+	}
+}
+
+func TestIngestQueueIntegration(t *testing.T) {
+	if os.Getenv("AGENT_MEM_INTEGRATION") == "" {
+		t.Skip("未设置 AGENT_MEM_INTEGRATION，跳过集成测试")
+	}
+	settings := defaultSettings()
+	settings.Storage.DatabaseURL = envOrDefault("DATABASE_URL", settings.Storage.DatabaseURL)
+	settings.Embedding.Provider = "mock"
+	settings.Embedding.Dimension = 1536
+	settings.IngestQueue.PollIntervalMs = 50
+
+	os.Setenv("AGENT_MEM_LLM_MODE", "mock")
+	app, err := NewApp(settings)
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer app.Close()
+
+	ctx := context.Background()
+	if err := app.EnsureSchema(ctx, true); err != nil {
+		t.Fatalf("初始化表结构失败: %v", err)
+	}
+
+	async := true
+	queued, err := app.IngestMemoryTool(ctx, IngestMemoryInput{
+		OwnerID: "personal", ProjectKey: "queue-test", ContentType: "ops",
+		Content: "异步写入：部署脚本改为蓝绿发布，回滚窗口十分钟", SkipLLM: true, Async: &async,
+	})
+	if err != nil {
+		t.Fatalf("异步写入失败: %v", err)
+	}
+	if queued.Status != jobQueued || queued.JobID == "" || queued.ID == "" {
+		t.Fatalf("异步写入应立即返回任务: %+v", queued)
+	}
+
+	// worker 未启动：原文已可被全文检索召回
+	search, err := app.SearchMemories(ctx, SearchInput{OwnerID: "personal", ProjectKey: "queue-test", Query: "蓝绿发布"})
+	if err != nil {
+		t.Fatalf("检索失败: %v", err)
+	}
+	if len(search.Results) == 0 || search.Results[0].ID != queued.ID {
+		t.Fatalf("待处理记忆应可被全文检索召回: %+v", search.Results)
+	}
+
+	queueCtx, stopQueue := context.WithCancel(ctx)
+	app.ingestQueue.Start(queueCtx)
+	defer func() {
+		stopQueue()
+		app.ingestQueue.Drain(ctx)
+	}()
+
+	var status IngestJobStatus
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		out, err := app.IngestStatus(ctx, IngestStatusInput{JobIDs: []string{queued.JobID, "job_missing"}})
+		if err != nil {
+			t.Fatalf("查询任务状态失败: %v", err)
+		}
+		if out.Results[1].Status != "not_found" {
+			t.Fatalf("未知任务应返回 not_found: %+v", out.Results[1])
+		}
+		status = out.Results[0]
+		if status.Status == jobDone || status.Status == jobFailed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("任务未在超时前完成: %+v", status)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if status.Status != jobDone || status.ResultStatus != "created" || status.MemoryID != queued.ID {
+		t.Fatalf("任务结果错误: %+v", status)
+	}
+
+	again, err := app.IngestMemoryTool(ctx, IngestMemoryInput{
+		OwnerID: "personal", ProjectKey: "queue-test", ContentType: "ops",
+		Content: "异步写入：部署脚本改为蓝绿发布，回滚窗口十分钟", SkipLLM: true, Async: &async,
+	})
+	if err != nil {
+		t.Fatalf("异步写入失败: %v", err)
+	}
+	if again.Status != "duplicate" || again.ID != queued.ID || again.JobID != "" {
+		t.Fatalf("重复内容应在入队前识别: %+v", again)
+	}
+
+	// 最终失败：临时记忆随失败标记一起删除，不再被查重当作已有内容
+	stopQueue()
+	app.ingestQueue.Drain(ctx)
+	failing, err := app.IngestMemoryTool(ctx, IngestMemoryInput{
+		OwnerID: "personal", ProjectKey: "queue-test", ContentType: "ops",
+		Content: "异步写入：失败任务的临时记忆应被清理", SkipLLM: true, Async: &async,
+	})
+	if err != nil {
+		t.Fatalf("异步写入失败: %v", err)
+	}
+	job, ok, err := app.store.ClaimIngestJob(ctx, "worker-test", time.Minute)
+	if err != nil || !ok || job.ID != failing.JobID {
+		t.Fatalf("领取任务失败: %+v ok=%v err=%v", job, ok, err)
+	}
+	if err := app.failIngestJob(ctx, "worker-test", job, "boom"); err != nil {
+		t.Fatalf("标记任务失败出错: %v", err)
+	}
+	if _, err := app.store.FetchMemorySummary(ctx, failing.ID); err == nil {
+		t.Fatalf("失败任务的临时记忆应被删除")
+	}
+	failedStatus, err := app.IngestStatus(ctx, IngestStatusInput{JobIDs: []string{failing.JobID}})
+	if err != nil || failedStatus.Results[0].Status != jobFailed || failedStatus.Results[0].MemoryID != "" || failedStatus.Results[0].LastError != "boom" {
+		t.Fatalf("失败任务状态错误: %+v err=%v", failedStatus, err)
+	}
+	retried, err := app.IngestMemoryTool(ctx, IngestMemoryInput{
+		OwnerID: "personal", ProjectKey: "queue-test", ContentType: "ops",
+		Content: "异步写入：失败任务的临时记忆应被清理", SkipLLM: true,
+	})
+	if err != nil || retried.Status != "created" {
+		t.Fatalf("失败后重新写入不应被视为重复: %+v err=%v", retried, err)
+	}
+}
+
+func TestJobsIntegration(t *testing.T) {
+	if os.Getenv("AGENT_MEM_INTEGRATION") == "" {
+		t.Skip("未设置 AGENT_MEM_INTEGRATION，跳过集成测试")
+	}
+	settings := defaultSettings()
+	settings.Storage.DatabaseURL = envOrDefault("DATABASE_URL", settings.Storage.DatabaseURL)
+	settings.Embedding.Provider = "mock"
+	settings.Embedding.Dimension = 1536
+	settings.Jobs.PollIntervalMs = 50
+
+	os.Setenv("AGENT_MEM_LLM_MODE", "mock")
+	app, err := NewApp(settings)
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer app.Close()
+
+	ctx := context.Background()
+	if err := app.EnsureSchema(ctx, true); err != nil {
+		t.Fatalf("初始化表结构失败: %v", err)
+	}
+
+	// 长内容写入随事务入队前瞻任务
+	result, err := app.IngestMemory(ctx, IngestMemoryInput{
+		OwnerID: "personal", ProjectKey: "jobs-test", ContentType: "plan", SkipLLM: true,
+		Content: strings.Repeat("计划：下个迭代把检索服务拆分为独立进程，并补齐压测与告警。", 10),
+	})
+	if err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	queued, err := app.store.FetchJobs(ctx, jobQueued, jobKindForesight, 10)
+	if err != nil || len(queued) != 1 {
+		t.Fatalf("应入队一个前瞻任务: %+v err=%v", queued, err)
+	}
+
+	// 同一计划的同一触发时间点只入队一次
+	slot := time.Now().UTC().Truncate(time.Minute)
+	for range 2 {
+		job, _ := newJob(jobKindPruneJobs, nil, settings.Jobs.MaxAttempts)
+		job.Schedule, job.ScheduledFor = "prune_jobs", slot
+		if _, err := insertJobTx(ctx, app.store.pool, job); err != nil {
+			t.Fatalf("定时任务入队失败: %v", err)
+		}
+	}
+	scheduled, _ := app.store.FetchJobs(ctx, "", jobKindPruneJobs, 10)
+	if len(scheduled) != 1 || scheduled[0].Schedule != "prune_jobs" {
+		t.Fatalf("同一触发时间点应只入队一次: %+v", scheduled)
+	}
+
+	cancelID, err := app.EnqueueJob(ctx, jobKindReembed, reembedJobPayload{Limit: 10})
+	if err != nil {
+		t.Fatalf("入队失败: %v", err)
+	}
+	if cancelled, err := app.store.CancelJobs(ctx, []string{cancelID}); err != nil || cancelled != 1 {
+		t.Fatalf("取消任务失败: %d err=%v", cancelled, err)
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	app.jobs.Start(jobsCtx)
+	defer func() {
+		stopJobs()
+		app.jobs.Drain(ctx)
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		done, err := app.store.FetchJobs(ctx, jobDone, "", 10)
+		if err != nil {
+			t.Fatalf("查询任务失败: %v", err)
+		}
+		if len(done) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("任务未在超时前完成: %+v", done)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if status, _ := app.store.FetchJobStatus(ctx, cancelID); status != jobCancelled {
+		t.Fatalf("已取消的任务不应被执行: %s", status)
+	}
+	foresights, err := app.QueryForesights(ctx, ForesightInput{OwnerID: "personal", MemoryID: result.ID})
+	if err != nil || len(foresights.Foresights) == 0 {
+		t.Fatalf("前瞻任务应生成前瞻记忆: %+v err=%v", foresights, err)
+	}
+}
+
+func TestDistillIncrementalIntegration(t *testing.T) {
+	if os.Getenv("AGENT_MEM_INTEGRATION") == "" {
+		t.Skip("未设置 AGENT_MEM_INTEGRATION，跳过集成测试")
+	}
+	settings := defaultSettings()
+	settings.Storage.DatabaseURL = envOrDefault("DATABASE_URL", settings.Storage.DatabaseURL)
+	settings.Embedding.Provider = "mock"
+	settings.Embedding.Dimension = 1536
+	settings.Distill.Policies = []DistillPolicyConfig{{ProjectKey: "*", MinNewMemories: 2, SinceDays: 30}}
+
+	os.Setenv("AGENT_MEM_LLM_MODE", "mock")
+	app, err := NewApp(settings)
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer app.Close()
+
+	ctx := context.Background()
+	if err := app.EnsureSchema(ctx, true); err != nil {
+		t.Fatalf("初始化表结构失败: %v", err)
+	}
+	ingest := func(content string) string {
+		result, err := app.IngestMemory(ctx, IngestMemoryInput{OwnerID: "personal", ProjectKey: "distill-test", ContentType: "development", Content: content, SkipLLM: true})
+		if err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		return result.ID
+	}
+	first := []string{ingest("缓存层改用 Redis 集群，淘汰策略为 allkeys-lru"), ingest("订单服务拆分读写库，写库单主")}
+
+	// 策略检查：未蒸馏的记忆达到阈值时入队蒸馏任务，重复检查不重复入队
+	for range 2 {
+		if err := runDistillPoliciesJob(ctx, app, nil); err != nil {
+			t.Fatalf("自动蒸馏检查失败: %v", err)
+		}
+	}
+	pending, err := app.store.FetchJobs(ctx, jobQueued, jobKindDistill, 10)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("应入队一个蒸馏任务: %+v err=%v", pending, err)
+	}
+	if err := runDistillJob(ctx, app, pending[0].Payload); err != nil {
+		t.Fatalf("蒸馏任务失败: %v", err)
+	}
+
+	digests, err := app.store.FetchDistilledSummaries(ctx, mustProjectID(t, app, "distill-test"), 10)
+	if err != nil || len(digests) != 1 {
+		t.Fatalf("应生成一条蒸馏结果: %+v err=%v", digests, err)
+	}
+	digestID := digests[0].ID
+	relations, err := app.QueryRelations(ctx, RelationsInput{MemoryID: digestID, Direction: "outgoing", RelationType: "DERIVED_FROM"})
+	if err != nil || len(relations.Relations) != len(first) {
+		t.Fatalf("蒸馏结果应指向源记忆: %+v err=%v", relations, err)
+	}
+
+	// 没有新记忆时不再蒸馏
+	empty, err := app.DistillMemories(ctx, DistillInput{OwnerID: "personal", ProjectKey: "distill-test"})
+	if err != nil || empty.Status != "empty" {
+		t.Fatalf("没有未蒸馏的记忆时应返回 empty: %+v err=%v", empty, err)
+	}
+
+	// 增量：只蒸馏新记忆，并以新版本替换 latest
+	third := ingest("支付回调改为幂等处理，重复通知直接返回成功")
+	out, err := app.DistillMemories(ctx, DistillInput{OwnerID: "personal", ProjectKey: "distill-test"})
+	if err != nil {
+		t.Fatalf("增量蒸馏失败: %v", err)
+	}
+	if out.ID != digestID || out.Result != "updated" || len(out.SourceIDs) != 1 || out.SourceIDs[0] != third {
+		t.Fatalf("增量蒸馏应只处理新记忆并更新上一版: %+v", out)
+	}
+	chain, err := app.MemoryChain(ctx, MemoryChainInput{OwnerID: "personal", MemoryID: digestID})
+	if err != nil || len(chain.Versions) != 1 {
+		t.Fatalf("上一版蒸馏结果应保存为历史版本: %+v err=%v", chain, err)
+	}
+	digests, _ = app.store.FetchDistilledSummaries(ctx, mustProjectID(t, app, "distill-test"), 10)
+	if len(digests) != 1 {
+		t.Fatalf("latest 路径只应保留一条蒸馏结果: %+v", digests)
+	}
+
+	// 上一版没有向量时仍以它为仲裁候选并替换
+	if _, err := app.store.pool.Exec(ctx, `UPDATE memories SET avg_embedding = NULL WHERE id = $1`, digestID); err != nil {
+		t.Fatalf("清除向量失败: %v", err)
+	}
+	ingest("日志采集改为按服务分索引，保留三十天")
+	out, err = app.DistillMemories(ctx, DistillInput{OwnerID: "personal", ProjectKey: "distill-test"})
+	if err != nil || out.ID != digestID || out.Result != "updated" {
+		t.Fatalf("上一版没有向量时应替换上一版: %+v err=%v", out, err)
+	}
+	digests, _ = app.store.FetchDistilledSummaries(ctx, mustProjectID(t, app, "distill-test"), 10)
+	if len(digests) != 1 {
+		t.Fatalf("latest 路径只应保留一条蒸馏结果: %+v", digests)
+	}
+}
+
+func mustProjectID(t *testing.T, app *App, projectKey string) string {
+	t.Helper()
+	projectID, err := app.store.FindProjectIDByKey(context.Background(), "personal", projectKey)
+	if err != nil || projectID == "" {
+		t.Fatalf("项目不存在: %s err=%v", projectKey, err)
+	}
+	return projectID
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// jobHandler 执行一个后台任务；返回 AppError 表示参数错误，任务直接失败不再重试
+type jobHandler func(ctx context.Context, a *App, payload json.RawMessage) error
+
+var jobHandlers = map[string]jobHandler{
+	jobKindForesight:       runForesightJob,
+	jobKindCleanForesights: runCleanForesightsJob,
+	jobKindReembed:         runReembedJob,
+	jobKindPruneVersions:   runPruneVersionsJob,
+	jobKindPruneJobs:       runPruneJobsJob,
+	jobKindDistill:         runDistillJob,
+	jobKindDistillPolicies: runDistillPoliciesJob,
+}
+
+const (
+	defaultReembedLimit     = 100
+	maxReembedLimit         = 1000
+	defaultKeepVersions     = 20
+	defaultJobRetentionDays = 30
+)
+
+// decodeJobPayload 严格解析任务参数：未知字段视为配置错误；空参数按默认值处理
+func decodeJobPayload(payload json.RawMessage, target any) error {
+	if len(bytes.TrimSpace(payload)) == 0 || string(bytes.TrimSpace(payload)) == "null" {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return newValidationError("invalid_request", "ERR_INVALID_JOB_PAYLOAD", "任务参数无效: "+err.Error(), http.StatusBadRequest)
+	}
+	return nil
+}
+
+type foresightJobPayload struct {
+	MemoryID  string `json:"memory_id"`
+	OwnerID   string `json:"owner_id"`
+	ProjectID string `json:"project_id"`
+}
+
+// runForesightJob 记忆在任务执行前已被删除时跳过
+func runForesightJob(ctx context.Context, a *App, payload json.RawMessage) error {
+	var params foresightJobPayload
+	if err := decodeJobPayload(payload, &params); err != nil {
+		return err
+	}
+	if params.MemoryID == "" || params.ProjectID == "" {
+		return newValidationError("invalid_request", "ERR_INVALID_JOB_PAYLOAD", "memory_id 与 project_id 不能为空", http.StatusBadRequest)
+	}
+	rows, err := a.store.FetchMemories(ctx, []string{params.MemoryID})
+	if err != nil {
+		return fmt.Errorf("读取记忆失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	row := rows[0]
+	if err := a.GenerateForesights(ctx, row.ID, params.ProjectID, row.Content, row.Summary, row.ContentType); err != nil {
+		return err
+	}
+	// 前瞻参与检索召回
+	a.invalidateSearchCache(params.OwnerID, params.ProjectID)
+	return nil
+}
+
+func runCleanForesightsJob(ctx context.Context, a *App, payload json.RawMessage) error {
+	if err := decodeJobPayload(payload, &struct{}{}); err != nil {
+		return err
+	}
+	cleaned, err := a.store.CleanExpiredForesights(ctx)
+	if err != nil {
+		return fmt.Errorf("清理过期前瞻失败: %w", err)
+	}
+	if cleaned > 0 {
+		log.Printf("[INFO] 清理过期前瞻: %d 条", cleaned)
+	}
+	return nil
+}
+
+type reembedJobPayload struct {
+	Limit int `json:"limit,omitempty"`
+}
+
+// runReembedJob 为缺少向量的记忆补算片段向量与平均向量；单条失败不影响其他记忆，全部处理后返回错误以便重试
+func runReembedJob(ctx context.Context, a *App, payload json.RawMessage) error {
+	params := reembedJobPayload{Limit: defaultReembedLimit}
+	if err := decodeJobPayload(payload, &params); err != nil {
+		return err
+	}
+	if params.Limit <= 0 || params.Limit > maxReembedLimit {
+		return newValidationError("invalid_request", "ERR_INVALID_JOB_PAYLOAD", fmt.Sprintf("limit 取值范围为 1-%d", maxReembedLimit), http.StatusBadRequest)
+	}
+	memoryIDs, err := a.store.FetchMemoriesMissingEmbeddings(ctx, params.Limit)
+	if err != nil {
+		return fmt.Errorf("查询缺少向量的记忆失败: %w", err)
+	}
+	var errs []error
+	var updated []string
+	for _, memoryID := range memoryIDs {
+		ok, err := a.reembedMemory(ctx, memoryID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", memoryID, err))
+			continue
+		}
+		if ok {
+			updated = append(updated, memoryID)
+		}
+	}
+	if len(updated) > 0 {
+		a.invalidateSearchCacheForMemories(ctx, updated...)
+		log.Printf("[INFO] 重新向量化记忆: %d 条", len(updated))
+	}
+	return errors.Join(errs...)
+}
+
+func (a *App) reembedMemory(ctx context.Context, memoryID string) (bool, error) {
+	fragments, err := a.store.FetchFragmentsByMemoryIDs(ctx, []string{memoryID})
+	if err != nil {
+		return false, fmt.Errorf("读取片段失败: %w", err)
+	}
+	if len(fragments) == 0 {
+		return false, nil
+	}
+	texts := make([]string, len(fragments))
+	for idx, frag := range fragments {
+		texts[idx] = frag.Content
+	}
+	embeddings, err := a.embedder.EmbedBatch(ctx, texts)
+	if err != nil {
+		return false, fmt.Errorf("向量化失败: %w", err)
+	}
+	if len(embeddings) != len(texts) {
+		return false, errors.New("向量数量与片段数量不一致")
+	}
+	avg := l2Normalize(averageEmbedding(embeddings, a.embedder.dimension))
+	return a.store.UpdateMemoryEmbeddings(ctx, memoryID, fragments, embeddings, avg)
+}
+
+type pruneVersionsJobPayload struct {
+	Keep int `json:"keep,omitempty"`
+}
+
+func runPruneVersionsJob(ctx context.Context, a *App, payload json.RawMessage) error {
+	params := pruneVersionsJobPayload{Keep: defaultKeepVersions}
+	if err := decodeJobPayload(payload, &params); err != nil {
+		return err
+	}
+	if params.Keep < 1 {
+		return newValidationError("invalid_request", "ERR_INVALID_JOB_PAYLOAD", "keep 不能小于 1", http.StatusBadRequest)
+	}
+	pruned, err := a.store.PruneMemoryVersions(ctx, params.Keep)
+	if err != nil {
+		return fmt.Errorf("清理历史版本失败: %w", err)
+	}
+	if pruned > 0 {
+		log.Printf("[INFO] 清理历史版本: %d 条", pruned)
+	}
+	return nil
+}
+
+type pruneJobsJobPayload struct {
+	RetentionDays int `json:"retention_days,omitempty"`
+}
+
+func runPruneJobsJob(ctx context.Context, a *App, payload json.RawMessage) error {
+	params := pruneJobsJobPayload{RetentionDays: defaultJobRetentionDays}
+	if err := decodeJobPayload(payload, &params); err != nil {
+		return err
+	}
+	if params.RetentionDays < 1 {
+		return newValidationError("invalid_request", "ERR_INVALID_JOB_PAYLOAD", "retention_days 不能小于 1", http.StatusBadRequest)
+	}
+	before := time.Now().UTC().AddDate(0, 0, -params.RetentionDays)
+	pruned, err := a.store.PruneFinishedJobs(ctx, before)
+	if err != nil {
+		return fmt.Errorf("清理已结束任务失败: %w", err)
+	}
+	if pruned > 0 {
+		log.Printf("[INFO] 清理已结束任务: %d 条", pruned)
+	}
+	return nil
+}
+
+func runDistillJob(ctx context.Context, a *App, payload json.RawMessage) error {
+	var input DistillInput
+	if err := decodeJobPayload(payload, &input); err != nil {
+		return err
+	}
+	output, err := a.DistillMemories(ctx, input)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] 定时蒸馏完成: project=%s id=%s status=%s sources=%d", input.ProjectKey, output.ID, output.Status, output.SourceCount)
+	return nil
+}
+
+// runDistillPoliciesJob 按 distill.policies 检查各项目，为满足条件的项目入队蒸馏任务（已有排队或执行中的任务时跳过）
+func runDistillPoliciesJob(ctx context.Context, a *App, payload json.RawMessage) error {
+	if err := decodeJobPayload(payload, &struct{}{}); err != nil {
+		return err
+	}
+	if len(a.settings.Distill.Policies) == 0 {
+		return nil
+	}
+	projects, err := a.store.FetchAllProjects(ctx)
+	if err != nil {
+		return fmt.Errorf("查询项目失败: %w", err)
+	}
+	now := time.Now().UTC()
+	enqueued := 0
+	for _, project := range projects {
+		policy, ok := matchDistillPolicy(a.settings.Distill.Policies, project.OwnerID, project.ProjectKey)
+		if !ok {
+			continue
+		}
+		sinceTs := now.Add(-time.Duration(policy.SinceDays) * 24 * time.Hour).Unix()
+		pending, lastDistilled, err := a.store.FetchDistillBacklog(ctx, project.ID, sinceTs, policy.Scope)
+		if err != nil {
+			return fmt.Errorf("查询项目 %s 的蒸馏积压失败: %w", project.ProjectKey, err)
+		}
+		var last time.Time
+		if lastDistilled > 0 {
+			last = time.Unix(lastDistilled, 0).UTC()
+		}
+		if !distillDue(policy, pending, last, now) {
+			continue
+		}
+		input := DistillInput{
+			OwnerID:           project.OwnerID,
+			ProjectKey:        project.ProjectKey,
+			Scope:             policy.Scope,
+			SinceDays:         policy.SinceDays,
+			TargetContentType: policy.TargetContentType,
+		}
+		running, err := a.store.HasPendingJob(ctx, jobKindDistill, map[string]string{"owner_id": input.OwnerID, "project_key": input.ProjectKey})
+		if err != nil {
+			return fmt.Errorf("查询蒸馏任务失败: %w", err)
+		}
+		if running {
+			continue
+		}
+		if _, err := a.EnqueueJob(ctx, jobKindDistill, input); err != nil {
+			return err
+		}
+		enqueued++
+	}
+	if enqueued > 0 {
+		log.Printf("[INFO] 自动蒸馏入队: %d 个项目", enqueued)
+	}
+	return nil
+}
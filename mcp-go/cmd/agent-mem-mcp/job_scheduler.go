@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// 定时计划：多实例部署时由持有 Postgres advisory lock 的实例（leader）按 cron 入队，
+// 任务本身仍由各实例的 worker 领取执行；同一计划的同一触发时间点只入队一次
+const (
+	// jobSchedulerLockKey advisory lock 键（"agmemjob"）
+	jobSchedulerLockKey int64 = 0x61676d656d6a6f62
+	jobSchedulerTick          = 15 * time.Second
+	// maxScheduleCatchUp 单个计划一次最多补入队的触发次数（如 @every 1m 的计划在 leader 切换后）
+	maxScheduleCatchUp = 10
+)
+
+type scheduledJob struct {
+	config  JobScheduleConfig
+	cron    cronSchedule
+	payload []byte
+}
+
+// JobScheduler 定时计划 leader：持有专用连接上的 advisory lock，连接断开时锁随之释放，由其他实例接管
+type JobScheduler struct {
+	app       *App
+	schedules []scheduledJob
+	conn      *pgxpool.Conn
+	// lastTick leader 上次检查到的时间，(lastTick, now] 内的触发时间点会被入队
+	lastTick time.Time
+}
+
+// NewJobScheduler cron 无效、任务类型未知或重名的计划记录日志后跳过，不影响其他计划
+func NewJobScheduler(app *App, settings JobsConfig) *JobScheduler {
+	scheduler := &JobScheduler{app: app}
+	seen := map[string]bool{}
+	for _, config := range settings.Schedules {
+		if seen[config.Name] {
+			log.Printf("[WARN] 定时计划重名，已跳过: %s", config.Name)
+			continue
+		}
+		if _, ok := jobHandlers[config.Kind]; !ok {
+			log.Printf("[WARN] 定时计划 %s 的任务类型未知，已跳过: %s", config.Name, config.Kind)
+			continue
+		}
+		schedule, err := parseCron(config.Cron)
+		if err != nil {
+			log.Printf("[WARN] 定时计划 %s 已跳过: %v", config.Name, err)
+			continue
+		}
+		payload := []byte("{}")
+		if len(config.Payload) > 0 {
+			encoded, err := json.Marshal(config.Payload)
+			if err != nil {
+				log.Printf("[WARN] 定时计划 %s 的 payload 无法序列化，已跳过: %v", config.Name, err)
+				continue
+			}
+			payload = encoded
+		}
+		seen[config.Name] = true
+		scheduler.schedules = append(scheduler.schedules, scheduledJob{config: config, cron: schedule, payload: payload})
+	}
+	return scheduler
+}
+
+// Run 阻塞直到 ctx 取消；退出时释放 leader
+func (s *JobScheduler) Run(ctx context.Context) {
+	if len(s.schedules) == 0 {
+		return
+	}
+	defer s.release()
+	ticker := time.NewTicker(jobSchedulerTick)
+	defer ticker.Stop()
+	for {
+		s.tick(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *JobScheduler) tick(ctx context.Context, now time.Time) {
+	if !s.lead(ctx) {
+		return
+	}
+	// 刚成为 leader 时不补入队此前的触发时间点（可能已由上一任 leader 入队；重复入队由唯一索引兜底）
+	if s.lastTick.IsZero() {
+		s.lastTick = now
+		return
+	}
+	enqueued := 0
+	for _, job := range s.schedules {
+		for _, slot := range dueSlots(job.cron, s.lastTick, now, maxScheduleCatchUp) {
+			insert := JobInsert{
+				ID:           newJobID(),
+				Kind:         job.config.Kind,
+				Payload:      job.payload,
+				MaxAttempts:  s.app.settings.Jobs.MaxAttempts,
+				RunAt:        slot,
+				Schedule:     job.config.Name,
+				ScheduledFor: slot,
+			}
+			inserted, err := insertJobTx(ctx, s.app.store.pool, insert)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("[WARN] 定时计划 %s 入队失败: %v", job.config.Name, err)
+				}
+				// 下次 tick 重试本时间段
+				return
+			}
+			if inserted {
+				enqueued++
+			}
+		}
+	}
+	s.lastTick = now
+	if enqueued > 0 {
+		s.app.jobs.Wake()
+	}
+}
+
+// lead 尝试成为或保持 leader；连接失效时放弃 leader，下次 tick 重新竞争
+func (s *JobScheduler) lead(ctx context.Context) bool {
+	if s.conn != nil {
+		if err := s.conn.Ping(ctx); err == nil {
+			return true
+		}
+		log.Printf("[WARN] 定时计划 leader 连接失效，重新选主")
+		s.conn.Conn().Close(context.Background())
+		s.conn.Release()
+		s.conn = nil
+		s.lastTick = time.Time{}
+	}
+	conn, err := s.app.store.pool.Acquire(ctx)
+	if err != nil {
+		return false
+	}
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, jobSchedulerLockKey).Scan(&locked); err != nil || !locked {
+		conn.Release()
+		return false
+	}
+	s.conn = conn
+	log.Printf("本实例成为定时计划 leader: schedules=%d", len(s.schedules))
+	return true
+}
+
+func (s *JobScheduler) release() {
+	if s.conn == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := s.conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, jobSchedulerLockKey); err != nil {
+		// 解锁失败时关闭连接，锁随会话释放
+		s.conn.Conn().Close(ctx)
+	}
+	s.conn.Release()
+	s.conn = nil
+}
+
+// dueSlots (after, until] 内的触发时间点，最多 limit 个（保留最近的）
+func dueSlots(schedule cronSchedule, after, until time.Time, limit int) []time.Time {
+	var slots []time.Time
+	for next := schedule.Next(after); !next.IsZero() && !next.After(until); next = schedule.Next(next) {
+		slots = append(slots, next)
+		if len(slots) > limit {
+			slots = slots[1:]
+		}
+	}
+	return slots
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// 后台任务：任务持久化在 jobs 表，各实例的 worker 以 SKIP LOCKED 领取，失败按指数退避重试。
+// 执行超过 stale_after_seconds 的任务会被重新领取，处理函数需幂等
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobDone      = "done"
+	jobFailed    = "failed"
+	jobCancelled = "cancelled"
+
+	jobKindForesight       = "foresight"
+	jobKindCleanForesights = "clean_foresights"
+	jobKindReembed         = "reembed"
+	jobKindPruneVersions   = "prune_versions"
+	jobKindPruneJobs       = "prune_jobs"
+	jobKindDistill         = "distill"
+	jobKindDistillPolicies = "distill_policies"
+)
+
+// errJobCancelled 执行中的任务被 agent-mem jobs cancel 取消
+var errJobCancelled = errors.New("任务已取消")
+
+// JobRecord jobs 表中的任务；时间为 Unix 秒，Schedule 为入队的定时计划名（手动入队为空）
+type JobRecord struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	Status      string          `json:"status"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       int64           `json:"run_at"`
+	Schedule    string          `json:"schedule,omitempty"`
+	LastError   string          `json:"last_error,omitempty"`
+	LockedBy    string          `json:"locked_by,omitempty"`
+	CreatedAt   int64           `json:"created_at"`
+	FinishedAt  int64           `json:"finished_at,omitempty"`
+}
+
+// JobInsert LockedBy 非空时直接以执行中状态写入（jobs run -local 在当前进程执行）；
+// Schedule 与 ScheduledFor 相同的任务只写入一次（选主切换时避免重复入队）
+type JobInsert struct {
+	ID           string
+	Kind         string
+	Payload      []byte
+	MaxAttempts  int
+	RunAt        time.Time
+	Schedule     string
+	ScheduledFor time.Time
+	LockedBy     string
+}
+
+func newJob(kind string, payload any, maxAttempts int) (JobInsert, error) {
+	data := []byte("{}")
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return JobInsert{}, fmt.Errorf("任务参数序列化失败: %w", err)
+		}
+		data = encoded
+	}
+	return JobInsert{ID: newJobID(), Kind: kind, Payload: data, MaxAttempts: maxAttempts, RunAt: time.Now().UTC()}, nil
+}
+
+// insertJobTx 返回 false 表示该定时计划的同一时间点已入队
+func insertJobTx(ctx context.Context, tx pgxTx, job JobInsert) (bool, error) {
+	status := jobQueued
+	attempts := 0
+	if job.LockedBy != "" {
+		status = jobRunning
+		attempts = 1
+	}
+	var scheduledFor any
+	if !job.ScheduledFor.IsZero() {
+		scheduledFor = job.ScheduledFor
+	}
+	tag, err := tx.Exec(ctx, `
+INSERT INTO jobs (id, kind, payload, status, attempts, max_attempts, run_at, schedule, scheduled_for, locked_by, locked_at)
+VALUES ($1,$2,$3::jsonb,$4,$5,$6,$7,$8,$9,$10,CASE WHEN $10::text IS NULL THEN NULL ELSE NOW() END)
+ON CONFLICT (schedule, scheduled_for) WHERE schedule IS NOT NULL DO NOTHING`,
+		job.ID, job.Kind, string(job.Payload), status, attempts, job.MaxAttempts, job.RunAt,
+		nullableString(job.Schedule), scheduledFor, nullableString(job.LockedBy))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// EnqueueJob 事务外入队并唤醒本实例的 worker
+func (a *App) EnqueueJob(ctx context.Context, kind string, payload any) (string, error) {
+	job, err := newJob(kind, payload, a.settings.Jobs.MaxAttempts)
+	if err != nil {
+		return "", err
+	}
+	if _, err := insertJobTx(ctx, a.store.pool, job); err != nil {
+		return "", fmt.Errorf("任务入队失败: %w", err)
+	}
+	a.jobs.Wake()
+	return job.ID, nil
+}
+
+// JobRunner 后台任务 worker 池
+type JobRunner struct {
+	app      *App
+	settings JobsConfig
+	workers  *workerPool
+}
+
+func NewJobRunner(app *App, settings JobsConfig) *JobRunner {
+	return &JobRunner{
+		app:      app,
+		settings: settings,
+		workers:  newWorkerPool("后台", settings.Workers, time.Duration(settings.PollIntervalMs)*time.Millisecond, app.store.ReleaseJobs),
+	}
+}
+
+func (r *JobRunner) Wake() {
+	if r == nil {
+		return
+	}
+	r.workers.Wake()
+}
+
+// Start 启动 worker；ctx 取消后不再领取新任务，执行中的任务继续完成（由 Drain 等待）
+func (r *JobRunner) Start(ctx context.Context) {
+	if r == nil || !r.settings.Enabled {
+		return
+	}
+	r.workers.Start(ctx, r.app.instanceID(), r.step)
+}
+
+// Drain 等待执行中的任务完成，最多 drain_timeout_seconds；超时后本实例仍持有的任务放回队列
+func (r *JobRunner) Drain(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	r.workers.Drain(ctx, time.Duration(r.settings.DrainTimeoutSeconds)*time.Second)
+}
+
+func (r *JobRunner) step(ctx context.Context, workerID string) bool {
+	job, ok, err := r.app.store.ClaimJob(ctx, workerID, r.staleAfter())
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[WARN] 领取后台任务失败: %v", err)
+		}
+		return false
+	}
+	if ok {
+		r.run(ctx, workerID, job)
+	}
+	return ok
+}
+
+// run 执行已领取的任务并记录结果：参数错误或次数用尽标记失败，其他错误按退避重新入队
+func (r *JobRunner) run(ctx context.Context, workerID string, job JobRecord) {
+	// 退出信号不打断执行中的任务；超时与重新领取的判定一致
+	base := context.WithoutCancel(ctx)
+	runCtx, cancel := context.WithTimeout(base, r.staleAfter())
+	defer cancel()
+
+	err := r.execute(runCtx, job)
+	switch {
+	case err == nil:
+		if err := r.app.store.CompleteJob(base, job.ID, workerID); err != nil {
+			log.Printf("[WARN] 更新任务状态失败: job=%s err=%v", job.ID, err)
+		}
+		return
+	case errors.Is(err, errJobCancelled):
+		log.Printf("[INFO] 后台任务已取消: job=%s kind=%s", job.ID, job.Kind)
+		return
+	}
+	var appErr *AppError
+	if errors.As(err, &appErr) || job.Attempts >= job.MaxAttempts {
+		log.Printf("[WARN] 后台任务失败: job=%s kind=%s attempts=%d err=%v", job.ID, job.Kind, job.Attempts, err)
+		if err := r.app.store.FailJob(base, job.ID, workerID, err.Error()); err != nil {
+			log.Printf("[WARN] 更新任务状态失败: job=%s err=%v", job.ID, err)
+		}
+		return
+	}
+	delay := retryBackoff(r.settings.BackoffSeconds, r.settings.MaxBackoffSeconds, job.Attempts)
+	log.Printf("[WARN] 后台任务 %s 后重试: job=%s kind=%s attempts=%d/%d err=%v", delay, job.ID, job.Kind, job.Attempts, job.MaxAttempts, err)
+	if err := r.app.store.RetryJob(base, job.ID, workerID, err.Error(), delay); err != nil {
+		log.Printf("[WARN] 更新任务状态失败: job=%s err=%v", job.ID, err)
+	}
+}
+
+// execute 执行期间轮询任务状态，被取消时中止处理函数
+func (r *JobRunner) execute(ctx context.Context, job JobRecord) error {
+	handler, ok := jobHandlers[job.Kind]
+	if !ok {
+		return newValidationError("invalid_request", "ERR_UNKNOWN_JOB_KIND", "未知任务类型: "+job.Kind, 400)
+	}
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		ticker := time.NewTicker(time.Duration(r.settings.PollIntervalMs) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				if status, err := r.app.store.FetchJobStatus(runCtx, job.ID); err == nil && status == jobCancelled {
+					cancel(errJobCancelled)
+					return
+				}
+			}
+		}
+	}()
+	err := handler(runCtx, r.app, job.Payload)
+	if cause := context.Cause(runCtx); errors.Is(cause, errJobCancelled) {
+		return errJobCancelled
+	}
+	return err
+}
+
+func (r *JobRunner) staleAfter() time.Duration {
+	return time.Duration(r.settings.StaleAfterSeconds) * time.Second
+}
+
+// workerPool 轮询领取任务的 worker 池（异步写入与后台任务共用）
+type workerPool struct {
+	name    string
+	workers int
+	poll    time.Duration
+	// release Drain 放弃等待时把这些 worker 仍持有的任务放回队列
+	release func(ctx context.Context, workerIDs []string) (int64, error)
+	// wake 本实例有任务入队时唤醒空闲 worker，其他实例入队的任务靠轮询发现
+	wake      chan struct{}
+	wg        sync.WaitGroup
+	workerIDs []string
+}
+
+// workerReleaseTimeout Drain 超时后放回任务的数据库操作时限
+const workerReleaseTimeout = 5 * time.Second
+
+func newWorkerPool(name string, workers int, poll time.Duration, release func(ctx context.Context, workerIDs []string) (int64, error)) *workerPool {
+	return &workerPool{name: name, workers: workers, poll: poll, release: release, wake: make(chan struct{}, 1)}
+}
+
+func (p *workerPool) Wake() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start step 领取并执行一个任务，没有可领取的任务时返回 false，worker 等待唤醒或下一次轮询
+func (p *workerPool) Start(ctx context.Context, origin string, step func(ctx context.Context, workerID string) bool) {
+	for idx := range p.workers {
+		workerID := fmt.Sprintf("%s/%d", origin, idx)
+		p.workerIDs = append(p.workerIDs, workerID)
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for ctx.Err() == nil {
+				if step(ctx, workerID) {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+				case <-p.wake:
+				case <-time.After(p.poll):
+				}
+			}
+		}()
+	}
+	log.Printf("%s worker 已启动: workers=%d", p.name, p.workers)
+}
+
+// Drain 最多等待 timeout；仍未完成的任务放回队列，由其他实例（或重启后）立即重新领取，
+// 执行中的任务随后提交结果时因锁持有者不符而被丢弃（异步写入整体回滚，后台任务处理函数需幂等）
+func (p *workerPool) Drain(ctx context.Context, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	if len(p.workerIDs) == 0 || p.release == nil {
+		return
+	}
+	releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), workerReleaseTimeout)
+	defer cancelRelease()
+	released, err := p.release(releaseCtx, p.workerIDs)
+	if err != nil {
+		log.Printf("[WARN] %s任务未在退出前完成，放回队列失败，将在 stale_after_seconds 后被重新领取: %v", p.name, err)
+		return
+	}
+	log.Printf("[WARN] %s任务未在退出前完成，已放回队列: %d 个", p.name, released)
+}
+
+// retryBackoff 第 attempts 次失败后的退避：baseSeconds 起每次翻倍，不超过 maxSeconds
+func retryBackoff(baseSeconds, maxSeconds, attempts int) time.Duration {
+	delay := time.Duration(baseSeconds) * time.Second
+	maxDelay := time.Duration(maxSeconds) * time.Second
+	for range max(attempts-1, 0) {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return min(delay, maxDelay)
+}
+
+// runJobsCommand agent-mem jobs list|run|cancel
+func runJobsCommand(args []string) int {
+	const usage = `用法:
+  agent-mem jobs list [-status queued] [-kind reembed] [-limit 50] [-json]
+  agent-mem jobs run [-payload '{"limit":100}'] [-local] <计划名|任务类型>
+  agent-mem jobs cancel <job_id>...`
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	command := args[0]
+	fs := flag.NewFlagSet("jobs "+command, flag.ContinueOnError)
+	configPath := fs.String("config", "", "配置文件路径")
+	var (
+		status  *string
+		kind    *string
+		limit   *int
+		asJSON  *bool
+		payload *string
+		local   *bool
+	)
+	switch command {
+	case "list":
+		status = fs.String("status", "", "按状态过滤：queued/running/done/failed/cancelled")
+		kind = fs.String("kind", "", "按任务类型过滤")
+		limit = fs.Int("limit", 50, "最多列出的任务数")
+		asJSON = fs.Bool("json", false, "以 JSON 输出")
+	case "run":
+		payload = fs.String("payload", "", "任务参数（JSON，覆盖定时计划中的 payload）")
+		local = fs.Bool("local", false, "在当前进程中立即执行（默认入队，由运行中的服务执行）")
+	case "cancel":
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if (command == "run" && fs.NArg() != 1) || (command == "cancel" && fs.NArg() == 0) {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	settings, err := loadSettings(*configPath)
+	if err != nil {
+		log.Printf("[CRITICAL] 配置加载失败: %v", err)
+		return 1
+	}
+	app, err := NewApp(settings)
+	if err != nil {
+		log.Printf("[CRITICAL] 应用初始化失败: %v", err)
+		return 1
+	}
+	defer app.Close()
+	ctx := context.Background()
+	if err := app.EnsureSchema(ctx, false); err != nil {
+		log.Printf("[CRITICAL] 数据库 schema 初始化失败: %v", err)
+		return 1
+	}
+
+	switch command {
+	case "list":
+		jobs, err := app.store.FetchJobs(ctx, strings.TrimSpace(*status), strings.TrimSpace(*kind), *limit)
+		if err != nil {
+			log.Printf("[CRITICAL] 查询任务失败: %v", err)
+			return 1
+		}
+		if *asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			_ = encoder.Encode(jobs)
+			return 0
+		}
+		writeJobsTable(os.Stdout, jobs)
+		return 0
+	case "run":
+		job, err := resolveJobRun(settings.Jobs, fs.Arg(0), *payload)
+		if err != nil {
+			log.Printf("[CRITICAL] %v", err)
+			return 2
+		}
+		if *local {
+			return runJobLocally(ctx, app, job)
+		}
+		if _, err := insertJobTx(ctx, app.store.pool, job); err != nil {
+			log.Printf("[CRITICAL] 任务入队失败: %v", err)
+			return 1
+		}
+		fmt.Println(job.ID)
+		return 0
+	default:
+		cancelled, err := app.store.CancelJobs(ctx, fs.Args())
+		if err != nil {
+			log.Printf("[CRITICAL] 取消任务失败: %v", err)
+			return 1
+		}
+		fmt.Printf("已取消 %d 个任务\n", cancelled)
+		if cancelled < int64(fs.NArg()) {
+			return 1
+		}
+		return 0
+	}
+}
+
+// resolveJobRun name 优先匹配定时计划名（沿用其任务类型与参数），其次为任务类型
+func resolveJobRun(settings JobsConfig, name, payload string) (JobInsert, error) {
+	kind := name
+	var params any
+	for _, schedule := range settings.Schedules {
+		if schedule.Name == name {
+			kind = schedule.Kind
+			params = schedule.Payload
+			break
+		}
+	}
+	if _, ok := jobHandlers[kind]; !ok {
+		return JobInsert{}, fmt.Errorf("未知的定时计划或任务类型: %s", name)
+	}
+	if strings.TrimSpace(payload) != "" {
+		var raw json.RawMessage
+		if err := json.Unmarshal([]byte(payload), &raw); err != nil {
+			return JobInsert{}, fmt.Errorf("payload 不是合法 JSON: %w", err)
+		}
+		params = raw
+	}
+	return newJob(kind, params, settings.MaxAttempts)
+}
+
+func runJobLocally(ctx context.Context, app *App, job JobInsert) int {
+	workerID := fmt.Sprintf("cli-%d", os.Getpid())
+	job.LockedBy = workerID
+	if _, err := insertJobTx(ctx, app.store.pool, job); err != nil {
+		log.Printf("[CRITICAL] 任务写入失败: %v", err)
+		return 1
+	}
+	app.jobs.run(ctx, workerID, JobRecord{ID: job.ID, Kind: job.Kind, Payload: job.Payload, Attempts: 1, MaxAttempts: job.MaxAttempts})
+	status, err := app.store.FetchJobStatus(ctx, job.ID)
+	if err != nil {
+		log.Printf("[CRITICAL] 查询任务状态失败: %v", err)
+		return 1
+	}
+	fmt.Printf("%s %s\n", job.ID, status)
+	if status != jobDone {
+		return 1
+	}
+	return 0
+}
+
+func writeJobsTable(out *os.File, jobs []JobRecord) {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tKIND\tSTATUS\tATTEMPTS\tRUN_AT\tSCHEDULE\tLAST_ERROR")
+	for _, job := range jobs {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d/%d\t%s\t%s\t%s\n",
+			job.ID, job.Kind, job.Status, job.Attempts, job.MaxAttempts,
+			time.Unix(job.RunAt, 0).Local().Format("2006-01-02 15:04:05"),
+			job.Schedule, truncateRunes(job.LastError, 60))
+	}
+	_ = writer.Flush()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDefaultJobSchedules(t *testing.T) {
+	scheduler := NewJobScheduler(&App{settings: defaultSettings()}, defaultSettings().Jobs)
+	if len(scheduler.schedules) != len(defaultSettings().Jobs.Schedules) {
+		t.Fatalf("默认定时计划应全部有效: %d", len(scheduler.schedules))
+	}
+
+	settings := defaultSettings().Jobs
+	settings.Schedules = []JobScheduleConfig{
+		{Name: "a", Kind: jobKindReembed, Cron: "@daily"},
+		{Name: "a", Kind: jobKindReembed, Cron: "@hourly"},
+		{Name: "b", Kind: "unknown", Cron: "@daily"},
+		{Name: "c", Kind: jobKindPruneJobs, Cron: "bad"},
+	}
+	scheduler = NewJobScheduler(&App{settings: defaultSettings()}, settings)
+	if len(scheduler.schedules) != 1 || scheduler.schedules[0].config.Cron != "@daily" {
+		t.Fatalf("重名、类型未知或 cron 无效的计划应跳过: %+v", scheduler.schedules)
+	}
+}
+
+func TestResolveJobRun(t *testing.T) {
+	settings := defaultSettings().Jobs
+	settings.Schedules = append(settings.Schedules, JobScheduleConfig{Name: "weekly_digest", Kind: jobKindDistill, Cron: "@weekly", Payload: map[string]any{"project_key": "demo"}})
+
+	job, err := resolveJobRun(settings, "weekly_digest", "")
+	if err != nil {
+		t.Fatalf("按计划名触发失败: %v", err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(job.Payload, &payload); err != nil || job.Kind != jobKindDistill || payload["project_key"] != "demo" {
+		t.Fatalf("应沿用计划的任务类型与参数: kind=%s payload=%s", job.Kind, job.Payload)
+	}
+
+	job, err = resolveJobRun(settings, jobKindReembed, `{"limit":5}`)
+	if err != nil || job.Kind != jobKindReembed || string(job.Payload) != `{"limit":5}` || job.MaxAttempts != settings.MaxAttempts {
+		t.Fatalf("按任务类型触发错误: %+v err=%v", job, err)
+	}
+
+	if _, err := resolveJobRun(settings, "missing", ""); err == nil {
+		t.Fatalf("未知名称应报错")
+	}
+	if _, err := resolveJobRun(settings, jobKindReembed, `{limit`); err == nil {
+		t.Fatalf("非法 JSON 应报错")
+	}
+}
+
+func TestJobPayloadValidation(t *testing.T) {
+	app := &App{settings: defaultSettings()}
+	cases := map[string]string{
+		jobKindReembed:         `{"limit":0}`,
+		jobKindPruneVersions:   `{"keep":0}`,
+		jobKindPruneJobs:       `{"retention_days":-1}`,
+		jobKindCleanForesights: `{"unexpected":true}`,
+		jobKindForesight:       `{}`,
+		jobKindDistill:         `{"project":"demo"}`,
+	}
+	for kind, payload := range cases {
+		// 参数校验先于数据库访问
+		err := jobHandlers[kind](context.Background(), app, json.RawMessage(payload))
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.Code != "ERR_INVALID_JOB_PAYLOAD" {
+			t.Fatalf("%s 参数 %s 应校验失败: %v", kind, payload, err)
+		}
+	}
+
+	runner := NewJobRunner(app, app.settings.Jobs)
+	err := runner.execute(context.Background(), JobRecord{ID: "task_1", Kind: "unknown"})
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Code != "ERR_UNKNOWN_JOB_KIND" {
+		t.Fatalf("未知任务类型应直接失败: %v", err)
+	}
+}
+
+func TestWorkerPoolDrainReleasesOnTimeout(t *testing.T) {
+	var released []string
+	pool := newWorkerPool("测试", 2, time.Hour, func(_ context.Context, workerIDs []string) (int64, error) {
+		released = workerIDs
+		return int64(len(workerIDs)), nil
+	})
+	block := make(chan struct{})
+	defer close(block)
+	running := make(chan struct{}, 2)
+	ctx, stop := context.WithCancel(context.Background())
+	pool.Start(ctx, "inst", func(context.Context, string) bool {
+		running <- struct{}{}
+		<-block
+		return false
+	})
+	<-running
+	<-running
+	stop()
+	pool.Drain(context.Background(), 20*time.Millisecond)
+	if !slices.Equal(released, []string{"inst/0", "inst/1"}) {
+		t.Fatalf("超时后应放回本池 worker 持有的任务: %v", released)
+	}
+
+	released = nil
+	idle := newWorkerPool("测试", 1, time.Hour, func(_ context.Context, workerIDs []string) (int64, error) {
+		released = workerIDs
+		return 0, nil
+	})
+	idleCtx, stopIdle := context.WithCancel(context.Background())
+	idle.Start(idleCtx, "inst", func(context.Context, string) bool { return false })
+	stopIdle()
+	idle.Drain(context.Background(), time.Second)
+	if released != nil {
+		t.Fatalf("任务全部完成时不应放回任务: %v", released)
+	}
+}
@@ -57,7 +57,7 @@ func NewLLMClient(settings Settings) *LLMClient {
 	}
 }
 
-func (l *LLMClient) Summarize(content string) string {
+func (l *LLMClient) Summarize(ctx context.Context, content string) string {
 	if l.mock {
 		return mockSummary(content)
 	}
@@ -67,7 +67,7 @@ func (l *LLMClient) Summarize(content string) string {
 		return cached
 	}
 	prompt := "请将以下文档内容压缩为 3-5 句摘要，突出核心结论。\n\n内容：\n" + truncate(content, 12000)
-	raw, err := l.client.ChatCompletion(context.Background(), model, prompt, 0.2, 400)
+	raw, err := l.client.ChatCompletion(ctx, model, prompt, 0.2, 400)
 	if err != nil {
 		return ""
 	}
@@ -78,7 +78,7 @@ func (l *LLMClient) Summarize(content string) string {
 	return result
 }
 
-func (l *LLMClient) ExtractTags(content string) []string {
+func (l *LLMClient) ExtractTags(ctx context.Context, content string) []string {
 	if l.mock {
 		return fallbackTags(content)
 	}
@@ -88,10 +88,13 @@ func (l *LLMClient) ExtractTags(content string) []string {
 		return cached
 	}
 	prompt := "请从以下文本中提取 3-10 个简短标签，输出 JSON 数组（字符串列表），不要输出其他内容。\n\n文本：\n" + truncate(content, 8000)
-	raw, err := l.client.ChatCompletion(context.Background(), model, prompt, 0.2, 200)
+	raw, err := l.client.ChatCompletion(ctx, model, prompt, 0.2, 200)
 	if err != nil {
 		result := fallbackTags(content)
-		l.setCachedTags(l.tagsCache, cacheKey, result)
+		// 调用被取消时不缓存降级结果
+		if ctx.Err() == nil {
+			l.setCachedTags(l.tagsCache, cacheKey, result)
+		}
 		return result
 	}
 	cleaned := strings.TrimSpace(raw)
@@ -123,7 +126,7 @@ func (l *LLMClient) ExtractTags(content string) []string {
 	return result
 }
 
-func (l *LLMClient) ExtractIndex(contentType, summary string, tags []string, content string) (MemoryAxes, []string) {
+func (l *LLMClient) ExtractIndex(ctx context.Context, contentType, summary string, tags []string, content string) (MemoryAxes, []string) {
 	if !l.settings.Indexing.Enabled {
 		return MemoryAxes{}, nil
 	}
@@ -157,7 +160,7 @@ summary: %s
 tags: %s
 content: %s`, contentType, truncate(summary, 2000), truncate(strings.Join(tags, ","), 500), truncate(content, 2000))
 
-	raw, err := l.client.ChatCompletion(context.Background(), model, prompt, 0.2, 300)
+	raw, err := l.client.ChatCompletion(ctx, model, prompt, 0.2, 300)
 	if err != nil {
 		return MemoryAxes{}, nil
 	}
@@ -200,7 +203,7 @@ func axesFromMap(data map[string]any) MemoryAxes {
 	}
 }
 
-func (l *LLMClient) ExpandQuery(query string) []string {
+func (l *LLMClient) ExpandQuery(ctx context.Context, query string) []string {
 	if !l.settings.QueryExpand.Enabled {
 		return fallbackQueryKeywords(query, l.settings.QueryExpand.MaxKeywords)
 	}
@@ -220,7 +223,7 @@ func (l *LLMClient) ExpandQuery(query string) []string {
 		return cached
 	}
 	prompt := fmt.Sprintf("请将以下检索问题扩展为 %d 个以内的关键词或同义短语，输出 JSON 数组（字符串列表），不要输出其他内容。\\n\\n问题：\\n%s", maxKeywords, truncate(query, 2000))
-	raw, err := l.client.ChatCompletion(context.Background(), model, prompt, 0.2, 200)
+	raw, err := l.client.ChatCompletion(ctx, model, prompt, 0.2, 200)
 	if err != nil {
 		return fallbackQueryKeywords(query, maxKeywords)
 	}
@@ -463,13 +466,13 @@ func cloneAxes(axes MemoryAxes) MemoryAxes {
 	}
 }
 
-// Distill 将多条记忆摘要蒸馏为一段精炼的知识总结
-func (l *LLMClient) Distill(summaries []string, projectKey string) string {
+// Distill 将多条记忆摘要蒸馏为一段精炼的知识总结；previous 为上一版蒸馏结果，非空时在其基础上增量合并
+func (l *LLMClient) Distill(ctx context.Context, previous string, summaries []string, projectKey string) string {
 	if len(summaries) == 0 {
 		return ""
 	}
 	if l.mock {
-		// mock 模式：拼接前 3 条作为蒸馏结果
+		// mock 模式：上一版结果与前 3 条拼接作为蒸馏结果
 		var parts []string
 		for i, s := range summaries {
 			if i >= 3 {
@@ -477,6 +480,9 @@ func (l *LLMClient) Distill(summaries []string, projectKey string) string {
 			}
 			parts = append(parts, strings.TrimSpace(s))
 		}
+		if strings.TrimSpace(previous) != "" {
+			parts = append([]string{strings.TrimPrefix(strings.TrimSpace(previous), "[蒸馏] ")}, parts...)
+		}
 		return "[蒸馏] " + strings.Join(parts, "；")
 	}
 
@@ -491,6 +497,13 @@ func (l *LLMClient) Distill(summaries []string, projectKey string) string {
 		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, strings.TrimSpace(s)))
 	}
 	summaryBlock := truncate(sb.String(), 12000)
+	previousBlock := ""
+	if strings.TrimSpace(previous) != "" {
+		previousBlock = fmt.Sprintf(`
+已有知识总结（上一次蒸馏的结果，请在此基础上合并新记忆：保留仍然有效的内容，用新记忆更新或修正过时的结论）：
+%s
+`, truncate(strings.TrimSpace(previous), 6000))
+	}
 
 	prompt := fmt.Sprintf(`你是知识蒸馏专家。请将以下来自项目「%s」的多条工作记忆摘要蒸馏为一段精炼的长期知识总结。
 
@@ -501,13 +514,13 @@ func (l *LLMClient) Distill(summaries []string, projectKey string) string {
 4. 保留重要的技术细节和数据指标
 5. 输出结构清晰的知识总结，使用层级标题
 6. 无损核心信息，但去除冗余表述
-
+%s
 原始记忆摘要（共 %d 条）：
 %s
 
-请输出蒸馏后的知识总结：`, projectKey, len(summaries), summaryBlock)
+请输出蒸馏后的知识总结：`, projectKey, previousBlock, len(summaries), summaryBlock)
 
-	raw, err := l.client.ChatCompletion(context.Background(), model, prompt, 0.3, 2000)
+	raw, err := l.client.ChatCompletion(ctx, model, prompt, 0.3, 2000)
 	if err != nil {
 		return ""
 	}
@@ -515,7 +528,7 @@ func (l *LLMClient) Distill(summaries []string, projectKey string) string {
 }
 
 // PredictForesights 基于记忆内容生成 1-3 条前瞻预测
-func (l *LLMClient) PredictForesights(content, summary, contentType string) []string {
+func (l *LLMClient) PredictForesights(ctx context.Context, content, summary, contentType string) []string {
 	if l.mock {
 		return mockForesights(summary)
 	}
@@ -535,7 +548,7 @@ func (l *LLMClient) PredictForesights(content, summary, contentType string) []st
 
 只输出 JSON 数组，不要输出其他内容。`, contentType, truncate(summary, 2000), truncate(content, 6000))
 
-	raw, err := l.client.ChatCompletion(context.Background(), model, prompt, 0.3, 300)
+	raw, err := l.client.ChatCompletion(ctx, model, prompt, 0.3, 300)
 	if err != nil {
 		return nil
 	}
@@ -577,7 +590,7 @@ func mockForesights(summary string) []string {
 	return []string{"可能需要相关文档", "接下来可能进行测试"}
 }
 
-func (l *LLMClient) Rerank(query string, documents []string, topN int) ([]RerankResult, error) {
+func (l *LLMClient) Rerank(ctx context.Context, query string, documents []string, topN int) ([]RerankResult, error) {
 	if l.mock {
 		return nil, nil
 	}
@@ -588,7 +601,7 @@ func (l *LLMClient) Rerank(query string, documents []string, topN int) ([]Rerank
 	if model == "" {
 		return nil, fmt.Errorf("缺少 rerank 模型配置")
 	}
-	return l.client.Rerank(context.Background(), model, query, documents, topN)
+	return l.client.Rerank(ctx, model, query, documents, topN)
 }
 
 // ArbitrateResult 仲裁结果
@@ -598,43 +611,65 @@ const (
 	ArbitrateReplace  ArbitrateResult = "REPLACE"   // 新内容替换旧内容
 	ArbitrateKeepBoth ArbitrateResult = "KEEP_BOTH" // 保留两者，新建记忆
 	ArbitrateSkip     ArbitrateResult = "SKIP"      // 跳过，不写入
+	ArbitrateMerge    ArbitrateResult = "MERGE"     // 合并新旧内容后替换（仅由用户在冲突确认中选择）
 )
 
-// Arbitrate 判断新知识与已有知识的关系
-// 输入：新摘要、旧摘要
-// 输出：REPLACE / KEEP_BOTH / SKIP
-func (l *LLMClient) Arbitrate(newSummary, oldSummary string) ArbitrateResult {
-	if l.mock {
-		// mock 模式：简单规则判断
-		return mockArbitrate(newSummary, oldSummary)
-	}
-
-	model := strings.TrimSpace(l.settings.LLM.ModelArbitrate)
-	if model == "" {
-		model = "qwen-flash" // 默认用便宜快速的模型
-	}
-
-	prompt := fmt.Sprintf(`你是知识库管理员。判断新知识与已有知识的关系。
+// defaultArbitratePrompt 仲裁提示词模板，{old}/{new} 分别替换为旧/新摘要；可通过 llm.prompt_arbitrate 覆盖
+const defaultArbitratePrompt = `你是知识库管理员。判断新知识与已有知识的关系。
 
 【已有知识摘要】
-%s
+{old}
 
 【新知识摘要】
-%s
+{new}
 
 请判断：
 1. 如果新知识是旧知识的更新/修正/补充版本（同一主题的迭代）→ 输出 REPLACE
 2. 如果新旧知识主题不同，只是表述相似（不同主题）→ 输出 KEEP_BOTH
 3. 如果新旧知识几乎完全相同，无新增价值（重复内容）→ 输出 SKIP
 
-只输出一个词：REPLACE 或 KEEP_BOTH 或 SKIP`, oldSummary, newSummary)
+只输出一个词：REPLACE 或 KEEP_BOTH 或 SKIP`
 
-	raw, err := l.client.ChatCompletion(context.Background(), model, prompt, 0.1, 20)
+// Arbitrate 判断新知识与已有知识的关系
+// 输入：新摘要、旧摘要
+// 输出：REPLACE / KEEP_BOTH / SKIP
+func (l *LLMClient) Arbitrate(ctx context.Context, newSummary, oldSummary string) ArbitrateResult {
+	action, err := l.arbitrate(ctx, newSummary, oldSummary)
 	if err != nil {
 		// 出错时保守处理：保留两者
 		return ArbitrateKeepBoth
 	}
+	return action
+}
+
+// arbitrate 与 Arbitrate 相同，但返回模型调用错误（仲裁评估需要区分出错与真实判定）
+func (l *LLMClient) arbitrate(ctx context.Context, newSummary, oldSummary string) (ArbitrateResult, error) {
+	if l.mock {
+		// mock 模式：简单规则判断
+		return mockArbitrate(newSummary, oldSummary), nil
+	}
+
+	model := strings.TrimSpace(l.settings.LLM.ModelArbitrate)
+	if model == "" {
+		model = "qwen-flash" // 默认用便宜快速的模型
+	}
+
+	prompt := buildArbitratePrompt(l.settings.LLM.PromptArbitrate, oldSummary, newSummary)
+	raw, err := l.client.ChatCompletion(ctx, model, prompt, 0.1, 20)
+	if err != nil {
+		return ArbitrateKeepBoth, err
+	}
+	return parseArbitrateResult(raw), nil
+}
+
+func buildArbitratePrompt(template, oldSummary, newSummary string) string {
+	if strings.TrimSpace(template) == "" {
+		template = defaultArbitratePrompt
+	}
+	return strings.NewReplacer("{old}", oldSummary, "{new}", newSummary).Replace(template)
+}
 
+func parseArbitrateResult(raw string) ArbitrateResult {
 	result := strings.TrimSpace(strings.ToUpper(raw))
 	switch {
 	case strings.Contains(result, "REPLACE"):
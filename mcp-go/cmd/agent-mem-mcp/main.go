@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,9 +20,22 @@ import (
 )
 
 func main() {
+	// 子命令：agent-mem eval 运行检索评估黄金集
+	if len(os.Args) > 1 && os.Args[1] == "eval" {
+		os.Exit(runEvalCommand(os.Args[2:]))
+	}
+	// 子命令：agent-mem jobs 查看、手动触发与取消后台任务
+	if len(os.Args) > 1 && os.Args[1] == "jobs" {
+		os.Exit(runJobsCommand(os.Args[2:]))
+	}
+	// 子命令：agent-mem feedback-report 输出检索反馈排序漂移报告
+	if len(os.Args) > 1 && os.Args[1] == "feedback-report" {
+		os.Exit(runFeedbackReportCommand(os.Args[2:]))
+	}
 	var (
 		host      = flag.String("host", defaultHost, "监听地址")
 		port      = flag.Int("port", defaultPort, "监听端口")
+		grpcPort  = flag.Int("grpc-port", 0, "gRPC 监听端口（0 表示不启用）")
 		transport = flag.String("transport", "http", "传输方式：http/sse/streamable/stdio")
 		config    = flag.String("config", "", "配置文件路径")
 		resetDB   = flag.Bool("reset-db", false, "重建数据库表结构（清空数据）")
@@ -48,12 +63,40 @@ func main() {
 
 	server := buildServer(app)
 
+	// 监听跨实例变更通知：失效本地检索缓存并推送资源订阅更新
+	feedCtx, stopFeed := context.WithCancel(context.Background())
+	defer stopFeed()
+	go app.changes.Run(feedCtx)
+
+	// 异步写入与后台任务 worker：退出时停止领取新任务，等待执行中的任务完成
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	app.ingestQueue.Start(workerCtx)
+	app.jobs.Start(workerCtx)
+	if settings.Jobs.Enabled {
+		go NewJobScheduler(app, settings.Jobs).Run(workerCtx)
+	}
+	// drainWorkers 停止领取新任务，等待执行中的任务完成；超时（各自的 drain_timeout_seconds，
+	// 与 HTTP/gRPC 的关闭超时相互独立）后未完成的任务放回队列
+	drainWorkers := func() {
+		stopWorkers()
+		var wg sync.WaitGroup
+		wg.Go(func() { app.ingestQueue.Drain(context.Background()) })
+		wg.Go(func() { app.jobs.Drain(context.Background()) })
+		wg.Wait()
+	}
+
 	switch strings.ToLower(*transport) {
 	case "stdio":
-		ctx := context.Background()
-		if err := server.Run(ctx, &mcp.StdioTransport{}); err != nil {
+		// stdin 关闭或收到 SIGINT/SIGTERM 时结束会话，与 HTTP 模式走同一退出流程
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		err := server.Run(ctx, &mcp.StdioTransport{})
+		stop()
+		drainWorkers()
+		if err != nil && !errors.Is(err, context.Canceled) {
 			log.Fatalf("[CRITICAL] STDIO 传输失败: %v", err)
 		}
+		log.Println("服务已关闭")
 		return
 	case "sse", "streamable", "http", "both":
 		// 继续 HTTP 模式
@@ -88,7 +131,8 @@ func main() {
 	registerHTTPRoutes(mux, app)
 
 	addr := fmt.Sprintf("%s:%d", *host, *port)
-	handler := requireToken(mux, envOrDefault("AGENT_MEM_HTTP_TOKEN", ""))
+	token := envOrDefault("AGENT_MEM_HTTP_TOKEN", "")
+	handler := requireToken(mux, token)
 	srv := &http.Server{
 		Addr:              addr,
 		Handler:           handler,
@@ -111,13 +155,33 @@ func main() {
 		}
 	}()
 
+	var grpcSrv *grpcMemoryServer
+	if *grpcPort > 0 {
+		grpcAddr := fmt.Sprintf("%s:%d", *host, *grpcPort)
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatalf("[CRITICAL] gRPC 监听失败: %v", err)
+		}
+		grpcSrv = newGRPCServer(app, token)
+		go func() {
+			log.Printf("gRPC 服务启动: %s", grpcAddr)
+			if err := grpcSrv.Serve(listener); err != nil {
+				log.Fatalf("[CRITICAL] gRPC 服务异常退出: %v", err)
+			}
+		}()
+	}
+
 	sig := <-quit
 	log.Printf("收到信号 %v，开始优雅关闭...", sig)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	if grpcSrv != nil {
+		grpcSrv.Shutdown(ctx)
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Printf("[CRITICAL] 优雅关闭失败: %v", err)
 	}
+	drainWorkers()
 	log.Println("服务已关闭")
 }
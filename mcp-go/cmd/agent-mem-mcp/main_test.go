@@ -279,3 +279,17 @@ func TestDistanceToSimilarity(t *testing.T) {
 		t.Fatalf("距离0.5应转为相似度0.5")
 	}
 }
+
+func TestSearchInvalidTimeRange(t *testing.T) {
+	input := SearchInput{
+		OwnerID: "personal",
+		Query:   "auth decision",
+		Scope:   "all",
+		Limit:   5,
+		Since:   200,
+		Until:   100,
+	}
+	if err := validateSearchInput(input); err == nil {
+		t.Fatalf("期望 since 晚于 until 时报错")
+	}
+}
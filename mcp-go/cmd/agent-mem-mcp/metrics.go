@@ -18,7 +18,7 @@ func (a *App) Metrics(ctx context.Context, input IndexInput) (MetricsResponse, e
 	cacheKey := metricsCacheKey(normalized)
 	if a.metrics != nil {
 		if cached, ok := a.metrics.Get(cacheKey); ok {
-			return cached, nil
+			return a.withSearchCacheMetrics(cached), nil
 		}
 	}
 
@@ -30,7 +30,7 @@ func (a *App) Metrics(ctx context.Context, input IndexInput) (MetricsResponse, e
 			return MetricsResponse{}, err
 		}
 		if projectID == "" {
-			return MetricsResponse{Content: ""}, nil
+			return a.withSearchCacheMetrics(MetricsResponse{Content: ""}), nil
 		}
 	}
 
@@ -72,7 +72,23 @@ func (a *App) Metrics(ctx context.Context, input IndexInput) (MetricsResponse, e
 	if a.metrics != nil {
 		a.metrics.Set(cacheKey, resp)
 	}
-	return resp, nil
+	return a.withSearchCacheMetrics(resp), nil
+}
+
+// withSearchCacheMetrics 追加检索缓存计数（进程级、实时值，不进入 metrics 缓存）
+func (a *App) withSearchCacheMetrics(resp MetricsResponse) MetricsResponse {
+	if a.searchCache == nil {
+		return resp
+	}
+	stats := a.searchCache.Stats()
+	var builder strings.Builder
+	builder.WriteString(resp.Content)
+	fmt.Fprintf(&builder, "agent_mem_search_cache_hits_total %d\n", stats.Hits)
+	fmt.Fprintf(&builder, "agent_mem_search_cache_misses_total %d\n", stats.Misses)
+	fmt.Fprintf(&builder, "agent_mem_search_cache_invalidations_total %d\n", stats.Invalidations)
+	fmt.Fprintf(&builder, "agent_mem_search_cache_entries %d\n", stats.Entries)
+	resp.Content = builder.String()
+	return resp
 }
 
 func writeGauge(builder *strings.Builder, name string, value any, input IndexInput) {
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// /openapi.json：由 httpRoutes 与 MCP 工具使用的同一组入参/出参结构体生成 OpenAPI 3.1 文档，
+// 结构体 schema 的推断方式与 go-sdk 生成工具 inputSchema/outputSchema 一致（jsonschema.ForType）
+const openAPIVersion = "3.1.0"
+
+// openAPIRecursiveSchemas 自引用类型（path_tree 的 children）ForType 无法推断，改为在 components 中手写并以 $ref 引用
+var openAPIRecursiveSchemas = map[reflect.Type]*jsonschema.Schema{
+	reflect.TypeFor[IndexPathNode](): {
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"name":     {Type: "string"},
+			"count":    {Type: "integer"},
+			"children": {Type: "array", Items: &jsonschema.Schema{Ref: "#/components/schemas/IndexPathNode"}},
+		},
+		Required: []string{"name", "count"},
+	},
+}
+
+var openAPIDocument = sync.OnceValues(func() (map[string]any, error) {
+	return buildOpenAPIDocument(httpRoutes)
+})
+
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "仅支持 GET", "ERR_METHOD")
+		return
+	}
+	doc, err := openAPIDocument()
+	if err != nil {
+		writeAppError(w, err, "openapi")
+		return
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func buildOpenAPIDocument(routes []httpRoute) (map[string]any, error) {
+	schemas := map[string]any{}
+	typeSchemas := map[reflect.Type]*jsonschema.Schema{}
+	for t, schema := range openAPIRecursiveSchemas {
+		schemas[t.Name()] = schema
+		typeSchemas[t] = &jsonschema.Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	// schemaRef 把结构体 schema 收进 components.schemas，按 Go 类型名引用
+	schemaRef := func(t reflect.Type) (map[string]any, *jsonschema.Schema, error) {
+		schema, err := jsonschema.ForType(t, &jsonschema.ForOptions{TypeSchemas: typeSchemas})
+		if err != nil {
+			return nil, nil, err
+		}
+		schemas[t.Name()] = schema
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}, schema, nil
+	}
+	errorRef, _, err := schemaRef(reflect.TypeFor[ErrorResponse]())
+	if err != nil {
+		return nil, err
+	}
+	errorResponse := func(description string) map[string]any {
+		return map[string]any{
+			"description": description,
+			"content":     map[string]any{"application/json": map[string]any{"schema": errorRef}},
+		}
+	}
+
+	paths := map[string]any{}
+	for _, route := range routes {
+		inputRef, inputSchema, err := schemaRef(route.Input)
+		if err != nil {
+			return nil, fmt.Errorf("%s 入参 schema 生成失败: %w", route.Path, err)
+		}
+		outputRef, _, err := schemaRef(route.Output)
+		if err != nil {
+			return nil, fmt.Errorf("%s 出参 schema 生成失败: %w", route.Path, err)
+		}
+
+		operation := map[string]any{
+			"operationId": strings.ReplaceAll(route.Tool, ".", "_"),
+			"summary":     route.Summary,
+			"description": "对应 MCP 工具 " + route.Tool,
+			"tags":        []string{"memories"},
+		}
+		if route.Method == http.MethodGet || route.QueryInput {
+			operation["parameters"] = openAPIQueryParameters(route.Input, inputSchema)
+		} else {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": inputRef}},
+			}
+		}
+		success := map[string]any{
+			"description": "成功",
+			"content":     map[string]any{"application/json": map[string]any{"schema": outputRef}},
+		}
+		if route.TextOutput {
+			success["content"] = map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}}
+		}
+		operation["responses"] = map[string]any{
+			"200":     success,
+			"400":     errorResponse("参数错误"),
+			"401":     errorResponse("未授权（设置了 AGENT_MEM_HTTP_TOKEN 时）"),
+			"404":     errorResponse("资源不存在"),
+			"405":     errorResponse("请求方法不支持"),
+			"500":     errorResponse("服务器错误"),
+			"default": errorResponse("错误"),
+		}
+		paths[route.Path] = map[string]any{strings.ToLower(route.Method): operation}
+	}
+
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":       "agent-mem",
+			"version":     serverVersion,
+			"description": "agent-mem REST 接口，与 MCP 工具一一对应；错误统一返回 ErrorResponse",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+	}, nil
+}
+
+// openAPIQueryParameters 入参结构体的顶层字段按声明顺序展开为 query 参数：
+// 数组可重复传参（?tags=a&tags=b），对象（如 axes）以 JSON 字符串传参
+func openAPIQueryParameters(t reflect.Type, schema *jsonschema.Schema) []any {
+	var params []any
+	for idx := range t.NumField() {
+		field := t.Field(idx)
+		name := jsonFieldName(field)
+		if name == "" {
+			continue
+		}
+		property := schema.Properties[name]
+		if property == nil {
+			continue
+		}
+		param := map[string]any{
+			"name":     name,
+			"in":       "query",
+			"required": slices.Contains(schema.Required, name),
+		}
+		switch {
+		case schemaHasType(property, "object"):
+			param["content"] = map[string]any{"application/json": map[string]any{"schema": property}}
+		case schemaHasType(property, "array"):
+			param["schema"] = property
+			param["style"] = "form"
+			param["explode"] = true
+		default:
+			param["schema"] = property
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func schemaHasType(schema *jsonschema.Schema, typ string) bool {
+	return schema.Type == typ || slices.Contains(schema.Types, typ)
+}
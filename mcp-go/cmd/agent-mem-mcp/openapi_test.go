@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestHTTPRoutesCoverAllTools(t *testing.T) {
+	app := &App{settings: defaultSettings(), changes: NewChangeFeed(nil)}
+	server := buildServer(app)
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("服务端连接失败: %v", err)
+	}
+	defer serverSession.Close()
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("客户端连接失败: %v", err)
+	}
+	defer session.Close()
+
+	tools, err := session.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatalf("列出工具失败: %v", err)
+	}
+	routes := map[string]httpRoute{}
+	for _, route := range httpRoutes {
+		routes[route.Tool] = route
+	}
+	doc, err := buildOpenAPIDocument(httpRoutes)
+	if err != nil {
+		t.Fatalf("生成 OpenAPI 文档失败: %v", err)
+	}
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	for _, tool := range tools.Tools {
+		route, ok := routes[tool.Name]
+		if !ok {
+			t.Fatalf("工具 %s 缺少 REST 接口", tool.Name)
+		}
+		// OpenAPI 中的入参 schema 与 MCP 工具 inputSchema 一致
+		if want, got := normalizeJSON(t, tool.InputSchema), normalizeJSON(t, schemas[route.Input.Name()]); !reflect.DeepEqual(want, got) {
+			t.Fatalf("工具 %s 入参 schema 不一致:\nmcp=%v\nopenapi=%v", tool.Name, want, got)
+		}
+	}
+}
+
+func normalizeJSON(t *testing.T, value any) any {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+	var normalized any
+	_ = json.Unmarshal(data, &normalized)
+	return normalized
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, &App{settings: defaultSettings()})
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("状态码错误: %d", recorder.Code)
+	}
+	var doc struct {
+		OpenAPI string                               `json:"openapi"`
+		Paths   map[string]map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("文档解析失败: %v", err)
+	}
+	if doc.OpenAPI != openAPIVersion || len(doc.Paths) != len(httpRoutes) {
+		t.Fatalf("文档内容错误: openapi=%s paths=%d", doc.OpenAPI, len(doc.Paths))
+	}
+
+	search := doc.Paths["/memories/search"]["get"]
+	params := map[string]map[string]any{}
+	for _, item := range search["parameters"].([]any) {
+		param := item.(map[string]any)
+		params[param["name"].(string)] = param
+	}
+	if params["tags"]["explode"] != true || params["axes"]["content"] == nil || params["query"]["schema"] == nil {
+		t.Fatalf("query 参数展开错误: %v", params)
+	}
+	if _, ok := doc.Paths["/memories/distill"]["post"]["requestBody"]; !ok {
+		t.Fatalf("POST 接口应使用 JSON 请求体")
+	}
+	if _, ok := doc.Paths["/memories/rollback"]["post"]["parameters"]; !ok {
+		t.Fatalf("rollback 以 query 传参")
+	}
+}
+
+func TestHTTPErrorEnvelope(t *testing.T) {
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, &App{settings: defaultSettings()})
+	cases := []struct {
+		method string
+		target string
+		body   string
+		status int
+		code   string
+	}{
+		{http.MethodGet, "/memories/link", "", http.StatusMethodNotAllowed, "ERR_METHOD"},
+		{http.MethodPost, "/memories/link", `{"source_id":"a","extra":1}`, http.StatusBadRequest, "ERR_INVALID_FIELD"},
+		{http.MethodPost, "/memories/link", `{"target_id":"b","relation_type":"RELATED"}`, http.StatusBadRequest, "ERR_INVALID_SOURCE_ID"},
+		{http.MethodGet, "/memories/relations", "", http.StatusBadRequest, "ERR_INVALID_MEMORY_ID"},
+		{http.MethodGet, "/memories/relations?memory_id=m&foo=1", "", http.StatusBadRequest, "ERR_INVALID_FIELD"},
+		{http.MethodGet, "/memories/foresights?owner_id=personal", "", http.StatusBadRequest, "ERR_INVALID_FORESIGHT_TARGET"},
+		{http.MethodPost, "/memories/distill", `{"owner_id":"personal"}`, http.StatusBadRequest, "ERR_INVALID_PROJECT"},
+		{http.MethodPost, "/memories/rollback?arbitration_id=x", "", http.StatusBadRequest, "ERR_INVALID_ARBITRATION_ID"},
+	}
+	for _, tc := range cases {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+		var payload ErrorResponse
+		_ = json.Unmarshal(recorder.Body.Bytes(), &payload)
+		if recorder.Code != tc.status || payload.Code != tc.code {
+			t.Fatalf("%s %s 错误响应不符: status=%d code=%s", tc.method, tc.target, recorder.Code, payload.Code)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// 进度通知：客户端在工具调用中携带 progressToken 时，长耗时流程（写入、检索、蒸馏）按阶段上报进度。
+// 进度统一换算为 0-100；嵌套流程（如蒸馏中的写入、写入中的分批向量化）通过 progressScope 映射到父流程的一段区间
+const progressTotal = 100
+
+type progressKey struct{}
+
+type progressState struct {
+	sink *progressSink
+	// 当前流程在顶层刻度中占据的区间 [base, base+span]
+	base float64
+	span float64
+}
+
+// progressSink 保证上报值单调递增（并发召回源可能乱序完成）
+type progressSink struct {
+	mu     sync.Mutex
+	last   float64
+	notify func(progress float64, message string)
+}
+
+func (s *progressSink) report(progress float64, message string) {
+	s.mu.Lock()
+	if progress <= s.last {
+		s.mu.Unlock()
+		return
+	}
+	s.last = progress
+	s.mu.Unlock()
+	s.notify(progress, message)
+}
+
+func withProgress(ctx context.Context, notify func(progress float64, message string)) context.Context {
+	return context.WithValue(ctx, progressKey{}, progressState{sink: &progressSink{notify: notify}, span: progressTotal})
+}
+
+// withoutProgress 子流程不单独上报（如批量写入中并发预处理的各条目，由批量流程按条目数统一上报）
+func withoutProgress(ctx context.Context) context.Context {
+	return context.WithValue(ctx, progressKey{}, nil)
+}
+
+// toolProgress 请求带 progressToken 时返回可上报进度的 ctx
+func toolProgress(ctx context.Context, req *mcp.CallToolRequest) context.Context {
+	if req == nil || req.Session == nil || req.Params == nil {
+		return ctx
+	}
+	token := req.Params.GetProgressToken()
+	if token == nil {
+		return ctx
+	}
+	session := req.Session
+	return withProgress(ctx, func(progress float64, message string) {
+		_ = session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      progress,
+			Total:         progressTotal,
+			Message:       message,
+		})
+	})
+}
+
+// reportProgress 上报当前流程完成了 done/total
+func reportProgress(ctx context.Context, done, total float64, message string) {
+	state, ok := ctx.Value(progressKey{}).(progressState)
+	if !ok || total <= 0 {
+		return
+	}
+	state.sink.report(state.base+state.span*min(done/total, 1), message)
+}
+
+// progressScope 子流程占当前流程 from/total 到 to/total 的区间
+func progressScope(ctx context.Context, from, to, total float64) context.Context {
+	state, ok := ctx.Value(progressKey{}).(progressState)
+	if !ok || total <= 0 {
+		return ctx
+	}
+	state.base += state.span * from / total
+	state.span = state.span * (to - from) / total
+	return context.WithValue(ctx, progressKey{}, state)
+}
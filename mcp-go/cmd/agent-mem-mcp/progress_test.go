@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestProgressScopeScalesNested(t *testing.T) {
+	var got []float64
+	ctx := withProgress(context.Background(), func(progress float64, _ string) {
+		got = append(got, progress)
+	})
+
+	reportProgress(ctx, 1, 4, "读取")
+	ingestCtx := progressScope(ctx, 2, 4, 4)
+	reportProgress(ingestCtx, 1, 2, "写入")
+	// 子流程中的再次嵌套（分批向量化）
+	embedCtx := progressScope(ingestCtx, 1, 2, 2)
+	reportProgress(embedCtx, 5, 10, "向量化 5/10")
+	// 回退或重复的进度不上报
+	reportProgress(ctx, 1, 4, "乱序")
+	reportProgress(embedCtx, 10, 10, "向量化 10/10")
+	reportProgress(ingestCtx, 2, 2, "写入完成")
+
+	want := []float64{25, 75, 87.5, 100}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("进度换算错误: %v", got)
+	}
+
+	// 没有 progressToken 时上报为空操作
+	reportProgress(progressScope(context.Background(), 0, 1, 2), 1, 1, "无")
+}
+
+func TestToolProgressNotifications(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "slow"}, func(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, struct{}, error) {
+		ctx = toolProgress(ctx, req)
+		for step := 1; step <= 3; step++ {
+			reportProgress(ctx, float64(step), 3, "阶段")
+		}
+		return nil, struct{}{}, nil
+	})
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("服务端连接失败: %v", err)
+	}
+	defer serverSession.Close()
+
+	var (
+		mu       sync.Mutex
+		received []float64
+	)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			if req.Params.Total != progressTotal {
+				t.Errorf("total 应为 %d: %v", progressTotal, req.Params.Total)
+			}
+			received = append(received, req.Params.Progress)
+		},
+	})
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("客户端连接失败: %v", err)
+	}
+	defer session.Close()
+
+	// 不带 progressToken 不推送
+	if _, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "slow"}); err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	// SetProgressToken 在 Meta 为 nil 时不会写入，直接构造 _meta
+	params := &mcp.CallToolParams{Name: "slow", Meta: mcp.Meta{"progressToken": "job-1"}}
+	if _, err := session.CallTool(ctx, params); err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		count := len(received)
+		mu.Unlock()
+		if count >= 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 3 || received[2] != progressTotal {
+		t.Fatalf("进度通知错误: %v", received)
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// MCP prompts：常用记忆工作流，宿主可作为斜杠命令展示；取值时按参数预先检索/拉取时间线填入上下文
+const (
+	promptRecallBeforeTask = "recall-before-task"
+	promptRecordDecision   = "record-decision"
+	promptBugPostmortem    = "record-bug-postmortem"
+	promptSummarizeSession = "summarize-session-into-memory"
+	promptResolveConflict  = "resolve-conflict"
+
+	promptSearchLimit   = 8
+	promptTimelineLimit = 15
+	promptTimelineDays  = 7
+)
+
+var (
+	promptArgProjectKey = &mcp.PromptArgument{Name: "project_key", Title: "项目", Description: "项目 key（工作目录名）", Required: true}
+	promptArgTopic      = &mcp.PromptArgument{Name: "topic", Title: "主题", Description: "主题关键词，用于检索相关记忆与组织 index_path", Required: true}
+	promptArgTags       = &mcp.PromptArgument{Name: "tags", Title: "标签", Description: "逗号分隔的标签（可补全已有标签，保持命名一致）"}
+)
+
+type promptHandler func(ctx context.Context, app *App, args map[string]string) (string, error)
+
+type promptSpec struct {
+	prompt  *mcp.Prompt
+	handler promptHandler
+}
+
+func promptSpecs() []promptSpec {
+	return []promptSpec{
+		{
+			prompt: &mcp.Prompt{
+				Name:        promptRecallBeforeTask,
+				Title:       "任务前召回记忆",
+				Description: "开始任务前按任务描述打包相关记忆（蒸馏摘要、最新结论、相关片段、前瞻）",
+				Arguments: []*mcp.PromptArgument{
+					promptArgProjectKey,
+					{Name: "task", Title: "任务", Description: "即将开始的任务描述", Required: true},
+				},
+			},
+			handler: recallBeforeTaskPrompt,
+		},
+		{
+			prompt: &mcp.Prompt{
+				Name:        promptRecordDecision,
+				Title:       "记录技术决策",
+				Description: "记录技术决策与选型理由，写入前对照已有相关决策",
+				Arguments: []*mcp.PromptArgument{
+					promptArgProjectKey,
+					promptArgTopic,
+					{Name: "decision", Title: "决策", Description: "决策内容（可留空，由当前对话整理）"},
+					{Name: "content_type", Title: "类型", Description: "技术决策用 insight（默认），架构/选型用 plan"},
+					promptArgTags,
+				},
+			},
+			handler: recordDecisionPrompt,
+		},
+		{
+			prompt: &mcp.Prompt{
+				Name:        promptBugPostmortem,
+				Title:       "Bug 复盘",
+				Description: "记录 Bug 现象、根因、修复与预防，写入前检索同类问题",
+				Arguments: []*mcp.PromptArgument{
+					promptArgProjectKey,
+					{Name: "topic", Title: "问题", Description: "问题现象或涉及模块", Required: true},
+					{Name: "component", Title: "组件", Description: "出问题的组件（写入 axes.component）"},
+					promptArgTags,
+				},
+			},
+			handler: bugPostmortemPrompt,
+		},
+		{
+			prompt: &mcp.Prompt{
+				Name:        promptSummarizeSession,
+				Title:       "会话沉淀为记忆",
+				Description: "把本次会话的结论整理为记忆写入，附近期已写入记忆以避免重复",
+				Arguments: []*mcp.PromptArgument{
+					promptArgProjectKey,
+					{Name: "topic", Title: "主题", Description: "会话主题（可选）"},
+				},
+			},
+			handler: summarizeSessionPrompt,
+		},
+		{
+			prompt: &mcp.Prompt{
+				Name:        promptResolveConflict,
+				Title:       "裁决冲突结论",
+				Description: "对比同一主题下的冲突记忆与现有结论，给出裁决并更新 latest 路径",
+				Arguments: []*mcp.PromptArgument{
+					promptArgProjectKey,
+					promptArgTopic,
+				},
+			},
+			handler: resolveConflictPrompt,
+		},
+	}
+}
+
+func registerPrompts(server *mcp.Server, app *App) {
+	for _, spec := range promptSpecs() {
+		server.AddPrompt(spec.prompt, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			args := map[string]string{}
+			for key, value := range req.Params.Arguments {
+				args[key] = strings.TrimSpace(value)
+			}
+			for _, argument := range spec.prompt.Arguments {
+				if argument.Required && args[argument.Name] == "" {
+					return nil, newValidationError("invalid_request", "ERR_INVALID_PROMPT_ARGUMENT", argument.Name+" 不能为空", 400)
+				}
+			}
+			text, err := spec.handler(ctx, app, args)
+			if err != nil {
+				return nil, err
+			}
+			return &mcp.GetPromptResult{
+				Description: spec.prompt.Description,
+				Messages:    []*mcp.PromptMessage{{Role: "user", Content: &mcp.TextContent{Text: text}}},
+			}, nil
+		})
+	}
+}
+
+func recallBeforeTaskPrompt(ctx context.Context, app *App, args map[string]string) (string, error) {
+	pack, err := app.BuildContext(ctx, ContextInput{OwnerID: app.resourceOwner(), ProjectKey: args["project_key"], Task: args["task"]})
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "即将在项目 %s 中开始任务：%s\n\n", args["project_key"], args["task"])
+	builder.WriteString("以下是按任务从记忆库打包的上下文（[mem:ID] 为来源，需要全文时用 mem.get）：\n\n")
+	if strings.TrimSpace(pack.Text) == "" {
+		builder.WriteString("（记忆库中暂无相关记忆）\n")
+	} else {
+		builder.WriteString(pack.Text)
+	}
+	builder.WriteString("\n请先基于以上上下文确认已有的设计、决策与约定，再开始任务；上下文不足时用 mem.search 细查。若任务产生新的结论/方案/决策，完成后用 mem.ingest_memory 写入。")
+	return builder.String(), nil
+}
+
+func recordDecisionPrompt(ctx context.Context, app *App, args map[string]string) (string, error) {
+	related, err := app.promptSearchSection(ctx, args["project_key"], args["topic"], nil)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "请为项目 %s 记录关于「%s」的技术决策。\n\n", args["project_key"], args["topic"])
+	if decision := args["decision"]; decision != "" {
+		fmt.Fprintf(&builder, "决策内容：%s\n\n", decision)
+	} else {
+		builder.WriteString("决策内容：从当前对话中整理。\n\n")
+	}
+	builder.WriteString("## 已有相关记忆\n" + related + "\n")
+	builder.WriteString(`## 写入要求
+- 内容包含：背景、可选方案、最终选择、选型理由与权衡取舍、影响范围
+- 调用 mem.ingest_memory：content_type="` + promptArgOr(args, "content_type", "insight") + `"，index_path=["dialogs","<主题>","latest"]，tags 包含 "decision"` + promptTagsHint(args) + `
+- 若与上面已有的决策冲突：改用 index_path=["conflict","<主题>","..."] 且 tags=["conflict"]，并用 mem.link 以 CONTRADICTS 关联旧记忆
+- 若是对已有决策的补充：写入后用 mem.link 以 SUPPORTS 关联`)
+	return builder.String(), nil
+}
+
+func bugPostmortemPrompt(ctx context.Context, app *App, args map[string]string) (string, error) {
+	related, err := app.promptSearchSection(ctx, args["project_key"], args["topic"], nil)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "请为项目 %s 记录 Bug 复盘：%s\n\n", args["project_key"], args["topic"])
+	builder.WriteString("## 同类问题的已有记忆\n" + related + "\n")
+	builder.WriteString(`## 写入要求
+- 内容包含：问题现象（报错/复现步骤）、根因、修复方案（关键代码或配置）、预防措施
+- 调用 mem.ingest_memory：content_type="testing"，tags 包含 "bug" 与涉及的模块名` + promptTagsHint(args) + `
+` + promptComponentHint(args) + `- 若与上面某条记忆是同一问题的复发：写入后用 mem.link 以 FOLLOWING 关联；若推翻了之前的根因判断，用 CONTRADICTS 关联`)
+	return builder.String(), nil
+}
+
+func summarizeSessionPrompt(ctx context.Context, app *App, args map[string]string) (string, error) {
+	timeline, err := app.Timeline(ctx, TimelineInput{OwnerID: app.resourceOwner(), ProjectKey: args["project_key"], Days: promptTimelineDays, Limit: promptTimelineLimit})
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "请把本次会话中产生的结论整理为项目 %s 的记忆", args["project_key"])
+	if topic := args["topic"]; topic != "" {
+		fmt.Fprintf(&builder, "（主题：%s）", topic)
+	}
+	fmt.Fprintf(&builder, "。\n\n## 近 %d 天已写入的记忆（避免重复写入）\n", promptTimelineDays)
+	if len(timeline.Results) == 0 {
+		builder.WriteString("（暂无）\n")
+	}
+	for _, item := range timeline.Results {
+		fmt.Fprintf(&builder, "- [mem:%s] (%s) %s\n", item.ID, item.ContentType, strings.ReplaceAll(item.Summary, "\n", " "))
+	}
+	builder.WriteString(`
+## 写入要求
+- 每个独立结论单独调用一次 mem.ingest_memory，闲聊、一次性操作不写入
+- 上面已记录且没有变化的结论不要重复写入；有更新时写入新结论，系统会自动仲裁替换
+- content_type 按内容选择：
+
+| 场景 | content_type |
+|:---|:---:|
+| 需求、功能边界、接口契约 | requirement |
+| 架构设计与变更、技术选型 | plan |
+| 实现方案、数据模型、开发规范 | development |
+| 测试策略、Bug 记录 | testing |
+| 踩坑总结、技术决策 | insight |
+| 逆向发现、协议分析 | discovery |
+| 市场研究、策略结论 | research |
+| 部署配置、运维记录 | ops |`)
+	return builder.String(), nil
+}
+
+func resolveConflictPrompt(ctx context.Context, app *App, args map[string]string) (string, error) {
+	conflicts, err := app.promptSearchSection(ctx, args["project_key"], args["topic"], &[]string{"conflict"})
+	if err != nil {
+		return "", err
+	}
+	current, err := app.promptSearchSection(ctx, args["project_key"], args["topic"], nil)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "项目 %s 中关于「%s」的结论存在冲突，请裁决。\n\n", args["project_key"], args["topic"])
+	builder.WriteString("## 标记为冲突的记忆\n" + conflicts + "\n")
+	builder.WriteString("## 该主题的其他相关记忆\n" + current + "\n")
+	builder.WriteString(`## 裁决步骤
+1. 用 mem.get 获取上述记忆全文，必要时用 mem.memory_chain 查看版本演进
+2. 对比双方依据与时间，判断哪条结论仍然成立；无法判断时向用户确认
+3. 把裁决后的结论写入 mem.ingest_memory：index_path=["dialogs","<主题>","latest"]，content 中说明裁决理由
+4. 用 mem.link 以 CONTRADICTS 关联被推翻的记忆；若最近一次 REPLACE 替换错误，可用 mem.rollback 撤销`)
+	return builder.String(), nil
+}
+
+func promptArgOr(args map[string]string, name, fallback string) string {
+	if value := args[name]; value != "" {
+		return value
+	}
+	return fallback
+}
+
+func promptTagsHint(args map[string]string) string {
+	tags := normalizeTags(strings.Split(args["tags"], ","))
+	if len(tags) == 0 {
+		return ""
+	}
+	return "，另加标签：" + strings.Join(tags, ", ")
+}
+
+func promptComponentHint(args map[string]string) string {
+	if args["component"] == "" {
+		return ""
+	}
+	return "- axes.component 填写：" + args["component"] + "\n"
+}
+
+// promptSearchSection 检索主题相关记忆并渲染为引用列表；不记录曝光（并非用户发起的检索）
+func (a *App) promptSearchSection(ctx context.Context, projectKey, topic string, tags *[]string) (string, error) {
+	owner := a.resourceOwner()
+	// 主题是自由文本：去掉引号，避免被当作未闭合的短语语法
+	query := strings.TrimSpace(strings.ReplaceAll(topic, `"`, " "))
+	resp, err := a.searchWithOptions(ctx, SearchInput{
+		OwnerID:    owner,
+		ProjectKey: projectKey,
+		Query:      query,
+		Scope:      "all",
+		Tags:       tags,
+		Limit:      promptSearchLimit,
+	}, a.internalSearchOptions(ctx, owner))
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Results) == 0 {
+		return "（无）\n", nil
+	}
+	var builder strings.Builder
+	for _, result := range resp.Results {
+		fmt.Fprintf(&builder, "- [mem:%s] (%s) %s\n", result.ID, result.ContentType, strings.ReplaceAll(strings.TrimSpace(result.Snippet), "\n", " "))
+	}
+	return builder.String(), nil
+}
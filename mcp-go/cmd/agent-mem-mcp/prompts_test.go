@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestPromptsRegistered(t *testing.T) {
+	server := buildServer(&App{settings: defaultSettings()})
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("服务端连接失败: %v", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("客户端连接失败: %v", err)
+	}
+	defer session.Close()
+
+	listed, err := session.ListPrompts(ctx, nil)
+	if err != nil {
+		t.Fatalf("列出 prompts 失败: %v", err)
+	}
+	names := map[string]*mcp.Prompt{}
+	for _, prompt := range listed.Prompts {
+		names[prompt.Name] = prompt
+	}
+	for _, name := range []string{promptRecallBeforeTask, promptRecordDecision, promptBugPostmortem, promptSummarizeSession, promptResolveConflict} {
+		prompt, ok := names[name]
+		if !ok {
+			t.Fatalf("缺少 prompt: %s", name)
+		}
+		if len(prompt.Arguments) == 0 || prompt.Arguments[0].Name != "project_key" || !prompt.Arguments[0].Required {
+			t.Fatalf("prompt %s 首个参数应为必填的 project_key", name)
+		}
+	}
+
+	// 必填参数缺失时在访问数据库前即报错
+	_, err = session.GetPrompt(ctx, &mcp.GetPromptParams{Name: promptRecordDecision, Arguments: map[string]string{"project_key": "demo", "topic": "  "}})
+	if err == nil || !strings.Contains(err.Error(), "topic") {
+		t.Fatalf("缺少 topic 应报错: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// parsedQuery mem.search 结构化查询解析结果：
+// 过滤操作符（type:/tag:/-tag:/path:/after:/before:）转为 SearchInput 过滤条件，
+// 其余词与 "短语" 组成检索文本（用于向量与词法召回），短语在 BM25 召回中要求原文连续命中
+type parsedQuery struct {
+	Text         string
+	Phrases      []string
+	ContentTypes []string
+	Tags         []string
+	ExcludeTags  []string
+	IndexPath    []string
+	Since        int64
+	Until        int64
+	// Structured 为 false 表示纯自然语言查询，检索行为与以往完全一致
+	Structured bool
+}
+
+// parseSearchQuery 解析查询语法，例如：type:plan tag:auth -tag:deprecated path:dialogs/api/* after:2026-09-01 "exact phrase" pgvector。
+// 未识别的 key:value（如 URL、"error: xxx"）、缺少取值的操作符（如 "type: null"）与未闭合的引号均按普通词处理；
+// 引号仅在词首生效，词中的引号（如 don"t）原样保留
+func parseSearchQuery(raw string) (parsedQuery, error) {
+	var parsed parsedQuery
+	var textParts []string
+	runes := []rune(strings.TrimSpace(raw))
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		if runes[i] == '"' {
+			if phrase, next, ok := readQuoted(runes, i); ok {
+				i = next
+				parsed.Structured = true
+				if phrase == "" {
+					continue
+				}
+				parsed.Phrases = append(parsed.Phrases, phrase)
+				textParts = append(textParts, phrase)
+				continue
+			}
+		}
+
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != ':' {
+			i++
+		}
+		key := string(runes[start:i])
+		operator := i < len(runes) && runes[i] == ':' && isQueryOperator(key)
+		if !operator {
+			i = skipWord(runes, i)
+			textParts = append(textParts, string(runes[start:i]))
+			continue
+		}
+
+		i++ // 跳过冒号
+		var value string
+		quoted := false
+		if i < len(runes) && runes[i] == '"' {
+			value, i, quoted = readQuoted(runes, i)
+		}
+		if !quoted {
+			valueStart := i
+			i = skipWord(runes, i)
+			value = string(runes[valueStart:i])
+		}
+		value = strings.TrimSpace(value)
+		if value == "" || (!quoted && strings.HasPrefix(value, `"`)) {
+			// 缺少取值或引号未闭合：整个词按普通词处理
+			i = skipWord(runes, i)
+			textParts = append(textParts, string(runes[start:i]))
+			continue
+		}
+		if err := parsed.applyOperator(strings.ToLower(key), value); err != nil {
+			return parsedQuery{}, err
+		}
+		parsed.Structured = true
+	}
+	parsed.Text = strings.Join(textParts, " ")
+	if parsed.Structured && strings.TrimSpace(parsed.Text) == "" {
+		return parsedQuery{}, newValidationError("invalid_request", "ERR_INVALID_QUERY", "查询语法错误：除过滤条件外至少需要一个检索词或短语", 400)
+	}
+	return parsed, nil
+}
+
+func isQueryOperator(key string) bool {
+	switch strings.ToLower(key) {
+	case "type", "tag", "-tag", "path", "after", "before":
+		return true
+	}
+	return false
+}
+
+func (p *parsedQuery) applyOperator(key, value string) error {
+	switch key {
+	case "type":
+		p.ContentTypes = appendUnique(p.ContentTypes, value)
+	case "tag":
+		p.Tags = appendUnique(p.Tags, value)
+	case "-tag":
+		p.ExcludeTags = appendUnique(p.ExcludeTags, value)
+	case "path":
+		if len(p.IndexPath) > 0 {
+			return newValidationError("invalid_request", "ERR_INVALID_QUERY", "查询语法错误：path: 只能出现一次", 400)
+		}
+		value = strings.TrimSuffix(strings.TrimSuffix(value, "*"), "/")
+		var segments []string
+		for _, segment := range strings.Split(value, "/") {
+			if segment = strings.TrimSpace(segment); segment != "" {
+				segments = append(segments, segment)
+			}
+		}
+		if len(segments) == 0 {
+			return newValidationError("invalid_request", "ERR_INVALID_QUERY", "查询语法错误：path: 缺少取值", 400)
+		}
+		p.IndexPath = segments
+	case "after", "before":
+		ts, err := parseTimeQuery(value)
+		if err != nil {
+			return newValidationError("invalid_request", "ERR_INVALID_QUERY", "查询语法错误："+key+": 时间格式错误（支持 YYYY-MM-DD、RFC3339 或秒级时间戳）", 400)
+		}
+		if key == "after" {
+			p.Since = ts
+		} else if _, dateOnly := parseDateOnly(value); dateOnly {
+			// before:YYYY-MM-DD 不含当天（until 为闭区间上界）
+			p.Until = ts - 1
+		} else {
+			p.Until = ts
+		}
+	}
+	return nil
+}
+
+// readQuoted 读取从 start（引号位置）开始的引号内容，返回内容与引号后的位置；引号未闭合时 ok 为 false
+func readQuoted(runes []rune, start int) (string, int, bool) {
+	for end := start + 1; end < len(runes); end++ {
+		if runes[end] == '"' {
+			return strings.TrimSpace(string(runes[start+1 : end])), end + 1, true
+		}
+	}
+	return "", start, false
+}
+
+// skipWord 返回从 i 开始到下一个空白字符的位置
+func skipWord(runes []rune, i int) int {
+	for i < len(runes) && !unicode.IsSpace(runes[i]) {
+		i++
+	}
+	return i
+}
+
+func appendUnique(values []string, value string) []string {
+	if stringInSlice(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+// applySearchQuerySyntax 将查询中的过滤操作符合并进 SearchInput（query 保持原文，检索时再取文本与短语）：
+// type: 与 content_types 取并集，tag:/-tag: 追加到 tags/exclude_tags，after:/before: 与 since/until 取更严格者，
+// path: 与 index_path 同时给出且不一致时报错
+func applySearchQuerySyntax(input SearchInput) (SearchInput, error) {
+	parsed, err := parseSearchQuery(input.Query)
+	if err != nil {
+		return input, err
+	}
+	if !parsed.Structured {
+		return input, nil
+	}
+	input.ContentTypes = mergeStringListPtr(input.ContentTypes, parsed.ContentTypes)
+	input.Tags = mergeStringListPtr(input.Tags, parsed.Tags)
+	input.ExcludeTags = mergeStringListPtr(input.ExcludeTags, parsed.ExcludeTags)
+	if len(parsed.IndexPath) > 0 {
+		if input.IndexPath != nil && len(*input.IndexPath) > 0 && strings.Join(*input.IndexPath, "/") != strings.Join(parsed.IndexPath, "/") {
+			return input, newValidationError("invalid_request", "ERR_INVALID_QUERY", "查询语法错误：path: 与 index_path 参数冲突", 400)
+		}
+		indexPath := parsed.IndexPath
+		input.IndexPath = &indexPath
+	}
+	if parsed.Since > input.Since {
+		input.Since = parsed.Since
+	}
+	if parsed.Until > 0 && (input.Until == 0 || parsed.Until < input.Until) {
+		input.Until = parsed.Until
+	}
+	return input, nil
+}
+
+func mergeStringListPtr(current *[]string, extra []string) *[]string {
+	if len(extra) == 0 {
+		return current
+	}
+	var merged []string
+	if current != nil {
+		merged = append(merged, *current...)
+	}
+	merged = uniqueStrings(append(merged, extra...))
+	return &merged
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSearchQueryStructured(t *testing.T) {
+	parsed, err := parseSearchQuery(`type:plan tag:auth -tag:deprecated path:dialogs/api/* after:2026-09-01 "exact phrase" pgvector`)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if !parsed.Structured {
+		t.Fatalf("应识别为结构化查询")
+	}
+	if parsed.Text != "exact phrase pgvector" {
+		t.Fatalf("检索文本错误: %q", parsed.Text)
+	}
+	if !reflect.DeepEqual(parsed.Phrases, []string{"exact phrase"}) {
+		t.Fatalf("短语解析错误: %v", parsed.Phrases)
+	}
+	if !reflect.DeepEqual(parsed.ContentTypes, []string{"plan"}) || !reflect.DeepEqual(parsed.Tags, []string{"auth"}) || !reflect.DeepEqual(parsed.ExcludeTags, []string{"deprecated"}) {
+		t.Fatalf("过滤条件解析错误: %+v", parsed)
+	}
+	if !reflect.DeepEqual(parsed.IndexPath, []string{"dialogs", "api"}) {
+		t.Fatalf("path 解析错误: %v", parsed.IndexPath)
+	}
+	want := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC).Unix()
+	if parsed.Since != want {
+		t.Fatalf("after 解析错误: %d", parsed.Since)
+	}
+}
+
+func TestParseSearchQueryNaturalLanguageUnchanged(t *testing.T) {
+	for _, query := range []string{"数据库连接池怎么配置", "error: timeout at http://example.com/a", `don"t panic`, "error type: null", "tag: foo", `"未闭合短语`, `登录 "超时 重试`, `tag:"未闭合 标签`} {
+		parsed, err := parseSearchQuery(query)
+		if err != nil {
+			t.Fatalf("自然语言查询不应报错: %q %v", query, err)
+		}
+		if parsed.Structured || parsed.Text != query {
+			t.Fatalf("自然语言查询应原样保留: %q -> %+v", query, parsed)
+		}
+	}
+}
+
+func TestParseSearchQueryOperatorWithoutValueKeepsOtherFilters(t *testing.T) {
+	parsed, err := parseSearchQuery(`tag:auth type: "登录 超时" "未闭合`)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.Tags, []string{"auth"}) || len(parsed.ContentTypes) != 0 {
+		t.Fatalf("缺少取值的 type: 不应成为过滤条件: %+v", parsed)
+	}
+	if !reflect.DeepEqual(parsed.Phrases, []string{"登录 超时"}) {
+		t.Fatalf("短语解析错误: %v", parsed.Phrases)
+	}
+	if parsed.Text != `type: 登录 超时 "未闭合` {
+		t.Fatalf("缺少取值的操作符与未闭合引号应按普通词保留: %q", parsed.Text)
+	}
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	for _, query := range []string{"after:yesterday 部署", "type:plan tag:auth", "path:a path:b 查询"} {
+		_, err := parseSearchQuery(query)
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.Code != "ERR_INVALID_QUERY" {
+			t.Fatalf("应返回 ERR_INVALID_QUERY: %q %v", query, err)
+		}
+	}
+}
+
+func TestApplySearchQuerySyntaxMerges(t *testing.T) {
+	tags := []string{"go"}
+	input := SearchInput{Query: "tag:auth before:2026-10-01 登录", Tags: &tags, Until: time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC).Unix()}
+	merged, err := applySearchQuerySyntax(input)
+	if err != nil {
+		t.Fatalf("合并失败: %v", err)
+	}
+	if merged.Tags == nil || !reflect.DeepEqual(*merged.Tags, []string{"go", "auth"}) {
+		t.Fatalf("tags 合并错误: %v", merged.Tags)
+	}
+	if merged.Until != time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC).Unix()-1 {
+		t.Fatalf("until 应取更严格者且不含 before 当天: %d", merged.Until)
+	}
+	if merged.Query != input.Query {
+		t.Fatalf("query 应保持原文")
+	}
+
+	path := []string{"other"}
+	if _, err := applySearchQuerySyntax(SearchInput{Query: "path:dialogs 查询", IndexPath: &path}); err == nil {
+		t.Fatalf("path 与 index_path 冲突应报错")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// resourceSubscriptions 本实例被订阅的资源 URI（按订阅次数计数）；变更时只对已订阅的 URI 推送，
+// 会话级的订阅关系由 SDK 维护
+type resourceSubscriptions struct {
+	mu   sync.Mutex
+	uris map[string]int
+}
+
+func newResourceSubscriptions() *resourceSubscriptions {
+	return &resourceSubscriptions{uris: map[string]int{}}
+}
+
+func (s *resourceSubscriptions) subscribe(_ context.Context, req *mcp.SubscribeRequest) error {
+	uri := req.Params.URI
+	if !strings.HasPrefix(uri, resourceScheme) {
+		return mcp.ResourceNotFoundError(uri)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uris[uri]++
+	return nil
+}
+
+func (s *resourceSubscriptions) unsubscribe(_ context.Context, req *mcp.UnsubscribeRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.uris[req.Params.URI] <= 1 {
+		delete(s.uris, req.Params.URI)
+	} else {
+		s.uris[req.Params.URI]--
+	}
+	return nil
+}
+
+// match 返回受变更影响且已被订阅的 URI：精确匹配 changedResourceURIs；
+// 删除项目时其下所有路径/最新结论订阅均受影响；resync 时全部订阅都需要重新读取
+func (s *resourceSubscriptions) match(event changeEvent) []string {
+	changed := map[string]bool{}
+	for _, uri := range changedResourceURIs(event) {
+		changed[uri] = true
+	}
+	var deletedPrefixes []string
+	if event.Kind == changeKindDelete && event.ProjectKey != "" {
+		deletedPrefixes = []string{pathResourceURI(event.ProjectKey, nil)}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matched []string
+	for uri := range s.uris {
+		if event.Kind == changeKindResync || changed[uri] || hasAnyPrefix(uri, deletedPrefixes) {
+			matched = append(matched, uri)
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+func hasAnyPrefix(value string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// changedResourceURIs 一次变更影响的资源：项目列表（统计变化）、项目概览、最新结论、
+// 变更记忆本身，以及新旧 index_path 的每一级前缀节点
+func changedResourceURIs(event changeEvent) []string {
+	uris := []string{resourceProjectsURI}
+	if event.ProjectKey != "" {
+		uris = append(uris, projectResourceURI(event.ProjectKey), latestResourceURI(event.ProjectKey))
+		for _, path := range event.IndexPaths {
+			for depth := 1; depth <= len(path); depth++ {
+				uris = append(uris, pathResourceURI(event.ProjectKey, path[:depth]))
+			}
+		}
+	}
+	for _, memoryID := range event.MemoryIDs {
+		uris = append(uris, memoryResourceURI(memoryID))
+	}
+	return uniqueStrings(uris)
+}
+
+// registerResourceNotifications 变更通知 → notifications/resources/updated；
+// 项目新建或删除时资源列表变化，另发 notifications/resources/list_changed
+func registerResourceNotifications(server *mcp.Server, app *App, subscriptions *resourceSubscriptions) {
+	if app.changes == nil {
+		return
+	}
+	app.changes.OnChange(func(event changeEvent) {
+		// 资源按当前实例的 owner 暴露，其他 owner 的变更与本实例的资源无关
+		if event.Kind != changeKindResync && event.OwnerID != app.resourceOwner() {
+			return
+		}
+		ctx := context.Background()
+		for _, uri := range subscriptions.match(event) {
+			_ = server.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: uri})
+		}
+		if event.ProjectCreated || event.Kind == changeKindDelete || event.Kind == changeKindResync {
+			// SDK 没有直接发送 list_changed 的接口：重新注册同一资源会触发（去抖后的）list_changed 广播
+			server.AddResource(projectsResource(), app.readProjectsResource)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestChangeEventEncodeTruncates(t *testing.T) {
+	event := changeEvent{Kind: changeKindIngest, Origin: "node-a", OwnerID: "personal", ProjectKey: "demo"}
+	for idx := 0; idx < changeMaxMemoryIDs+10; idx++ {
+		event.MemoryIDs = append(event.MemoryIDs, fmt.Sprintf("mem_%d", idx))
+	}
+	event.IndexPaths = [][]string{nil, {"dialogs", "api"}}
+	payload, err := encodeChangeEvent(event)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if len(payload) >= 8000 {
+		t.Fatalf("负载超过 NOTIFY 上限: %d", len(payload))
+	}
+	decoded, err := decodeChangeEvent(payload)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if len(decoded.MemoryIDs) != changeMaxMemoryIDs || !reflect.DeepEqual(decoded.IndexPaths, [][]string{{"dialogs", "api"}}) {
+		t.Fatalf("截断结果错误: ids=%d paths=%v", len(decoded.MemoryIDs), decoded.IndexPaths)
+	}
+	if _, err := decodeChangeEvent(`{"owner_id":"personal"}`); err == nil {
+		t.Fatalf("缺少 kind 应解码失败")
+	}
+}
+
+func TestChangedResourceURIs(t *testing.T) {
+	event := changeEvent{
+		Kind:       changeKindReplace,
+		ProjectKey: "demo",
+		MemoryIDs:  []string{"mem_1"},
+		IndexPaths: [][]string{{"dialogs", "api", "latest"}, {"dialogs", "db"}},
+	}
+	want := []string{
+		"mem://projects",
+		"mem://projects/demo",
+		"mem://project/demo/latest",
+		"mem://path/demo/dialogs",
+		"mem://path/demo/dialogs/api",
+		"mem://path/demo/dialogs/api/latest",
+		"mem://path/demo/dialogs/db",
+		"mem://memory/mem_1",
+	}
+	if got := changedResourceURIs(event); !reflect.DeepEqual(got, want) {
+		t.Fatalf("受影响资源错误: %v", got)
+	}
+}
+
+func TestResourceSubscriptionsMatch(t *testing.T) {
+	subscriptions := newResourceSubscriptions()
+	ctx := context.Background()
+	for _, uri := range []string{"mem://memory/mem_1", "mem://path/demo/dialogs/api", "mem://path/other/x", "mem://projects/demo"} {
+		if err := subscriptions.subscribe(ctx, &mcp.SubscribeRequest{Params: &mcp.SubscribeParams{URI: uri}}); err != nil {
+			t.Fatalf("订阅失败: %v", err)
+		}
+	}
+	if err := subscriptions.subscribe(ctx, &mcp.SubscribeRequest{Params: &mcp.SubscribeParams{URI: "file:///etc/passwd"}}); err == nil {
+		t.Fatalf("非 mem:// 资源应拒绝订阅")
+	}
+
+	got := subscriptions.match(changeEvent{Kind: changeKindIngest, ProjectKey: "demo", MemoryIDs: []string{"mem_2"}, IndexPaths: [][]string{{"dialogs", "api", "v2"}}})
+	if !reflect.DeepEqual(got, []string{"mem://path/demo/dialogs/api", "mem://projects/demo"}) {
+		t.Fatalf("写入匹配错误: %v", got)
+	}
+	got = subscriptions.match(changeEvent{Kind: changeKindDelete, ProjectKey: "other"})
+	if !reflect.DeepEqual(got, []string{"mem://path/other/x"}) {
+		t.Fatalf("删除项目应匹配其下所有路径: %v", got)
+	}
+	if got = subscriptions.match(changeEvent{Kind: changeKindResync}); len(got) != 4 {
+		t.Fatalf("resync 应匹配全部订阅: %v", got)
+	}
+
+	_ = subscriptions.unsubscribe(ctx, &mcp.UnsubscribeRequest{Params: &mcp.UnsubscribeParams{URI: "mem://projects/demo"}})
+	if got = subscriptions.match(changeEvent{Kind: changeKindIngest, ProjectKey: "demo"}); len(got) != 0 {
+		t.Fatalf("取消订阅后不应匹配: %v", got)
+	}
+}
+
+func TestResourceUpdatedNotification(t *testing.T) {
+	app := &App{settings: defaultSettings(), changes: NewChangeFeed(nil)}
+	server := buildServer(app)
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("服务端连接失败: %v", err)
+	}
+	defer serverSession.Close()
+
+	updated := make(chan string, 4)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, &mcp.ClientOptions{
+		ResourceUpdatedHandler: func(_ context.Context, req *mcp.ResourceUpdatedNotificationRequest) {
+			updated <- req.Params.URI
+		},
+	})
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("客户端连接失败: %v", err)
+	}
+	defer session.Close()
+
+	caps := session.InitializeResult().Capabilities
+	if caps.Resources == nil || !caps.Resources.Subscribe {
+		t.Fatalf("应声明 resources.subscribe 能力")
+	}
+	memoryURI := memoryResourceURI("mem_1")
+	if err := session.Subscribe(ctx, &mcp.SubscribeParams{URI: memoryURI}); err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+
+	// 其他 owner 的变更不推送；同 owner 的变更（含其他实例）推送
+	app.changes.dispatch(changeEvent{Kind: changeKindReplace, Origin: "node-b", OwnerID: "someone-else", MemoryIDs: []string{"mem_1"}})
+	app.changes.dispatch(changeEvent{Kind: changeKindReplace, Origin: "node-b", OwnerID: app.resourceOwner(), ProjectKey: "demo", MemoryIDs: []string{"mem_1"}})
+	select {
+	case uri := <-updated:
+		if uri != memoryURI {
+			t.Fatalf("通知 URI 错误: %s", uri)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("未收到 resources/updated 通知")
+	}
+	select {
+	case uri := <-updated:
+		t.Fatalf("其他 owner 的变更或未订阅资源不应通知: %s", uri)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// MCP 资源：客户端可浏览并把记忆作为资源附加到上下文（无需调用工具）
+const (
+	resourceScheme        = "mem://"
+	resourceProjectsURI   = "mem://projects"
+	resourceProjectPrefix = "mem://projects/"
+	resourceMemoryPrefix  = "mem://memory/"
+	resourcePathPrefix    = "mem://path/"
+	resourceLatestPrefix  = "mem://project/"
+	resourceLatestSuffix  = "/latest"
+
+	// resources/list 每页条目数；每个项目读取的 index_path 上限
+	resourcesPageSize       = 100
+	resourceProjectBatch    = 20
+	resourcePathLimit       = 500
+	resourcePathMemoryLimit = 50
+	resourceLatestLimit     = 20
+)
+
+// registerResources 注册资源与资源模板，并接管 resources/list 以按项目与 index_path 树动态分页
+func registerResources(server *mcp.Server, app *App) {
+	server.AddResource(projectsResource(), app.readProjectsResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "mem://projects/{project_key}",
+		Name:        "project",
+		Title:       "项目概览",
+		Description: "项目的标签/纵横轴聚合与 index_path 树",
+		MIMEType:    "application/json",
+	}, app.readProjectResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "mem://memory/{id}",
+		Name:        "memory",
+		Title:       "记忆全文",
+		Description: "单条记忆的完整内容",
+		MIMEType:    "text/markdown",
+	}, app.readMemoryResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "mem://path/{project_key}/{+index_path}",
+		Name:        "index-path",
+		Title:       "索引路径",
+		Description: "index_path 下的子路径与记忆列表",
+		MIMEType:    "text/markdown",
+	}, app.readPathResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "mem://project/{project_key}/latest",
+		Name:        "project-latest",
+		Title:       "最新结论",
+		Description: "项目中 latest 路径下的最新结论与蒸馏摘要",
+		MIMEType:    "text/markdown",
+	}, app.readLatestResource)
+
+	server.AddReceivingMiddleware(func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method != "resources/list" {
+				return next(ctx, method, req)
+			}
+			cursor := ""
+			if params, ok := req.GetParams().(*mcp.ListResourcesParams); ok && params != nil {
+				cursor = params.Cursor
+			}
+			return app.ListResources(ctx, cursor)
+		}
+	})
+}
+
+func projectsResource() *mcp.Resource {
+	return &mcp.Resource{
+		URI:         resourceProjectsURI,
+		Name:        "projects",
+		Title:       "项目列表",
+		Description: "当前 owner 的全部项目及记忆统计",
+		MIMEType:    "application/json",
+	}
+}
+
+// escapeURISegment 仅保留 RFC 3986 unreserved 字符，其余百分号编码（与 URI 模板的简单展开一致）
+func escapeURISegment(value string) string {
+	var builder strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9', b == '-', b == '.', b == '_', b == '~':
+			builder.WriteByte(b)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", b)
+		}
+	}
+	return builder.String()
+}
+
+func projectResourceURI(projectKey string) string {
+	return resourceProjectPrefix + escapeURISegment(projectKey)
+}
+
+func memoryResourceURI(memoryID string) string {
+	return resourceMemoryPrefix + escapeURISegment(memoryID)
+}
+
+func latestResourceURI(projectKey string) string {
+	return resourceLatestPrefix + escapeURISegment(projectKey) + resourceLatestSuffix
+}
+
+func pathResourceURI(projectKey string, indexPath []string) string {
+	segments := make([]string, 0, len(indexPath))
+	for _, segment := range indexPath {
+		segments = append(segments, escapeURISegment(segment))
+	}
+	return resourcePathPrefix + escapeURISegment(projectKey) + "/" + strings.Join(segments, "/")
+}
+
+// parseResourceSegments 去掉前缀/后缀后按 / 切分并逐段解码
+func parseResourceSegments(uri, prefix, suffix string) ([]string, bool) {
+	if !strings.HasPrefix(uri, prefix) || !strings.HasSuffix(uri, suffix) {
+		return nil, false
+	}
+	rest := strings.TrimSuffix(strings.TrimPrefix(uri, prefix), suffix)
+	if rest == "" {
+		return nil, false
+	}
+	var segments []string
+	for _, raw := range strings.Split(rest, "/") {
+		segment, err := url.PathUnescape(raw)
+		if err != nil || strings.TrimSpace(segment) == "" {
+			return nil, false
+		}
+		segments = append(segments, segment)
+	}
+	return segments, true
+}
+
+func (a *App) resourceOwner() string {
+	owner, err := resolveOwnerID("", a.settings)
+	if err != nil {
+		return defaultOwnerID
+	}
+	return owner
+}
+
+// ListResources resources/list：首项为项目列表，随后按项目（最近更新在前）列出项目概览、最新结论与 index_path 树的每个节点。
+// 游标记录当前项目的排序键与已输出条目数，项目内条目顺序稳定，翻页不重不漏
+func (a *App) ListResources(ctx context.Context, rawCursor string) (*mcp.ListResourcesResult, error) {
+	cursor, err := decodeCursor(rawCursor, cursorKindResources)
+	if err != nil {
+		return nil, err
+	}
+	owner := a.resourceOwner()
+	result := &mcp.ListResourcesResult{Resources: []*mcp.Resource{}}
+	if cursor == nil {
+		result.Resources = append(result.Resources, &mcp.Resource{URI: resourceProjectsURI, Name: "projects", Title: "项目列表", MIMEType: "application/json"})
+	}
+
+	// 先补完游标所在项目的剩余条目
+	var after *pageCursor
+	if cursor != nil {
+		project := ProjectListItem{ProjectKey: cursor.Key, ProjectName: cursor.Key, LatestTs: cursor.Ts}
+		entries, err := a.projectResources(ctx, owner, project)
+		if err != nil {
+			return nil, err
+		}
+		if done := a.appendResourcePage(result, project, entries, int(cursor.ID)); !done {
+			return result, nil
+		}
+		after = &pageCursor{Ts: cursor.Ts, Key: cursor.Key}
+	}
+
+	for {
+		projects, err := a.store.ListProjects(ctx, owner, after, resourceProjectBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			entries, err := a.projectResources(ctx, owner, project)
+			if err != nil {
+				return nil, err
+			}
+			if done := a.appendResourcePage(result, project, entries, 0); !done {
+				return result, nil
+			}
+		}
+		if len(projects) < resourceProjectBatch {
+			return result, nil
+		}
+		last := projects[len(projects)-1]
+		after = &pageCursor{Ts: last.LatestTs, Key: last.ProjectKey}
+	}
+}
+
+// appendResourcePage 从 offset 起追加项目条目；页满时设置 NextCursor 并返回 false
+func (a *App) appendResourcePage(result *mcp.ListResourcesResult, project ProjectListItem, entries []*mcp.Resource, offset int) bool {
+	for idx := offset; idx < len(entries); idx++ {
+		if len(result.Resources) >= resourcesPageSize {
+			result.NextCursor = encodeCursor(pageCursor{Kind: cursorKindResources, Ts: project.LatestTs, Key: project.ProjectKey, ID: int64(idx)})
+			return false
+		}
+		result.Resources = append(result.Resources, entries[idx])
+	}
+	return true
+}
+
+// projectResources 单个项目的资源条目：项目概览、最新结论、index_path 树各节点（深度优先，按记忆数降序）
+func (a *App) projectResources(ctx context.Context, owner string, project ProjectListItem) ([]*mcp.Resource, error) {
+	title := project.ProjectName
+	if title == "" {
+		title = project.ProjectKey
+	}
+	entries := []*mcp.Resource{
+		{URI: projectResourceURI(project.ProjectKey), Name: "project:" + project.ProjectKey, Title: title, MIMEType: "application/json"},
+		{URI: latestResourceURI(project.ProjectKey), Name: "latest:" + project.ProjectKey, Title: title + " · 最新结论", MIMEType: "text/markdown"},
+	}
+	projectID, err := a.store.FindProjectIDByKey(ctx, owner, project.ProjectKey)
+	if err != nil || projectID == "" {
+		return entries, err
+	}
+	paths, err := a.store.FetchIndexPaths(ctx, projectID, owner, resourcePathLimit, nil)
+	if err != nil {
+		return nil, err
+	}
+	var walk func(prefix []string, nodes []IndexPathNode)
+	walk = func(prefix []string, nodes []IndexPathNode) {
+		for _, node := range nodes {
+			path := append(append([]string(nil), prefix...), node.Name)
+			entries = append(entries, &mcp.Resource{
+				URI:         pathResourceURI(project.ProjectKey, path),
+				Name:        "path:" + project.ProjectKey + "/" + strings.Join(path, "/"),
+				Title:       strings.Join(path, " / "),
+				Description: fmt.Sprintf("%d 条记忆", node.Count),
+				MIMEType:    "text/markdown",
+			})
+			walk(path, node.Children)
+		}
+	}
+	walk(nil, buildIndexPathTree(paths, 0, 0))
+	return entries, nil
+}
+
+func jsonResource(uri string, value any) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "application/json", Text: string(data)}}}, nil
+}
+
+func markdownResource(uri, text string) *mcp.ReadResourceResult {
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "text/markdown", Text: text}}}
+}
+
+func (a *App) readProjectsResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	projects, err := a.store.ListProjects(ctx, a.resourceOwner(), nil, defaultListProjectsLimit*2)
+	if err != nil {
+		return nil, err
+	}
+	if projects == nil {
+		projects = []ProjectListItem{}
+	}
+	return jsonResource(req.Params.URI, projects)
+}
+
+func (a *App) readProjectResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	segments, ok := parseResourceSegments(uri, resourceProjectPrefix, "")
+	if !ok || len(segments) != 1 {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	owner := a.resourceOwner()
+	projectID, err := a.store.FindProjectIDByKey(ctx, owner, segments[0])
+	if err != nil || projectID == "" {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	index, err := a.Index(ctx, IndexInput{OwnerID: owner, ProjectKey: segments[0], ProjectName: segments[0]})
+	if err != nil {
+		return nil, err
+	}
+	return jsonResource(uri, index)
+}
+
+func (a *App) readMemoryResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	segments, ok := parseResourceSegments(uri, resourceMemoryPrefix, "")
+	if !ok || len(segments) != 1 {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	rows, err := a.store.FetchMemories(ctx, segments)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	row := rows[0]
+	if ownerID, _, err := a.store.FindMemoryOwnerProject(ctx, row.ID); err != nil || ownerID != a.resourceOwner() {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	result := markdownResource(uri, row.Content)
+	result.Contents[0].Meta = mcp.Meta{
+		"content_type": row.ContentType,
+		"summary":      row.Summary,
+		"tags":         row.Tags,
+		"index_path":   row.IndexPath,
+		"ts":           row.Ts,
+	}
+	return result, nil
+}
+
+func (a *App) readPathResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	segments, ok := parseResourceSegments(uri, resourcePathPrefix, "")
+	if !ok || len(segments) < 2 {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	owner := a.resourceOwner()
+	projectKey, indexPath := segments[0], segments[1:]
+	projectID, err := a.store.FindProjectIDByKey(ctx, owner, projectKey)
+	if err != nil || projectID == "" {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	paths, err := a.store.FetchIndexPaths(ctx, projectID, owner, resourcePathLimit, indexPath)
+	if err != nil {
+		return nil, err
+	}
+	memories, err := a.store.FetchMemoriesByIndexPath(ctx, projectID, indexPath, resourcePathMemoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 && len(memories) == 0 {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# %s / %s\n", projectKey, strings.Join(indexPath, " / "))
+	if children := buildIndexPathTree(trimIndexPathCounts(paths, indexPath), 1, 0); len(children) > 0 {
+		builder.WriteString("\n## 子路径\n")
+		for _, child := range children {
+			childPath := append(append([]string(nil), indexPath...), child.Name)
+			fmt.Fprintf(&builder, "- [%s](%s)（%d）\n", child.Name, pathResourceURI(projectKey, childPath), child.Count)
+		}
+	}
+	writeMemoryLinks(&builder, "记忆", memories)
+	return markdownResource(uri, builder.String()), nil
+}
+
+func (a *App) readLatestResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	segments, ok := parseResourceSegments(uri, resourceLatestPrefix, resourceLatestSuffix)
+	if !ok || len(segments) != 1 {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	projectID, err := a.store.FindProjectIDByKey(ctx, a.resourceOwner(), segments[0])
+	if err != nil || projectID == "" {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	distilled, err := a.store.FetchDistilledSummaries(ctx, projectID, 1)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := a.store.FetchLatestPathSummaries(ctx, projectID, resourceLatestLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# %s · 最新结论\n", segments[0])
+	for _, section := range []struct {
+		title string
+		rows  []MemorySummaryRow
+	}{{"蒸馏摘要", distilled}, {"latest 路径", latest}} {
+		if len(section.rows) == 0 {
+			continue
+		}
+		fmt.Fprintf(&builder, "\n## %s\n", section.title)
+		for _, row := range section.rows {
+			fmt.Fprintf(&builder, "- [%s](%s) %s\n", row.ID, memoryResourceURI(row.ID), strings.ReplaceAll(strings.TrimSpace(row.Summary), "\n", " "))
+		}
+	}
+	if len(distilled) == 0 && len(latest) == 0 {
+		builder.WriteString("\n暂无 latest 路径记忆，可用 mem.timeline 查看最近记忆。\n")
+	}
+	return markdownResource(uri, builder.String()), nil
+}
+
+func writeMemoryLinks(builder *strings.Builder, title string, memories []TimelineRecord) {
+	if len(memories) == 0 {
+		return
+	}
+	fmt.Fprintf(builder, "\n## %s\n", title)
+	for _, memory := range memories {
+		summary := strings.ReplaceAll(strings.TrimSpace(memory.Summary), "\n", " ")
+		if summary == "" {
+			summary = memory.ID
+		}
+		fmt.Fprintf(builder, "- [%s](%s) `%s` %s\n", summary, memoryResourceURI(memory.ID), memory.ContentType, time.Unix(memory.Ts, 0).UTC().Format("2006-01-02"))
+	}
+}
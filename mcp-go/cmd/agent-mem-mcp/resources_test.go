@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestResourceURIRoundTrip(t *testing.T) {
+	uri := pathResourceURI("我的 项目", []string{"dialogs", "api/v1", "latest"})
+	if uri != "mem://path/%E6%88%91%E7%9A%84%20%E9%A1%B9%E7%9B%AE/dialogs/api%2Fv1/latest" {
+		t.Fatalf("路径资源 URI 编码错误: %s", uri)
+	}
+	segments, ok := parseResourceSegments(uri, resourcePathPrefix, "")
+	if !ok || !reflect.DeepEqual(segments, []string{"我的 项目", "dialogs", "api/v1", "latest"}) {
+		t.Fatalf("路径资源 URI 解析错误: %v", segments)
+	}
+	segments, ok = parseResourceSegments(latestResourceURI("a:b"), resourceLatestPrefix, resourceLatestSuffix)
+	if !ok || len(segments) != 1 || segments[0] != "a:b" {
+		t.Fatalf("latest 资源 URI 解析错误: %v", segments)
+	}
+	if _, ok := parseResourceSegments("mem://path//x", resourcePathPrefix, ""); ok {
+		t.Fatalf("空段应解析失败")
+	}
+}
+
+func TestResourcesRegistered(t *testing.T) {
+	server := buildServer(&App{settings: defaultSettings()})
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("服务端连接失败: %v", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("客户端连接失败: %v", err)
+	}
+	defer session.Close()
+
+	templates, err := session.ListResourceTemplates(ctx, nil)
+	if err != nil {
+		t.Fatalf("列出资源模板失败: %v", err)
+	}
+	if len(templates.ResourceTemplates) != 4 {
+		t.Fatalf("资源模板数量错误: %d", len(templates.ResourceTemplates))
+	}
+	// resources/list 由中间件接管：非法游标在访问数据库前即被拒绝
+	if _, err := session.ListResources(ctx, &mcp.ListResourcesParams{Cursor: "bad"}); err == nil {
+		t.Fatalf("非法游标应报错")
+	}
+}
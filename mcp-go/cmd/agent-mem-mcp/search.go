@@ -6,6 +6,8 @@ import (
 	"sort"
 	"strings"
 	"unicode"
+
+	"github.com/pgvector/pgvector-go"
 )
 
 type Searcher struct {
@@ -13,6 +15,17 @@ type Searcher struct {
 	llm      *LLMClient
 	embedder *Embedder
 	settings Settings
+	cache    *SearchCache
+	feedback *FeedbackTracker
+}
+
+// searchOptions 单次检索的排序与副作用控制：ranking 为 nil 时使用默认 RRF 权重。
+// 用户发起的检索见 userSearchOptions：使用学习后的排序、走缓存并记录曝光；服务内部检索（mem.context、提示词）见 internalSearchOptions，
+// 不记录曝光；漂移报告与 eval 等离线检索显式指定排序，关闭缓存与曝光记录
+type searchOptions struct {
+	ranking           *rankingModel
+	useCache          bool
+	recordImpressions bool
 }
 
 type SourceRows struct {
@@ -20,15 +33,26 @@ type SourceRows struct {
 	Rows []FragmentRow
 }
 
-func NewSearcher(store *Store, llm *LLMClient, embedder *Embedder, settings Settings) *Searcher {
-	return &Searcher{store: store, llm: llm, embedder: embedder, settings: settings}
+func NewSearcher(store *Store, llm *LLMClient, embedder *Embedder, settings Settings, cache *SearchCache, feedback *FeedbackTracker) *Searcher {
+	return &Searcher{store: store, llm: llm, embedder: embedder, settings: settings, cache: cache, feedback: feedback}
 }
 
-func (s *Searcher) Search(ctx context.Context, input SearchInput) (SearchResponse, error) {
+// searchProgressSteps 检索进度阶段：多路召回、查询扩展、融合与重排、结果组装
+const searchProgressSteps = 4
+
+func (s *Searcher) search(ctx context.Context, input SearchInput, opts searchOptions) (SearchResponse, error) {
 	query := strings.TrimSpace(input.Query)
 	if query == "" {
 		return SearchResponse{}, fmt.Errorf("query 不能为空")
 	}
+	// 结构化查询：过滤操作符已由 applySearchQuerySyntax 合并进 input，这里只取检索文本与短语
+	parsed, err := parseSearchQuery(query)
+	if err != nil {
+		return SearchResponse{}, err
+	}
+	if parsed.Structured {
+		query = parsed.Text
+	}
 
 	limit := input.Limit
 	if limit <= 0 {
@@ -40,20 +64,54 @@ func (s *Searcher) Search(ctx context.Context, input SearchInput) (SearchRespons
 		scope = "all"
 	}
 
-	axes := MemoryAxes{}
-	if input.Axes != nil {
-		axes = *input.Axes
+	// 缓存键包含排序参数版本，反馈学习更新权重后不会命中旧排序
+	cacheKey := searchCacheKey(input) + opts.ranking.version()
+	if opts.useCache {
+		if cached, ok := s.cache.Get(cacheKey); ok {
+			return cached, nil
+		}
 	}
-	var indexPath []string
-	if input.IndexPath != nil {
-		indexPath = *input.IndexPath
+
+	// 翻页：游标记录此前各页已返回的记忆，重新融合排序后剔除这些记忆，分数变化时页间也不重叠、不遗漏
+	cursor, err := decodeCursor(input.Cursor, cursorKindSearch)
+	if err != nil {
+		return SearchResponse{}, err
+	}
+	fingerprint := searchFingerprint(input)
+	depth := 0
+	if cursor != nil {
+		if cursor.Fingerprint != fingerprint {
+			return SearchResponse{}, newValidationError("invalid_request", "ERR_INVALID_CURSOR", "cursor 与当前检索参数不匹配", 400)
+		}
+		if cursor.Depth <= 0 || cursor.Depth >= maxSearchPageDepth || cursor.Depth != len(cursor.Seen) {
+			return SearchResponse{}, newValidationError("invalid_request", "ERR_INVALID_CURSOR", "cursor 格式错误", 400)
+		}
+		depth = cursor.Depth
 	}
+
+	filter := buildFragmentFilter(input, scope)
+	filter.Phrases = parsed.Phrases
 	profile := derefString(input.Profile, "deep")
 	mode := derefString(input.Mode, "compact")
 
 	projectScoped := strings.TrimSpace(input.ProjectKey) != ""
 	projectID := ""
-	if projectScoped {
+	if input.ProjectKeys != nil && len(*input.ProjectKeys) > 0 {
+		// 多项目检索：走 owner 级查询，再用 project_id 集合过滤
+		keys := *input.ProjectKeys
+		if projectScoped {
+			keys = uniqueStrings(append([]string{input.ProjectKey}, keys...))
+		}
+		projectIDs, err := s.store.FindProjectIDsByKeys(ctx, input.OwnerID, keys)
+		if err != nil {
+			return SearchResponse{}, err
+		}
+		if len(projectIDs) == 0 {
+			return SearchResponse{Results: []SearchResult{}, Metadata: SearchMetadata{Total: 0, Returned: 0, NextAction: "use_ids_to_call_mem_get"}}, nil
+		}
+		filter.ProjectIDs = projectIDs
+		projectScoped = false
+	} else if projectScoped {
 		var err error
 		projectID, err = s.store.FindProjectIDByKey(ctx, input.OwnerID, input.ProjectKey)
 		if err != nil {
@@ -71,97 +129,126 @@ func (s *Searcher) Search(ctx context.Context, input SearchInput) (SearchRespons
 	case "deep":
 		initialMultiplier = 8
 	}
-	initialLimit := limit * initialMultiplier
-	var vectorRows []FragmentRow
-	vector, err := s.embedder.EmbedQuery(query)
-	if err == nil && s.embedder != nil && s.embedder.provider != "mock" {
-		if projectScoped {
-			vectorRows, err = s.store.SearchVectorFragments(ctx, vector, projectID, scope, axes, indexPath, initialLimit)
-		} else {
-			vectorRows, err = s.store.SearchVectorFragmentsByOwner(ctx, vector, input.OwnerID, scope, axes, indexPath, initialLimit)
-		}
-		if err != nil {
-			return SearchResponse{}, err
-		}
-	}
+	initialLimit := (limit + depth) * initialMultiplier
 
 	lexicalQuery := normalizeQuery(query)
 	if lexicalQuery == "" {
 		lexicalQuery = query
 	}
 
-	var sources []SourceRows
-
-	var keywordRows []FragmentRow
-	if projectScoped {
-		keywordRows, err = s.store.SearchKeywordFragments(ctx, lexicalQuery, projectID, scope, axes, indexPath, initialLimit)
-	} else {
-		keywordRows, err = s.store.SearchKeywordFragmentsByOwner(ctx, lexicalQuery, input.OwnerID, scope, axes, indexPath, initialLimit)
+	// 各召回源并发执行，结果写入固定槽位，保持融合顺序稳定
+	runner := newSourceRunner(ctx, s.settings.SearchSources)
+	var keywordRows, bm25Rows, fuzzyRows, vectorRows, foresightRows []FragmentRow
+	runner.Go("keyword", func(ctx context.Context) error {
+		var err error
+		if projectScoped {
+			keywordRows, err = s.store.SearchKeywordFragments(ctx, lexicalQuery, projectID, filter, initialLimit)
+		} else {
+			keywordRows, err = s.store.SearchKeywordFragmentsByOwner(ctx, lexicalQuery, input.OwnerID, filter, initialLimit)
+		}
+		return err
+	})
+	runner.Go("bm25", func(ctx context.Context) error {
+		var err error
+		if projectScoped {
+			bm25Rows, err = s.store.SearchBM25Fragments(ctx, lexicalQuery, projectID, filter, initialLimit)
+		} else {
+			bm25Rows, err = s.store.SearchBM25FragmentsByOwner(ctx, lexicalQuery, input.OwnerID, filter, initialLimit)
+		}
+		return err
+	})
+	// 模糊召回：pg_trgm 容忍拼写错误与不完整标识符，仅在配置启用的 profile 下执行
+	if s.fuzzyEnabled(profile) && len([]rune(lexicalQuery)) >= 3 {
+		runner.Go("fuzzy", func(ctx context.Context) error {
+			var err error
+			if projectScoped {
+				fuzzyRows, err = s.store.SearchFuzzyFragments(ctx, lexicalQuery, projectID, s.settings.Fuzzy.Threshold, filter, initialLimit)
+			} else {
+				fuzzyRows, err = s.store.SearchFuzzyFragmentsByOwner(ctx, lexicalQuery, input.OwnerID, s.settings.Fuzzy.Threshold, filter, initialLimit)
+			}
+			return err
+		})
+	}
+	var embedding *queryEmbedding
+	if s.embedder != nil && s.embedder.provider != "mock" {
+		// 向量与前瞻共用一次查询 embedding；embedding 失败时两者均记为降级（highlight 模式复用该向量定位最相似句）
+		embedding = s.startQueryEmbedding(ctx, query)
+		runner.Go("vector", func(ctx context.Context) error {
+			vector, err := embedding.wait(ctx)
+			if err != nil {
+				return err
+			}
+			if projectScoped {
+				vectorRows, err = s.store.SearchVectorFragments(ctx, vector, projectID, filter, initialLimit)
+			} else {
+				vectorRows, err = s.store.SearchVectorFragmentsByOwner(ctx, vector, input.OwnerID, filter, initialLimit)
+			}
+			return err
+		})
+		// 前瞻记忆搜索：搜索 foresight 向量，将命中的 source_memory_id 对应片段加入结果集（失败不影响检索，记为降级）
+		runner.Go("foresight", func(ctx context.Context) error {
+			vector, err := embedding.wait(ctx)
+			if err != nil {
+				return err
+			}
+			var hits []ForesightRow
+			if projectScoped {
+				hits, err = s.store.SearchForesightVectors(ctx, vector, projectID, limit)
+			} else {
+				hits, err = s.store.SearchForesightVectorsByOwner(ctx, vector, input.OwnerID, limit)
+			}
+			if err != nil {
+				return fmt.Errorf("%w: 前瞻检索失败: %w", errSourceDegraded, err)
+			}
+			var foresightMemoryIDs []string
+			seen := map[string]bool{}
+			for _, fr := range hits {
+				if !seen[fr.SourceMemoryID] {
+					seen[fr.SourceMemoryID] = true
+					foresightMemoryIDs = append(foresightMemoryIDs, fr.SourceMemoryID)
+				}
+			}
+			if len(foresightMemoryIDs) > 0 {
+				rows, err := s.store.FetchTopFragmentsByMemoryIDs(ctx, foresightMemoryIDs, filter)
+				if err != nil {
+					return fmt.Errorf("%w: 读取前瞻命中片段失败: %w", errSourceDegraded, err)
+				}
+				foresightRows = rows
+			}
+			return nil
+		})
 	}
-	if err != nil {
+	if err := runner.Wait(); err != nil {
 		return SearchResponse{}, err
 	}
+	reportProgress(ctx, 1, searchProgressSteps, "多路召回")
+
+	var sources []SourceRows
 	if len(keywordRows) > 0 {
 		sources = append(sources, SourceRows{Name: "keyword", Rows: keywordRows})
 	}
-
-	var bm25Rows []FragmentRow
-	if projectScoped {
-		bm25Rows, err = s.store.SearchBM25Fragments(ctx, lexicalQuery, projectID, scope, axes, indexPath, initialLimit)
-	} else {
-		bm25Rows, err = s.store.SearchBM25FragmentsByOwner(ctx, lexicalQuery, input.OwnerID, scope, axes, indexPath, initialLimit)
-	}
-	if err != nil {
-		return SearchResponse{}, err
-	}
 	if len(bm25Rows) > 0 {
 		sources = append(sources, SourceRows{Name: "bm25", Rows: bm25Rows})
 	}
-
+	if len(fuzzyRows) > 0 {
+		sources = append(sources, SourceRows{Name: "fuzzy", Rows: fuzzyRows})
+	}
 	// 关键词/BM25 已命中时，向量结果必须包含关键词，避免噪声召回
 	if len(vectorRows) > 0 && (len(keywordRows) > 0 || len(bm25Rows) > 0) {
-		tokens := tokenizeLexicalQuery(lexicalQuery)
+		tokens := tokenizeLexicalQuery(s.store.tokenizer, lexicalQuery)
 		vectorRows = filterRowsByTokens(vectorRows, tokens)
 	}
+	vectorHits := map[string]bool{}
 	if len(vectorRows) > 0 {
 		sources = append(sources, SourceRows{Name: "vector", Rows: vectorRows})
-	}
-
-	// 前瞻记忆搜索：搜索 foresight 向量，将命中的 source_memory_id 对应片段加入结果集
-	if err == nil && s.embedder != nil && s.embedder.provider != "mock" {
-		var foresightMemoryIDs []string
-		if projectScoped {
-			foresightRows, fErr := s.store.SearchForesightVectors(ctx, vector, projectID, limit)
-			if fErr == nil {
-				seen := map[string]bool{}
-				for _, fr := range foresightRows {
-					if !seen[fr.SourceMemoryID] {
-						seen[fr.SourceMemoryID] = true
-						foresightMemoryIDs = append(foresightMemoryIDs, fr.SourceMemoryID)
-					}
-				}
-			}
-		} else {
-			foresightRows, fErr := s.store.SearchForesightVectorsByOwner(ctx, vector, input.OwnerID, limit)
-			if fErr == nil {
-				seen := map[string]bool{}
-				for _, fr := range foresightRows {
-					if !seen[fr.SourceMemoryID] {
-						seen[fr.SourceMemoryID] = true
-						foresightMemoryIDs = append(foresightMemoryIDs, fr.SourceMemoryID)
-					}
-				}
-			}
-		}
-		if len(foresightMemoryIDs) > 0 {
-			foresightFragments, fErr := s.store.FetchTopFragmentsByMemoryIDs(ctx, foresightMemoryIDs)
-			if fErr == nil && len(foresightFragments) > 0 {
-				sources = append(sources, SourceRows{Name: "foresight", Rows: foresightFragments})
-			}
+		for _, row := range vectorRows {
+			vectorHits[row.FragmentID] = true
 		}
-		// lazy cleanup 过期前瞻
-		_, _ = s.store.CleanExpiredForesights(ctx)
 	}
+	if len(foresightRows) > 0 {
+		sources = append(sources, SourceRows{Name: "foresight", Rows: foresightRows})
+	}
+	timedOut := runner.TimedOut()
 
 	useExpand := s.settings.QueryExpand.Enabled && s.llm != nil
 	if useExpand && s.llm.mock {
@@ -180,37 +267,13 @@ func (s *Searcher) Search(ctx context.Context, input SearchInput) (SearchRespons
 	baseCount := countUniqueMemories(sources)
 	shouldExpand := useExpand && (baseCount < expandThreshold || (len(keywordRows) == 0 && len(bm25Rows) == 0))
 	if shouldExpand {
-		expanded := s.llm.ExpandQuery(query)
-		for _, keyword := range uniqueStrings(expanded) {
-			if keyword == "" || keyword == lexicalQuery {
-				continue
-			}
-			var expandedKeywordRows []FragmentRow
-			if projectScoped {
-				expandedKeywordRows, err = s.store.SearchKeywordFragments(ctx, keyword, projectID, scope, axes, indexPath, initialLimit)
-			} else {
-				expandedKeywordRows, err = s.store.SearchKeywordFragmentsByOwner(ctx, keyword, input.OwnerID, scope, axes, indexPath, initialLimit)
-			}
-			if err != nil {
-				return SearchResponse{}, err
-			}
-			if len(expandedKeywordRows) > 0 {
-				sources = append(sources, SourceRows{Name: "keyword", Rows: expandedKeywordRows})
-			}
-
-			var expandedBM25Rows []FragmentRow
-			if projectScoped {
-				expandedBM25Rows, err = s.store.SearchBM25Fragments(ctx, keyword, projectID, scope, axes, indexPath, initialLimit)
-			} else {
-				expandedBM25Rows, err = s.store.SearchBM25FragmentsByOwner(ctx, keyword, input.OwnerID, scope, axes, indexPath, initialLimit)
-			}
-			if err != nil {
-				return SearchResponse{}, err
-			}
-			if len(expandedBM25Rows) > 0 {
-				sources = append(sources, SourceRows{Name: "bm25", Rows: expandedBM25Rows})
-			}
+		expandSources, expandTimedOut, err := s.searchExpanded(ctx, query, lexicalQuery, projectScoped, projectID, input.OwnerID, filter, initialLimit)
+		if err != nil {
+			return SearchResponse{}, err
 		}
+		sources = append(sources, expandSources...)
+		timedOut = append(timedOut, expandTimedOut...)
+		reportProgress(ctx, 2, searchProgressSteps, "查询扩展")
 	}
 
 	var (
@@ -218,32 +281,43 @@ func (s *Searcher) Search(ctx context.Context, input SearchInput) (SearchRespons
 		traceMap map[string]*SearchTrace
 	)
 	if s.settings.SearchExplain.Enabled {
-		combined, traceMap = rrfMergeWithTrace(sources...)
+		combined, traceMap = rrfMergeWithTraceWeighted(opts.ranking.weight, sources...)
 	} else {
-		combined = rrfMerge(sources...)
+		combined = rrfMergeWeighted(opts.ranking.weight, sources...)
 	}
+	combined = sortByRank(opts.ranking.applyBoosts(combined))
 	if len(combined) == 0 {
-		return SearchResponse{Results: []SearchResult{}, Metadata: SearchMetadata{Total: 0, Returned: 0, NextAction: "use_ids_to_call_mem_get"}}, nil
+		return SearchResponse{Results: []SearchResult{}, Metadata: SearchMetadata{Total: 0, Returned: 0, NextAction: "use_ids_to_call_mem_get", TimedOutSources: timedOut}}, nil
 	}
 
-	combined = dedupeByMemory(combined, limit*3)
+	// 重排作用于整个去重后的候选池，再按游标剔除已返回的记忆并分页，重排可以把池中靠后的候选提到本页
+	combined = dedupeByMemory(combined, limit*3+depth)
 	totalCount := len(combined)
+
 	useRerank := s.settings.Rerank.Enabled && s.llm != nil
 	if profile == "fast" {
 		useRerank = false
 	}
-	if len(combined) <= limit {
+	if len(combined) <= 1 {
 		useRerank = false
 	}
 	if len(sources) <= 1 {
 		useRerank = false
 	}
-	combined = maybeRerank(ctx, s, query, combined, limit, useRerank)
+	combined = maybeRerank(ctx, s, query, combined, limit+depth, useRerank)
 
-	if len(combined) > limit {
-		combined = combined[:limit]
+	var seen []string
+	if cursor != nil {
+		seen = cursor.Seen
 	}
+	combined, hasMore := pageAfterSeen(combined, seen, limit)
+	reportProgress(ctx, 3, searchProgressSteps, "融合与重排")
 
+	var terms []string
+	vectorHighlights := map[int]string{}
+	if mode == "highlight" {
+		terms = highlightTerms(s.store.tokenizer, lexicalQuery, parsed.Phrases)
+	}
 	results := make([]SearchResult, 0, len(combined))
 	for _, row := range combined {
 		result := SearchResult{ID: row.MemoryID}
@@ -252,6 +326,14 @@ func (s *Searcher) Search(ctx context.Context, input SearchInput) (SearchRespons
 			if mode != "compact" {
 				snippet = buildSnippet(row.Content, 200)
 			}
+			if mode == "highlight" {
+				if highlighted, spans, ok := buildHighlightSnippet(row.Content, terms, highlightSnippetRunes); ok {
+					snippet = highlighted
+					result.Highlights = spans
+				} else if vectorHits[row.FragmentID] {
+					vectorHighlights[len(results)] = row.Content
+				}
+			}
 			result.Snippet = snippet
 			result.ContentType = row.ContentType
 			result.ProjectKey = row.ProjectKey
@@ -270,24 +352,193 @@ func (s *Searcher) Search(ctx context.Context, input SearchInput) (SearchRespons
 		results = append(results, result)
 	}
 
+	s.highlightVectorSentences(ctx, embedding, results, vectorHighlights)
+
 	// 对 top 5 结果附带 outgoing 关系的 target memory ID 列表
 	enrichRelatedIDs(ctx, s.store, results)
+	reportProgress(ctx, searchProgressSteps, searchProgressSteps, "检索完成")
+
+	nextCursor := ""
+	truncated := false
+	if hasMore {
+		if depth+len(combined) < maxSearchPageDepth {
+			nextCursor = encodeCursor(pageCursor{
+				Kind:        cursorKindSearch,
+				Depth:       depth + len(combined),
+				Seen:        appendSeenMemories(seen, combined),
+				Fingerprint: fingerprint,
+			})
+		} else {
+			truncated = true
+		}
+	}
 
-	return SearchResponse{
+	response := SearchResponse{
 		Results: results,
 		Metadata: SearchMetadata{
 			Total:      totalCount,
 			Returned:   len(results),
 			NextAction: "use_ids_to_call_mem_get",
+			NextCursor: nextCursor,
+			Truncated:  truncated,
+			// 超时被丢弃的召回源
+			TimedOutSources: timedOut,
 		},
-	}, nil
+	}
+	if opts.recordImpressions {
+		s.feedback.RecordImpressions(input.OwnerID, input.Query, results, memorySourceNames(sources))
+	}
+	if !opts.useCache || len(timedOut) > 0 {
+		// 降级结果（有召回源超时或失败）不缓存，下次检索重新尝试全部召回源
+		return response, nil
+	}
+	// 缓存依赖：项目级检索依赖 project_id，多项目依赖 project_id 集合，owner 级检索依赖整个 owner
+	cacheProjects := filter.ProjectIDs
+	if projectScoped {
+		cacheProjects = []string{projectID}
+	}
+	s.cache.Set(cacheKey, input.OwnerID, cacheProjects, response)
+	return response, nil
+}
+
+// searchExpanded 查询扩展：LLM 生成扩展词后，各扩展词的关键词/BM25 查询并发执行
+func (s *Searcher) searchExpanded(ctx context.Context, query, lexicalQuery string, projectScoped bool, projectID, ownerID string, filter FragmentFilter, initialLimit int) ([]SourceRows, []string, error) {
+	expandCtx, cancel := context.WithTimeout(ctx, sourceTimeout(s.settings.SearchSources, "expand"))
+	expanded, err := awaitResult(expandCtx, func(ctx context.Context) []string { return s.llm.ExpandQuery(ctx, query) })
+	cancel()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, []string{"expand"}, nil
+	}
+
+	var keywords []string
+	for _, keyword := range uniqueStrings(expanded) {
+		if keyword == "" || keyword == lexicalQuery {
+			continue
+		}
+		keywords = append(keywords, keyword)
+	}
+	keywordRows := make([][]FragmentRow, len(keywords))
+	bm25Rows := make([][]FragmentRow, len(keywords))
+	runner := newSourceRunner(ctx, s.settings.SearchSources)
+	for idx, keyword := range keywords {
+		runner.Go("keyword:"+keyword, func(ctx context.Context) error {
+			var err error
+			if projectScoped {
+				keywordRows[idx], err = s.store.SearchKeywordFragments(ctx, keyword, projectID, filter, initialLimit)
+			} else {
+				keywordRows[idx], err = s.store.SearchKeywordFragmentsByOwner(ctx, keyword, ownerID, filter, initialLimit)
+			}
+			return err
+		})
+		runner.Go("bm25:"+keyword, func(ctx context.Context) error {
+			var err error
+			if projectScoped {
+				bm25Rows[idx], err = s.store.SearchBM25Fragments(ctx, keyword, projectID, filter, initialLimit)
+			} else {
+				bm25Rows[idx], err = s.store.SearchBM25FragmentsByOwner(ctx, keyword, ownerID, filter, initialLimit)
+			}
+			return err
+		})
+	}
+	if err := runner.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	var sources []SourceRows
+	for idx := range keywords {
+		if len(keywordRows[idx]) > 0 {
+			sources = append(sources, SourceRows{Name: "keyword", Rows: keywordRows[idx]})
+		}
+		if len(bm25Rows[idx]) > 0 {
+			sources = append(sources, SourceRows{Name: "bm25", Rows: bm25Rows[idx]})
+		}
+	}
+	return sources, runner.TimedOut(), nil
+}
+
+// queryEmbedding 查询向量只计算一次，供 vector 与 foresight 召回源共享
+type queryEmbedding struct {
+	done   chan struct{}
+	vector pgvector.Vector
+	err    error
+}
+
+// startQueryEmbedding 在后台计算查询向量，超时沿用 vector 召回源的超时
+func (s *Searcher) startQueryEmbedding(ctx context.Context, query string) *queryEmbedding {
+	embedding := &queryEmbedding{done: make(chan struct{})}
+	go func() {
+		defer close(embedding.done)
+		embedCtx, cancel := context.WithTimeout(ctx, sourceTimeout(s.settings.SearchSources, "vector"))
+		defer cancel()
+		embedding.vector, embedding.err = s.embedder.EmbedQueryContext(embedCtx, query)
+	}()
+	return embedding
+}
+
+// wait 等待查询向量；embedding 失败或超时返回 errSourceDegraded（依赖该向量的召回源记为降级），ctx 结束返回 ctx 错误
+func (e *queryEmbedding) wait(ctx context.Context) (pgvector.Vector, error) {
+	select {
+	case <-e.done:
+		if e.err != nil {
+			return pgvector.Vector{}, fmt.Errorf("%w: 查询向量计算失败: %w", errSourceDegraded, e.err)
+		}
+		return e.vector, nil
+	case <-ctx.Done():
+		return pgvector.Vector{}, ctx.Err()
+	}
+}
+
+// buildFragmentFilter 将 SearchInput 中的过滤条件收敛为 FragmentFilter（ProjectIDs 由调用方解析后填入）
+func buildFragmentFilter(input SearchInput, scope string) FragmentFilter {
+	filter := FragmentFilter{
+		Scope: scope,
+		Since: input.Since,
+		Until: input.Until,
+	}
+	if input.Axes != nil {
+		filter.Axes = *input.Axes
+	}
+	if input.IndexPath != nil {
+		filter.IndexPath = *input.IndexPath
+	}
+	if input.ContentTypes != nil {
+		filter.ContentTypes = *input.ContentTypes
+	}
+	if input.Tags != nil {
+		filter.Tags = *input.Tags
+	}
+	if input.ExcludeTags != nil {
+		filter.ExcludeTags = *input.ExcludeTags
+	}
+	return filter
+}
+
+// memorySourceNames 每条记忆由哪些召回源命中（用于反馈学习各源权重）
+func memorySourceNames(sources []SourceRows) map[string][]string {
+	result := map[string][]string{}
+	for _, source := range sources {
+		for _, row := range source.Rows {
+			if !stringInSlice(result[row.MemoryID], source.Name) {
+				result[row.MemoryID] = append(result[row.MemoryID], source.Name)
+			}
+		}
+	}
+	return result
 }
 
 func rrfMerge(sources ...SourceRows) []FragmentRow {
+	return rrfMergeWeighted(rrfWeight, sources...)
+}
+
+// rrfMergeWeighted 同 rrfMerge，召回源权重由 weightOf 提供（反馈学习后的权重）
+func rrfMergeWeighted(weightOf func(string) float64, sources ...SourceRows) []FragmentRow {
 	const k = 60.0
 	combined := map[string]*FragmentRow{}
 	for _, source := range sources {
-		weight := rrfWeight(source.Name)
+		weight := weightOf(source.Name)
 		for idx, row := range source.Rows {
 			rank := float64(idx + 1)
 			score := weight * (1.0 / (k + rank))
@@ -318,11 +569,15 @@ func rrfMerge(sources ...SourceRows) []FragmentRow {
 }
 
 func rrfMergeWithTrace(sources ...SourceRows) ([]FragmentRow, map[string]*SearchTrace) {
+	return rrfMergeWithTraceWeighted(rrfWeight, sources...)
+}
+
+func rrfMergeWithTraceWeighted(weightOf func(string) float64, sources ...SourceRows) ([]FragmentRow, map[string]*SearchTrace) {
 	const k = 60.0
 	combined := map[string]*FragmentRow{}
 	traces := map[string]*SearchTrace{}
 	for _, source := range sources {
-		weight := rrfWeight(source.Name)
+		weight := weightOf(source.Name)
 		for idx, row := range source.Rows {
 			rank := float64(idx + 1)
 			score := weight * (1.0 / (k + rank))
@@ -369,6 +624,11 @@ func rrfMergeWithTrace(sources ...SourceRows) ([]FragmentRow, map[string]*Search
 	return results, traces
 }
 
+// fuzzyEnabled 判断当前 profile 是否启用模糊召回
+func (s *Searcher) fuzzyEnabled(profile string) bool {
+	return s.settings.Fuzzy.Enabled && stringInSlice(s.settings.Fuzzy.Profiles, profile)
+}
+
 func rrfWeight(name string) float64 {
 	switch name {
 	case "vector":
@@ -379,19 +639,21 @@ func rrfWeight(name string) float64 {
 		return 1.0
 	case "foresight":
 		return 0.6
+	case "fuzzy":
+		return 0.5
 	default:
 		return 1.0
 	}
 }
 
-func tokenizeLexicalQuery(query string) []string {
-	parts := strings.Fields(query)
+// tokenizeLexicalQuery 使用与 BM25 相同的分词器切分查询，丢弃单字词项
+func tokenizeLexicalQuery(tokenizer Tokenizer, query string) []string {
+	parts := uniqueStrings(tokenizer.Tokenize(query))
 	if len(parts) == 0 {
 		return nil
 	}
 	tokens := make([]string, 0, len(parts))
 	for _, part := range parts {
-		part = strings.TrimSpace(part)
 		if len([]rune(part)) < 2 {
 			continue
 		}
@@ -427,6 +689,60 @@ func filterRowsByTokens(rows []FragmentRow, tokens []string) []FragmentRow {
 	return filtered
 }
 
+// sortByRank 检索结果的全序：分数降序，同分按时间降序、记忆 ID 升序（同一排序参数下分页顺序确定）
+func sortByRank(rows []FragmentRow) []FragmentRow {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rankedBefore(rows[i], rows[j].RankScore, rows[j].Ts, rows[j].MemoryID)
+	})
+	return rows
+}
+
+func rankedBefore(row FragmentRow, score float64, ts int64, memoryID string) bool {
+	if row.RankScore != score {
+		return row.RankScore > score
+	}
+	if row.Ts != ts {
+		return row.Ts > ts
+	}
+	return row.MemoryID < memoryID
+}
+
+// seenMemoryDigest 游标中已返回记忆的短摘要，控制游标长度（深度上限内不会超过 maxCursorLength）
+func seenMemoryDigest(memoryID string) string {
+	return hashContent(memoryID)[:10]
+}
+
+// pageAfterSeen 从排好序的候选池中剔除此前各页已返回的记忆，取前 limit 条作为本页
+func pageAfterSeen(rows []FragmentRow, seen []string, limit int) ([]FragmentRow, bool) {
+	if len(seen) > 0 {
+		skip := make(map[string]bool, len(seen))
+		for _, digest := range seen {
+			skip[digest] = true
+		}
+		remaining := make([]FragmentRow, 0, len(rows))
+		for _, row := range rows {
+			if !skip[seenMemoryDigest(row.MemoryID)] {
+				remaining = append(remaining, row)
+			}
+		}
+		rows = remaining
+	}
+	if len(rows) > limit {
+		return rows[:limit], true
+	}
+	return rows, false
+}
+
+// appendSeenMemories 返回追加本页记忆摘要后的已返回列表（不修改入参）
+func appendSeenMemories(seen []string, page []FragmentRow) []string {
+	next := make([]string, 0, len(seen)+len(page))
+	next = append(next, seen...)
+	for _, row := range page {
+		next = append(next, seenMemoryDigest(row.MemoryID))
+	}
+	return next
+}
+
 func dedupeByMemory(rows []FragmentRow, limit int) []FragmentRow {
 	if limit <= 0 {
 		limit = len(rows)
@@ -464,7 +780,7 @@ func maybeRerank(ctx context.Context, s *Searcher, query string, rows []Fragment
 	for _, row := range rows {
 		docs = append(docs, truncateRunes(strings.TrimSpace(row.Content), 2000))
 	}
-	results, err := s.llm.Rerank(query, docs, topN)
+	results, err := s.llm.Rerank(ctx, query, docs, topN)
 	if err != nil || len(results) == 0 {
 		return rows
 	}
@@ -493,6 +809,12 @@ func maybeRerank(ctx context.Context, s *Searcher, query string, rows []Fragment
 		}
 		return ordered[i].RankScore > ordered[j].RankScore
 	})
+	// 重排只覆盖前 topN 条：未参与重排的候选保持融合顺序排在后面，仍可在后续页返回
+	for idx, row := range rows {
+		if !seen[idx] {
+			ordered = append(ordered, row)
+		}
+	}
 	return ordered
 }
 
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SearchCache 缓存完整检索结果，按 owner/项目精确失效：
+// 项目级检索只依赖对应 project_id；owner 级检索（未限定项目）依赖该 owner 下所有项目。
+type SearchCache struct {
+	mu         sync.Mutex
+	entries    map[string]cachedSearch
+	ttl        time.Duration
+	maxEntries int
+
+	hits          uint64
+	misses        uint64
+	invalidations uint64
+}
+
+type cachedSearch struct {
+	Value      SearchResponse
+	OwnerID    string
+	ProjectIDs []string // 为空表示 owner 级检索
+	Expires    time.Time
+}
+
+// SearchCacheStats 检索缓存命中统计，输出到 mem.metrics
+type SearchCacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Invalidations uint64
+	Entries       int
+}
+
+// NewSearchCache search.cache.ttl_seconds=0 时关闭缓存
+func NewSearchCache(settings SearchCacheConfig) *SearchCache {
+	return &SearchCache{
+		entries:    map[string]cachedSearch{},
+		ttl:        time.Duration(settings.TTLSeconds) * time.Second,
+		maxEntries: settings.MaxEntries,
+	}
+}
+
+func (c *SearchCache) enabled() bool {
+	return c != nil && c.ttl > 0
+}
+
+func (c *SearchCache) Get(key string) (SearchResponse, bool) {
+	if !c.enabled() || key == "" {
+		return SearchResponse{}, false
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if ok && entry.Expires.Before(now) {
+		delete(c.entries, key)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return SearchResponse{}, false
+	}
+	c.hits++
+	return cloneSearchResponse(entry.Value), true
+}
+
+func (c *SearchCache) Set(key, ownerID string, projectIDs []string, value SearchResponse) {
+	if !c.enabled() || key == "" {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.maxEntries {
+		c.prune(now)
+	}
+	c.entries[key] = cachedSearch{
+		Value:      cloneSearchResponse(value),
+		OwnerID:    ownerID,
+		ProjectIDs: slices.Clone(projectIDs),
+		Expires:    now.Add(c.ttl),
+	}
+}
+
+// InvalidateProject 清除依赖该项目的缓存：项目级命中该 project_id 的条目 + 同 owner 的 owner 级条目。
+// ownerID 为空时视为未知 owner，清除所有 owner 级条目；projectID 为空时视为未知项目，清除所有项目级条目。
+func (c *SearchCache) InvalidateProject(ownerID, projectID string) int {
+	if !c.enabled() {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := 0
+	for key, entry := range c.entries {
+		affected := false
+		if len(entry.ProjectIDs) == 0 {
+			affected = ownerID == "" || entry.OwnerID == ownerID
+		} else {
+			affected = projectID == "" || stringInSlice(entry.ProjectIDs, projectID)
+		}
+		if affected {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	if removed > 0 {
+		c.invalidations++
+	}
+	return removed
+}
+
+// Clear 清空全部缓存（变更通知可能丢失时使用）
+func (c *SearchCache) Clear() int {
+	if !c.enabled() {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := len(c.entries)
+	c.entries = map[string]cachedSearch{}
+	if removed > 0 {
+		c.invalidations++
+	}
+	return removed
+}
+
+func (c *SearchCache) Stats() SearchCacheStats {
+	if c == nil {
+		return SearchCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return SearchCacheStats{
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Invalidations: c.invalidations,
+		Entries:       len(c.entries),
+	}
+}
+
+func (c *SearchCache) prune(now time.Time) {
+	for key, entry := range c.entries {
+		if entry.Expires.Before(now) {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) < c.maxEntries {
+		return
+	}
+	target := c.maxEntries - c.maxEntries/10
+	if target <= 0 {
+		target = 1
+	}
+	for key := range c.entries {
+		delete(c.entries, key)
+		if len(c.entries) <= target {
+			break
+		}
+	}
+}
+
+// cloneSearchResponse 深拷贝结果中的切片与指针，调用方修改返回值不影响缓存
+func cloneSearchResponse(value SearchResponse) SearchResponse {
+	cloned := value
+	cloned.Metadata.TimedOutSources = slices.Clone(value.Metadata.TimedOutSources)
+	if value.Results != nil {
+		cloned.Results = make([]SearchResult, len(value.Results))
+		for idx, result := range value.Results {
+			result.IndexPath = slices.Clone(result.IndexPath)
+			result.RelatedIDs = slices.Clone(result.RelatedIDs)
+			result.Highlights = slices.Clone(result.Highlights)
+			if result.Axes != nil {
+				axes := MemoryAxes{
+					Domain:    slices.Clone(result.Axes.Domain),
+					Stack:     slices.Clone(result.Axes.Stack),
+					Problem:   slices.Clone(result.Axes.Problem),
+					Lifecycle: slices.Clone(result.Axes.Lifecycle),
+					Component: slices.Clone(result.Axes.Component),
+				}
+				result.Axes = &axes
+			}
+			if result.Trace != nil {
+				trace := SearchTrace{Sources: slices.Clone(result.Trace.Sources), Ranks: maps.Clone(result.Trace.Ranks), RRFScore: result.Trace.RRFScore}
+				result.Trace = &trace
+			}
+			cloned.Results[idx] = result
+		}
+	}
+	return cloned
+}
+
+// searchCacheKey 基于归一化后的 SearchInput（含 cursor/limit）生成缓存键
+func searchCacheKey(input SearchInput) string {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return ""
+	}
+	return "search:" + hashContent(string(data))
+}
+
+// invalidateSearchCache 在项目数据变更后失效检索缓存
+func (a *App) invalidateSearchCache(ownerID, projectID string) {
+	if a.searchCache == nil || projectID == "" {
+		return
+	}
+	a.searchCache.InvalidateProject(ownerID, projectID)
+}
+
+// invalidateSearchCacheForMemories 按记忆 ID 反查所属 owner/项目后失效缓存；查不到时以空 owner 与项目调用 InvalidateProject，即清空全部缓存
+func (a *App) invalidateSearchCacheForMemories(ctx context.Context, memoryIDs ...string) {
+	if a.searchCache == nil {
+		return
+	}
+	for _, memoryID := range uniqueStrings(memoryIDs) {
+		ownerID, projectID, err := a.store.FindMemoryOwnerProject(ctx, memoryID)
+		if err != nil || projectID == "" {
+			a.searchCache.InvalidateProject("", "")
+			continue
+		}
+		a.searchCache.InvalidateProject(ownerID, projectID)
+	}
+}
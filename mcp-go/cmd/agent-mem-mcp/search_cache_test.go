@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestSearchCache() *SearchCache {
+	return &SearchCache{entries: map[string]cachedSearch{}, ttl: time.Minute, maxEntries: 10}
+}
+
+func TestSearchCacheHitMiss(t *testing.T) {
+	cache := newTestSearchCache()
+	if _, ok := cache.Get("k1"); ok {
+		t.Fatalf("空缓存不应命中")
+	}
+	cache.Set("k1", "owner", []string{"p1"}, SearchResponse{Results: []SearchResult{{ID: "m1"}}})
+	value, ok := cache.Get("k1")
+	if !ok || len(value.Results) != 1 || value.Results[0].ID != "m1" {
+		t.Fatalf("缓存命中失败: %v %+v", ok, value)
+	}
+	value.Results[0].ID = "x"
+	if again, _ := cache.Get("k1"); again.Results[0].ID != "m1" {
+		t.Fatalf("缓存结果未进行拷贝隔离")
+	}
+	stored := SearchResponse{Results: []SearchResult{{ID: "m2", IndexPath: []string{"a"}, Highlights: []HighlightSpan{{Start: 0, End: 1}}}}}
+	cache.Set("k2", "owner", []string{"p1"}, stored)
+	stored.Results[0].IndexPath[0] = "changed"
+	got, _ := cache.Get("k2")
+	got.Results[0].Highlights[0].End = 9
+	if again, _ := cache.Get("k2"); again.Results[0].IndexPath[0] != "a" || again.Results[0].Highlights[0].End != 1 {
+		t.Fatalf("缓存结果的切片应深拷贝: %+v", again.Results[0])
+	}
+	stats := cache.Stats()
+	if stats.Hits != 4 || stats.Misses != 1 || stats.Entries != 2 {
+		t.Fatalf("命中统计错误: %+v", stats)
+	}
+}
+
+func TestSearchCacheInvalidateProject(t *testing.T) {
+	cache := newTestSearchCache()
+	cache.Set("p1", "owner", []string{"p1"}, SearchResponse{})
+	cache.Set("p2", "owner", []string{"p2"}, SearchResponse{})
+	cache.Set("set", "owner", []string{"p2", "p1"}, SearchResponse{})
+	cache.Set("owner", "owner", nil, SearchResponse{})
+	cache.Set("other-owner", "other", nil, SearchResponse{})
+
+	if removed := cache.InvalidateProject("owner", "p1"); removed != 3 {
+		t.Fatalf("应失效 3 条，实际 %d", removed)
+	}
+	for _, key := range []string{"p2", "other-owner"} {
+		if _, ok := cache.entries[key]; !ok {
+			t.Fatalf("无关条目 %s 不应失效", key)
+		}
+	}
+	if cache.Stats().Invalidations != 1 {
+		t.Fatalf("失效计数错误: %+v", cache.Stats())
+	}
+	cache.InvalidateProject("", "")
+	if _, ok := cache.entries["other-owner"]; ok {
+		t.Fatalf("未知 owner 时应清除所有 owner 级条目")
+	}
+	if _, ok := cache.entries["p2"]; ok {
+		t.Fatalf("未知项目时应清除所有项目级条目")
+	}
+}
+
+func TestSearchCacheDisabledByZeroTTL(t *testing.T) {
+	cache := NewSearchCache(SearchCacheConfig{TTLSeconds: 0, MaxEntries: 10})
+	cache.Set("k", "owner", nil, SearchResponse{})
+	if _, ok := cache.Get("k"); ok {
+		t.Fatalf("TTL=0 时缓存应关闭")
+	}
+}
+
+func TestSearchCacheKeyIncludesCursor(t *testing.T) {
+	base := SearchInput{OwnerID: "o", Query: "q", Limit: 10}
+	paged := base
+	paged.Cursor = "abc"
+	if searchCacheKey(base) == searchCacheKey(paged) {
+		t.Fatalf("不同游标应对应不同缓存键")
+	}
+}
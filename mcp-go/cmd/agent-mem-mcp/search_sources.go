@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// errSourceDegraded 召回源依赖的可选服务失败（如查询 embedding、前瞻检索），该源按超时处理：丢弃并记录，不使检索失败
+var errSourceDegraded = errors.New("召回源不可用")
+
+// sourceRunner 并发执行检索召回源：errgroup 限制并发数，每个源独立超时；
+// 超时或返回 errSourceDegraded 的源被丢弃并记录，其他错误仍使整次检索失败（同时取消其余源）
+type sourceRunner struct {
+	parent   context.Context
+	ctx      context.Context
+	group    *errgroup.Group
+	settings SearchSourcesConfig
+
+	mu       sync.Mutex
+	timedOut []string
+}
+
+func newSourceRunner(ctx context.Context, settings SearchSourcesConfig) *sourceRunner {
+	group, groupCtx := errgroup.WithContext(ctx)
+	if settings.Concurrency > 0 {
+		group.SetLimit(settings.Concurrency)
+	}
+	return &sourceRunner{parent: ctx, ctx: groupCtx, group: group, settings: settings}
+}
+
+// Go 以 name 对应的超时执行召回源；name 形如 "keyword:扩展词" 时按冒号前的源名取超时
+func (r *sourceRunner) Go(name string, fn func(ctx context.Context) error) {
+	r.group.Go(func() error {
+		ctx, cancel := context.WithTimeout(r.ctx, sourceTimeout(r.settings, name))
+		defer cancel()
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if r.parent.Err() == nil && (errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errSourceDegraded)) {
+			r.markTimedOut(name, err)
+			return nil
+		}
+		return err
+	})
+}
+
+func (r *sourceRunner) markTimedOut(name string, err error) {
+	log.Printf("[WARN] 检索召回源超时或失败已丢弃: %s err=%v", name, err)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !stringInSlice(r.timedOut, name) {
+		r.timedOut = append(r.timedOut, name)
+	}
+}
+
+func (r *sourceRunner) Wait() error {
+	return r.group.Wait()
+}
+
+// TimedOut 返回超时或失败被丢弃的召回源（排序后，便于缓存与比较）
+func (r *sourceRunner) TimedOut() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := append([]string(nil), r.timedOut...)
+	sort.Strings(result)
+	return result
+}
+
+func sourceTimeout(settings SearchSourcesConfig, name string) time.Duration {
+	key := name
+	if idx := strings.Index(key, ":"); idx >= 0 {
+		key = key[:idx]
+	}
+	if ms, ok := settings.TimeoutsMs[key]; ok && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	if settings.TimeoutMs > 0 {
+		return time.Duration(settings.TimeoutMs) * time.Millisecond
+	}
+	return time.Duration(defaultSettings().SearchSources.TimeoutMs) * time.Millisecond
+}
+
+// awaitResult 等待不以错误形式返回超时的调用（如查询扩展 LLM 请求超时会回退为本地关键词），ctx 结束时立即返回；
+// 返回前取消传给 fn 的 ctx，后台调用随之中止，其结果被丢弃
+func awaitResult[T any](ctx context.Context, fn func(ctx context.Context) T) (T, error) {
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan T, 1)
+	go func() {
+		done <- fn(callCtx)
+	}()
+	select {
+	case value := <-done:
+		return value, nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSourceRunnerDropsTimedOutSource(t *testing.T) {
+	runner := newSourceRunner(context.Background(), SearchSourcesConfig{Concurrency: 2, TimeoutMs: 1000, TimeoutsMs: map[string]int{"vector": 10}})
+	var keywordDone bool
+	runner.Go("vector", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	runner.Go("keyword", func(ctx context.Context) error {
+		keywordDone = true
+		return nil
+	})
+	if err := runner.Wait(); err != nil {
+		t.Fatalf("超时的召回源不应导致检索失败: %v", err)
+	}
+	if !keywordDone {
+		t.Fatalf("未超时的召回源应正常执行")
+	}
+	if got := runner.TimedOut(); !reflect.DeepEqual(got, []string{"vector"}) {
+		t.Fatalf("超时源记录错误: %v", got)
+	}
+}
+
+func TestSourceRunnerPropagatesError(t *testing.T) {
+	runner := newSourceRunner(context.Background(), SearchSourcesConfig{Concurrency: 1, TimeoutMs: 1000})
+	boom := errors.New("boom")
+	runner.Go("bm25", func(ctx context.Context) error { return boom })
+	if err := runner.Wait(); !errors.Is(err, boom) {
+		t.Fatalf("非超时错误应返回: %v", err)
+	}
+	if len(runner.TimedOut()) != 0 {
+		t.Fatalf("非超时错误不应记为超时")
+	}
+}
+
+func TestSourceTimeoutOverride(t *testing.T) {
+	settings := SearchSourcesConfig{TimeoutMs: 1500, TimeoutsMs: map[string]int{"bm25": 200}}
+	if got := sourceTimeout(settings, "bm25:扩展词"); got != 200*time.Millisecond {
+		t.Fatalf("扩展词应沿用源名超时: %v", got)
+	}
+	if got := sourceTimeout(settings, "keyword"); got != 1500*time.Millisecond {
+		t.Fatalf("未覆盖的源应使用默认超时: %v", got)
+	}
+}
+
+func TestAwaitResultTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	if _, err := awaitResult(ctx, func(context.Context) int { <-release; return 1 }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("调用超时应返回 DeadlineExceeded: %v", err)
+	}
+}
+
+func TestSourceRunnerMarksDegradedSource(t *testing.T) {
+	runner := newSourceRunner(context.Background(), SearchSourcesConfig{Concurrency: 2, TimeoutMs: 1000})
+	embedding := &queryEmbedding{done: make(chan struct{}), err: errors.New("embedding 服务不可用")}
+	close(embedding.done)
+	runner.Go("vector", func(ctx context.Context) error {
+		_, err := embedding.wait(ctx)
+		return err
+	})
+	if err := runner.Wait(); err != nil {
+		t.Fatalf("embedding 失败不应导致检索失败: %v", err)
+	}
+	if got := runner.TimedOut(); !reflect.DeepEqual(got, []string{"vector"}) {
+		t.Fatalf("embedding 失败的召回源应记为降级: %v", got)
+	}
+}
+
+func TestAwaitResultCancelsCallOnTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	stopped := make(chan struct{})
+	_, err := awaitResult(ctx, func(ctx context.Context) int {
+		<-ctx.Done()
+		close(stopped)
+		return 0
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("调用超时应返回 DeadlineExceeded: %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("超时后应取消后台调用的 ctx")
+	}
+}
@@ -29,3 +29,28 @@ func TestRRFMergeWithTrace(t *testing.T) {
 		t.Fatalf("RRF 分数未设置: %+v", item.RRFScore)
 	}
 }
+
+func TestFuzzyEnabledByProfile(t *testing.T) {
+	settings := defaultSettings()
+	searcher := &Searcher{settings: settings}
+	if !searcher.fuzzyEnabled("deep") || !searcher.fuzzyEnabled("balanced") {
+		t.Fatalf("deep/balanced 默认应启用模糊召回")
+	}
+	if searcher.fuzzyEnabled("fast") {
+		t.Fatalf("fast 默认不应启用模糊召回")
+	}
+	searcher.settings.Fuzzy.Enabled = false
+	if searcher.fuzzyEnabled("deep") {
+		t.Fatalf("关闭后不应启用模糊召回")
+	}
+}
+
+func TestRRFFuzzyWeightBelowExact(t *testing.T) {
+	merged := rrfMerge(
+		SourceRows{Name: "fuzzy", Rows: []FragmentRow{{FragmentID: "f1"}}},
+		SourceRows{Name: "keyword", Rows: []FragmentRow{{FragmentID: "f2"}}},
+	)
+	if len(merged) != 2 || merged[0].FragmentID != "f2" {
+		t.Fatalf("同名次下精确匹配应排在模糊匹配之前: %+v", merged)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pgvector/pgvector-go"
+)
+
+// mem.similar 标签/纵横轴重合度加权（相似度为 0-1，加权上限各 0.1，仅用于同档次内调整顺序）
+const (
+	similarTagBoost  = 0.1
+	similarAxesBoost = 0.1
+	// 开启加权时先多取候选再重排
+	similarCandidateMultiplier = 3
+)
+
+// SimilarMemories 以种子记忆的 avg_embedding 检索近邻（不调用 embedding API），排除种子及其版本链
+func (a *App) SimilarMemories(ctx context.Context, input SimilarInput) (SearchResponse, error) {
+	normalized, err := normalizeSimilarInput(input, a.settings)
+	if err != nil {
+		return SearchResponse{}, err
+	}
+	if err := validateSimilarInput(normalized); err != nil {
+		return SearchResponse{}, err
+	}
+	empty := SearchResponse{Results: []SearchResult{}, Metadata: SearchMetadata{Total: 0, Returned: 0, NextAction: "use_ids_to_call_mem_get"}}
+
+	seed, err := a.store.FetchMemorySnapshot(ctx, normalized.MemoryID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return SearchResponse{}, newValidationError("not_found", "ERR_MEMORY_NOT_FOUND", "memory_id 不存在", 404)
+		}
+		return SearchResponse{}, err
+	}
+	if len(seed.AvgEmbedding) == 0 {
+		return empty, nil
+	}
+
+	query := MemoryVectorQuery{OwnerID: normalized.OwnerID, Filter: FragmentFilter{Scope: normalized.Scope}}
+	if normalized.ContentTypes != nil {
+		query.Filter.ContentTypes = *normalized.ContentTypes
+	}
+	if normalized.Axes != nil {
+		query.Filter.Axes = *normalized.Axes
+	}
+	if normalized.IndexPath != nil {
+		query.Filter.IndexPath = *normalized.IndexPath
+	}
+	if normalized.ProjectKeys != nil && len(*normalized.ProjectKeys) > 0 {
+		keys := *normalized.ProjectKeys
+		if normalized.ProjectKey != "" {
+			keys = uniqueStrings(append([]string{normalized.ProjectKey}, keys...))
+		}
+		projectIDs, err := a.store.FindProjectIDsByKeys(ctx, normalized.OwnerID, keys)
+		if err != nil {
+			return SearchResponse{}, err
+		}
+		if len(projectIDs) == 0 {
+			return empty, nil
+		}
+		query.Filter.ProjectIDs = projectIDs
+	} else if normalized.ProjectKey != "" {
+		projectID, err := a.store.FindProjectIDByKey(ctx, normalized.OwnerID, normalized.ProjectKey)
+		if err != nil {
+			return SearchResponse{}, err
+		}
+		if projectID == "" {
+			return empty, nil
+		}
+		query.ProjectID = projectID
+	}
+
+	chain, err := a.store.FetchVersionChainIDs(ctx, seed.ID)
+	if err != nil {
+		return SearchResponse{}, err
+	}
+	query.ExcludeIDs = uniqueStrings(append(chain, seed.ID))
+
+	fetchLimit := normalized.Limit
+	if normalized.WithTags || normalized.WithAxes {
+		fetchLimit = normalized.Limit * similarCandidateMultiplier
+	}
+	rows, err := a.store.SearchMemoryVectors(ctx, pgvector.NewVector(seed.AvgEmbedding), query, fetchLimit)
+	if err != nil {
+		return SearchResponse{}, err
+	}
+	if len(rows) == 0 {
+		return empty, nil
+	}
+
+	results := make([]SearchResult, 0, len(rows))
+	for _, row := range rows {
+		score := distanceToSimilarity(row.Distance)
+		if normalized.WithTags {
+			score += similarTagBoost * jaccard(seed.Tags, row.Tags)
+		}
+		if normalized.WithAxes {
+			score += similarAxesBoost * jaccard(flattenAxes(seed.Axes), flattenAxes(row.Axes))
+		}
+		results = append(results, SearchResult{
+			ID:          row.ID,
+			ContentType: row.ContentType,
+			ProjectKey:  row.ProjectKey,
+			Axes:        axesPtr(row.Axes),
+			IndexPath:   row.IndexPath,
+			Score:       score,
+			Ts:          row.Ts,
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	total := len(results)
+	if len(results) > normalized.Limit {
+		results = results[:normalized.Limit]
+	}
+	enrichRelatedIDs(ctx, a.store, results)
+
+	return SearchResponse{
+		Results: results,
+		Metadata: SearchMetadata{
+			Total:      total,
+			Returned:   len(results),
+			NextAction: "use_ids_to_call_mem_get",
+		},
+	}, nil
+}
+
+// flattenAxes 将纵横轴展开为 "axis:value" 集合，便于计算重合度
+func flattenAxes(axes MemoryAxes) []string {
+	var values []string
+	add := func(axis string, items []string) {
+		for _, item := range items {
+			values = append(values, axis+":"+item)
+		}
+	}
+	add("domain", axes.Domain)
+	add("stack", axes.Stack)
+	add("problem", axes.Problem)
+	add("lifecycle", axes.Lifecycle)
+	add("component", axes.Component)
+	return values
+}
+
+// jaccard 计算两个字符串集合的 Jaccard 系数，任一为空返回 0
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, value := range a {
+		set[value] = true
+	}
+	union := len(set)
+	intersection := 0
+	seen := map[string]bool{}
+	for _, value := range b {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		if set[value] {
+			intersection++
+		} else {
+			union++
+		}
+	}
+	return float64(intersection) / float64(union)
+}
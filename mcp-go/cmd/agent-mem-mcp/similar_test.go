@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJaccard(t *testing.T) {
+	if got := jaccard([]string{"a", "b"}, []string{"b", "c", "c"}); got < 0.33 || got > 0.34 {
+		t.Fatalf("jaccard 计算错误: %v", got)
+	}
+	if got := jaccard(nil, []string{"a"}); got != 0 {
+		t.Fatalf("空集合应返回 0: %v", got)
+	}
+}
+
+func TestFlattenAxes(t *testing.T) {
+	values := flattenAxes(MemoryAxes{Domain: []string{"auth"}, Stack: []string{"go"}})
+	if len(values) != 2 || values[0] != "domain:auth" || values[1] != "stack:go" {
+		t.Fatalf("axes 展开错误: %v", values)
+	}
+}
+
+func TestValidateSimilarInputRequiresMemoryID(t *testing.T) {
+	input, err := normalizeSimilarInput(SimilarInput{OwnerID: "personal"}, defaultSettings())
+	if err != nil {
+		t.Fatalf("normalize 失败: %v", err)
+	}
+	if input.Limit != defaultSimilarLimit || input.Scope != "all" {
+		t.Fatalf("默认值错误: %+v", input)
+	}
+	var appErr *AppError
+	if err := validateSimilarInput(input); !errors.As(err, &appErr) || appErr.Code != "ERR_INVALID_MEMORY_ID" {
+		t.Fatalf("缺少 memory_id 应返回 ERR_INVALID_MEMORY_ID: %v", err)
+	}
+}
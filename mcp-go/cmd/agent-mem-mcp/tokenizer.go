@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Tokenizer 词法分词器：同一实现同时用于片段入库（生成 lexemes）与查询，保证两侧切分一致
+type Tokenizer interface {
+	// Name 标识分词器（含词典指纹），入库时记录，切换后由 backfill 重建
+	Name() string
+	Tokenize(text string) []string
+}
+
+// newTokenizer 根据配置构建分词器：bigram（默认）/ dict / simple
+func newTokenizer(cfg LexicalConfig) (Tokenizer, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Tokenizer)) {
+	case "", "bigram":
+		return bigramTokenizer{}, nil
+	case "simple":
+		return simpleTokenizer{}, nil
+	case "dict":
+		words, err := loadTokenizerDict(cfg.DictPath)
+		if err != nil {
+			return nil, err
+		}
+		return newDictTokenizer(words), nil
+	default:
+		return nil, fmt.Errorf("未知分词器: %s", cfg.Tokenizer)
+	}
+}
+
+// simpleTokenizer 按非字母数字切分并转小写，等价于原 to_tsvector('simple') 行为
+type simpleTokenizer struct{}
+
+func (simpleTokenizer) Name() string { return "simple" }
+
+func (simpleTokenizer) Tokenize(text string) []string {
+	var tokens []string
+	splitLexicalRuns(text, func(run []rune, cjk bool) {
+		tokens = append(tokens, strings.ToLower(string(run)))
+	})
+	return tokens
+}
+
+// bigramTokenizer 连续中日韩字符按相邻二元切分（单字保留为一元），其他字母数字按词切分
+type bigramTokenizer struct{}
+
+func (bigramTokenizer) Name() string { return "bigram" }
+
+func (bigramTokenizer) Tokenize(text string) []string {
+	var tokens []string
+	splitLexicalRuns(text, func(run []rune, cjk bool) {
+		if cjk {
+			tokens = appendBigrams(tokens, run)
+			return
+		}
+		tokens = append(tokens, strings.ToLower(string(run)))
+	})
+	return tokens
+}
+
+// dictTokenizer 词典正向最大匹配；词典未覆盖的连续片段退化为 bigram
+type dictTokenizer struct {
+	words  map[string]bool
+	maxLen int
+	name   string
+}
+
+func newDictTokenizer(words []string) dictTokenizer {
+	tokenizer := dictTokenizer{words: make(map[string]bool, len(words))}
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" {
+			continue
+		}
+		tokenizer.words[word] = true
+		if n := len([]rune(word)); n > tokenizer.maxLen {
+			tokenizer.maxLen = n
+		}
+	}
+	tokenizer.name = "dict:" + hashContent(strings.Join(uniqueStrings(words), "\n"))[:8]
+	return tokenizer
+}
+
+func (t dictTokenizer) Name() string { return t.name }
+
+func (t dictTokenizer) Tokenize(text string) []string {
+	var tokens []string
+	splitLexicalRuns(text, func(run []rune, cjk bool) {
+		if !cjk {
+			tokens = append(tokens, strings.ToLower(string(run)))
+			return
+		}
+		var pending []rune
+		for i := 0; i < len(run); {
+			matched := 0
+			for size := min(t.maxLen, len(run)-i); size >= 2; size-- {
+				if t.words[string(run[i:i+size])] {
+					matched = size
+					break
+				}
+			}
+			if matched == 0 {
+				pending = append(pending, run[i])
+				i++
+				continue
+			}
+			tokens = appendBigrams(tokens, pending)
+			pending = pending[:0]
+			tokens = append(tokens, string(run[i:i+matched]))
+			i += matched
+		}
+		tokens = appendBigrams(tokens, pending)
+	})
+	return tokens
+}
+
+// loadTokenizerDict 读取词典文件：每行一个词，行内空白后的内容（如词频）忽略，# 开头为注释
+func loadTokenizerDict(path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("dict 分词器需要配置 lexical.dict_path")
+	}
+	file, err := os.Open(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("读取分词词典失败: %w", err)
+	}
+	defer file.Close()
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, strings.Fields(line)[0])
+	}
+	return words, scanner.Err()
+}
+
+// splitLexicalRuns 将文本切为字母数字连续段，中日韩字符单独成段
+func splitLexicalRuns(text string, emit func(run []rune, cjk bool)) {
+	var run []rune
+	runCJK := false
+	flush := func() {
+		if len(run) > 0 {
+			emit(run, runCJK)
+			run = nil
+		}
+	}
+	for _, r := range text {
+		switch {
+		case isCJKRune(r):
+			if !runCJK {
+				flush()
+			}
+			runCJK = true
+			run = append(run, r)
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if runCJK {
+				flush()
+			}
+			runCJK = false
+			run = append(run, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+}
+
+func isCJKRune(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+func appendBigrams(tokens []string, run []rune) []string {
+	if len(run) == 1 {
+		return append(tokens, string(run))
+	}
+	for i := 0; i+1 < len(run); i++ {
+		tokens = append(tokens, string(run[i:i+2]))
+	}
+	return tokens
+}
+
+// lexemeFormat 词项文档格式：保留词项顺序与重复，tsvector 位置与原文相邻关系一致（短语以 <-> 匹配）；
+// 格式变更时修改该值，入库记录的 lexeme_tokenizer 随之变化，启动时由 backfill 重建
+const lexemeFormat = "pos"
+
+// lexemeTokenizerName 入库时记录的分词器标识（分词器名 + 词项文档格式）
+func lexemeTokenizerName(tokenizer Tokenizer) string {
+	return tokenizer.Name() + "+" + lexemeFormat
+}
+
+// lexemeDocument 生成入库用的词项文本（空格分隔，交给 to_tsvector('simple') 直接建索引）
+func lexemeDocument(tokenizer Tokenizer, text string) string {
+	return strings.Join(tokenizer.Tokenize(text), " ")
+}
+
+// lexemeQuery 生成 to_tsquery('simple') 表达式，所有词项必须命中；无可用词项返回空串
+func lexemeQuery(tokenizer Tokenizer, text string) string {
+	tokens := uniqueStrings(tokenizer.Tokenize(text))
+	if len(tokens) == 0 {
+		return ""
+	}
+	quoted := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		quoted = append(quoted, "'"+token+"'")
+	}
+	return strings.Join(quoted, " & ")
+}
+
+// lexemePhraseQuery 短语的词项按原顺序以 <->（相邻）连接；无可用词项返回空串
+func lexemePhraseQuery(tokenizer Tokenizer, phrase string) string {
+	tokens := tokenizer.Tokenize(phrase)
+	if len(tokens) == 0 {
+		return ""
+	}
+	quoted := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		quoted = append(quoted, "'"+token+"'")
+	}
+	return strings.Join(quoted, " <-> ")
+}
+
+// bm25Query BM25 召回的 tsquery：检索词的词项全部命中，且每个短语的词项在片段中相邻出现（短语同时参与 ts_rank_cd 打分）
+func bm25Query(tokenizer Tokenizer, keyword string, phrases []string) string {
+	var parts []string
+	if query := lexemeQuery(tokenizer, keyword); query != "" {
+		parts = append(parts, query)
+	}
+	for _, phrase := range phrases {
+		if query := lexemePhraseQuery(tokenizer, phrase); query != "" {
+			parts = append(parts, "("+query+")")
+		}
+	}
+	return strings.Join(parts, " & ")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBigramTokenizer(t *testing.T) {
+	got := bigramTokenizer{}.Tokenize("用户登录 API, 库v2")
+	want := []string{"用户", "户登", "登录", "api", "库", "v2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bigram 切分错误: got=%v want=%v", got, want)
+	}
+}
+
+func TestDictTokenizerFallsBackToBigram(t *testing.T) {
+	tokenizer := newDictTokenizer([]string{"数据库", "连接池"})
+	got := tokenizer.Tokenize("配置数据库连接池参数")
+	want := []string{"配置", "数据库", "连接池", "参数"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("dict 切分错误: got=%v want=%v", got, want)
+	}
+	if tokenizer.Name() == newDictTokenizer([]string{"数据库"}).Name() {
+		t.Fatalf("不同词典应生成不同的分词器名称")
+	}
+}
+
+func TestNewTokenizerDictFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dict.txt")
+	if err := os.WriteFile(path, []byte("# 注释\n数据库 100\n\n连接池\n"), 0o644); err != nil {
+		t.Fatalf("写入词典失败: %v", err)
+	}
+	tokenizer, err := newTokenizer(LexicalConfig{Tokenizer: "dict", DictPath: path})
+	if err != nil {
+		t.Fatalf("构建 dict 分词器失败: %v", err)
+	}
+	if got := tokenizer.Tokenize("数据库连接池"); !reflect.DeepEqual(got, []string{"数据库", "连接池"}) {
+		t.Fatalf("词典未生效: %v", got)
+	}
+	if _, err := newTokenizer(LexicalConfig{Tokenizer: "dict"}); err == nil {
+		t.Fatalf("缺少 dict_path 应报错")
+	}
+	if _, err := newTokenizer(LexicalConfig{Tokenizer: "unknown"}); err == nil {
+		t.Fatalf("未知分词器应报错")
+	}
+}
+
+func TestLexemeQuery(t *testing.T) {
+	if got := lexemeQuery(bigramTokenizer{}, "登录登录 api"); got != "'登录' & '录登' & 'api'" {
+		t.Fatalf("tsquery 构造错误: %s", got)
+	}
+	if got := lexemeQuery(bigramTokenizer{}, "!!!"); got != "" {
+		t.Fatalf("无词项时应返回空串: %s", got)
+	}
+}
+
+func TestBM25QueryPhraseUsesAdjacency(t *testing.T) {
+	got := bm25Query(bigramTokenizer{}, "数据库 连接池 pgx", []string{"数据库 连接池", "!!!"})
+	want := "'数据' & '据库' & '连接' & '接池' & 'pgx' & ('数据' <-> '据库' <-> '连接' <-> '接池')"
+	if got != want {
+		t.Fatalf("短语应以 <-> 连接并与检索词合并:\n got=%s\nwant=%s", got, want)
+	}
+	if got := bm25Query(bigramTokenizer{}, "!!!", nil); got != "" {
+		t.Fatalf("无词项时应返回空串: %s", got)
+	}
+}
+
+func TestLexemeDocumentKeepsPositions(t *testing.T) {
+	if got := lexemeDocument(simpleTokenizer{}, "a b a"); got != "a b a" {
+		t.Fatalf("词项文档应保留顺序与重复词项（短语匹配依赖位置）: %q", got)
+	}
+	if lexemeTokenizerName(bigramTokenizer{}) == (bigramTokenizer{}).Name() {
+		t.Fatalf("入库标识应包含词项文档格式，格式变更后触发重建")
+	}
+}
+
+func TestTokenizeLexicalQueryUsesTokenizer(t *testing.T) {
+	got := tokenizeLexicalQuery(bigramTokenizer{}, "用户登录 a")
+	want := []string{"用户", "户登", "登录"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("查询词项错误: got=%v want=%v", got, want)
+	}
+}
@@ -16,12 +16,66 @@ type IngestMemoryInput struct {
 	Axes        *MemoryAxes `json:"axes,omitempty"`
 	IndexPath   *[]string   `json:"index_path,omitempty"`
 	Ts          int64       `json:"ts"`
+	// 仲裁建议替换时通过 elicitation 请用户确认（需客户端支持）；未指定时使用 versioning.confirm_conflicts
+	ConfirmConflicts *bool `json:"confirm_conflicts,omitempty"`
+	// 异步写入：校验后入队立即返回 job_id，摘要、向量化与仲裁由后台 worker 完成（不发起冲突确认）
+	Async *bool `json:"async,omitempty"`
 }
 
+// IngestMemoryOutput 异步写入时 status 为 queued，id 为处理完成前用于全文检索的临时记忆
 type IngestMemoryOutput struct {
 	ID     string `json:"id"`
 	Status string `json:"status"`
 	Ts     int64  `json:"ts"`
+	JobID  string `json:"job_id,omitempty"`
+}
+
+type IngestStatusInput struct {
+	OwnerID string   `json:"owner_id,omitempty"`
+	JobIDs  []string `json:"job_ids"`
+}
+
+// IngestJobStatus 异步写入任务状态：status 为 queued/running/done/failed；
+// 完成后 result_status 与 memory_id 为最终写入结果（created/updated/duplicate/skipped），
+// 失败时临时记忆已删除、memory_id 为空，last_error 说明原因
+type IngestJobStatus struct {
+	JobID        string `json:"job_id"`
+	Status       string `json:"status"`
+	MemoryID     string `json:"memory_id,omitempty"`
+	ResultStatus string `json:"result_status,omitempty"`
+	Attempts     int    `json:"attempts"`
+	MaxAttempts  int    `json:"max_attempts"`
+	NextRunAt    int64  `json:"next_run_at,omitempty"`
+	LastError    string `json:"last_error,omitempty"`
+	CreatedAt    int64  `json:"created_at"`
+	UpdatedAt    int64  `json:"updated_at"`
+}
+
+type IngestStatusResponse struct {
+	Results []IngestJobStatus `json:"results"`
+}
+
+// IngestBatchInput mode：best_effort（默认，逐条提交）/ all_or_nothing（同一事务写入，任一条失败则整批不写入）
+type IngestBatchInput struct {
+	Items []IngestMemoryInput `json:"items"`
+	Mode  *string             `json:"mode,omitempty"`
+}
+
+// IngestBatchItemResult 单条写入结果：status 为 created/updated/duplicate/skipped/error，Index 为该条在请求中的序号（从 0 开始）
+type IngestBatchItemResult struct {
+	Index  int            `json:"index"`
+	ID     string         `json:"id,omitempty"`
+	Status string         `json:"status"`
+	Ts     int64          `json:"ts,omitempty"`
+	Error  *ErrorResponse `json:"error,omitempty"`
+}
+
+type IngestBatchOutput struct {
+	Mode string `json:"mode"`
+	// Committed 为 false 表示 all_or_nothing 整批回滚（各条 error 说明原因）
+	Committed bool                    `json:"committed"`
+	Results   []IngestBatchItemResult `json:"results"`
+	Counts    map[string]int          `json:"counts"`
 }
 
 type SearchInput struct {
@@ -37,6 +91,15 @@ type SearchInput struct {
 	Axes        *MemoryAxes `json:"axes,omitempty"`
 	IndexPath   *[]string   `json:"index_path,omitempty"`
 	Limit       int         `json:"limit"`
+	// 以下为可选过滤条件：多类型、多项目、标签必选/排除、时间范围（秒级时间戳）
+	ContentTypes *[]string `json:"content_types,omitempty"`
+	ProjectKeys  *[]string `json:"project_keys,omitempty"`
+	Tags         *[]string `json:"tags,omitempty"`
+	ExcludeTags  *[]string `json:"exclude_tags,omitempty"`
+	Since        int64     `json:"since,omitempty"`
+	Until        int64     `json:"until,omitempty"`
+	// cursor 为上一页返回的 metadata.next_cursor，需与首页使用相同的检索参数
+	Cursor string `json:"cursor,omitempty"`
 }
 
 type SearchResult struct {
@@ -52,12 +115,26 @@ type SearchResult struct {
 	ChunkIndex  int          `json:"chunk_index,omitempty"`
 	TotalChunks int          `json:"total_chunks,omitempty"`
 	RelatedIDs  []string     `json:"related_ids,omitempty"`
+	// Highlights mode=highlight 时返回，偏移为 snippet 内的 rune 下标
+	Highlights []HighlightSpan `json:"highlights,omitempty"`
+}
+
+// HighlightSpan snippet 中的命中区间 [start, end)；source 为 lexical（关键词/BM25 词项）或 vector（最相似句）
+type HighlightSpan struct {
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Source string `json:"source"`
 }
 
 type SearchMetadata struct {
 	Total      int    `json:"total"`
 	Returned   int    `json:"returned"`
 	NextAction string `json:"next_action"`
+	NextCursor string `json:"next_cursor,omitempty"` // 还有下一页时返回，原样回传即可翻页
+	// Truncated 已达到最大翻页深度，后续结果不再提供游标
+	Truncated bool `json:"truncated,omitempty"`
+	// TimedOutSources 超时或失败被丢弃的召回源，非空表示结果为降级结果（不写入检索缓存）
+	TimedOutSources []string `json:"timed_out_sources,omitempty"`
 }
 
 type SearchResponse struct {
@@ -65,6 +142,68 @@ type SearchResponse struct {
 	Metadata SearchMetadata `json:"metadata"`
 }
 
+// FeedbackInput mem.feedback：标记某条记忆对某次检索是否有用（query 可选，缺省时关联该记忆最近一次被检索返回的查询）
+type FeedbackInput struct {
+	OwnerID  string `json:"owner_id"`
+	MemoryID string `json:"memory_id"`
+	Query    string `json:"query,omitempty"`
+	// rating: helpful / unhelpful
+	Rating string `json:"rating"`
+	Note   string `json:"note,omitempty"`
+}
+
+type FeedbackOutput struct {
+	ID      int64    `json:"id"`
+	Status  string   `json:"status"`
+	Query   string   `json:"query,omitempty"`
+	Sources []string `json:"sources,omitempty"` // 返回该记忆的召回源，用于学习各源权重
+}
+
+// ContextInput mem.context：按任务描述与 token 预算打包上下文；priority 覆盖配置中的打包顺序
+type ContextInput struct {
+	OwnerID     string    `json:"owner_id"`
+	ProjectKey  string    `json:"project_key"`
+	Task        string    `json:"task"`
+	TokenBudget int       `json:"token_budget,omitempty"`
+	Priority    *[]string `json:"priority,omitempty"`
+}
+
+// ContextItem 打包条目：kind 为 distilled/latest/fragment/foresight，memory_id 为引用来源
+type ContextItem struct {
+	Kind      string `json:"kind"`
+	MemoryID  string `json:"memory_id"`
+	Text      string `json:"text"`
+	Tokens    int    `json:"tokens"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+type ContextPack struct {
+	Task        string        `json:"task"`
+	TokenBudget int           `json:"token_budget"`
+	TokensUsed  int           `json:"tokens_used"`
+	Priority    []string      `json:"priority"`
+	Items       []ContextItem `json:"items"`
+	Citations   []string      `json:"citations"`
+	Dropped     int           `json:"dropped"` // 因预算或配额未能放入的候选数
+	Text        string        `json:"text"`    // 可直接放入上下文的渲染结果，条目以 [mem:ID] 标注引用
+}
+
+// SimilarInput mem.similar：以已有记忆的 avg_embedding 为种子检索相近记忆
+type SimilarInput struct {
+	OwnerID      string      `json:"owner_id"`
+	MemoryID     string      `json:"memory_id"`
+	ProjectKey   string      `json:"project_key,omitempty"`
+	ProjectKeys  *[]string   `json:"project_keys,omitempty"`
+	Scope        string      `json:"scope,omitempty"`
+	ContentTypes *[]string   `json:"content_types,omitempty"`
+	Axes         *MemoryAxes `json:"axes,omitempty"`
+	IndexPath    *[]string   `json:"index_path,omitempty"`
+	// with_tags / with_axes：按与种子的标签、纵横轴重合度加权排序
+	WithTags bool `json:"with_tags,omitempty"`
+	WithAxes bool `json:"with_axes,omitempty"`
+	Limit    int  `json:"limit,omitempty"`
+}
+
 type GetMemoriesInput struct {
 	IDs     []string `json:"ids"`
 	OwnerID string   `json:"owner_id,omitempty"` // 可选，兼容 Codex 传参
@@ -93,6 +232,7 @@ type TimelineInput struct {
 	ProjectPath string `json:"project_path,omitempty"`
 	Days        int    `json:"days"`
 	Limit       int    `json:"limit"`
+	Cursor      string `json:"cursor,omitempty"`
 }
 
 type TimelineItem struct {
@@ -110,6 +250,7 @@ type TimelineResponse struct {
 type ListProjectsInput struct {
 	OwnerID string `json:"owner_id"`
 	Limit   int    `json:"limit"`
+	Cursor  string `json:"cursor,omitempty"`
 }
 
 type ProjectListItem struct {
@@ -187,9 +328,21 @@ type ArbitrationLogInsert struct {
 	OldSummary        string
 	NewSummary        string
 	Model             string
+	DecidedBy         string
 	CreatedAt         time.Time
 }
 
+// IngestJobRecord 被 worker 领取的异步写入任务；Attempts 含本次
+type IngestJobRecord struct {
+	ID          string
+	OwnerID     string
+	ProjectID   string
+	MemoryID    string
+	Payload     IngestMemoryInput
+	Attempts    int
+	MaxAttempts int
+}
+
 type FragmentInsert struct {
 	ID         string
 	MemoryID   string
@@ -294,6 +447,7 @@ type ArbitrationHistoryInput struct {
 	MemoryID   string `json:"memory_id,omitempty"`   // 可选：查特定记忆的仲裁历史
 	ProjectKey string `json:"project_key,omitempty"` // 可选：查特定项目的仲裁历史
 	Limit      int    `json:"limit"`
+	Cursor     string `json:"cursor,omitempty"`
 }
 
 type ArbitrationRecord struct {
@@ -305,6 +459,7 @@ type ArbitrationRecord struct {
 	OldSummary        string  `json:"old_summary"`
 	NewSummary        string  `json:"new_summary"`
 	Model             string  `json:"model"`
+	DecidedBy         string  `json:"decided_by"`
 	CreatedAt         int64   `json:"created_at"`
 }
 
@@ -357,6 +512,7 @@ type RelationsInput struct {
 	Direction    string `json:"direction,omitempty"`
 	RelationType string `json:"relation_type,omitempty"`
 	Limit        int    `json:"limit,omitempty"`
+	Cursor       string `json:"cursor,omitempty"`
 }
 
 type RelationsResponse struct {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"strings"
 	"time"
 	"unicode"
@@ -18,6 +19,9 @@ func (e *AppError) Error() string {
 	return e.Message
 }
 
+// maxSearchFilterValues 限制 content_types/project_keys 等列表过滤的长度
+const maxSearchFilterValues = 20
+
 // contentTypeSet 保留向后兼容，用于 scope 过滤
 var contentTypeSet = map[string]bool{
 	"requirement": true,
@@ -136,9 +140,118 @@ func validateSearchInput(input SearchInput) error {
 	if err := validateIndexPathPtr(input.IndexPath); err != nil {
 		return err
 	}
+	if err := validateSearchFilters(input); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validateSearchFilters(input SearchInput) error {
+	if input.ContentTypes != nil {
+		if len(*input.ContentTypes) > maxSearchFilterValues {
+			return newValidationError("invalid_request", "ERR_INVALID_CONTENT_TYPES", "content_types 数量过多", 400)
+		}
+		for _, ct := range *input.ContentTypes {
+			if len([]rune(ct)) > 50 || containsControl(ct) {
+				return newValidationError("invalid_request", "ERR_INVALID_CONTENT_TYPES", "content_types 无效", 400)
+			}
+		}
+	}
+	if input.ProjectKeys != nil {
+		if len(*input.ProjectKeys) > maxSearchFilterValues {
+			return newValidationError("invalid_request", "ERR_INVALID_PROJECT_KEYS", "project_keys 数量过多", 400)
+		}
+		for _, key := range *input.ProjectKeys {
+			if err := validateProjectKey(key); err != nil {
+				return err
+			}
+		}
+	}
+	if err := validateTagsPtr(input.Tags); err != nil {
+		return err
+	}
+	if err := validateTagsPtr(input.ExcludeTags); err != nil {
+		return err
+	}
+	if input.Since < 0 || input.Until < 0 {
+		return newValidationError("invalid_request", "ERR_INVALID_TIME_RANGE", "since/until 必须为正整数", 400)
+	}
+	if input.Since > 0 && input.Until > 0 && input.Since > input.Until {
+		return newValidationError("invalid_request", "ERR_INVALID_TIME_RANGE", "since 不能晚于 until", 400)
+	}
+	return nil
+}
+
+func validateFeedbackInput(input FeedbackInput) error {
+	if err := validateOwnerID(input.OwnerID); err != nil {
+		return err
+	}
+	if input.MemoryID == "" || len(input.MemoryID) > 100 || containsControl(input.MemoryID) {
+		return newValidationError("invalid_request", "ERR_INVALID_MEMORY_ID", "memory_id 无效", 400)
+	}
+	if input.Rating != "helpful" && input.Rating != "unhelpful" {
+		return newValidationError("invalid_request", "ERR_INVALID_RATING", "rating 必须为 helpful 或 unhelpful", 400)
+	}
+	if len([]rune(input.Query)) > 1000 {
+		return newValidationError("invalid_request", "ERR_INVALID_QUERY", "query 过长", 400)
+	}
+	if len([]rune(input.Note)) > 2000 || containsControl(input.Note) {
+		return newValidationError("invalid_request", "ERR_INVALID_NOTE", "note 无效", 400)
+	}
+	return nil
+}
+
+func validateContextInput(input ContextInput, cfg ContextConfig) error {
+	if err := validateOwnerID(input.OwnerID); err != nil {
+		return err
+	}
+	if err := validateProjectKey(input.ProjectKey); err != nil {
+		return err
+	}
+	if input.Task == "" || !hasMeaningfulContent(input.Task) {
+		return newValidationError("invalid_request", "ERR_INVALID_TASK", "task 不能为空", 400)
+	}
+	if len([]rune(input.Task)) > 1000 {
+		return newValidationError("invalid_request", "ERR_INVALID_TASK", "task 过长", 400)
+	}
+	if input.TokenBudget < contextMinItemTokens || input.TokenBudget > cfg.MaxTokenBudget {
+		return newValidationError("invalid_request", "ERR_INVALID_TOKEN_BUDGET", fmt.Sprintf("token_budget 必须在 %d-%d 之间", contextMinItemTokens, cfg.MaxTokenBudget), 400)
+	}
+	if input.Priority != nil {
+		return validateContextPriority(*input.Priority)
+	}
 	return nil
 }
 
+func validateSimilarInput(input SimilarInput) error {
+	if err := validateOwnerID(input.OwnerID); err != nil {
+		return err
+	}
+	if input.MemoryID == "" || len(input.MemoryID) > 100 || containsControl(input.MemoryID) {
+		return newValidationError("invalid_request", "ERR_INVALID_MEMORY_ID", "memory_id 无效", 400)
+	}
+	if input.ProjectKey != "" {
+		if err := validateProjectKey(input.ProjectKey); err != nil {
+			return err
+		}
+	}
+	if input.Scope != "all" {
+		if len([]rune(input.Scope)) > 50 || containsControl(input.Scope) {
+			return newValidationError("invalid_request", "ERR_INVALID_SCOPE", "scope 无效", 400)
+		}
+	}
+	if input.Limit < 1 || input.Limit > 100 {
+		return newValidationError("invalid_request", "ERR_INVALID_LIMIT", "limit 必须在 1-100 之间", 400)
+	}
+	if err := validateAxes(input.Axes); err != nil {
+		return err
+	}
+	if err := validateIndexPathPtr(input.IndexPath); err != nil {
+		return err
+	}
+	return validateSearchFilters(SearchInput{ContentTypes: input.ContentTypes, ProjectKeys: input.ProjectKeys})
+}
+
 func validateTimelineInput(input TimelineInput) error {
 	if err := validateOwnerID(input.OwnerID); err != nil {
 		return err
@@ -360,7 +473,7 @@ func validateSearchProfile(profile string) error {
 
 func validateSearchMode(mode string) error {
 	switch mode {
-	case "", "full", "ids", "compact":
+	case "", "full", "ids", "compact", "highlight":
 		return nil
 	default:
 		return newValidationError("invalid_request", "ERR_INVALID_MODE", "mode 无效", 400)
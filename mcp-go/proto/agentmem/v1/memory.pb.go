@@ -0,0 +1,826 @@
+// agent-mem gRPC 服务定义（服务端：mcp-go/cmd/agent-mem-mcp/grpc_server.go，启动参数 -grpc-port）。
+//
+// 一元调用的请求与响应使用 google.protobuf.Struct，字段与 REST 接口一致，结构定义见 /openapi.json 的 components.schemas；
+// 服务端按 schema 严格校验（未知字段返回 INVALID_ARGUMENT，ErrorInfo.reason 为 ERR_INVALID_FIELD）。
+// 流式调用（BulkIngest、Watch）使用下方的类型化消息，字段与对应结构体的 JSON 字段同名。
+// 错误以 google.rpc.ErrorInfo 详情返回：reason 为错误码，domain 为 agent-mem，metadata.error 为 error key。
+// 鉴权：metadata authorization: Bearer <token> 或 x-agent-mem-token: <token>（令牌同 AGENT_MEM_HTTP_TOKEN）。
+//
+// Go 代码（memory.pb.go、memory_grpc.pb.go）由本文件生成，修改后在 mcp-go/proto 目录执行：
+//   protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative agentmem/v1/memory.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: agentmem/v1/memory.proto
+
+package agentmemv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// 记忆多维索引，同 MemoryAxes
+type MemoryAxes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        []string               `protobuf:"bytes,1,rep,name=domain,proto3" json:"domain,omitempty"`
+	Stack         []string               `protobuf:"bytes,2,rep,name=stack,proto3" json:"stack,omitempty"`
+	Problem       []string               `protobuf:"bytes,3,rep,name=problem,proto3" json:"problem,omitempty"`
+	Lifecycle     []string               `protobuf:"bytes,4,rep,name=lifecycle,proto3" json:"lifecycle,omitempty"`
+	Component     []string               `protobuf:"bytes,5,rep,name=component,proto3" json:"component,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MemoryAxes) Reset() {
+	*x = MemoryAxes{}
+	mi := &file_agentmem_v1_memory_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MemoryAxes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MemoryAxes) ProtoMessage() {}
+
+func (x *MemoryAxes) ProtoReflect() protoreflect.Message {
+	mi := &file_agentmem_v1_memory_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MemoryAxes.ProtoReflect.Descriptor instead.
+func (*MemoryAxes) Descriptor() ([]byte, []int) {
+	return file_agentmem_v1_memory_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MemoryAxes) GetDomain() []string {
+	if x != nil {
+		return x.Domain
+	}
+	return nil
+}
+
+func (x *MemoryAxes) GetStack() []string {
+	if x != nil {
+		return x.Stack
+	}
+	return nil
+}
+
+func (x *MemoryAxes) GetProblem() []string {
+	if x != nil {
+		return x.Problem
+	}
+	return nil
+}
+
+func (x *MemoryAxes) GetLifecycle() []string {
+	if x != nil {
+		return x.Lifecycle
+	}
+	return nil
+}
+
+func (x *MemoryAxes) GetComponent() []string {
+	if x != nil {
+		return x.Component
+	}
+	return nil
+}
+
+// 单条记忆写入参数，同 mem.ingest_memory 入参（BulkIngest 不支持 async 与冲突确认）
+type IngestMemoryInput struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	OwnerId     string                 `protobuf:"bytes,1,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	ProjectKey  string                 `protobuf:"bytes,2,opt,name=project_key,json=projectKey,proto3" json:"project_key,omitempty"`
+	ProjectName string                 `protobuf:"bytes,3,opt,name=project_name,json=projectName,proto3" json:"project_name,omitempty"`
+	MachineName string                 `protobuf:"bytes,4,opt,name=machine_name,json=machineName,proto3" json:"machine_name,omitempty"`
+	ProjectPath string                 `protobuf:"bytes,5,opt,name=project_path,json=projectPath,proto3" json:"project_path,omitempty"`
+	ContentType string                 `protobuf:"bytes,6,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Content     string                 `protobuf:"bytes,7,opt,name=content,proto3" json:"content,omitempty"`
+	Summary     string                 `protobuf:"bytes,8,opt,name=summary,proto3" json:"summary,omitempty"`
+	Tags        []string               `protobuf:"bytes,9,rep,name=tags,proto3" json:"tags,omitempty"`
+	SkipLlm     bool                   `protobuf:"varint,10,opt,name=skip_llm,json=skipLlm,proto3" json:"skip_llm,omitempty"`
+	Axes        *MemoryAxes            `protobuf:"bytes,11,opt,name=axes,proto3" json:"axes,omitempty"`
+	IndexPath   []string               `protobuf:"bytes,12,rep,name=index_path,json=indexPath,proto3" json:"index_path,omitempty"`
+	// 秒级时间戳，0 表示使用服务端当前时间
+	Ts            int64 `protobuf:"varint,13,opt,name=ts,proto3" json:"ts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestMemoryInput) Reset() {
+	*x = IngestMemoryInput{}
+	mi := &file_agentmem_v1_memory_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestMemoryInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestMemoryInput) ProtoMessage() {}
+
+func (x *IngestMemoryInput) ProtoReflect() protoreflect.Message {
+	mi := &file_agentmem_v1_memory_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestMemoryInput.ProtoReflect.Descriptor instead.
+func (*IngestMemoryInput) Descriptor() ([]byte, []int) {
+	return file_agentmem_v1_memory_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *IngestMemoryInput) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *IngestMemoryInput) GetProjectKey() string {
+	if x != nil {
+		return x.ProjectKey
+	}
+	return ""
+}
+
+func (x *IngestMemoryInput) GetProjectName() string {
+	if x != nil {
+		return x.ProjectName
+	}
+	return ""
+}
+
+func (x *IngestMemoryInput) GetMachineName() string {
+	if x != nil {
+		return x.MachineName
+	}
+	return ""
+}
+
+func (x *IngestMemoryInput) GetProjectPath() string {
+	if x != nil {
+		return x.ProjectPath
+	}
+	return ""
+}
+
+func (x *IngestMemoryInput) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *IngestMemoryInput) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *IngestMemoryInput) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+func (x *IngestMemoryInput) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *IngestMemoryInput) GetSkipLlm() bool {
+	if x != nil {
+		return x.SkipLlm
+	}
+	return false
+}
+
+func (x *IngestMemoryInput) GetAxes() *MemoryAxes {
+	if x != nil {
+		return x.Axes
+	}
+	return nil
+}
+
+func (x *IngestMemoryInput) GetIndexPath() []string {
+	if x != nil {
+		return x.IndexPath
+	}
+	return nil
+}
+
+func (x *IngestMemoryInput) GetTs() int64 {
+	if x != nil {
+		return x.Ts
+	}
+	return 0
+}
+
+// 错误体，同 REST 错误响应
+type ErrorResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         string                 `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Code          string                 `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErrorResponse) Reset() {
+	*x = ErrorResponse{}
+	mi := &file_agentmem_v1_memory_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorResponse) ProtoMessage() {}
+
+func (x *ErrorResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agentmem_v1_memory_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorResponse.ProtoReflect.Descriptor instead.
+func (*ErrorResponse) Descriptor() ([]byte, []int) {
+	return file_agentmem_v1_memory_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ErrorResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ErrorResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ErrorResponse) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *ErrorResponse) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+// 单条写入结果：index 为该条在流中的序号，status 为 created/updated/duplicate/skipped/error
+type IngestBatchItemResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Ts            int64                  `protobuf:"varint,4,opt,name=ts,proto3" json:"ts,omitempty"`
+	Error         *ErrorResponse         `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestBatchItemResult) Reset() {
+	*x = IngestBatchItemResult{}
+	mi := &file_agentmem_v1_memory_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestBatchItemResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestBatchItemResult) ProtoMessage() {}
+
+func (x *IngestBatchItemResult) ProtoReflect() protoreflect.Message {
+	mi := &file_agentmem_v1_memory_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestBatchItemResult.ProtoReflect.Descriptor instead.
+func (*IngestBatchItemResult) Descriptor() ([]byte, []int) {
+	return file_agentmem_v1_memory_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *IngestBatchItemResult) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *IngestBatchItemResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *IngestBatchItemResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *IngestBatchItemResult) GetTs() int64 {
+	if x != nil {
+		return x.Ts
+	}
+	return 0
+}
+
+func (x *IngestBatchItemResult) GetError() *ErrorResponse {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type BulkIngestResponse struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Results       []*IngestBatchItemResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	Succeeded     int32                    `protobuf:"varint,2,opt,name=succeeded,proto3" json:"succeeded,omitempty"`
+	Failed        int32                    `protobuf:"varint,3,opt,name=failed,proto3" json:"failed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BulkIngestResponse) Reset() {
+	*x = BulkIngestResponse{}
+	mi := &file_agentmem_v1_memory_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BulkIngestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BulkIngestResponse) ProtoMessage() {}
+
+func (x *BulkIngestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agentmem_v1_memory_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BulkIngestResponse.ProtoReflect.Descriptor instead.
+func (*BulkIngestResponse) Descriptor() ([]byte, []int) {
+	return file_agentmem_v1_memory_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BulkIngestResponse) GetResults() []*IngestBatchItemResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *BulkIngestResponse) GetSucceeded() int32 {
+	if x != nil {
+		return x.Succeeded
+	}
+	return 0
+}
+
+func (x *BulkIngestResponse) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+// 订阅条件，均为空时推送全部变更；resync 事件总是推送
+type WatchInput struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OwnerId       string                 `protobuf:"bytes,1,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	ProjectKey    string                 `protobuf:"bytes,2,opt,name=project_key,json=projectKey,proto3" json:"project_key,omitempty"`
+	Kinds         []string               `protobuf:"bytes,3,rep,name=kinds,proto3" json:"kinds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchInput) Reset() {
+	*x = WatchInput{}
+	mi := &file_agentmem_v1_memory_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchInput) ProtoMessage() {}
+
+func (x *WatchInput) ProtoReflect() protoreflect.Message {
+	mi := &file_agentmem_v1_memory_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchInput.ProtoReflect.Descriptor instead.
+func (*WatchInput) Descriptor() ([]byte, []int) {
+	return file_agentmem_v1_memory_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WatchInput) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *WatchInput) GetProjectKey() string {
+	if x != nil {
+		return x.ProjectKey
+	}
+	return ""
+}
+
+func (x *WatchInput) GetKinds() []string {
+	if x != nil {
+		return x.Kinds
+	}
+	return nil
+}
+
+type IndexPath struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Segments      []string               `protobuf:"bytes,1,rep,name=segments,proto3" json:"segments,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndexPath) Reset() {
+	*x = IndexPath{}
+	mi := &file_agentmem_v1_memory_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndexPath) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexPath) ProtoMessage() {}
+
+func (x *IndexPath) ProtoReflect() protoreflect.Message {
+	mi := &file_agentmem_v1_memory_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexPath.ProtoReflect.Descriptor instead.
+func (*IndexPath) Descriptor() ([]byte, []int) {
+	return file_agentmem_v1_memory_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *IndexPath) GetSegments() []string {
+	if x != nil {
+		return x.Segments
+	}
+	return nil
+}
+
+// 记忆变更事件，同资源订阅通知：kind 为 ingest/replace/rollback/delete/resync，origin 为产生变更的实例
+type ChangeEvent struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Kind           string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Origin         string                 `protobuf:"bytes,2,opt,name=origin,proto3" json:"origin,omitempty"`
+	OwnerId        string                 `protobuf:"bytes,3,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	ProjectId      string                 `protobuf:"bytes,4,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	ProjectKey     string                 `protobuf:"bytes,5,opt,name=project_key,json=projectKey,proto3" json:"project_key,omitempty"`
+	ProjectCreated bool                   `protobuf:"varint,6,opt,name=project_created,json=projectCreated,proto3" json:"project_created,omitempty"`
+	MemoryIds      []string               `protobuf:"bytes,7,rep,name=memory_ids,json=memoryIds,proto3" json:"memory_ids,omitempty"`
+	IndexPaths     []*IndexPath           `protobuf:"bytes,8,rep,name=index_paths,json=indexPaths,proto3" json:"index_paths,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ChangeEvent) Reset() {
+	*x = ChangeEvent{}
+	mi := &file_agentmem_v1_memory_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeEvent) ProtoMessage() {}
+
+func (x *ChangeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_agentmem_v1_memory_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeEvent.ProtoReflect.Descriptor instead.
+func (*ChangeEvent) Descriptor() ([]byte, []int) {
+	return file_agentmem_v1_memory_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ChangeEvent) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetOrigin() string {
+	if x != nil {
+		return x.Origin
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetProjectKey() string {
+	if x != nil {
+		return x.ProjectKey
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetProjectCreated() bool {
+	if x != nil {
+		return x.ProjectCreated
+	}
+	return false
+}
+
+func (x *ChangeEvent) GetMemoryIds() []string {
+	if x != nil {
+		return x.MemoryIds
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetIndexPaths() []*IndexPath {
+	if x != nil {
+		return x.IndexPaths
+	}
+	return nil
+}
+
+var File_agentmem_v1_memory_proto protoreflect.FileDescriptor
+
+const file_agentmem_v1_memory_proto_rawDesc = "" +
+	"\n" +
+	"\x18agentmem/v1/memory.proto\x12\vagentmem.v1\x1a\x1cgoogle/protobuf/struct.proto\"\x90\x01\n" +
+	"\n" +
+	"MemoryAxes\x12\x16\n" +
+	"\x06domain\x18\x01 \x03(\tR\x06domain\x12\x14\n" +
+	"\x05stack\x18\x02 \x03(\tR\x05stack\x12\x18\n" +
+	"\aproblem\x18\x03 \x03(\tR\aproblem\x12\x1c\n" +
+	"\tlifecycle\x18\x04 \x03(\tR\tlifecycle\x12\x1c\n" +
+	"\tcomponent\x18\x05 \x03(\tR\tcomponent\"\x9a\x03\n" +
+	"\x11IngestMemoryInput\x12\x19\n" +
+	"\bowner_id\x18\x01 \x01(\tR\aownerId\x12\x1f\n" +
+	"\vproject_key\x18\x02 \x01(\tR\n" +
+	"projectKey\x12!\n" +
+	"\fproject_name\x18\x03 \x01(\tR\vprojectName\x12!\n" +
+	"\fmachine_name\x18\x04 \x01(\tR\vmachineName\x12!\n" +
+	"\fproject_path\x18\x05 \x01(\tR\vprojectPath\x12!\n" +
+	"\fcontent_type\x18\x06 \x01(\tR\vcontentType\x12\x18\n" +
+	"\acontent\x18\a \x01(\tR\acontent\x12\x18\n" +
+	"\asummary\x18\b \x01(\tR\asummary\x12\x12\n" +
+	"\x04tags\x18\t \x03(\tR\x04tags\x12\x19\n" +
+	"\bskip_llm\x18\n" +
+	" \x01(\bR\askipLlm\x12+\n" +
+	"\x04axes\x18\v \x01(\v2\x17.agentmem.v1.MemoryAxesR\x04axes\x12\x1d\n" +
+	"\n" +
+	"index_path\x18\f \x03(\tR\tindexPath\x12\x0e\n" +
+	"\x02ts\x18\r \x01(\x03R\x02ts\"q\n" +
+	"\rErrorResponse\x12\x14\n" +
+	"\x05error\x18\x01 \x01(\tR\x05error\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\tR\x04code\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\"\x97\x01\n" +
+	"\x15IngestBatchItemResult\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x0e\n" +
+	"\x02ts\x18\x04 \x01(\x03R\x02ts\x120\n" +
+	"\x05error\x18\x05 \x01(\v2\x1a.agentmem.v1.ErrorResponseR\x05error\"\x88\x01\n" +
+	"\x12BulkIngestResponse\x12<\n" +
+	"\aresults\x18\x01 \x03(\v2\".agentmem.v1.IngestBatchItemResultR\aresults\x12\x1c\n" +
+	"\tsucceeded\x18\x02 \x01(\x05R\tsucceeded\x12\x16\n" +
+	"\x06failed\x18\x03 \x01(\x05R\x06failed\"^\n" +
+	"\n" +
+	"WatchInput\x12\x19\n" +
+	"\bowner_id\x18\x01 \x01(\tR\aownerId\x12\x1f\n" +
+	"\vproject_key\x18\x02 \x01(\tR\n" +
+	"projectKey\x12\x14\n" +
+	"\x05kinds\x18\x03 \x03(\tR\x05kinds\"'\n" +
+	"\tIndexPath\x12\x1a\n" +
+	"\bsegments\x18\x01 \x03(\tR\bsegments\"\x95\x02\n" +
+	"\vChangeEvent\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x16\n" +
+	"\x06origin\x18\x02 \x01(\tR\x06origin\x12\x19\n" +
+	"\bowner_id\x18\x03 \x01(\tR\aownerId\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x04 \x01(\tR\tprojectId\x12\x1f\n" +
+	"\vproject_key\x18\x05 \x01(\tR\n" +
+	"projectKey\x12'\n" +
+	"\x0fproject_created\x18\x06 \x01(\bR\x0eprojectCreated\x12\x1d\n" +
+	"\n" +
+	"memory_ids\x18\a \x03(\tR\tmemoryIds\x127\n" +
+	"\vindex_paths\x18\b \x03(\v2\x16.agentmem.v1.IndexPathR\n" +
+	"indexPaths2\xc1\x05\n" +
+	"\x06Memory\x12:\n" +
+	"\x06Ingest\x12\x17.google.protobuf.Struct\x1a\x17.google.protobuf.Struct\x12?\n" +
+	"\vIngestBatch\x12\x17.google.protobuf.Struct\x1a\x17.google.protobuf.Struct\x12@\n" +
+	"\fIngestStatus\x12\x17.google.protobuf.Struct\x1a\x17.google.protobuf.Struct\x12:\n" +
+	"\x06Search\x12\x17.google.protobuf.Struct\x1a\x17.google.protobuf.Struct\x127\n" +
+	"\x03Get\x12\x17.google.protobuf.Struct\x1a\x17.google.protobuf.Struct\x12;\n" +
+	"\aContext\x12\x17.google.protobuf.Struct\x1a\x17.google.protobuf.Struct\x12<\n" +
+	"\bTimeline\x12\x17.google.protobuf.Struct\x1a\x17.google.protobuf.Struct\x12;\n" +
+	"\aSimilar\x12\x17.google.protobuf.Struct\x1a\x17.google.protobuf.Struct\x12<\n" +
+	"\bFeedback\x12\x17.google.protobuf.Struct\x1a\x17.google.protobuf.Struct\x12O\n" +
+	"\n" +
+	"BulkIngest\x12\x1e.agentmem.v1.IngestMemoryInput\x1a\x1f.agentmem.v1.BulkIngestResponse(\x01\x12<\n" +
+	"\x05Watch\x12\x17.agentmem.v1.WatchInput\x1a\x18.agentmem.v1.ChangeEvent0\x01B,Z*agent-mem-mcp/proto/agentmem/v1;agentmemv1b\x06proto3"
+
+var (
+	file_agentmem_v1_memory_proto_rawDescOnce sync.Once
+	file_agentmem_v1_memory_proto_rawDescData []byte
+)
+
+func file_agentmem_v1_memory_proto_rawDescGZIP() []byte {
+	file_agentmem_v1_memory_proto_rawDescOnce.Do(func() {
+		file_agentmem_v1_memory_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_agentmem_v1_memory_proto_rawDesc), len(file_agentmem_v1_memory_proto_rawDesc)))
+	})
+	return file_agentmem_v1_memory_proto_rawDescData
+}
+
+var file_agentmem_v1_memory_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_agentmem_v1_memory_proto_goTypes = []any{
+	(*MemoryAxes)(nil),            // 0: agentmem.v1.MemoryAxes
+	(*IngestMemoryInput)(nil),     // 1: agentmem.v1.IngestMemoryInput
+	(*ErrorResponse)(nil),         // 2: agentmem.v1.ErrorResponse
+	(*IngestBatchItemResult)(nil), // 3: agentmem.v1.IngestBatchItemResult
+	(*BulkIngestResponse)(nil),    // 4: agentmem.v1.BulkIngestResponse
+	(*WatchInput)(nil),            // 5: agentmem.v1.WatchInput
+	(*IndexPath)(nil),             // 6: agentmem.v1.IndexPath
+	(*ChangeEvent)(nil),           // 7: agentmem.v1.ChangeEvent
+	(*structpb.Struct)(nil),       // 8: google.protobuf.Struct
+}
+var file_agentmem_v1_memory_proto_depIdxs = []int32{
+	0,  // 0: agentmem.v1.IngestMemoryInput.axes:type_name -> agentmem.v1.MemoryAxes
+	2,  // 1: agentmem.v1.IngestBatchItemResult.error:type_name -> agentmem.v1.ErrorResponse
+	3,  // 2: agentmem.v1.BulkIngestResponse.results:type_name -> agentmem.v1.IngestBatchItemResult
+	6,  // 3: agentmem.v1.ChangeEvent.index_paths:type_name -> agentmem.v1.IndexPath
+	8,  // 4: agentmem.v1.Memory.Ingest:input_type -> google.protobuf.Struct
+	8,  // 5: agentmem.v1.Memory.IngestBatch:input_type -> google.protobuf.Struct
+	8,  // 6: agentmem.v1.Memory.IngestStatus:input_type -> google.protobuf.Struct
+	8,  // 7: agentmem.v1.Memory.Search:input_type -> google.protobuf.Struct
+	8,  // 8: agentmem.v1.Memory.Get:input_type -> google.protobuf.Struct
+	8,  // 9: agentmem.v1.Memory.Context:input_type -> google.protobuf.Struct
+	8,  // 10: agentmem.v1.Memory.Timeline:input_type -> google.protobuf.Struct
+	8,  // 11: agentmem.v1.Memory.Similar:input_type -> google.protobuf.Struct
+	8,  // 12: agentmem.v1.Memory.Feedback:input_type -> google.protobuf.Struct
+	1,  // 13: agentmem.v1.Memory.BulkIngest:input_type -> agentmem.v1.IngestMemoryInput
+	5,  // 14: agentmem.v1.Memory.Watch:input_type -> agentmem.v1.WatchInput
+	8,  // 15: agentmem.v1.Memory.Ingest:output_type -> google.protobuf.Struct
+	8,  // 16: agentmem.v1.Memory.IngestBatch:output_type -> google.protobuf.Struct
+	8,  // 17: agentmem.v1.Memory.IngestStatus:output_type -> google.protobuf.Struct
+	8,  // 18: agentmem.v1.Memory.Search:output_type -> google.protobuf.Struct
+	8,  // 19: agentmem.v1.Memory.Get:output_type -> google.protobuf.Struct
+	8,  // 20: agentmem.v1.Memory.Context:output_type -> google.protobuf.Struct
+	8,  // 21: agentmem.v1.Memory.Timeline:output_type -> google.protobuf.Struct
+	8,  // 22: agentmem.v1.Memory.Similar:output_type -> google.protobuf.Struct
+	8,  // 23: agentmem.v1.Memory.Feedback:output_type -> google.protobuf.Struct
+	4,  // 24: agentmem.v1.Memory.BulkIngest:output_type -> agentmem.v1.BulkIngestResponse
+	7,  // 25: agentmem.v1.Memory.Watch:output_type -> agentmem.v1.ChangeEvent
+	15, // [15:26] is the sub-list for method output_type
+	4,  // [4:15] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_agentmem_v1_memory_proto_init() }
+func file_agentmem_v1_memory_proto_init() {
+	if File_agentmem_v1_memory_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_agentmem_v1_memory_proto_rawDesc), len(file_agentmem_v1_memory_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_agentmem_v1_memory_proto_goTypes,
+		DependencyIndexes: file_agentmem_v1_memory_proto_depIdxs,
+		MessageInfos:      file_agentmem_v1_memory_proto_msgTypes,
+	}.Build()
+	File_agentmem_v1_memory_proto = out.File
+	file_agentmem_v1_memory_proto_goTypes = nil
+	file_agentmem_v1_memory_proto_depIdxs = nil
+}
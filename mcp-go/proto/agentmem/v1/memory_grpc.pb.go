@@ -0,0 +1,532 @@
+// agent-mem gRPC 服务定义（服务端：mcp-go/cmd/agent-mem-mcp/grpc_server.go，启动参数 -grpc-port）。
+//
+// 一元调用的请求与响应使用 google.protobuf.Struct，字段与 REST 接口一致，结构定义见 /openapi.json 的 components.schemas；
+// 服务端按 schema 严格校验（未知字段返回 INVALID_ARGUMENT，ErrorInfo.reason 为 ERR_INVALID_FIELD）。
+// 流式调用（BulkIngest、Watch）使用下方的类型化消息，字段与对应结构体的 JSON 字段同名。
+// 错误以 google.rpc.ErrorInfo 详情返回：reason 为错误码，domain 为 agent-mem，metadata.error 为 error key。
+// 鉴权：metadata authorization: Bearer <token> 或 x-agent-mem-token: <token>（令牌同 AGENT_MEM_HTTP_TOKEN）。
+//
+// Go 代码（memory.pb.go、memory_grpc.pb.go）由本文件生成，修改后在 mcp-go/proto 目录执行：
+//   protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative agentmem/v1/memory.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: agentmem/v1/memory.proto
+
+package agentmemv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Memory_Ingest_FullMethodName       = "/agentmem.v1.Memory/Ingest"
+	Memory_IngestBatch_FullMethodName  = "/agentmem.v1.Memory/IngestBatch"
+	Memory_IngestStatus_FullMethodName = "/agentmem.v1.Memory/IngestStatus"
+	Memory_Search_FullMethodName       = "/agentmem.v1.Memory/Search"
+	Memory_Get_FullMethodName          = "/agentmem.v1.Memory/Get"
+	Memory_Context_FullMethodName      = "/agentmem.v1.Memory/Context"
+	Memory_Timeline_FullMethodName     = "/agentmem.v1.Memory/Timeline"
+	Memory_Similar_FullMethodName      = "/agentmem.v1.Memory/Similar"
+	Memory_Feedback_FullMethodName     = "/agentmem.v1.Memory/Feedback"
+	Memory_BulkIngest_FullMethodName   = "/agentmem.v1.Memory/BulkIngest"
+	Memory_Watch_FullMethodName        = "/agentmem.v1.Memory/Watch"
+)
+
+// MemoryClient is the client API for Memory service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MemoryClient interface {
+	// IngestMemoryInput -> IngestMemoryOutput
+	Ingest(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	// IngestBatchInput -> IngestBatchOutput
+	IngestBatch(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	// IngestStatusInput -> IngestStatusResponse
+	IngestStatus(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	// SearchInput -> SearchResponse
+	Search(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	// GetMemoriesInput -> GetMemoriesResponse
+	Get(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	// ContextInput -> ContextPack
+	Context(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	// TimelineInput -> TimelineResponse
+	Timeline(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	// SimilarInput -> SearchResponse
+	Similar(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	// FeedbackInput -> FeedbackOutput
+	Feedback(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	// 客户端流：逐条发送待写入记忆，结束发送后返回每条的结果；服务端按批（每批最多 100 条）写入，单条失败只记入该条结果
+	BulkIngest(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[IngestMemoryInput, BulkIngestResponse], error)
+	// 服务端流：按 owner/项目/类型订阅变更事件；积压时丢弃并补发 kind=resync
+	Watch(ctx context.Context, in *WatchInput, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChangeEvent], error)
+}
+
+type memoryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMemoryClient(cc grpc.ClientConnInterface) MemoryClient {
+	return &memoryClient{cc}
+}
+
+func (c *memoryClient) Ingest(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(structpb.Struct)
+	err := c.cc.Invoke(ctx, Memory_Ingest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memoryClient) IngestBatch(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(structpb.Struct)
+	err := c.cc.Invoke(ctx, Memory_IngestBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memoryClient) IngestStatus(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(structpb.Struct)
+	err := c.cc.Invoke(ctx, Memory_IngestStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memoryClient) Search(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(structpb.Struct)
+	err := c.cc.Invoke(ctx, Memory_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memoryClient) Get(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(structpb.Struct)
+	err := c.cc.Invoke(ctx, Memory_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memoryClient) Context(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(structpb.Struct)
+	err := c.cc.Invoke(ctx, Memory_Context_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memoryClient) Timeline(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(structpb.Struct)
+	err := c.cc.Invoke(ctx, Memory_Timeline_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memoryClient) Similar(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(structpb.Struct)
+	err := c.cc.Invoke(ctx, Memory_Similar_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memoryClient) Feedback(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(structpb.Struct)
+	err := c.cc.Invoke(ctx, Memory_Feedback_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memoryClient) BulkIngest(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[IngestMemoryInput, BulkIngestResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Memory_ServiceDesc.Streams[0], Memory_BulkIngest_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[IngestMemoryInput, BulkIngestResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Memory_BulkIngestClient = grpc.ClientStreamingClient[IngestMemoryInput, BulkIngestResponse]
+
+func (c *memoryClient) Watch(ctx context.Context, in *WatchInput, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChangeEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Memory_ServiceDesc.Streams[1], Memory_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchInput, ChangeEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Memory_WatchClient = grpc.ServerStreamingClient[ChangeEvent]
+
+// MemoryServer is the server API for Memory service.
+// All implementations must embed UnimplementedMemoryServer
+// for forward compatibility.
+type MemoryServer interface {
+	// IngestMemoryInput -> IngestMemoryOutput
+	Ingest(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	// IngestBatchInput -> IngestBatchOutput
+	IngestBatch(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	// IngestStatusInput -> IngestStatusResponse
+	IngestStatus(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	// SearchInput -> SearchResponse
+	Search(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	// GetMemoriesInput -> GetMemoriesResponse
+	Get(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	// ContextInput -> ContextPack
+	Context(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	// TimelineInput -> TimelineResponse
+	Timeline(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	// SimilarInput -> SearchResponse
+	Similar(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	// FeedbackInput -> FeedbackOutput
+	Feedback(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	// 客户端流：逐条发送待写入记忆，结束发送后返回每条的结果；服务端按批（每批最多 100 条）写入，单条失败只记入该条结果
+	BulkIngest(grpc.ClientStreamingServer[IngestMemoryInput, BulkIngestResponse]) error
+	// 服务端流：按 owner/项目/类型订阅变更事件；积压时丢弃并补发 kind=resync
+	Watch(*WatchInput, grpc.ServerStreamingServer[ChangeEvent]) error
+	mustEmbedUnimplementedMemoryServer()
+}
+
+// UnimplementedMemoryServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMemoryServer struct{}
+
+func (UnimplementedMemoryServer) Ingest(context.Context, *structpb.Struct) (*structpb.Struct, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ingest not implemented")
+}
+func (UnimplementedMemoryServer) IngestBatch(context.Context, *structpb.Struct) (*structpb.Struct, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IngestBatch not implemented")
+}
+func (UnimplementedMemoryServer) IngestStatus(context.Context, *structpb.Struct) (*structpb.Struct, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IngestStatus not implemented")
+}
+func (UnimplementedMemoryServer) Search(context.Context, *structpb.Struct) (*structpb.Struct, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedMemoryServer) Get(context.Context, *structpb.Struct) (*structpb.Struct, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedMemoryServer) Context(context.Context, *structpb.Struct) (*structpb.Struct, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Context not implemented")
+}
+func (UnimplementedMemoryServer) Timeline(context.Context, *structpb.Struct) (*structpb.Struct, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Timeline not implemented")
+}
+func (UnimplementedMemoryServer) Similar(context.Context, *structpb.Struct) (*structpb.Struct, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Similar not implemented")
+}
+func (UnimplementedMemoryServer) Feedback(context.Context, *structpb.Struct) (*structpb.Struct, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Feedback not implemented")
+}
+func (UnimplementedMemoryServer) BulkIngest(grpc.ClientStreamingServer[IngestMemoryInput, BulkIngestResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method BulkIngest not implemented")
+}
+func (UnimplementedMemoryServer) Watch(*WatchInput, grpc.ServerStreamingServer[ChangeEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedMemoryServer) mustEmbedUnimplementedMemoryServer() {}
+func (UnimplementedMemoryServer) testEmbeddedByValue()                {}
+
+// UnsafeMemoryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MemoryServer will
+// result in compilation errors.
+type UnsafeMemoryServer interface {
+	mustEmbedUnimplementedMemoryServer()
+}
+
+func RegisterMemoryServer(s grpc.ServiceRegistrar, srv MemoryServer) {
+	// If the following call pancis, it indicates UnimplementedMemoryServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Memory_ServiceDesc, srv)
+}
+
+func _Memory_Ingest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemoryServer).Ingest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Memory_Ingest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemoryServer).Ingest(ctx, req.(*structpb.Struct))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Memory_IngestBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemoryServer).IngestBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Memory_IngestBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemoryServer).IngestBatch(ctx, req.(*structpb.Struct))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Memory_IngestStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemoryServer).IngestStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Memory_IngestStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemoryServer).IngestStatus(ctx, req.(*structpb.Struct))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Memory_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemoryServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Memory_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemoryServer).Search(ctx, req.(*structpb.Struct))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Memory_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemoryServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Memory_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemoryServer).Get(ctx, req.(*structpb.Struct))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Memory_Context_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemoryServer).Context(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Memory_Context_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemoryServer).Context(ctx, req.(*structpb.Struct))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Memory_Timeline_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemoryServer).Timeline(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Memory_Timeline_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemoryServer).Timeline(ctx, req.(*structpb.Struct))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Memory_Similar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemoryServer).Similar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Memory_Similar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemoryServer).Similar(ctx, req.(*structpb.Struct))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Memory_Feedback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemoryServer).Feedback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Memory_Feedback_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemoryServer).Feedback(ctx, req.(*structpb.Struct))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Memory_BulkIngest_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MemoryServer).BulkIngest(&grpc.GenericServerStream[IngestMemoryInput, BulkIngestResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Memory_BulkIngestServer = grpc.ClientStreamingServer[IngestMemoryInput, BulkIngestResponse]
+
+func _Memory_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchInput)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MemoryServer).Watch(m, &grpc.GenericServerStream[WatchInput, ChangeEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Memory_WatchServer = grpc.ServerStreamingServer[ChangeEvent]
+
+// Memory_ServiceDesc is the grpc.ServiceDesc for Memory service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Memory_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "agentmem.v1.Memory",
+	HandlerType: (*MemoryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ingest",
+			Handler:    _Memory_Ingest_Handler,
+		},
+		{
+			MethodName: "IngestBatch",
+			Handler:    _Memory_IngestBatch_Handler,
+		},
+		{
+			MethodName: "IngestStatus",
+			Handler:    _Memory_IngestStatus_Handler,
+		},
+		{
+			MethodName: "Search",
+			Handler:    _Memory_Search_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _Memory_Get_Handler,
+		},
+		{
+			MethodName: "Context",
+			Handler:    _Memory_Context_Handler,
+		},
+		{
+			MethodName: "Timeline",
+			Handler:    _Memory_Timeline_Handler,
+		},
+		{
+			MethodName: "Similar",
+			Handler:    _Memory_Similar_Handler,
+		},
+		{
+			MethodName: "Feedback",
+			Handler:    _Memory_Feedback_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BulkIngest",
+			Handler:       _Memory_BulkIngest_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _Memory_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "agentmem/v1/memory.proto",
+}